@@ -0,0 +1,49 @@
+package graphql
+
+import (
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+)
+
+// Allowlist holds the operations a handler accepts, by their QueryHash, so that a query a client
+// formats differently than when it was allowed is still accepted. An Allowlist is used concurrently.
+type Allowlist interface {
+	Allowed(hash string) bool
+}
+
+// WithAllowlist makes the handler reject the queries list does not hold, before they are parsed,
+// with the error OperationNotAllowed. Persisted queries are checked once their query is known.
+//
+//   graphql.HTTPHandler(schema, graphql.WithAllowlist(graphql.NewAllowlist(clientQueries...)))
+func WithAllowlist(list Allowlist) HandlerOption {
+	return func(h *Handler) {
+		h.Allowlist = list
+	}
+}
+
+// NewAllowlist returns an Allowlist holding queries, such as the operations extracted from the
+// clients when they are built.
+func NewAllowlist(queries ...string) Allowlist {
+	list := make(allowlist, len(queries))
+	for _, query := range queries {
+		list[QueryHash(query)] = true
+	}
+	return list
+}
+
+type allowlist map[string]bool
+
+func (l allowlist) Allowed(hash string) bool {
+	return l[hash]
+}
+
+// allowedQuery checks that list holds the query of param.
+func allowedQuery(list Allowlist, param execution.Params) *errors.GraphQLError {
+	if list.Allowed(QueryHash(param.Query)) {
+		return nil
+	}
+	return &errors.GraphQLError{
+		Message:    "OperationNotAllowed",
+		Extensions: map[string]interface{}{"code": "OPERATION_NOT_ALLOWED"},
+	}
+}
@@ -0,0 +1,53 @@
+package graphql_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler_Allowlist(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	calls := 0
+	build.Query().FieldFunc("hello", func(args struct {
+		Name *string `graphql:"name"`
+	}) string {
+		calls++
+		return "world"
+	})
+	allowed := `query Hello($name: String) { hello(name: $name) }`
+	handler := graphql.HTTPHandler(build.MustBuild(), graphql.WithAllowlist(graphql.NewAllowlist(allowed)),
+		graphql.WithPersistedQueries(graphql.NewQueryCache(10)))
+	post := func(body string) string {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+
+	// the query is allowed whatever its formatting
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, post(fmt.Sprintf(`{"query": %q, "variables": {"name": "a"}}`, allowed)))
+	reformatted := "query Hello(\n  $name: String\n) {\n  # greet\n  hello(name: $name)\n}"
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, post(fmt.Sprintf(`{"query": %q, "variables": {"name": "a"}}`, reformatted)))
+	assert.Equal(t, 2, calls)
+
+	notAllowed := `{"errors": [{"message": "OperationNotAllowed", "extensions": {"code": "OPERATION_NOT_ALLOWED"}}]}`
+	assert.JSONEq(t, notAllowed, post(`{"query": "{ hello }"}`))
+	assert.JSONEq(t, notAllowed, post(`{"query": "{ hello"}`))
+	assert.Equal(t, 2, calls)
+
+	// persisted queries are checked with their query
+	query := `{ hello(name: "a") }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := fmt.Sprintf(`{"persistedQuery": {"version": 1, "sha256Hash": %q}}`, hex.EncodeToString(sum[:]))
+	assert.JSONEq(t, notAllowed, post(fmt.Sprintf(`{"query": %q, "extensions": %s}`, query, extensions)))
+	assert.JSONEq(t, notAllowed, post(fmt.Sprintf(`{"extensions": %s}`, extensions)))
+	assert.Equal(t, 2, calls)
+}
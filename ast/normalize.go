@@ -0,0 +1,222 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Normalize prints the executable definitions of doc in a canonical form, so that documents
+// differing only by formatting print the same:
+//
+//   - tokens are separated by a single space, commas and comments are dropped
+//   - an anonymous query without variables and directives is printed in the shorthand form { ... }
+//   - arguments of fields and directives, and the fields of object values, are sorted by name
+//   - strings, block strings included, are printed as quoted strings
+//
+// Definitions, selections, directives and list items keep their order, as it is meaningful.
+// Type system definitions are not printed.
+func Normalize(doc *Document) string {
+	var buf bytes.Buffer
+	for _, definition := range doc.Definition {
+		switch definition := definition.(type) {
+		case *OperationDefinition:
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			printOperation(&buf, definition)
+		case *FragmentDefinition:
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString("fragment ")
+			buf.WriteString(definition.Name.Name)
+			printVariableDefinitions(&buf, definition.VariableDefinitions)
+			buf.WriteString(" on ")
+			buf.WriteString(definition.TypeCondition.Name.Name)
+			printDirectives(&buf, definition.Directives)
+			buf.WriteByte(' ')
+			printSelectionSet(&buf, definition.SelectionSet)
+		}
+	}
+	return buf.String()
+}
+
+func printOperation(buf *bytes.Buffer, op *OperationDefinition) {
+	if op.Operation != Query || op.Name != nil || len(op.Vars) > 0 || len(op.Directives) > 0 {
+		buf.WriteString(strings.ToLower(string(op.Operation)))
+		if op.Name != nil {
+			buf.WriteByte(' ')
+			buf.WriteString(op.Name.Name)
+		}
+		printVariableDefinitions(buf, op.Vars)
+		printDirectives(buf, op.Directives)
+		buf.WriteByte(' ')
+	}
+	printSelectionSet(buf, op.SelectionSet)
+}
+
+func printVariableDefinitions(buf *bytes.Buffer, vars []*VariableDefinition) {
+	if len(vars) == 0 {
+		return
+	}
+	buf.WriteByte('(')
+	for i, v := range vars {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('$')
+		buf.WriteString(v.Var.Name.Name)
+		buf.WriteString(": ")
+		buf.WriteString(v.Type.String())
+		if v.DefaultValue != nil {
+			buf.WriteString(" = ")
+			printValue(buf, v.DefaultValue)
+		}
+		printDirectives(buf, v.Directives)
+	}
+	buf.WriteByte(')')
+}
+
+func printSelectionSet(buf *bytes.Buffer, selectionSet *SelectionSet) {
+	buf.WriteByte('{')
+	for _, selection := range selectionSet.Selections {
+		buf.WriteByte(' ')
+		switch selection := selection.(type) {
+		case *Field:
+			if selection.Alias != nil && selection.Alias.Name != selection.Name.Name {
+				buf.WriteString(selection.Alias.Name)
+				buf.WriteString(": ")
+			}
+			buf.WriteString(selection.Name.Name)
+			printArguments(buf, selection.Arguments)
+			printDirectives(buf, selection.Directives)
+			if selection.SelectionSet != nil {
+				buf.WriteByte(' ')
+				printSelectionSet(buf, selection.SelectionSet)
+			}
+		case *FragmentSpread:
+			buf.WriteString("...")
+			buf.WriteString(selection.Name.Name)
+			printDirectives(buf, selection.Directives)
+		case *InlineFragment:
+			buf.WriteString("...")
+			if selection.TypeCondition != nil {
+				buf.WriteString(" on ")
+				buf.WriteString(selection.TypeCondition.Name.Name)
+			}
+			printDirectives(buf, selection.Directives)
+			buf.WriteByte(' ')
+			printSelectionSet(buf, selection.SelectionSet)
+		}
+	}
+	buf.WriteString(" }")
+}
+
+func printArguments(buf *bytes.Buffer, args []*Argument) {
+	if len(args) == 0 {
+		return
+	}
+	sorted := make([]*Argument, len(args))
+	copy(sorted, args)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name.Name < sorted[j].Name.Name })
+	buf.WriteByte('(')
+	for i, arg := range sorted {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(arg.Name.Name)
+		buf.WriteString(": ")
+		printValue(buf, arg.Value)
+	}
+	buf.WriteByte(')')
+}
+
+func printDirectives(buf *bytes.Buffer, directives []*Directive) {
+	for _, directive := range directives {
+		buf.WriteString(" @")
+		buf.WriteString(directive.Name.Name)
+		printArguments(buf, directive.Args)
+	}
+}
+
+func printValue(buf *bytes.Buffer, value Value) {
+	switch value := value.(type) {
+	case *Variable:
+		buf.WriteByte('$')
+		buf.WriteString(value.Name.Name)
+	case *IntValue:
+		buf.WriteString(value.Value)
+	case *FloatValue:
+		buf.WriteString(value.Value)
+	case *StringValue:
+		buf.WriteString(Quote(value.Value))
+	case *BooleanValue:
+		if value.Value {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case *NullValue:
+		buf.WriteString("null")
+	case *EnumValue:
+		buf.WriteString(value.Value)
+	case *ListValue:
+		buf.WriteByte('[')
+		for i, item := range value.Values {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			printValue(buf, item)
+		}
+		buf.WriteByte(']')
+	case *ObjectValue:
+		fields := make([]*ObjectField, len(value.Fields))
+		copy(fields, value.Fields)
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name.Name.Name < fields[j].Name.Name.Name })
+		buf.WriteByte('{')
+		for i, field := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(field.Name.Name.Name)
+			buf.WriteString(": ")
+			printValue(buf, field.Value)
+		}
+		buf.WriteString(" }")
+	}
+}
+
+// Quote quotes s as a GraphQL string, escaping quotes, backslashes and control characters.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PrintValue prints value as it is written in a document, such as ["foo", "bar"] or {a: 1, b: $b},
+// for messages about the values of a query.
+func PrintValue(value Value) string {
+	switch value := value.(type) {
+	case *Variable:
+		return "$" + value.Name.Name
+	case *IntValue:
+		return value.Value
+	case *FloatValue:
+		return value.Value
+	case *StringValue:
+		return Quote(value.Value)
+	case *BooleanValue:
+		return strconv.FormatBool(value.Value)
+	case *NullValue:
+		return "null"
+	case *EnumValue:
+		return value.Value
+	case *ListValue:
+		items := make([]string, len(value.Values))
+		for i, item := range value.Values {
+			items[i] = PrintValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *ObjectValue:
+		fields := make([]string, len(value.Fields))
+		for i, field := range value.Fields {
+			fields[i] = field.Name.Name.Name + ": " + PrintValue(field.Value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return ""
+}
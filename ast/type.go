@@ -78,3 +78,19 @@ func (n *NonNull) Location() errors.Location {
 func (n *NonNull) String() string {
 	return fmt.Sprintf("%s!", n.Type.String())
 }
+
+// NamedType returns the named type of t, unwrapping its lists and non-null types.
+func NamedType(t Type) *Named {
+	for {
+		switch typ := t.(type) {
+		case *NonNull:
+			t = typ.Type
+		case *List:
+			t = typ.Type
+		case *Named:
+			return typ
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WithBatching makes the handler answer the POST requests whose JSON body is an array of at most max
+// operations, as clients batching their operations send them, with the JSON array of their responses.
+// The operations run one after the other, with the Context of the request. The responses of a batch
+// are not written by the Encoder, and never get an ETag. Larger batches are answered with 400.
+func WithBatching(max int) HandlerOption {
+	return func(h *Handler) {
+		h.MaxBatch = max
+	}
+}
+
+// WithLoaders attaches the loaders returned by loaders to the context of every operation, see
+// dataloader.Attach, so that the operations of a batch request do not share the values they load.
+// loaders must return new loaders for every call.
+func WithLoaders(loaders func(ctx context.Context) map[string]*dataloader.Loader) HandlerOption {
+	return func(h *Handler) {
+		h.Loaders = loaders
+	}
+}
+
+// WithSharedLoaders makes the queries of a batch request share the loaders of WithLoaders created with
+// dataloader.BatchSafe, so that a key the queries all load is loaded once for the batch. The other
+// loaders stay those of each operation. The errors of the shared loaders are not: the keys which failed
+// are loaded again by the next operation, so that every error is that of the operation it is
+// reported for. A mutation gets loaders of its own, and the queries following it load their keys
+// again, as the values loaded before it may have changed.
+func WithSharedLoaders(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.SharedLoaders = enabled
+	}
+}
+
+// loaderScope holds the batch-safe loaders shared by the queries of a batch request.
+type loaderScope struct {
+	shared map[string]*dataloader.Loader
+}
+
+// clearErrors removes the errors of the shared loaders once an operation completed.
+func (s *loaderScope) clearErrors() {
+	if s == nil {
+		return
+	}
+	for _, loader := range s.shared {
+		loader.ClearErrors()
+	}
+}
+
+// decodeBody decodes the JSON body read from r into param or, when max is positive and the body is an
+// array, into the params of the operations of a batch request, which it returns.
+func decodeBody(r io.Reader, param *execution.Params, strict bool, max int) ([]execution.Params, error) {
+	if max <= 0 {
+		return nil, decodeJSON(r, param, strict)
+	}
+	reader := bufio.NewReader(r)
+	for {
+		next, err := reader.Peek(1)
+		if err != nil || next[0] != ' ' && next[0] != '\t' && next[0] != '\r' && next[0] != '\n' {
+			break
+		}
+		reader.ReadByte()
+	}
+	if next, err := reader.Peek(1); err != nil || next[0] != '[' {
+		return nil, decodeJSON(reader, param, strict)
+	}
+	var batch []execution.Params
+	if err := decodeJSON(reader, &batch, strict); err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("the batch has no operation")
+	}
+	if len(batch) > max {
+		return nil, fmt.Errorf("the batch has %d operations, more than %d", len(batch), max)
+	}
+	for i := range batch {
+		batch[i].Context = param.Context
+	}
+	return batch, nil
+}
+
+// executeBatch runs the operations of a batch request in order, and answers with the array of their
+// responses.
+func (h *Handler) executeBatch(ctx *Context, variant *variant, batch []execution.Params) {
+	var scope *loaderScope
+	if h.SharedLoaders {
+		scope = &loaderScope{}
+	}
+	// the errors of an operation are added to those of the Context once the batch completed, as they
+	// are its error otherwise, which would stop the following operations
+	requestErrs := ctx.Error
+	var errs errors.MultiError
+	responses := make([]*Response, len(batch))
+	for i, param := range batch {
+		start := time.Now()
+		var loaders map[string]dataloader.Stats
+		var slowFields []execution.FieldTiming
+		ctx.Error = requestErrs
+		responses[i], loaders, slowFields = h.run(ctx, variant, param, scope)
+		errs = append(errs, responses[i].Errors...)
+		scope.clearErrors()
+		h.logSlowQuery(ctx, variant.schema, param, time.Since(start), loaders, slowFields)
+	}
+	ctx.Error = append(requestErrs, errs...)
+	if headers, ok := ctx.Value(responseHeadersKey{}).(*responseHeaders); ok {
+		headers.writeTo(ctx.Writer)
+	}
+	encoded, err := json.Marshal(responses)
+	if err != nil {
+		ctx.ServerError(err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	ctx.Writer.WriteHeader(http.StatusOK)
+	ctx.Writer.Write(encoded)
+}
+
+// attachLoaders returns ctx with the loaders of WithLoaders attached for an operation of type operation,
+// and the stats the loaders it shares with the queries of scope before it had.
+func (h *Handler) attachLoaders(ctx context.Context, scope *loaderScope, operation ast.OperationType) (
+	context.Context, map[string]dataloader.Stats) {
+	if h.Loaders == nil {
+		return ctx, nil
+	}
+	loaders := h.Loaders(ctx)
+	if scope == nil {
+		return dataloader.Attach(ctx, loaders), nil
+	}
+	if operation != ast.Query {
+		// the values loaded before a mutation may be stale after it
+		scope.shared = nil
+		return dataloader.Attach(ctx, loaders), nil
+	}
+	if scope.shared == nil {
+		scope.shared = map[string]*dataloader.Loader{}
+	}
+	attached := make(map[string]*dataloader.Loader, len(loaders))
+	var before map[string]dataloader.Stats
+	for name, loader := range loaders {
+		if loader.BatchSafe() {
+			if shared, ok := scope.shared[name]; ok {
+				loader = shared
+			} else {
+				scope.shared[name] = loader
+			}
+			if before == nil {
+				before = map[string]dataloader.Stats{}
+			}
+			before[name] = loader.Stats()
+		}
+		attached[name] = loader
+	}
+	return dataloader.Attach(ctx, attached), before
+}
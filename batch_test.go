@@ -0,0 +1,160 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPHandler_Batching(t *testing.T) {
+	type Person struct {
+		ID int `graphql:"id"`
+	}
+	var peopleCalls, visitCalls int32
+	loaders := func(ctx context.Context) map[string]*dataloader.Loader {
+		return map[string]*dataloader.Loader{
+			"people": dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+				atomic.AddInt32(&peopleCalls, 1)
+				values := make([]interface{}, len(keys))
+				for i, key := range keys {
+					if key.(int) == 0 {
+						values[i] = fmt.Errorf("no person 0")
+					} else {
+						values[i] = fmt.Sprintf("p%d", key)
+					}
+				}
+				return values, nil
+			}, dataloader.BatchSafe()),
+			"visits": dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+				calls := atomic.AddInt32(&visitCalls, 1)
+				values := make([]interface{}, len(keys))
+				for i := range keys {
+					values[i] = int(calls)
+				}
+				return values, nil
+			}),
+		}
+	}
+	load := func(ctx context.Context, name string, key int) func() (interface{}, error) {
+		return dataloader.For(ctx, name).Load(ctx, key)
+	}
+	build := schemabuilder.NewSchema()
+	person := build.Object("Person", Person{})
+	person.FieldFunc("name", func(ctx context.Context, p Person) func() (string, error) {
+		thunk := load(ctx, "people", p.ID)
+		return func() (string, error) {
+			name, err := thunk()
+			if err != nil {
+				return "", err
+			}
+			return name.(string), nil
+		}
+	})
+	person.FieldFunc("visits", func(ctx context.Context, p Person) func() (int, error) {
+		thunk := load(ctx, "visits", p.ID)
+		return func() (int, error) {
+			visits, err := thunk()
+			if err != nil {
+				return 0, err
+			}
+			return visits.(int), nil
+		}
+	})
+	build.Query().FieldFunc("person", func(args struct {
+		ID int `graphql:"id"`
+	}) Person {
+		return Person{ID: args.ID}
+	})
+	build.Mutation().FieldFunc("rename", func(args struct {
+		ID int `graphql:"id"`
+	}) Person {
+		return Person{ID: args.ID}
+	})
+	schema := build.MustBuild()
+	post := func(handler http.Handler, body string) (int, string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return recorder.Code, recorder.Body.String()
+	}
+	batch := func(queries ...string) string {
+		operations := make([]string, len(queries))
+		for i, query := range queries {
+			operations[i] = fmt.Sprintf(`{"query": %q}`, query)
+		}
+		return "[" + strings.Join(operations, ", ") + "]"
+	}
+	const query = "{ person(id: 1) { name visits } }"
+	reset := func() {
+		atomic.StoreInt32(&peopleCalls, 0)
+		atomic.StoreInt32(&visitCalls, 0)
+	}
+
+	// the batch-safe loader makes one backend call for the batch, the other loaders one per operation
+	handler := graphql.HTTPHandler(schema, graphql.WithBatching(10), graphql.WithLoaders(loaders),
+		graphql.WithSharedLoaders(true), graphql.WithLoaderStats(true))
+	code, body := post(handler, batch(query, query, query, query, query))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peopleCalls))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&visitCalls))
+	assert.JSONEq(t, `[
+		{"data": {"person": {"name": "p1", "visits": 1}}, "extensions": {"loaders": {
+			"people": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0},
+			"visits": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0}}}},
+		{"data": {"person": {"name": "p1", "visits": 2}}, "extensions": {"loaders": {
+			"people": {"loads": 1, "hits": 1, "batches": 0, "keys": 0, "averageBatchSize": 0, "hitRatio": 1},
+			"visits": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0}}}},
+		{"data": {"person": {"name": "p1", "visits": 3}}, "extensions": {"loaders": {
+			"people": {"loads": 1, "hits": 1, "batches": 0, "keys": 0, "averageBatchSize": 0, "hitRatio": 1},
+			"visits": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0}}}},
+		{"data": {"person": {"name": "p1", "visits": 4}}, "extensions": {"loaders": {
+			"people": {"loads": 1, "hits": 1, "batches": 0, "keys": 0, "averageBatchSize": 0, "hitRatio": 1},
+			"visits": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0}}}},
+		{"data": {"person": {"name": "p1", "visits": 5}}, "extensions": {"loaders": {
+			"people": {"loads": 1, "hits": 1, "batches": 0, "keys": 0, "averageBatchSize": 0, "hitRatio": 1},
+			"visits": {"loads": 1, "hits": 0, "batches": 1, "keys": 1, "averageBatchSize": 1, "hitRatio": 0}}}}
+	]`, body)
+
+	// a key which failed is loaded again by the next operation, which reports its own error
+	reset()
+	code, body = post(handler, batch("{ person(id: 0) { name } }", "{ a: person(id: 0) { name } }"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peopleCalls))
+	assert.Contains(t, body, `"path":["person","name"]`)
+	assert.Contains(t, body, `"path":["a","name"]`)
+
+	// the queries after a mutation load their keys again
+	reset()
+	code, body = post(handler, batch(query, "mutation { rename(id: 1) { id } }", query))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peopleCalls))
+	assert.Contains(t, body, `"rename":{"id":1}`)
+
+	// without shared loaders every operation loads its keys
+	reset()
+	handler = graphql.HTTPHandler(schema, graphql.WithBatching(5), graphql.WithLoaders(loaders))
+	code, body = post(handler, batch(query, query, query, query, query))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&peopleCalls))
+	assert.NotContains(t, body, "extensions")
+
+	// a single operation is answered as before, larger batches are refused
+	code, body = post(handler, fmt.Sprintf(` {"query": %q}`, query))
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"data": {"person": {"name": "p1", "visits": 6}}}`, body)
+	code, _ = post(handler, batch(query, query, query, query, query, query))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(handler, "[]")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// batches are refused by the handlers without batching
+	code, _ = post(graphql.HTTPHandler(schema), batch(query))
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,23 @@
+package graphql
+
+import (
+	"context"
+	"github.com/shyptr/graphql/execution"
+)
+
+// BudgetRemaining returns the bytes the result of the operation executed with ctx may still retain, for
+// handlers whose executor has a budget, see execution.MemoryBudget, and false otherwise. Resolvers of
+// large lists check it to truncate their results before the operation fails:
+//
+//   handler := graphql.HTTPHandler(schema, graphql.WithExecutorOptions(execution.MemoryBudget(64<<20)))
+//
+//   func listEvents(ctx context.Context, args EventArgs) []*Event {
+//     events := loadEvents(args)
+//     if remaining, ok := graphql.BudgetRemaining(ctx); ok && int64(len(events))*eventSize > remaining {
+//       events = events[:remaining/eventSize]
+//     }
+//     return events
+//   }
+func BudgetRemaining(ctx context.Context) (int64, bool) {
+	return execution.BudgetRemaining(ctx)
+}
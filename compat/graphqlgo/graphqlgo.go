@@ -0,0 +1,104 @@
+// Package graphqlgo exposes a schema through the shapes of github.com/graphql-go/graphql, Params, Result
+// and Do, so that servers and middlewares written against that package can execute operations of a
+// schema built by schemabuilder by changing their imports. It does not depend on graphql-go.
+package graphqlgo
+
+import (
+	"context"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+)
+
+// ExecutableSchema executes operations, as graphql.Do of graphql-go executes them against a graphql.Schema.
+type ExecutableSchema interface {
+	Exec(ctx context.Context, query, operationName string, variables map[string]interface{}) *Result
+}
+
+// WrapSchema returns schema as an ExecutableSchema. Operations are executed by an execution.Executor
+// made with opts, which caches the plans of the queries it executes.
+func WrapSchema(schema *internal.Schema, opts ...execution.Option) ExecutableSchema {
+	return &executableSchema{executor: execution.NewExecutor(schema, opts...)}
+}
+
+type executableSchema struct {
+	executor *execution.Executor
+}
+
+func (s *executableSchema) Exec(ctx context.Context, query, operationName string, variables map[string]interface{}) *Result {
+	data, errs := s.executor.Do(execution.Params{
+		Query:         query,
+		OperationName: operationName,
+		Variables:     variables,
+		Context:       ctx,
+	})
+	return &Result{Data: data, Errors: formatErrors(errs)}
+}
+
+// Params are the parameters of Do, named as those of graphql.Params of graphql-go. Its RootObject is
+// missing, the resolvers of the root fields get no source value.
+type Params struct {
+	Schema         ExecutableSchema
+	RequestString  string
+	VariableValues map[string]interface{}
+	OperationName  string
+	Context        context.Context
+}
+
+// Result is the response to an operation, encoded by encoding/json as graphql.Result of graphql-go.
+type Result struct {
+	Data       interface{}            `json:"data"`
+	Errors     []FormattedError       `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// HasErrors reports whether r holds errors.
+func (r *Result) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// FormattedError is an error of a Result, as gqlerrors.FormattedError of graphql-go.
+type FormattedError struct {
+	Message    string                 `json:"message"`
+	Locations  []SourceLocation       `json:"locations"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e FormattedError) Error() string {
+	return e.Message
+}
+
+// SourceLocation is a location in a query, as location.SourceLocation of graphql-go.
+type SourceLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Do executes the operation of p against p.Schema, as graphql.Do of graphql-go.
+func Do(p Params) *Result {
+	if p.Schema == nil {
+		return &Result{Errors: []FormattedError{{Message: "graphqlgo: Params has no Schema"}}}
+	}
+	return p.Schema.Exec(p.Context, p.RequestString, p.OperationName, p.VariableValues)
+}
+
+func formatErrors(errs errors.MultiError) []FormattedError {
+	if len(errs) == 0 {
+		return nil
+	}
+	formatted := make([]FormattedError, len(errs))
+	for i, err := range errs {
+		locations := make([]SourceLocation, len(err.Locations))
+		for j, loc := range err.Locations {
+			locations[j] = SourceLocation{Line: loc.Line, Column: loc.Column}
+		}
+		formatted[i] = FormattedError{
+			Message:    err.Message,
+			Locations:  locations,
+			Path:       err.Path,
+			Extensions: err.Extensions,
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,56 @@
+package graphqlgo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/shyptr/graphql/compat/graphqlgo"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type user struct {
+	Name string `graphql:"name"`
+}
+
+func TestDo(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Object("User", user{})
+	build.Query().FieldFunc("user", func(args struct {
+		Name string `graphql:"name"`
+	}) (*user, error) {
+		if args.Name == "" {
+			return nil, errors.New("name is empty")
+		}
+		return &user{Name: args.Name}, nil
+	})
+	schema := graphqlgo.WrapSchema(build.MustBuild())
+
+	result := graphqlgo.Do(graphqlgo.Params{
+		Schema:         schema,
+		RequestString:  `query User($name: String!) { user(name: $name) { name } }`,
+		VariableValues: map[string]interface{}{"name": "alice"},
+		OperationName:  "User",
+		Context:        context.Background(),
+	})
+	assert.False(t, result.HasErrors())
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"user": {"name": "alice"}}}`, string(data))
+
+	result = schema.Exec(context.Background(), `{ user(name: "") { name } }`, "", nil)
+	assert.True(t, result.HasErrors())
+	data, err = json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data": {"user": null}, "errors": [
+		{"message": "name is empty", "locations": [{"line": 1, "column": 18}], "path": ["user"]}
+	]}`, string(data))
+
+	result = graphqlgo.Do(graphqlgo.Params{Schema: schema, RequestString: `{ nobody }`})
+	assert.Equal(t, []graphqlgo.FormattedError{{
+		Message:   `Cannot query field "nobody" on type "Query".`,
+		Locations: []graphqlgo.SourceLocation{{Line: 1, Column: 3}},
+	}}, result.Errors)
+	assert.Nil(t, result.Data)
+}
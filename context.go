@@ -29,6 +29,10 @@ type Context struct {
 	index                 int8
 	OperationName         string
 	Method                ast.OperationType
+	// SchemaHash is the hash of the schema the handler of HTTPHandlerFunc picked for the request.
+	SchemaHash string
+	// APIVersion is the version the handler of VersionedHandler picked for the request.
+	APIVersion string
 }
 
 var Ctx = &Context{
@@ -47,23 +51,37 @@ func GetContext(ctx context.Context) *Context {
 	return ctx.(*Context)
 }
 
+// requestContext returns the context of the request, which the Context follows for its deadline,
+// cancellation and the values it does not set.
+func (c *Context) requestContext() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	return
+	return c.requestContext().Deadline()
 }
 
 func (c *Context) Done() <-chan struct{} {
-	return nil
+	return c.requestContext().Done()
 }
 
+// Err returns the errors of the middlewares, or the error of the context of the request once the
+// client goes away or its deadline passes.
 func (c *Context) Err() error {
 	if len(c.Error) == 0 {
-		return nil
+		return c.requestContext().Err()
 	}
 	return c.Error
 }
 
 func (c *Context) Value(key interface{}) interface{} {
-	return c.keys[key]
+	if value, ok := c.keys[key]; ok {
+		return value
+	}
+	return c.requestContext().Value(key)
 }
 
 func (c *Context) Set(key, value interface{}) {
@@ -122,7 +140,6 @@ func (c *Context) ClientIP() string {
 
 func (c *Context) ServerError(msg string, code int) {
 	c.Error = append(c.Error, errors.New(msg))
-	c.Writer.status = code
 	http.Error(c.Writer, msg, code)
 }
 
@@ -135,7 +152,11 @@ func (r *Resp) Status() int {
 	return r.status
 }
 
+// WriteHeader writes the status code of the response once, the first status written wins.
 func (r *Resp) WriteHeader(statusCode int) {
+	if r.status != 0 {
+		return
+	}
 	r.status = statusCode
 	r.ResponseWriter.WriteHeader(statusCode)
 }
@@ -0,0 +1,302 @@
+// Package dataloader batches and caches the loads of the resolvers of a request, so that a list of
+// 100 persons resolving their friends makes one call to the backend instead of 100:
+//
+//   friends := dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+//     return db.FriendsOf(ctx, keys)
+//   })
+//   ctx = dataloader.Attach(ctx, map[string]*dataloader.Loader{"friends": friends})
+//
+// Resolvers load their values by returning the func resolving them from the Thunk of a Loader:
+//
+//   person.FieldFunc("friends", func(ctx context.Context, p Person) func() ([]Person, error) {
+//     thunk := dataloader.For(ctx, "friends").Load(ctx, p.ID)
+//     return func() ([]Person, error) {
+//       friends, err := thunk()
+//       if err != nil {
+//         return nil, err
+//       }
+//       return friends.([]Person), nil
+//     }
+//   })
+//
+// The executor calls those funcs once the resolvers of the level of the fields returned, after the
+// loaders attached to the context of the operation loaded the keys queued by the level, see
+// execution.WithDispatch. Loaders cache their values for as long as they live, the loaders of a
+// request are created for it, for example by the HTTP middleware attaching them to its context.
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"sort"
+	"sync"
+)
+
+// BatchFunc loads the values of keys: the value at the index of a key, or its error when the value
+// is an error. An error fails all the keys.
+type BatchFunc func(ctx context.Context, keys []interface{}) ([]interface{}, error)
+
+// Thunk returns the value of a key once it is loaded.
+type Thunk func() (interface{}, error)
+
+// ThunkMany returns the values of keys once they are loaded, and their errors, nil when all of them
+// loaded.
+type ThunkMany func() ([]interface{}, []error)
+
+// Loader batches the loads of the keys queued until Dispatch, and caches their values.
+type Loader struct {
+	batch     BatchFunc
+	maxBatch  int
+	batchSafe bool
+
+	mu      sync.Mutex
+	cache   map[interface{}]*entry
+	pending []pendingKey
+	stats   Stats
+}
+
+// Stats count the loads of a Loader since it was created, see its AverageBatchSize and HitRatio.
+// They are the LoaderStats a StatsCollector of the executor aggregates.
+type Stats = execution.LoaderStats
+
+type entry struct {
+	// done is closed once value and err are set
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type pendingKey struct {
+	key   interface{}
+	entry *entry
+}
+
+// Option configures a Loader.
+type Option func(*Loader)
+
+// MaxBatch loads at most n keys with one call to the BatchFunc, the keys of a dispatch are loaded in
+// batches of n in the order they were queued in.
+func MaxBatch(n int) Option {
+	return func(l *Loader) {
+		l.maxBatch = n
+	}
+}
+
+// BatchSafe declares that the loader only reads, and that its values do not depend on the operation
+// loading them, so that the queries of a batch request may share it, see graphql.WithSharedLoaders.
+func BatchSafe() Option {
+	return func(l *Loader) {
+		l.batchSafe = true
+	}
+}
+
+// NewLoader returns a Loader loading keys with batch.
+func NewLoader(batch BatchFunc, opts ...Option) *Loader {
+	l := &Loader{batch: batch, cache: make(map[interface{}]*entry)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load queues key, which must be comparable, unless its value is cached, and returns the Thunk of its
+// value. Calling the Thunk before the key is dispatched dispatches it.
+func (l *Loader) Load(ctx context.Context, key interface{}) Thunk {
+	l.mu.Lock()
+	l.stats.Loads++
+	e, ok := l.cache[key]
+	if ok {
+		l.stats.Hits++
+	} else {
+		e = &entry{done: make(chan struct{})}
+		l.cache[key] = e
+		l.pending = append(l.pending, pendingKey{key: key, entry: e})
+	}
+	l.mu.Unlock()
+	return func() (interface{}, error) {
+		select {
+		case <-e.done:
+		default:
+			l.Dispatch(ctx)
+			<-e.done
+		}
+		return e.value, e.err
+	}
+}
+
+// LoadMany is Load for keys.
+func (l *Loader) LoadMany(ctx context.Context, keys []interface{}) ThunkMany {
+	thunks := make([]Thunk, len(keys))
+	for i, key := range keys {
+		thunks[i] = l.Load(ctx, key)
+	}
+	return func() ([]interface{}, []error) {
+		values := make([]interface{}, len(keys))
+		var errs []error
+		for i, thunk := range thunks {
+			value, err := thunk()
+			if err != nil {
+				if errs == nil {
+					errs = make([]error, len(keys))
+				}
+				errs[i] = err
+			}
+			values[i] = value
+		}
+		return values, errs
+	}
+}
+
+// Prime caches value, or the error when it is one, as the value of key unless it is already cached.
+func (l *Loader) Prime(key, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	e := &entry{done: make(chan struct{})}
+	if err, ok := value.(error); ok {
+		e.err = err
+	} else {
+		e.value = value
+	}
+	close(e.done)
+	l.cache[key] = e
+}
+
+// Clear removes the value of key from the cache, the next Load of key loads it again.
+func (l *Loader) Clear(key interface{}) {
+	l.mu.Lock()
+	delete(l.cache, key)
+	l.mu.Unlock()
+}
+
+// BatchSafe reports whether the loader was created with the BatchSafe option.
+func (l *Loader) BatchSafe() bool {
+	return l.batchSafe
+}
+
+// ClearErrors removes the keys which failed to load from the cache, the next Load of them loads them
+// again. The keys which are still loading are kept.
+func (l *Loader) ClearErrors() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, e := range l.cache {
+		select {
+		case <-e.done:
+			if e.err != nil {
+				delete(l.cache, key)
+			}
+		default:
+		}
+	}
+}
+
+// Stats returns the counts of the loads of the loader so far.
+func (l *Loader) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// Dispatch loads the queued keys with the BatchFunc, in batches of at most MaxBatch keys.
+func (l *Loader) Dispatch(ctx context.Context) {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	for len(pending) > 0 {
+		size := len(pending)
+		if l.maxBatch > 0 && size > l.maxBatch {
+			size = l.maxBatch
+		}
+		l.mu.Lock()
+		l.stats.Batches++
+		l.stats.Keys += size
+		l.mu.Unlock()
+		l.load(ctx, pending[:size])
+		pending = pending[size:]
+	}
+}
+
+// load loads the keys of batch with one call to the BatchFunc, a panic of which fails them.
+func (l *Loader) load(ctx context.Context, batch []pendingKey) {
+	keys := make([]interface{}, len(batch))
+	for i, pending := range batch {
+		keys[i] = pending.key
+	}
+	var values []interface{}
+	var err error
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			err = fmt.Errorf("dataloader: the batch function panicked: %v", panicErr)
+		}
+		for i, pending := range batch {
+			if err != nil {
+				pending.entry.err = err
+			} else if valueErr, ok := values[i].(error); ok {
+				pending.entry.err = valueErr
+			} else {
+				pending.entry.value = values[i]
+			}
+			close(pending.entry.done)
+		}
+	}()
+	values, err = l.batch(ctx, keys)
+	if err == nil && len(values) != len(keys) {
+		err = fmt.Errorf("dataloader: the batch function returned %d values for %d keys", len(values), len(keys))
+	}
+}
+
+type loadersKey struct{}
+
+// Attach returns a copy of ctx holding loaders by their names, besides those already attached to ctx,
+// for the resolvers of the operations executed with it, see For. The executor dispatches them once
+// the resolvers of every level of fields returned, in the order of their names.
+func Attach(ctx context.Context, loaders map[string]*Loader) context.Context {
+	attached := make(map[string]*Loader)
+	for name, loader := range attachedLoaders(ctx) {
+		attached[name] = loader
+	}
+	names := make([]string, 0, len(loaders))
+	for name, loader := range loaders {
+		attached[name] = loader
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dispatched := make([]*Loader, len(names))
+	for i, name := range names {
+		dispatched[i] = loaders[name]
+	}
+	ctx = context.WithValue(ctx, loadersKey{}, attached)
+	return execution.WithDispatch(ctx, func(ctx context.Context) {
+		for _, loader := range dispatched {
+			loader.Dispatch(ctx)
+		}
+	})
+}
+
+// For returns the loader attached to ctx as name, or nil.
+func For(ctx context.Context, name string) *Loader {
+	return attachedLoaders(ctx)[name]
+}
+
+// AttachedStats returns the Stats of the loaders attached to ctx by their names, see Attach.
+func AttachedStats(ctx context.Context) map[string]Stats {
+	loaders := attachedLoaders(ctx)
+	if len(loaders) == 0 {
+		return nil
+	}
+	stats := make(map[string]Stats, len(loaders))
+	for name, loader := range loaders {
+		stats[name] = loader.Stats()
+	}
+	return stats
+}
+
+func attachedLoaders(ctx context.Context) map[string]*Loader {
+	loaders, _ := ctx.Value(loadersKey{}).(map[string]*Loader)
+	return loaders
+}
@@ -0,0 +1,142 @@
+package dataloader_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type Person struct {
+	ID   int    `graphql:"id"`
+	Name string `graphql:"name"`
+}
+
+// friendsOf loads the friends of the persons of ids: the next two persons, the friends of the
+// persons whose id is a multiple of 50 fail.
+func friendsOf(batches *[][]interface{}) dataloader.BatchFunc {
+	return func(ctx context.Context, ids []interface{}) ([]interface{}, error) {
+		*batches = append(*batches, ids)
+		friends := make([]interface{}, len(ids))
+		for i, id := range ids {
+			id := id.(int)
+			if id%50 == 0 {
+				friends[i] = fmt.Errorf("no friends of %d", id)
+				continue
+			}
+			friends[i] = []Person{{ID: id + 1, Name: fmt.Sprint("p", id+1)}, {ID: id + 2, Name: fmt.Sprint("p", id+2)}}
+		}
+		return friends, nil
+	}
+}
+
+func TestLoader_Execution(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	person := build.Object("Person", Person{})
+	person.FieldFunc("friends", func(ctx context.Context, p Person) func() ([]Person, error) {
+		thunk := dataloader.For(ctx, "friends").Load(ctx, p.ID)
+		return func() ([]Person, error) {
+			friends, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			return friends.([]Person), nil
+		}
+	})
+	build.Query().FieldFunc("persons", func() []Person {
+		persons := make([]Person, 100)
+		for i := range persons {
+			persons[i] = Person{ID: i + 1, Name: fmt.Sprint("p", i+1)}
+		}
+		return persons
+	})
+	schema := build.MustBuild()
+
+	var batches [][]interface{}
+	ctx := dataloader.Attach(context.Background(), map[string]*dataloader.Loader{
+		"friends": dataloader.NewLoader(friendsOf(&batches)),
+	})
+	data, errs := execution.Do(schema, execution.Params{Query: `{ persons { id friends { name } } }`, Context: ctx})
+	// the 100 resolvers of the friends of the persons make one batch
+	if assert.Len(t, batches, 1) {
+		assert.Len(t, batches[0], 100)
+		assert.Equal(t, 1, batches[0][0])
+		assert.Equal(t, 100, batches[0][99])
+	}
+	persons := data["persons"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"id":      1,
+		"friends": []interface{}{map[string]interface{}{"name": "p2"}, map[string]interface{}{"name": "p3"}},
+	}, persons[0])
+	assert.Equal(t, map[string]interface{}{"id": 50, "friends": nil}, persons[49])
+	// the keys fail one by one
+	assert.EqualError(t, errs, "[graphql: no friends of 50 (1:24) path: [persons 49 friends]\n"+
+		"graphql: no friends of 100 (1:24) path: [persons 99 friends]]")
+
+	// every level of friends makes a batch of the keys which are not cached, the friends of the
+	// friends are the persons 2 to 101
+	batches = nil
+	ctx = dataloader.Attach(context.Background(), map[string]*dataloader.Loader{
+		"friends": dataloader.NewLoader(friendsOf(&batches)),
+	})
+	_, errs = execution.Do(schema, execution.Params{Query: `{ persons { friends { friends { id } } } }`, Context: ctx})
+	if assert.Len(t, batches, 2) {
+		assert.Len(t, batches[0], 100)
+		assert.Equal(t, []interface{}{101}, batches[1])
+	}
+	assert.Len(t, errs, 6)
+}
+
+func TestLoader(t *testing.T) {
+	var batches [][]interface{}
+	loader := dataloader.NewLoader(friendsOf(&batches), dataloader.MaxBatch(2))
+	ctx := context.Background()
+
+	// the keys are queued until they are dispatched, in batches of at most MaxBatch keys
+	loader.Prime(3, []Person{{ID: 30}})
+	first := loader.Load(ctx, 1)
+	many := loader.LoadMany(ctx, []interface{}{2, 3, 50, 1, 4})
+	assert.Empty(t, batches)
+	loader.Dispatch(ctx)
+	assert.Equal(t, [][]interface{}{{1, 2}, {50, 4}}, batches)
+	// the primed key and the key loaded twice are hits
+	assert.Equal(t, dataloader.Stats{Loads: 6, Hits: 2, Batches: 2, Keys: 4}, loader.Stats())
+	assert.Equal(t, 2.0, loader.Stats().AverageBatchSize())
+	assert.InDelta(t, 1.0/3, loader.Stats().HitRatio(), 1e-9)
+
+	friends, err := first()
+	assert.NoError(t, err)
+	assert.Equal(t, []Person{{ID: 2, Name: "p2"}, {ID: 3, Name: "p3"}}, friends)
+	values, errs := many()
+	assert.Equal(t, []interface{}{
+		[]Person{{ID: 3, Name: "p3"}, {ID: 4, Name: "p4"}}, []Person{{ID: 30}}, nil,
+		[]Person{{ID: 2, Name: "p2"}, {ID: 3, Name: "p3"}}, []Person{{ID: 5, Name: "p5"}, {ID: 6, Name: "p6"}},
+	}, values)
+	assert.Equal(t, []error{nil, nil, fmt.Errorf("no friends of 50"), nil, nil}, errs)
+
+	// a thunk called before its key is dispatched dispatches it, a cleared key is loaded again
+	loader.Clear(1)
+	friends, err = loader.Load(ctx, 1)()
+	assert.NoError(t, err)
+	assert.Len(t, friends, 2)
+	assert.Equal(t, []interface{}{1}, batches[2])
+
+	// the errors of the batch function fail all the keys of the batch
+	failing := dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+		return []interface{}{"one value"}, nil
+	})
+	thunk := failing.Load(ctx, "a")
+	_, err = failing.Load(ctx, "b")()
+	assert.EqualError(t, err, "dataloader: the batch function returned 1 values for 2 keys")
+	_, err = thunk()
+	assert.EqualError(t, err, "dataloader: the batch function returned 1 values for 2 keys")
+
+	panicking := dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+		panic("backend down")
+	})
+	_, err = panicking.Load(ctx, "a")()
+	assert.EqualError(t, err, "dataloader: the batch function panicked: backend down")
+}
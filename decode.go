@@ -0,0 +1,106 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"io"
+	"io/ioutil"
+	"net/url"
+)
+
+// WithStrictJSON rejects request bodies whose JSON objects repeat a key, such as
+// {"variables": {"id": 1, "id": 2}}, instead of silently keeping the last value.
+func WithStrictJSON() HandlerOption {
+	return func(h *Handler) {
+		h.StrictJSON = true
+	}
+}
+
+// decodeJSON decodes the JSON read from r into v. Numbers are decoded as json.Number,
+// so that integers too large for a float64 keep their precision until the scalars parse them.
+// With strict, objects repeating a key are an error.
+func decodeJSON(r io.Reader, v interface{}, strict bool) error {
+	if strict {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if err := checkDuplicateKeys(json.NewDecoder(bytes.NewReader(data)), ""); err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// checkDuplicateKeys reads the next JSON value of decoder, and reports the first object repeating a key.
+// path locates the value in the request body.
+func checkDuplicateKeys(decoder *json.Decoder, path string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		keys := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			if keys[key] {
+				if path == "" {
+					return fmt.Errorf("duplicate key %q in the request body", key)
+				}
+				return fmt.Errorf("duplicate key %q in %q", key, path)
+			}
+			keys[key] = true
+			if err := checkDuplicateKeys(decoder, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; decoder.More(); i++ {
+			if err := checkDuplicateKeys(decoder, joinPath(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+	}
+	// the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// decodeQueryParams decodes the parameters of a GET request, query, operationName, and variables and
+// extensions encoded in JSON, into param. Empty variables and extensions are left nil.
+func decodeQueryParams(values url.Values, param *execution.Params, strict bool) error {
+	param.Query = values.Get("query")
+	param.OperationName = values.Get("operationName")
+	if variables := values.Get("variables"); variables != "" {
+		if err := decodeJSON(bytes.NewReader([]byte(variables)), &param.Variables, strict); err != nil {
+			return fmt.Errorf("variables are invalid JSON: %v", err)
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := decodeJSON(bytes.NewReader([]byte(extensions)), &param.Extensions, strict); err != nil {
+			return fmt.Errorf("extensions are invalid JSON: %v", err)
+		}
+	}
+	return nil
+}
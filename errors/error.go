@@ -1,16 +1,46 @@
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"fmt"
+)
 
+// GraphQLError is an error of a request, as the response lists it.
 type GraphQLError struct {
-	Message       string                 `json:"message"`
-	Locations     []Location             `json:"locations,omitempty"`
-	Path          []interface{}          `json:"path,omitempty"`
-	Rule          string                 `json:"-"`
-	ResolverError error                  `json:"-"`
-	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+	// Rule is the validation rule the document broke, such as "ArgumentsOfCorrectType". It is meant for
+	// programs telling errors apart, and is neither in the response nor in the string of the error.
+	Rule          string `json:"-"`
+	ResolverError error  `json:"-"`
+	// Extensions are the extensions of the error in the response, such as its code, see ExtendedError.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
+// ExtendedError is an error returned by a resolver which adds extensions to the error of the response,
+// for example a code telling the clients why the field failed:
+//
+//   type unauthenticated struct{}
+//
+//   func (unauthenticated) Error() string { return "not logged in" }
+//   func (unauthenticated) Extensions() map[string]interface{} {
+//     return map[string]interface{}{"code": "UNAUTHENTICATED"}
+//   }
+//
+// The errors wrapping an ExtendedError, as fmt.Errorf with %w does, add its extensions too.
+type ExtendedError interface {
+	error
+	Extensions() map[string]interface{}
+}
+
+// Error formats err as "graphql: " followed by its message, then each of its locations as " (line:column)",
+// then its path, if any, as " path: [a 0 b]":
+//
+//   graphql: Argument "input" has invalid value WRONG_TYPE.
+//   Expected type "String", found WRONG_TYPE. (3:48)
+//
+// Values of the document in messages are printed as they are written, with ast.PrintValue.
 func (err *GraphQLError) Error() string {
 	if err == nil {
 		return "<nil>"
@@ -26,8 +56,19 @@ func (err *GraphQLError) Error() string {
 	return str
 }
 
+// Unwrap returns the error the resolver returned, so that errors.Is and errors.As see through the
+// GraphQLError to the errors it wraps.
+func (err *GraphQLError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+	return err.ResolverError
+}
+
+// MultiError is the errors of a request, in the order they occurred.
 type MultiError []*GraphQLError
 
+// Error formats the errors of m in square brackets, one per line, or returns "" when m is empty.
 func (m MultiError) Error() string {
 	var res string
 	if len(m) > 0 {
@@ -41,6 +82,37 @@ func (m MultiError) Error() string {
 	return res
 }
 
+// Unwrap returns the errors of m.
+func (m MultiError) Unwrap() []error {
+	errs := make([]error, len(m))
+	for i, err := range m {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is reports whether one of the errors of m matches target, for the versions of errors.Is which do not
+// unwrap multiple errors.
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if stderrors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of m which matches target, for the versions of errors.As which do not unwrap
+// multiple errors.
+func (m MultiError) As(target interface{}) bool {
+	for _, err := range m {
+		if stderrors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 var _ error = (*GraphQLError)(nil)
 
 type Location struct {
@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/shyptr/graphql/execution"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// WithETag makes the handler answer queries with an ETag header, and with 304 Not Modified and no body
+// when the If-None-Match header of the request matches it, so that clients polling the same query
+// only download the responses which changed. Mutations and responses holding errors never get one.
+//
+// The ETag is derived from the encoded response, which the handler writes once every resolver has
+// returned, unless resolvers provide versions of the data they read with SetETagVersion.
+// The headers set by resolvers, such as Cache-Control, are sent with 304 responses too.
+func WithETag(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.ETag = enabled
+	}
+}
+
+// SetETagVersion adds version to the versions the ETag of the response is derived from, instead of
+// the encoded response, for resolvers which can tell the version of their data, such as the time it
+// was last updated, at less cost than resolving it. The ETag then covers the query, its variables and
+// every version set, under the same conditions as SetHeader.
+func SetETagVersion(ctx context.Context, version string) error {
+	return withResponseHeaders(ctx, func(headers *responseHeaders) {
+		headers.versions = append(headers.versions, version)
+	})
+}
+
+// bufferedResponse holds what an encoder writes, so that the ETag can be computed before the response is sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(statusCode int) {
+	b.status = statusCode
+}
+
+// encodeWithETag encodes result with encode, and writes it to the Writer of ctx with its ETag,
+// or writes 304 when the request already holds it. Responses whose status was already written by a
+// handler function are encoded as they are.
+func encodeWithETag(ctx *Context, encode ResponseEncoder, result *Response, param execution.Params, versions []string) error {
+	if ctx.Writer.status != 0 {
+		return encode(ctx.Writer, result)
+	}
+	buffered := &bufferedResponse{header: http.Header{}}
+	if err := encode(&Resp{ResponseWriter: buffered}, result); err != nil {
+		return err
+	}
+	if buffered.status == 0 {
+		buffered.status = http.StatusOK
+	}
+	header := ctx.Writer.Header()
+	for key, values := range buffered.header {
+		header[key] = values
+	}
+	if buffered.status != http.StatusOK {
+		ctx.Writer.WriteHeader(buffered.status)
+		_, err := ctx.Writer.Write(buffered.body.Bytes())
+		return err
+	}
+
+	var etag string
+	if len(versions) > 0 {
+		etag = versionETag(param, versions)
+	} else {
+		sum := sha256.Sum256(buffered.body.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+	header.Set("ETag", etag)
+	if etagMatches(ctx.Request.Header.Get("If-None-Match"), etag) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		ctx.Writer.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	ctx.Writer.WriteHeader(http.StatusOK)
+	_, err := ctx.Writer.Write(buffered.body.Bytes())
+	return err
+}
+
+// versionETag derives an ETag from the query, the operation name and the variables of param, and
+// versions, as the same versions may be set by requests reading different data.
+func versionETag(param execution.Params, versions []string) string {
+	// json.Marshal sorts the keys of maps
+	variables, _ := json.Marshal(param.Variables)
+	parts := []string{QueryHash(param.Query), param.OperationName, string(variables)}
+	sorted := append([]string(nil), versions...)
+	sort.Strings(sorted)
+	hash := sha256.New()
+	for _, part := range append(parts, sorted...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header ifNoneMatch lists etag, comparing them weakly.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
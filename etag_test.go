@@ -0,0 +1,97 @@
+package graphql_test
+
+import (
+	"context"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler_ETag(t *testing.T) {
+	counter := 0
+	version := "1"
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("counter", func(ctx context.Context) (int64, error) {
+		return int64(counter), graphql.SetHeader(ctx, "Cache-Control", "max-age=5")
+	})
+	build.Query().FieldFunc("versioned", func(ctx context.Context) (string, error) {
+		return "expensive", graphql.SetETagVersion(ctx, version)
+	})
+	build.Mutation().FieldFunc("increment", func() int64 {
+		counter++
+		return int64(counter)
+	})
+	schema := build.MustBuild()
+	handler := graphql.HTTPHandler(schema, graphql.WithETag(true))
+
+	post := func(handler http.Handler, body, etag string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	query := `{"query": "{ counter }"}`
+
+	t.Run("answers a matching ETag with 304", func(t *testing.T) {
+		res := post(handler, query, "")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"counter": 0}}`, res.Body.String())
+		etag := res.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		res = post(handler, query, `"other", `+etag)
+		assert.Equal(t, http.StatusNotModified, res.Code)
+		assert.Empty(t, res.Body.String())
+		assert.Equal(t, etag, res.Header().Get("ETag"))
+		assert.Equal(t, "max-age=5", res.Header().Get("Cache-Control"))
+
+		res = post(handler, query, "W/"+etag)
+		assert.Equal(t, http.StatusNotModified, res.Code)
+	})
+
+	t.Run("answers a changed response in full", func(t *testing.T) {
+		etag := post(handler, query, "").Header().Get("ETag")
+		res := post(handler, `{"query": "mutation { increment }"}`, etag)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"increment": 1}}`, res.Body.String())
+		assert.Empty(t, res.Header().Get("ETag"))
+
+		res = post(handler, query, etag)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"counter": 1}}`, res.Body.String())
+		assert.NotEqual(t, etag, res.Header().Get("ETag"))
+	})
+
+	t.Run("never answers mutations with 304", func(t *testing.T) {
+		res := post(handler, `{"query": "mutation { increment }"}`, "*")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"increment": 2}}`, res.Body.String())
+		assert.Equal(t, 2, counter)
+	})
+
+	t.Run("derives the ETag from the versions resolvers set", func(t *testing.T) {
+		res := post(handler, `{"query": "{ versioned }"}`, "")
+		etag := res.Header().Get("ETag")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, http.StatusNotModified, post(handler, `{"query": "{ versioned }"}`, etag).Code)
+		// another query setting the same version does not share its ETag
+		assert.Equal(t, http.StatusOK, post(handler, `{"query": "{ other: versioned }"}`, etag).Code)
+
+		version = "2"
+		assert.Equal(t, http.StatusOK, post(handler, `{"query": "{ versioned }"}`, etag).Code)
+	})
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		res := post(graphql.HTTPHandler(schema), query, "*")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Empty(t, res.Header().Get("ETag"))
+	})
+}
@@ -0,0 +1,79 @@
+// Command router serves two schemas from one ServeMux: a public one, and an admin one behind the
+// authentication middleware of the router, whose user reaches the resolvers through a context hook.
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/middleware"
+	"github.com/shyptr/graphql/schemabuilder"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type userKey struct{}
+
+// authenticate stores the user named by the bearer token in the context of the request, and
+// rejects requests without one.
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if user == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// withUser copies the user stored by authenticate to the Context the resolvers receive.
+func withUser(ctx *graphql.Context) {
+	ctx.Set(userKey{}, ctx.Request.Context().Value(userKey{}))
+}
+
+func publicSchema() *schemabuilder.Schema {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string {
+		return "hello"
+	})
+	return build
+}
+
+func adminSchema() *schemabuilder.Schema {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("me", func(ctx context.Context) (string, error) {
+		user, ok := ctx.Value(userKey{}).(string)
+		if !ok {
+			return "", errors.New("no user")
+		}
+		return user, nil
+	})
+	return build
+}
+
+func main() {
+	logger := log.New(os.Stderr, "graphql ", log.LstdFlags)
+
+	public := publicSchema().MustBuild()
+	introspection.AddIntrospectionToSchema(public)
+	admin := adminSchema().MustBuild()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", graphql.GraphiQLHandler("/query"))
+	mux.Handle("/query", graphql.HTTPHandler(public,
+		graphql.WithLogger(logger),
+		graphql.WithMaxDepth(10),
+		graphql.WithMiddleware(middleware.Recovery(), middleware.Logger()),
+	))
+	mux.Handle("/admin/query", authenticate(graphql.HTTPHandler(admin,
+		graphql.WithLogger(logger),
+		graphql.WithContextHook(withUser),
+		graphql.WithMiddleware(middleware.Recovery()),
+	)))
+
+	log.Fatal(http.ListenAndServe(":8008", mux))
+}
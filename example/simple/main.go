@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/internal"
 	"github.com/shyptr/graphql/introspection"
 	"github.com/shyptr/graphql/schemabuilder"
 	"net/http"
@@ -42,9 +43,9 @@ func RegisterPerson(schema *schemabuilder.Schema) {
 }
 
 func RegisterEnum(schema *schemabuilder.Schema) {
-	schema.Enum("identity", Identity(0), map[string]Identity{
-		"student": Student,
-		"teacher": Teacher,
+	schema.Enum("identity", Identity(0), map[string]interface{}{
+		"student": schemabuilder.DescField{Field: Student, Desc: "a person attending the lessons"},
+		"teacher": schemabuilder.DescField{Field: Teacher, Desc: "a person giving the lessons"},
 	}, "identity enum")
 }
 
@@ -81,13 +82,17 @@ func RegisterOperations(schema *schemabuilder.Schema) {
 	}, "add a person into db")
 }
 
-func main() {
+// BuildSchema builds the schema of the example, its SDL is testdata/schema.graphql.
+func BuildSchema() *internal.Schema {
 	builder := schemabuilder.NewSchema()
 	RegisterEnum(builder)
 	RegisterPerson(builder)
 	RegisterOperations(builder)
+	return builder.MustBuild()
+}
 
-	schema := builder.MustBuild()
+func main() {
+	schema := BuildSchema()
 	introspection.AddIntrospectionToSchema(schema)
 	http.Handle("/", graphql.GraphiQLHandler())
 	http.Handle("/query", graphql.HTTPHandler(schema))
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/printer"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the files of testdata")
+
+// TestSchema checks the schema of the example against testdata/schema.graphql, run it with -update
+// to write the file after changing the schema.
+func TestSchema(t *testing.T) {
+	printed := printer.Options{IncludeDescriptions: true, BlockDescriptions: true}.Print(BuildSchema())
+	if *update {
+		if err := ioutil.WriteFile("testdata/schema.graphql", []byte(printed), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := ioutil.ReadFile("testdata/schema.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(golden), printed)
+}
+
+// TestIdentityIntrospection checks the introspection of the identity enum, as GraphiQL shows it in its
+// documentation, against testdata/identity.json.
+func TestIdentityIntrospection(t *testing.T) {
+	schema := BuildSchema()
+	introspection.AddIntrospectionToSchema(schema)
+	data, errs := execution.Do(schema, execution.Params{Query: `{
+		__type(name: "identity") {
+			name
+			description
+			enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+		}
+	}`})
+	assert.Empty(t, errs)
+	printed, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile("testdata/identity.json", printed, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := ioutil.ReadFile("testdata/identity.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(golden), string(printed))
+}
+
+// TestIntrospection checks the result of the introspection query of GraphiQL on the schema of the
+// example against testdata/introspection.json.
+func TestIntrospection(t *testing.T) {
+	schema := BuildSchema()
+	introspection.AddIntrospectionToSchema(schema)
+	data, errs := execution.Do(schema, execution.Params{Query: introspection.IntrospectionQuery})
+	assert.Empty(t, errs)
+	printed, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile("testdata/introspection.json", printed, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := ioutil.ReadFile("testdata/introspection.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(golden), string(printed))
+}
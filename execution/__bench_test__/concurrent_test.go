@@ -0,0 +1,41 @@
+package __bench_test__
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkFanOut executes a query of 10 fields taking 50ms each with opts.
+func benchmarkFanOut(b *testing.B, opts ...execution.Option) {
+	build := schemabuilder.NewSchema()
+	var fields []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("backend%d", i)
+		build.Query().FieldFunc(name, func() int {
+			time.Sleep(50 * time.Millisecond)
+			return 1
+		})
+		fields = append(fields, name)
+	}
+	executor := execution.NewExecutor(build.MustBuild(), opts...)
+	query := "{ " + strings.Join(fields, " ") + " }"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, errs := executor.Do(execution.Params{Query: query}); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+	}
+}
+
+func BenchmarkExecutor_Serial(b *testing.B) {
+	benchmarkFanOut(b)
+}
+
+func BenchmarkExecutor_Concurrency(b *testing.B) {
+	benchmarkFanOut(b, execution.Concurrency(10))
+}
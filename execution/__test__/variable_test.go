@@ -21,6 +21,32 @@ type TestInputObject struct {
 	D *ComplexScalar `graphql:"d" json:"d,omitempty"`
 }
 
+// TestDefaultInputObject tells explicit nulls apart from omitted fields: A has a default value.
+type TestDefaultInputObject struct {
+	A *string `graphql:"a" json:"a"`
+	B *string `graphql:"b" json:"b"`
+	C string  `graphql:"c" json:"c"`
+}
+
+// TestOptionalInputObject tells explicit nulls apart from omitted fields with optional values: B has a
+// default value.
+type TestOptionalInputObject struct {
+	A schemabuilder.OptionalString `graphql:"a"`
+	B schemabuilder.OptionalString `graphql:"b"`
+	C string                       `graphql:"c"`
+}
+
+// describeOptional tells whether the optional value opt is omitted, null or set to a value.
+func describeOptional(opt schemabuilder.OptionalString) string {
+	if !opt.Set {
+		return "omitted"
+	}
+	if opt.Null {
+		return "null"
+	}
+	return opt.Value
+}
+
 type TestNestedInputObject struct {
 	Na TestInputObject `graphql:"na"`
 	Nb string          `graphql:"nb"`
@@ -64,15 +90,17 @@ func Init3() {
 		dest.SetString("DeserializedValue")
 		return nil
 	})
-	TestComplexScalar.LiteralFunc(func(value ast.Value) error {
+	TestComplexScalar.LiteralValueFunc(func(value ast.Value) (interface{}, error) {
 		if value.GetValue() != "SerializedValue" {
-			return fmt.Errorf("unexpected invariant triggered")
+			return nil, fmt.Errorf("unexpected invariant triggered")
 		}
-		return nil
+		return ComplexScalar("DeserializedValue"), nil
 	})
 
 	build.InputObject("TestInputObject", TestInputObject{}, "")
 	build.InputObject("TestNestedInputObject", TestNestedInputObject{}, "")
+	build.InputObject("TestDefaultInputObject", TestDefaultInputObject{}, "").FieldDefault("a", "default")
+	build.InputObject("TestOptionalInputObject", TestOptionalInputObject{}, "").FieldDefault("b", "default")
 
 	build.Enum("TestEnum", TestEnum(0), map[string]interface{}{
 		"NULL":          NULL,
@@ -105,6 +133,21 @@ func Init3() {
 	}) string {
 		return fieldWithInputArg(args)
 	}, "")
+	object.FieldFunc("fieldWithDefaultObjectInput", func(args struct {
+		Input *TestDefaultInputObject `graphql:"input"`
+	}) string {
+		return fieldWithInputArg(args)
+	}, "")
+	object.FieldFunc("fieldWithOptionalObjectInput", func(args struct {
+		Input *TestOptionalInputObject `graphql:"input"`
+	}) string {
+		return fmt.Sprintf("a: %s, b: %s", describeOptional(args.Input.A), describeOptional(args.Input.B))
+	}, "")
+	object.FieldFunc("fieldWithOptionalStringInput", func(args struct {
+		Input schemabuilder.OptionalString `graphql:"input"`
+	}) string {
+		return describeOptional(args.Input)
+	}, "")
 	object.FieldFunc("fieldWithNullableStringInput", func(args struct {
 		Input *string `graphql:"input"`
 	}) string {
@@ -189,11 +232,94 @@ func Test_Handler_Input(t *testing.T) {
           }
         `})
 				assert.Equal(t, errors.MultiError(nil), err)
+				// the resolver gets nil for a, b and d, which the omitempty of their json tags leaves out
 				assert.Equal(t, map[string]interface{}{
 					"fieldWithObjectInput": `{"c":"C"}`,
 				}, result)
 			})
 
+			t.Run("passes explicit null fields as nil instead of their default value", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          {
+            fieldWithDefaultObjectInput(input: {a: null, b: null, c: "C"})
+          }
+        `})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"fieldWithDefaultObjectInput": `{"a":null,"b":null,"c":"C"}`,
+				}, result)
+			})
+
+			t.Run("uses the default value of omitted fields", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          {
+            fieldWithDefaultObjectInput(input: {c: "C"})
+          }
+        `})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"fieldWithDefaultObjectInput": `{"a":"default","b":null,"c":"C"}`,
+				}, result)
+			})
+
+			t.Run("passes explicit null fields given by variables as nil", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          query q($a: String, $b: String) {
+            fieldWithDefaultObjectInput(input: {a: $a, b: $b, c: "C"})
+          }
+        `, Variables: map[string]interface{}{"a": nil, "b": nil}})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"fieldWithDefaultObjectInput": `{"a":null,"b":null,"c":"C"}`,
+				}, result)
+			})
+
+			t.Run("uses the default value of fields given variables which are not provided", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          query q($a: String) {
+            fieldWithDefaultObjectInput(input: {a: $a, c: "C"})
+          }
+        `})
+				assert.Equal(t, errors.MultiError(nil), err)
+				// a is absent, as if it was omitted, rather than null
+				assert.Equal(t, map[string]interface{}{
+					"fieldWithDefaultObjectInput": `{"a":"default","b":null,"c":"C"}`,
+				}, result)
+			})
+
+			t.Run("tells explicit null fields apart from omitted ones with optional values", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          {
+            null: fieldWithOptionalObjectInput(input: {a: null, b: null, c: "C"})
+            omitted: fieldWithOptionalObjectInput(input: {c: "C"})
+            set: fieldWithOptionalObjectInput(input: {a: "A", b: "B", c: "C"})
+            nullArgument: fieldWithOptionalStringInput(input: null)
+            omittedArgument: fieldWithOptionalStringInput
+          }
+        `})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"null":            "a: null, b: null",
+					"omitted":         "a: omitted, b: default",
+					"set":             "a: A, b: B",
+					"nullArgument":    "null",
+					"omittedArgument": "omitted",
+				}, result)
+			})
+
+			t.Run("tells explicit null fields apart from omitted ones with optional values given by variables", func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: `
+          query q($a: String, $b: String) {
+            fieldWithOptionalObjectInput(input: {a: $a, b: $b, c: "C"})
+          }
+        `, Variables: map[string]interface{}{"a": nil}})
+				assert.Equal(t, errors.MultiError(nil), err)
+				// b is given a variable which is not provided, as if it was omitted
+				assert.Equal(t, map[string]interface{}{
+					"fieldWithOptionalObjectInput": "a: null, b: default",
+				}, result)
+			})
+
 			t.Run("properly parses null value in list", func(t *testing.T) {
 				result, err := execution.Do(schema, execution.Params{Query: `
           {
@@ -212,7 +338,7 @@ func Test_Handler_Input(t *testing.T) {
             fieldWithObjectInput(input: ["foo", "bar", "baz"])
           }
         `})
-				assert.EqualError(t, err, "[graphql: Argument \"input\" has invalid value [%!s(*ast.StringValue=&{StringValue foo {3 42}}) %!s(*ast.StringValue=&{StringValue bar {3 49}}) %!s(*ast.StringValue=&{StringValue baz {3 56}})].\nExpected \"TestInputObject\", found not an object. (3:41)]")
+				assert.EqualError(t, err, "[graphql: Argument \"input\" has invalid value [\"foo\", \"bar\", \"baz\"].\nExpected \"TestInputObject\", found not an object. (3:41)]")
 			})
 
 			t.Run("properly runs parseLiteral on complex scalar types", func(t *testing.T) {
@@ -244,6 +370,32 @@ func Test_Handler_Input(t *testing.T) {
 				}, result)
 			})
 
+			t.Run("tells explicit null fields apart from omitted ones with optional values", func(t *testing.T) {
+				query := `
+          query q($input: TestOptionalInputObject, $a: String) {
+            object: fieldWithOptionalObjectInput(input: $input)
+            argument: fieldWithOptionalStringInput(input: $a)
+          }`
+				result, err := execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{
+					"input": map[string]interface{}{"a": nil, "c": "C"},
+				}})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"object":   "a: null, b: default",
+					"argument": "omitted",
+				}, result)
+
+				result, err = execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{
+					"input": map[string]interface{}{"b": nil, "c": "C"},
+					"a":     nil,
+				}})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{
+					"object":   "a: omitted, b: null",
+					"argument": "null",
+				}, result)
+			})
+
 			t.Run("uses undefined when variable not provided", func(t *testing.T) {
 				result, err := execution.Do(schema, execution.Params{Query: `
           query q($input: String) {
@@ -317,6 +469,11 @@ func Test_Handler_Input(t *testing.T) {
 				assert.Equal(t, map[string]interface{}{
 					"fieldWithObjectInput": `{"c":"foo","d":"DeserializedValue"}`,
 				}, result)
+
+				// the resolver receives the same input from a literal
+				literal, err := execution.Do(schema, execution.Params{Query: `{ fieldWithObjectInput(input: {c: "foo", d: "SerializedValue"}) }`})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, result, literal)
 			})
 
 			t.Run("errors on null for nested non-null", func(t *testing.T) {
@@ -653,7 +810,7 @@ func Test_Handler_Input(t *testing.T) {
           fieldWithObjectInput(input: $input)
         }
       `, Variables: map[string]interface{}{"input": "WhoKnows"}})
-			assert.EqualError(t, err, "[graphql: Unexpected type node: UnknownType! (0:0)]")
+			assert.EqualError(t, err, "[graphql: Unknown type \"UnknownType\". (2:24)]")
 		})
 	})
 
@@ -680,7 +837,7 @@ func Test_Handler_Input(t *testing.T) {
           fieldWithDefaultArgumentValue(input: WRONG_TYPE)
         }
       `})
-			assert.EqualError(t, err, "[graphql: Argument \"input\" has invalid value WRONG_TYPE.\nExpected type \"String\", found &{EnumValue WRONG_TYPE {%!s(int=3) %!s(int=48)}}. (3:48)]")
+			assert.EqualError(t, err, "[graphql: Argument \"input\" has invalid value WRONG_TYPE.\nExpected type \"String\", found WRONG_TYPE. (3:48)]")
 		})
 
 		t.Run("when no runtime value is provided to a non-null argument", func(t *testing.T) {
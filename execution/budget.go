@@ -0,0 +1,107 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/shyptr/graphql/errors"
+	"sync/atomic"
+)
+
+// ErrMemoryBudgetExceeded is the error of operations whose result outgrew the budget set by MemoryBudget.
+var ErrMemoryBudgetExceeded = stderrors.New("MemoryBudgetExceeded")
+
+// SizeEstimator estimates the bytes a completed scalar or enum value retains in the result.
+type SizeEstimator func(value interface{}) int64
+
+// MemoryBudget aborts operations whose result is estimated to retain more than bytes while it is
+// completed, before it is encoded, so that a huge response fails instead of exhausting the memory of
+// the server. The data of such an operation is dropped and its errors end with one whose ResolverError
+// is ErrMemoryBudgetExceeded.
+//
+// The estimate adds up the values as they complete, with the sizes known at that time: estimator, or
+// EstimateSize when it is not given, for scalars and enums, and a fixed overhead for the objects and
+// lists holding them. Resolvers read what is left with BudgetRemaining to truncate their own results.
+func MemoryBudget(bytes int64, estimator ...SizeEstimator) Option {
+	return func(e *Executor) {
+		e.budget = bytes
+		e.estimate = EstimateSize
+		if len(estimator) > 0 {
+			e.estimate = estimator[0]
+		}
+	}
+}
+
+// EstimateSize estimates the bytes value retains: the length of strings and byte slices and the size of
+// the header referencing them, or of a word for numbers, booleans and other values.
+func EstimateSize(value interface{}) int64 {
+	switch value := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(value)) + 16
+	case json.Number:
+		return int64(len(value)) + 16
+	case []byte:
+		return int64(len(value)) + 24
+	case json.RawMessage:
+		return int64(len(value)) + 24
+	}
+	return 16
+}
+
+const (
+	// objectOverhead is the size of the map of a completed object, fieldOverhead the size of one of its entries
+	// besides the key.
+	objectOverhead = 48
+	fieldOverhead  = 32
+	// listOverhead is the size of the slice of a completed list, itemOverhead the size of one of its items.
+	listOverhead = 24
+	itemOverhead = 16
+)
+
+// memoryBudget counts the bytes the result of an operation retains.
+type memoryBudget struct {
+	limit    int64
+	used     int64
+	estimate SizeEstimator
+}
+
+type memoryBudgetKey struct{}
+
+// BudgetRemaining returns the bytes the result of the operation executed with ctx may still retain under
+// its MemoryBudget, which may be negative once exceeded, and false when the operation has no budget.
+func BudgetRemaining(ctx context.Context) (int64, bool) {
+	budget, ok := ctx.Value(memoryBudgetKey{}).(*memoryBudget)
+	if !ok {
+		return 0, false
+	}
+	return budget.limit - atomic.LoadInt64(&budget.used), true
+}
+
+// charge adds bytes to the budget, a nil budget counts nothing.
+func (b *memoryBudget) charge(bytes int64) {
+	if b != nil {
+		atomic.AddInt64(&b.used, bytes)
+	}
+}
+
+// chargeValue adds the estimated size of the scalar or enum value to the budget.
+func (b *memoryBudget) chargeValue(value interface{}) {
+	if b != nil {
+		b.charge(b.estimate(value))
+	}
+}
+
+func (b *memoryBudget) exceeded() bool {
+	return b != nil && atomic.LoadInt64(&b.used) > b.limit
+}
+
+func (b *memoryBudget) err() *errors.GraphQLError {
+	return &errors.GraphQLError{
+		Message:       fmt.Sprintf("MemoryBudgetExceeded: the result retains more than the budget of %d bytes", b.limit),
+		ResolverError: ErrMemoryBudgetExceeded,
+		Extensions:    map[string]interface{}{"code": "MemoryBudgetExceeded"},
+	}
+}
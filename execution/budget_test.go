@@ -0,0 +1,60 @@
+package execution_test
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	type Item struct {
+		Name string `graphql:"name"`
+	}
+	var remaining []int64
+	build := schemabuilder.NewSchema()
+	build.Object("Item", Item{})
+	build.Query().FieldFunc("items", func(ctx context.Context, args struct {
+		N int64 `graphql:"n"`
+	}) []Item {
+		if left, ok := execution.BudgetRemaining(ctx); ok {
+			remaining = append(remaining, left)
+		}
+		items := make([]Item, args.N)
+		for i := range items {
+			items[i].Name = strings.Repeat("x", 100)
+		}
+		return items
+	})
+	schema := build.MustBuild()
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ items(n: 10) { name } }`}, execution.MemoryBudget(10000))
+	assert.Empty(t, errs)
+	assert.Len(t, data["items"], 10)
+	if assert.Len(t, remaining, 1) {
+		// the root object and its field are counted before the resolver runs
+		assert.True(t, remaining[0] > 9900 && remaining[0] < 10000, remaining[0])
+	}
+
+	data, errs = execution.Do(schema, execution.Params{Query: `{ items(n: 100000) { name } }`}, execution.MemoryBudget(10000))
+	assert.Nil(t, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "MemoryBudgetExceeded: the result retains more than the budget of 10000 bytes", errs[0].Message)
+		assert.Equal(t, map[string]interface{}{"code": "MemoryBudgetExceeded"}, errs[0].Extensions)
+		assert.True(t, stderrors.Is(errs[0], execution.ErrMemoryBudgetExceeded))
+	}
+
+	// the estimator sizes the scalars
+	_, errs = execution.Do(schema, execution.Params{Query: `{ items(n: 10) { name } }`}, execution.MemoryBudget(10000, func(value interface{}) int64 {
+		return 1000
+	}))
+	assert.Len(t, errs, 1)
+
+	remaining = nil
+	_, errs = execution.Do(schema, execution.Params{Query: `{ items(n: 10) { name } }`})
+	assert.Empty(t, errs)
+	assert.Empty(t, remaining)
+}
@@ -0,0 +1,75 @@
+package execution
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxCompletionDepth is the number of objects the completion of a value nests unless
+// MaxCompletionDepth sets it.
+const DefaultMaxCompletionDepth = 1000
+
+// MaxCompletionDepth fails the fields whose value nests more than n objects when it is completed, the
+// root object counting as one. The nesting of a completed value follows the one of the selections of
+// the operation, so the limit bounds operations which MaxDepth does not check, such as those of
+// handlers without it, and is higher than MaxDepth where both are set. It is DefaultMaxCompletionDepth
+// when n is not positive.
+func MaxCompletionDepth(n int) Option {
+	return func(e *Executor) {
+		e.maxCompletionDepth = n
+	}
+}
+
+// DetectCycles fails the fields whose value is a pointer to an object already being completed on the
+// path of the field, with the error "cyclic value detected at path ...", to find the resolvers returning
+// cyclic object graphs. It is not enabled by default, as a graph may legitimately return an object
+// again below itself, such as a person who is one of their own friends.
+func DetectCycles() Option {
+	return func(e *Executor) {
+		e.detectCycles = true
+	}
+}
+
+// enterObject counts the completion of the object value one level deeper, and fails when it nests
+// too deep or, with DetectCycles, when value is a pointer to one of the objects it is nested in.
+// Every successful call is followed by a call to leaveObject.
+func (e *exeContext) enterObject(value reflect.Value) error {
+	max := e.maxCompletionDepth
+	if max <= 0 {
+		max = DefaultMaxCompletionDepth
+	}
+	if e.depth >= max {
+		return fmt.Errorf("value completion exceeded the maximum depth %d at path %s", max, formatPath(e.path))
+	}
+	if e.detectCycles {
+		var pointer uintptr
+		if value.Kind() == reflect.Ptr {
+			pointer = value.Pointer()
+		}
+		for _, ancestor := range e.ancestors {
+			if pointer != 0 && ancestor == pointer {
+				return fmt.Errorf("cyclic value detected at path %s", formatPath(e.path))
+			}
+		}
+		e.ancestors = append(e.ancestors, pointer)
+	}
+	e.depth++
+	return nil
+}
+
+func (e *exeContext) leaveObject() {
+	e.depth--
+	if e.detectCycles {
+		e.ancestors = e.ancestors[:len(e.ancestors)-1]
+	}
+}
+
+// formatPath joins the keys of path with dots.
+func formatPath(path []interface{}) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = fmt.Sprint(key)
+	}
+	return strings.Join(keys, ".")
+}
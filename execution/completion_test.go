@@ -0,0 +1,62 @@
+package execution_test
+
+import (
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type Person struct {
+	Name    string    `graphql:"name"`
+	Friends []*Person `graphql:"friends"`
+}
+
+func TestExecutor_CompletionDepth(t *testing.T) {
+	// alice is one of her own friends
+	alice := &Person{Name: "alice"}
+	bob := &Person{Name: "bob", Friends: []*Person{alice}}
+	alice.Friends = []*Person{alice, bob}
+	build := schemabuilder.NewSchema()
+	build.Object("Person", Person{})
+	build.Query().FieldFunc("me", func() *Person { return alice })
+	build.Query().FieldFunc("bob", func() *Person { return bob })
+	schema := build.MustBuild()
+	query := `{ me { name friends { name friends { name } } } }`
+
+	data, errs := execution.Do(schema, execution.Params{Query: query})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"me": map[string]interface{}{
+		"name": "alice",
+		"friends": []interface{}{
+			map[string]interface{}{"name": "alice", "friends": []interface{}{
+				map[string]interface{}{"name": "alice"}, map[string]interface{}{"name": "bob"},
+			}},
+			map[string]interface{}{"name": "bob", "friends": []interface{}{
+				map[string]interface{}{"name": "alice"},
+			}},
+		},
+	}}, data)
+
+	data, errs = execution.Do(schema, execution.Params{Query: query}, execution.DetectCycles())
+	assert.Equal(t, map[string]interface{}{"me": map[string]interface{}{"name": "alice", "friends": nil}}, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "cyclic value detected at path me.friends.0", errs[0].Message)
+		assert.Equal(t, []interface{}{"me", "friends"}, errs[0].Path)
+	}
+
+	// bob is below alice once
+	data, errs = execution.Do(schema, execution.Params{Query: `{ bob { friends { name } } }`}, execution.DetectCycles())
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"bob": map[string]interface{}{"friends": []interface{}{
+		map[string]interface{}{"name": "alice"},
+	}}}, data)
+
+	// the root object, me and the friends of me fit in 3 objects, not the friends of both friends
+	_, errs = execution.Do(schema, execution.Params{Query: query}, execution.MaxCompletionDepth(3))
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "value completion exceeded the maximum depth 3 at path me.friends.0.friends.0", errs[0].Message)
+	}
+	_, errs = execution.Do(schema, execution.Params{Query: query}, execution.MaxCompletionDepth(4))
+	assert.Empty(t, errs)
+}
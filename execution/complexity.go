@@ -0,0 +1,163 @@
+package execution
+
+import (
+	"encoding/json"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/internal"
+	"math"
+	"strings"
+)
+
+// MaxComplexity rejects the operations whose complexity is more than max, before any resolver is called,
+// with a single error of the rule MaxComplexityExceeded. Depth limits alone let wide queries through,
+// such as { all { friends { friends { name } } } } over lists of thousands of values:
+//
+//   graphql.HTTPHandler(schema, graphql.WithRules(execution.MaxComplexity(1000)))
+//
+// A field counts 1, or its Complexity, see schemabuilder.Cost and schemabuilder.CostFunc, besides the
+// complexity of the fields selected on its values. Those of a list are counted as many times as its
+// first or limit argument asks for, or once without such an argument. A field of an interface or a
+// union counts the costliest of its possible objects. The introspection fields, such as __schema
+// and __typename, count nothing.
+func MaxComplexity(max int) Rule {
+	return func(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}) error {
+		var name string
+		if op.Name != nil {
+			name = op.Name.Name
+		}
+		operationType, selectionSet, err := ApplySelectionSet(schema, document, name, vars)
+		if err != nil {
+			return err
+		}
+		root := schema.Query
+		switch operationType {
+		case ast.Mutation:
+			root = schema.Mutation
+		case ast.Subscription:
+			root = schema.Subscription
+		}
+		object, ok := root.(*internal.Object)
+		if !ok {
+			return nil
+		}
+		complexity, err := selectionSetComplexity(object, selectionSet, max)
+		if err != nil {
+			return err
+		}
+		if complexity > max {
+			return printErr(op.Loc, "MaxComplexityExceeded", "The operation is more complex than the maximum complexity of %d.", max)
+		}
+		return nil
+	}
+}
+
+// selectionSetComplexity returns the complexity of the fields of selectionSet selected on an object of
+// type typ, or a complexity more than max as soon as it is known to be.
+func selectionSetComplexity(typ *internal.Object, selectionSet *internal.SelectionSet, max int) (int, error) {
+	if selectionSet == nil {
+		return 0, nil
+	}
+	selections, err := flatten(typ, selectionSet, DefaultMaxObjectFields)
+	if err != nil {
+		return 0, err
+	}
+	complexity := 0
+	for _, selection := range selections {
+		field := typ.Fields[selection.Name]
+		if field == nil || strings.HasPrefix(selection.Name, "__") {
+			// introspection
+			continue
+		}
+		named, err := unwrapType(field.Type)
+		if err != nil {
+			return 0, err
+		}
+		var objects []*internal.Object
+		switch named := named.(type) {
+		case *internal.Object:
+			objects = []*internal.Object{named}
+		case *internal.Interface:
+			for _, name := range sortedObjectNames(named.PossibleTypes) {
+				objects = append(objects, named.PossibleTypes[name])
+			}
+		case *internal.Union:
+			for _, name := range sortedObjectNames(named.Types) {
+				objects = append(objects, named.Types[name])
+			}
+		}
+		// a value is of one of the possible objects, the costliest one is counted
+		child := 0
+		for _, object := range objects {
+			cost, err := selectionSetComplexity(object, selection.SelectionSet, max)
+			if err != nil {
+				return 0, err
+			}
+			if cost > child {
+				child = cost
+			}
+		}
+		args := explainArgs(field, selection.Args)
+		if size, ok := listSize(field.Type, args); ok {
+			if child > 0 && size > int64(max)/int64(child) {
+				child = max + 1
+			} else {
+				child = capComplexity(int64(child)*size, max)
+			}
+		}
+		cost := 1 + child
+		if field.Complexity != nil {
+			cost = field.Complexity(args, child)
+		}
+		complexity = capComplexity(int64(complexity)+int64(cost), max)
+		if complexity > max {
+			return complexity, nil
+		}
+	}
+	return complexity, nil
+}
+
+// listSize returns the number of values the list typ is asked for by the first or limit argument of args.
+func listSize(typ internal.Type, args map[string]interface{}) (int64, bool) {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		typ = nonNull.Type
+	}
+	if _, ok := typ.(*internal.List); !ok {
+		return 0, false
+	}
+	for _, name := range []string{"first", "limit"} {
+		var size int64
+		switch value := args[name].(type) {
+		case int:
+			size = int64(value)
+		case int64:
+			size = value
+		case float64:
+			// a float too large for an int64 does not convert to one
+			size = math.MaxInt64
+			if value < math.MaxInt64 {
+				size = int64(value)
+			}
+		case json.Number:
+			n, err := value.Int64()
+			if err != nil {
+				continue
+			}
+			size = n
+		default:
+			continue
+		}
+		if size < 0 {
+			size = 0
+		}
+		return size, true
+	}
+	return 0, false
+}
+
+// capComplexity returns complexity, or max+1 when it is more than max, so that sums can not overflow.
+func capComplexity(complexity int64, max int) int {
+	if complexity > int64(max) {
+		return max + 1
+	}
+	return int(complexity)
+}
@@ -0,0 +1,136 @@
+package execution
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"runtime"
+	"sync"
+)
+
+// Concurrency resolves the sibling fields of query operations on up to n goroutines at a time, the
+// one executing the operation included, so that a query fanning out to several backends takes about
+// as long as its slowest field instead of the sum of their latencies:
+//
+//   executor := execution.NewExecutor(schema, execution.Concurrency(8))
+//
+// The fields of mutations are always resolved one after the other, as the specification asks. A
+// field waits for no goroutine: when all of them are busy it is resolved by the goroutine of its
+// object. The result and its errors are the same as those of a serial execution, in the same order,
+// and a panic on one of the goroutines fails the field it resolves only. The resolvers of concurrent
+// operations must be safe for concurrent use. n less than 2 resolves the fields one after the other.
+func Concurrency(n int) Option {
+	return func(e *Executor) {
+		e.concurrency = n
+	}
+}
+
+// operationState is the state of the execution of an operation shared by the goroutines resolving
+// its fields.
+type operationState struct {
+	// failed is set by the first error of a FailFast execution, interrupted once a field reported
+	// that the context of the operation is done
+	failed, interrupted int32
+	// slots holds a token for every goroutine resolving fields besides the one executing the
+	// operation, it is nil when the fields are resolved one after the other
+	slots chan struct{}
+	// pending are the fields of the level being resolved whose resolvers returned thunks
+	mu      sync.Mutex
+	pending []*pendingField
+	// slowFields times the resolvers, see RecordSlowFields
+	slowFields *slowFields
+	// flattened are the selections of the selection sets flattened for an object type, guarded by mu
+	flattened map[flattenKey][]*internal.Selection
+	// objectTypes are the object types of the results of the objects by their pointers, recorded for
+	// the post-processors, guarded by mu
+	objectTypes map[uintptr]*internal.Object
+	// nullData is set once a null propagated from a field whose resolver returned a thunk to the data
+	nullData bool
+}
+
+type flattenKey struct {
+	typ          *internal.Object
+	selectionSet *internal.SelectionSet
+}
+
+// branch returns a copy of the context for resolving a field on another goroutine, with its own path,
+// ancestors, errors and warnings.
+func (e *exeContext) branch() *exeContext {
+	branch := *e
+	branch.errs, branch.warnings = nil, nil
+	branch.path = append([]interface{}(nil), e.path...)
+	branch.ancestors = append([]uintptr(nil), e.ancestors...)
+	branch.slots = append([]nullSlot(nil), e.slots...)
+	return &branch
+}
+
+// executeFieldsConcurrently is the part of executeObject resolving selections on goroutines while
+// slots are free. The errors and warnings of every field are added to ctx in the order of selections.
+func (e *Executor) executeFieldsConcurrently(ctx *exeContext, typ *internal.Object, source interface{},
+	selections []*internal.Selection) (map[string]interface{}, error) {
+	type result struct {
+		ctx   *exeContext
+		value interface{}
+		ok    bool
+		err   error
+	}
+	results := make([]result, len(selections))
+	// the fields are set once they are all resolved, the slots of the thunks refer to the map before
+	fields := make(map[string]interface{})
+	ctx.recordObject(fields, typ)
+	var wg sync.WaitGroup
+	for i, selection := range selections {
+		branch := ctx.branch()
+		results[i].ctx = branch
+		if !branch.startField(selection) {
+			break
+		}
+		branch.enterSlot(fieldSlot(fields, typ, selection))
+		select {
+		case ctx.state.slots <- struct{}{}:
+			wg.Add(1)
+			go func(i int, selection *internal.Selection) {
+				defer func() {
+					if panicErr := recover(); panicErr != nil {
+						const size = 64 << 10
+						buf := make([]byte, size)
+						buf = buf[:runtime.Stack(buf, false)]
+						// the path of the branch is left where the panic happened
+						branch := results[i].ctx
+						branch.path = append(append([]interface{}(nil), ctx.path...), selection.Alias)
+						branch.addErr(selection.Loc, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf))
+						results[i].value, results[i].ok = nil, true
+						if field := typ.Fields[selection.Name]; field != nil {
+							if _, nonNull := field.Type.(*internal.NonNull); nonNull {
+								results[i].err = errNull
+							}
+						}
+					}
+					<-ctx.state.slots
+					wg.Done()
+				}()
+				results[i].value, results[i].ok, results[i].err = e.executeField(results[i].ctx, typ, source, selection)
+			}(i, selection)
+		default:
+			results[i].value, results[i].ok, results[i].err = e.executeField(branch, typ, source, selection)
+		}
+	}
+	wg.Wait()
+
+	var null bool
+	for i, result := range results {
+		if result.ctx == nil {
+			break
+		}
+		ctx.errs = append(ctx.errs, result.ctx.errs...)
+		ctx.warnings = append(ctx.warnings, result.ctx.warnings...)
+		if result.err != nil {
+			null = true
+		} else if result.ok {
+			setField(fields, selections[i].Alias, result.value)
+		}
+	}
+	if null {
+		return nil, errNull
+	}
+	return fields, nil
+}
@@ -0,0 +1,117 @@
+package execution_test
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gauge records the most goroutines running between its enter and leave calls at a time.
+type gauge struct {
+	mu           sync.Mutex
+	running, max int
+}
+
+func (g *gauge) enter() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running++
+	if g.running > g.max {
+		g.max = g.running
+	}
+}
+
+func (g *gauge) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+}
+
+type broken struct{}
+
+func TestConcurrency(t *testing.T) {
+	type Order struct {
+		ID int `graphql:"id"`
+	}
+	var running gauge
+	slow := func(value int, delay time.Duration) func() (int, error) {
+		return func() (int, error) {
+			running.enter()
+			defer running.leave()
+			time.Sleep(delay)
+			if value < 0 {
+				return 0, fmt.Errorf("failed after %s", delay)
+			}
+			return value, nil
+		}
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("Order", Order{})
+	build.Scalar("Broken", broken{}, func(value interface{}, dest reflect.Value) error { return nil }).
+		Serialize = func(value interface{}) (interface{}, error) { panic("cannot serialize") }
+	query := build.Query()
+	query.FieldFunc("a", slow(1, 30*time.Millisecond))
+	query.FieldFunc("b", slow(2, 30*time.Millisecond))
+	query.FieldFunc("c", slow(3, 30*time.Millisecond))
+	query.FieldFunc("d", slow(4, 30*time.Millisecond))
+	// the failing fields are nullable, so that they do not make the data null
+	nullable := func(resolve func() (int, error)) func() (*int, error) {
+		return func() (*int, error) {
+			_, err := resolve()
+			return nil, err
+		}
+	}
+	query.FieldFunc("late", nullable(slow(-1, 40*time.Millisecond)))
+	query.FieldFunc("early", nullable(slow(-1, 10*time.Millisecond)))
+	query.FieldFunc("required", slow(-1, 10*time.Millisecond))
+	query.FieldFunc("orders", func() []Order { return []Order{{ID: 1}, {ID: 2}} })
+	query.FieldFunc("broken", func() *broken { return &broken{} })
+	var mutating gauge
+	build.Mutation().FieldFunc("save", func() bool {
+		mutating.enter()
+		defer mutating.leave()
+		time.Sleep(10 * time.Millisecond)
+		return true
+	})
+	schema := build.MustBuild()
+
+	// the fields run at most 3 at a time, and their result and errors are those of a serial execution
+	start := time.Now()
+	data, errs := execution.Do(schema, execution.Params{Query: `{ a b c d late orders { id } early }`}, execution.Concurrency(3))
+	elapsed := time.Since(start)
+	assert.Equal(t, map[string]interface{}{
+		"a": 1, "b": 2, "c": 3, "d": 4, "late": nil, "early": nil,
+		"orders": []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}},
+	}, data)
+	assert.EqualError(t, errs, "[graphql: failed after 40ms (1:11) path: [late]\ngraphql: failed after 10ms (1:30) path: [early]]")
+	assert.Equal(t, 3, running.max)
+	assert.True(t, elapsed < 150*time.Millisecond, "resolved in %s", elapsed)
+
+	serialData, serialErrs := execution.Do(schema, execution.Params{Query: `{ a b c d late orders { id } early }`})
+	assert.Equal(t, data, serialData)
+	assert.Equal(t, errs.Error(), serialErrs.Error())
+
+	// a panic on a goroutine fails its field only
+	data, errs = execution.Do(schema, execution.Params{Query: `{ broken a }`}, execution.Concurrency(2))
+	assert.Equal(t, map[string]interface{}{"broken": nil, "a": 1}, data)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Message, "graphql: panic: cannot serialize")
+		assert.Equal(t, []interface{}{"broken"}, errs[0].Path)
+	}
+
+	// a non-null field failing on a goroutine makes its object null
+	data, errs = execution.Do(schema, execution.Params{Query: `{ a required b }`}, execution.Concurrency(3))
+	assert.Nil(t, data)
+	assert.EqualError(t, errs, "[graphql: failed after 10ms (1:5) path: [required]]")
+
+	// the fields of mutations are resolved one after the other
+	data, errs = execution.Do(schema, execution.Params{Query: `mutation { first: save second: save third: save }`}, execution.Concurrency(3))
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"first": true, "second": true, "third": true}, data)
+	assert.Equal(t, 1, mutating.max)
+}
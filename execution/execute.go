@@ -2,33 +2,233 @@ package execution
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"github.com/shyptr/graphql/ast"
 	"github.com/shyptr/graphql/errors"
 	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/internal/clock"
 	"github.com/shyptr/graphql/schemabuilder"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// Executor executes operations. An Executor created by NewExecutor owns the schema it executes
+// operations against, and the cache of their plans, see Do and Swap.
 type Executor struct {
-	iterate bool
+	iterate                 bool
+	failFast                bool
+	strictFields            bool
+	allowUnknownInputFields bool
+	maxDepth                int
+	maxCompletionDepth      int
+	maxObjectFields         int
+	maxExpandedFields       int
+	detectCycles            bool
+	budget                  int64
+	estimate                SizeEstimator
+	clock                   clock.Clock
+	plans                   PlanCache
+	usage                   *UsageCollector
+	stats                   *StatsCollector
+	transform               VariableTransform
+	rewriter                DocumentRewriter
+	concurrency             int
+	postProcessors          []ResponsePostProcessor
+	// current holds the *version served by Do
+	current atomic.Value
+}
+
+// Option configures an Executor.
+type Option func(*Executor)
+
+// FailFast stops the execution on the first field error: the operation context is cancelled,
+// no further resolver is called and the response holds the data and the error collected so far.
+func FailFast() Option {
+	return func(e *Executor) {
+		e.failFast = true
+	}
+}
+
+// StrictFields makes a field without a resolver fail when the map or struct its object resolved to
+// has no value for it, instead of resolving it to null, to catch the fields of a schema written in
+// SDL which its data source names differently. Missing values of non-null fields always fail.
+func StrictFields() Option {
+	return func(e *Executor) {
+		e.strictFields = true
+	}
+}
+
+// AllowUnknownInputFields makes the executor drop the fields of input object values, whether written in
+// the query or given by variables, which their type does not define, logging a warning with the log
+// package, instead of rejecting the operation. It is meant for servers whose clients may send fields
+// of a newer version of the schema.
+func AllowUnknownInputFields() Option {
+	return func(e *Executor) {
+		e.allowUnknownInputFields = true
+	}
+}
+
+// MaxDepth rejects operations nesting fields deeper than n, counting the fields of fragments where
+// they are spread, to bound the cost of malicious queries. The root fields have depth 1.
+func MaxDepth(n int) Option {
+	return func(e *Executor) {
+		e.maxDepth = n
+	}
+}
+
+// DefaultMaxObjectFields is the number of fields an object may be selected with unless MaxObjectFields
+// sets it.
+const DefaultMaxObjectFields = 500
+
+// MaxObjectFields fails the objects selected with more than n fields, counting the distinct response
+// keys of the object once its fragments are expanded and the fields excluded by skip and include are
+// dropped. The result of every object holds one entry per key, so the limit bounds operations stacking
+// thousands of aliases of cheap fields such as __typename on one object, which complexity limits may
+// undercount. It is DefaultMaxObjectFields when n is not positive.
+func MaxObjectFields(n int) Option {
+	return func(e *Executor) {
+		e.maxObjectFields = n
+	}
+}
+
+// DefaultMaxExpandedFields is the number of fields an operation may expand to unless MaxExpandedFields
+// sets it.
+const DefaultMaxExpandedFields = 10000
+
+// MaxExpandedFields rejects the operations whose selection sets hold more than n fields once their
+// fragments are spread, with a single error of the rule MaxExpandedFieldsExceeded, before any resolver
+// is called. The fields of the same response key of an object are merged, and a fragment spread many
+// times on an object counts once, but a fragment spread in many fields counts in each of them: ten
+// fragments each spreading the next one in two aliased fields expand to 2^10 fields, which no cycle
+// detection catches. It is DefaultMaxExpandedFields when n is not positive.
+func MaxExpandedFields(n int) Option {
+	return func(e *Executor) {
+		e.maxExpandedFields = n
+	}
+}
+
+// WithClock replaces the time source of the executor, such as the one of the times usage is
+// recorded at, it is meant for tests.
+func WithClock(clock clock.Clock) Option {
+	return func(e *Executor) {
+		e.clock = clock
+	}
+}
+
+// NewExecutor returns an Executor of operations against schema, configured by opts.
+func NewExecutor(schema *internal.Schema, opts ...Option) *Executor {
+	e := &Executor{clock: clock.Real}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.plans == nil {
+		e.plans = NewPlanCache(DefaultPlanCacheSize)
+	}
+	if cache, ok := e.plans.(StatsCache); ok && e.stats != nil {
+		e.stats.AddCache("plans", cache)
+	}
+	e.Swap(schema)
+	return e
+}
+
+// Swap makes the executor execute the operations of the following calls to Do against schema.
+// Operations already running finish against the schema they started with. The plans of the previous
+// schema are kept in the cache, and plans for schema are made as operations come.
+func (e *Executor) Swap(schema *internal.Schema) {
+	e.current.Store(&version{schema: schema, hash: SchemaHash(schema)})
+}
+
+// Schema returns the schema the following calls to Do execute operations against.
+func (e *Executor) Schema() *internal.Schema {
+	if v, ok := e.current.Load().(*version); ok {
+		return v.schema
+	}
+	return nil
+}
+
+// now returns the time of the clock of the executor, Executors which are not created by NewExecutor
+// use the real one.
+func (e *Executor) now() time.Time {
+	if e.clock == nil {
+		return clock.Real.Now()
+	}
+	return e.clock.Now()
 }
 
 type exeContext struct {
 	context.Context
 	errs errors.MultiError
-	path []interface{}
+	// warnings are the fields which resolved to null with a warning rather than an error
+	warnings []Warning
+	path     []interface{}
+	cancel   context.CancelFunc
+	// dynamic is the DynamicOutput of the field being completed
+	dynamic bool
+	// budget is set by MemoryBudget
+	budget *memoryBudget
+	// depth is the number of objects being completed, ancestors are their pointers with DetectCycles
+	depth              int
+	maxCompletionDepth int
+	maxObjectFields    int
+	detectCycles       bool
+	ancestors          []uintptr
+	// slots are the positions of the result the field being resolved is within, see propagateNull
+	slots []nullSlot
+	// state is shared by the copies of the context resolving fields concurrently
+	state *operationState
 }
 
 func (e *exeContext) addErr(location errors.Location, err error) {
-	e.errs = append(e.errs, &errors.GraphQLError{
+	if warning, ok := err.(*internal.Warning); ok {
+		e.addWarning(warning)
+		return
+	}
+	if err == context.Canceled && e.cancel != nil && atomic.LoadInt32(&e.state.failed) == 1 {
+		// the fields resolved concurrently with the one failing a FailFast execution are cancelled
+		return
+	}
+	graphqlErr := &errors.GraphQLError{
 		Message:       err.Error(),
 		ResolverError: err,
 		Locations:     []errors.Location{location},
-		Path:          e.path,
-	})
+		// the path keeps changing while the execution goes on
+		Path: append([]interface{}(nil), e.path...),
+	}
+	var extended errors.ExtendedError
+	if stderrors.As(err, &extended) {
+		if extensions := extended.Extensions(); len(extensions) > 0 {
+			graphqlErr.Extensions = extensions
+		}
+	}
+	e.errs = append(e.errs, graphqlErr)
+	if e.done() != nil {
+		atomic.StoreInt32(&e.state.interrupted, 1)
+	}
+	if e.cancel != nil {
+		atomic.StoreInt32(&e.state.failed, 1)
+		e.cancel()
+	}
+}
+
+// stopped reports whether a fail fast execution already failed, or the result exceeded its memory budget.
+func (e *exeContext) stopped() bool {
+	return e.cancel != nil && atomic.LoadInt32(&e.state.failed) == 1 || e.budget.exceeded() ||
+		atomic.LoadInt32(&e.state.interrupted) == 1
+}
+
+// done returns the error of the context of the operation once it is cancelled or past its deadline.
+func (e *exeContext) done() error {
+	if err := e.Err(); err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	return nil
 }
 
 func (e *exeContext) updatePath(add bool, path ...interface{}) {
@@ -44,62 +244,193 @@ type Params struct {
 	Query         string                 `json:"query"`
 	OperationName string                 `json:"operationName"`
 	Variables     map[string]interface{} `json:"variables"`
-	Context       context.Context        `json:"context"`
+	Extensions    map[string]interface{} `json:"extensions"`
+	// Context is the context the resolvers get. Once it is cancelled or past its deadline the remaining
+	// fields are not resolved: the field being resolved fails with the error of the context and the
+	// others are left out of the data.
+	Context context.Context `json:"context"`
+	// Rules are the optional validation rules to run for this request, such as NoDeprecated.
+	Rules []Rule `json:"-"`
+}
+
+// Result is the response to an operation, encoded as the GraphQL specification describes it
+// by encoding/json. Errors are intentionally serialized first based on the advice in
+// https://github.com/facebook/graphql/commit/7b40390d48680b15cb93e02d46ac5eb249689876#diff-757cea6edf0288677a9eea4cfc801d87R107
+type Result struct {
+	Errors     []*errors.GraphQLError `json:"errors,omitempty"`
+	Data       interface{}            `json:"data,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// WithReadOnly returns a copy of ctx marking the operation executed with it as read-only, so that
+// code checking IsReadOnly refuses to write. Do and the HTTP handler mark query operations themselves.
+func WithReadOnly(ctx context.Context) context.Context {
+	return internal.WithReadOnly(ctx)
 }
 
-func Do(schema *internal.Schema, param Params) (interface{}, errors.MultiError) {
+// IsReadOnly reports whether the operation executed with ctx is read-only.
+func IsReadOnly(ctx context.Context) bool {
+	return internal.IsReadOnly(ctx)
+}
+
+// Do executes the operation of param against schema, with an Executor configured by opts, such as
+// FailFast or MaxDepth:
+//
+//   data, errs := execution.Do(schema, params, execution.FailFast(), execution.MaxDepth(10))
+//
+// The data holds the fields which could be resolved besides the errors of the others: a field which
+// failed is null, or makes its object null when it is non-null, up to the closest nullable field or
+// list item. The data is nil when a non-null root field failed, or when the operation was rejected
+// before it was executed.
+func Do(schema *internal.Schema, param Params, opts ...Option) (map[string]interface{}, errors.MultiError) {
+	return NewExecutor(schema, opts...).Do(param)
+}
 
-	doc, err := internal.Parse(param.Query)
+// Do executes the operation of param against the schema of the executor, parsing and validating
+// the query only when its plan is not cached yet.
+func (e *Executor) Do(param Params) (map[string]interface{}, errors.MultiError) {
+	v, ok := e.current.Load().(*version)
+	if !ok {
+		return nil, errors.MultiError{errors.New("executor has no schema, create it with NewExecutor")}
+	}
+	query, err := e.RewriteQuery(param.Context, param.Query)
 	if err != nil {
 		return nil, []*errors.GraphQLError{err.(*errors.GraphQLError)}
 	}
+	plan := e.plan(v, query)
+	if plan.Err != nil {
+		if err, ok := plan.Err.(*errors.GraphQLError); ok {
+			return nil, errors.MultiError{err}
+		}
+		return nil, errors.MultiError{errors.New("%s", plan.Err.Error())}
+	}
 
-	operationType, selectionSet, err := ApplySelectionSet(schema, doc, param.OperationName, param.Variables)
+	vars, err := e.TransformVariables(param.Context, plan.Document, param.OperationName, param.Variables)
 	if err != nil {
 		return nil, []*errors.GraphQLError{err.(*errors.GraphQLError)}
 	}
-	root := schema.Query
+	operationType, selectionSet, err := e.ApplySelectionSet(v.schema, plan.Document, param.OperationName, vars)
+	if err != nil {
+		return nil, []*errors.GraphQLError{err.(*errors.GraphQLError)}
+	}
+	if e.maxDepth > 0 {
+		if err := checkDepth(selectionSet, e.maxDepth, 1); err != nil {
+			return nil, errors.MultiError{err}
+		}
+	}
+	if errs := ValidateRules(v.schema, plan.Document, param.OperationName, vars, param.Rules...); len(errs) > 0 {
+		return nil, errs
+	}
+	root := v.schema.Query
 	if operationType == ast.Mutation {
-		root = schema.Mutation
+		root = v.schema.Mutation
 	}
-	executor := &Executor{}
 	ctx := param.Context
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return executor.Execute(ctx, root, nil, selectionSet)
+	if operationType == ast.Query {
+		ctx = WithReadOnly(ctx)
+	}
+	data, errs := e.Execute(ctx, root, nil, selectionSet)
+	response, _ := data.(map[string]interface{})
+	return response, errs
 }
 
 func (e *Executor) Execute(ctx context.Context, typ internal.Type, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, errors.MultiError) {
-	exeCtx := &exeContext{Context: ctx}
+	if e.usage != nil {
+		e.usage.record(Coordinates(typ, selectionSet), e.now())
+	}
+	exeCtx := &exeContext{Context: internal.WithMemo(ctx), maxCompletionDepth: e.maxCompletionDepth,
+		maxObjectFields: e.maxObjectFields, detectCycles: e.detectCycles, state: &operationState{}}
+	exeCtx.state.slowFields, _ = ctx.Value(slowFieldsKey{}).(*slowFields)
+	if e.concurrency > 1 && IsReadOnly(ctx) {
+		exeCtx.state.slots = make(chan struct{}, e.concurrency-1)
+	}
+	if len(e.postProcessors) > 0 {
+		exeCtx.state.objectTypes = make(map[uintptr]*internal.Object)
+	}
+	if e.failFast {
+		exeCtx.Context, exeCtx.cancel = context.WithCancel(exeCtx.Context)
+		defer exeCtx.cancel()
+	}
+	if e.budget > 0 {
+		exeCtx.budget = &memoryBudget{limit: e.budget, estimate: e.estimate}
+		exeCtx.Context = context.WithValue(exeCtx.Context, memoryBudgetKey{}, exeCtx.budget)
+	}
 	response, err := e.execute(exeCtx, typ, source, selectionSet)
-	if err != nil {
+	if err != nil && err != errNull {
 		exeCtx.addErr(selectionSet.Loc, err)
 	}
+	e.completeThunks(exeCtx)
+	if exeCtx.state.nullData {
+		response = nil
+	}
+	exeCtx.reportWarnings()
+	// errors are listed by location, those of the items of a list keep the order of the items
+	sort.SliceStable(exeCtx.errs, func(i, j int) bool {
+		return exeCtx.errs[i].Locations[0].Before(exeCtx.errs[j].Locations[0])
+	})
+	if exeCtx.budget.exceeded() {
+		// the partial result is dropped, it is what the budget protects the memory from
+		return nil, append(exeCtx.errs, exeCtx.budget.err())
+	}
+	if len(e.postProcessors) > 0 {
+		if err := e.postProcess(exeCtx, typ, response, selectionSet); err != nil {
+			return nil, errors.MultiError{err}
+		}
+	}
 	return response, exeCtx.errs
 }
 
 func (e *Executor) execute(ctx *exeContext, typ internal.Type, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, error) {
 	if err := ctx.Err(); err != nil {
-		if err, ok := err.(errors.MultiError); ok {
-			if err != nil {
-				return nil, err
-			}
-		} else {
+		if multi, ok := err.(errors.MultiError); !ok || multi != nil {
 			return nil, err
 		}
 	}
 	switch typ := typ.(type) {
 	case *internal.Scalar:
+		source, err := nullableValue(typ, source)
+		if err != nil {
+			return nil, err
+		}
+		if unwrap(source) == nil {
+			return nil, nil
+		}
+		if !ctx.dynamic {
+			if err := checkScalarOutput(typ, source); err != nil {
+				return nil, err
+			}
+		}
+		value := unwrap(source)
 		if typ.Serialize != nil {
-			return typ.Serialize(source)
+			if value, err = typ.Serialize(source); err != nil {
+				return nil, err
+			}
 		}
-		return unwrap(source), nil
+		// raw JSON is encoded as it is, it must not break the response
+		if raw, ok := value.(json.RawMessage); ok && !json.Valid(raw) {
+			return nil, fmt.Errorf("%s serialized to invalid JSON", typ.Name)
+		}
+		ctx.budget.chargeValue(value)
+		return value, nil
 	case *internal.Enum:
 		val := unwrap(source)
+		if val == nil {
+			return nil, nil
+		}
+		if typ.OutputMapper != nil {
+			mapped, err := typ.OutputMapper(val)
+			if err != nil {
+				return nil, err
+			}
+			val = mapped
+		}
 		if mapVal, ok := typ.Map[val]; ok {
+			ctx.budget.chargeValue(mapVal)
 			return mapVal, nil
 		}
 		return nil, errors.New("enum is not valid")
@@ -112,12 +443,18 @@ func (e *Executor) execute(ctx *exeContext, typ internal.Type, source interface{
 	case *internal.List:
 		return e.executeList(ctx, typ, source, selectionSet)
 	case *internal.NonNull:
+		// a nil slice is an empty list where the list can not be null, see schemabuilder.StrictNullability
+		if _, ok := typ.Type.(*internal.List); ok {
+			if value := reflect.ValueOf(source); value.Kind() == reflect.Slice && value.IsNil() {
+				return []interface{}{}, nil
+			}
+		}
 		result, err := e.execute(ctx, typ.Type, source, selectionSet)
 		if err != nil {
 			return nil, err
 		}
 		if result == nil {
-			return nil, fmt.Errorf("cannot return null for non-nullable field %v", typ.Type)
+			return nil, &nullError{typ: typ.Type}
 		}
 		return result, nil
 	default:
@@ -125,6 +462,25 @@ func (e *Executor) execute(ctx *exeContext, typ internal.Type, source interface{
 	}
 }
 
+// errNull is returned instead of the value of a non-null field which is null because of an error
+// already added to the context, the null propagates to the closest nullable field or list item,
+// or to the data of the response, without another error.
+var errNull = stderrors.New("graphql: a non-null field is null")
+
+// nullError reports a null value of the non-null type typ, resolveAndExecute names the field it
+// belongs to when typ is the type of a field.
+type nullError struct {
+	typ internal.Type
+}
+
+func (e *nullError) Error() string {
+	return fmt.Sprintf("cannot return null for non-nullable field %s", e.typ.String())
+}
+
+func nonNullFieldError(typ *internal.Object, field *internal.Field) error {
+	return fmt.Errorf("Cannot return null for non-nullable field %s.%s.", typ.Name, field.Name)
+}
+
 // unwrap will return the value associated with a pointer type, or nil if the pointer is nil
 func unwrap(v interface{}) interface{} {
 	i := reflect.ValueOf(v)
@@ -137,148 +493,354 @@ func unwrap(v interface{}) interface{} {
 	return i.Interface()
 }
 
+// TypeNamer is implemented by the values which name their object type themselves, such as the proxies
+// of the objects of an upstream server, whose Go type is the same whatever the type of the object.
+// The object type of such a value of an interface or a union is the possible type it names, before
+// the TypeResolve of the abstract type is consulted, and its __typename is the name it returns.
+type TypeNamer interface {
+	GraphQLTypeName() string
+}
+
+// namedObject returns the object among possible, the possible types of the abstract type kind name, which
+// source names when it is a TypeNamer, with false when it is not one. A name which is not a possible
+// type is an error.
+func namedObject(source interface{}, possible map[string]*internal.Object, kind, name string) (*internal.Object, bool, error) {
+	namer, ok := source.(TypeNamer)
+	if !ok {
+		return nil, false, nil
+	}
+	typeName := namer.GraphQLTypeName()
+	if object, ok := possible[typeName]; ok {
+		return object, true, nil
+	}
+	return nil, true, fmt.Errorf("%T is named %q, which is not a possible type of %s %s", source, typeName, kind, name)
+}
+
+// builtinScalars are the scalars of the specification, whose values are strings, numbers and booleans.
+var builtinScalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// checkScalarOutput rejects values of built-in scalars which are not strings, numbers or booleans, such
+// as a struct a resolver returns for a String field after a refactoring, which Serialize would otherwise
+// send as a JSON string. Byte slices and types implementing json.Marshaler are accepted.
+func checkScalarOutput(typ *internal.Scalar, source interface{}) error {
+	if !builtinScalars[typ.Name] {
+		return nil
+	}
+	value := unwrap(source)
+	switch value.(type) {
+	case []byte, json.Marshaler:
+		return nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Func, reflect.Chan:
+		return fmt.Errorf("expected %s, resolver returned %T", typ.Name, value)
+	}
+	return nil
+}
+
+// nullableValue returns nil for the nullable wrappers such as sql.NullString which are not valid,
+// anything implementing driver.Valuer is null when its value is nil. A valid wrapper is passed to
+// Serialize dereferenced, built-in scalars only serialize plain values and get the value of the wrapper.
+// Types implementing json.Marshaler serialize themselves and are left as they are.
+func nullableValue(typ *internal.Scalar, source interface{}) (interface{}, error) {
+	val := unwrap(source)
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	if _, ok := val.(json.Marshaler); ok {
+		return source, nil
+	}
+	valuer, ok := val.(driver.Valuer)
+	if !ok {
+		return source, nil
+	}
+	value, err := valuer.Value()
+	if err != nil || value == nil {
+		return nil, err
+	}
+	if builtinScalars[typ.Name] {
+		return value, nil
+	}
+	return val, nil
+}
+
 func (e *Executor) executeUnion(ctx *exeContext, typ *internal.Union, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
 	}
-
-	fields := make(map[string]interface{})
-
-	var possibleTypes []string
-	for typString, object := range typ.Types {
-		inner := value
-		if inner.Kind() == reflect.Ptr && inner.Elem().Kind() == reflect.Struct {
-			inner = inner.Elem()
+	if object, ok, err := namedObject(source, typ.Types, "union", typ.Name); ok {
+		if err != nil {
+			return nil, err
 		}
-		inner = *schemabuilder.GetField(inner, typString)
-		if inner.IsNil() {
-			continue
+		return e.executeObject(ctx, object, source, selectionSet)
+	}
+	if typ.TypeResolve != nil {
+		if unwrap(source) == nil {
+			return nil, nil
 		}
-		possibleTypes = append(possibleTypes, object.String())
-		for _, selection := range selectionSet.Selections {
-			func() {
-				ctx.updatePath(true, selection.Name)
-				defer func() {
-					ctx.updatePath(false)
-				}()
-				if selection.Name == "__typename" {
-					fields[selection.Alias] = object.Name
-					return
-				}
-				field := object.Fields[selection.Name]
-				if field != nil {
-					resolved, err := e.resolveAndExecute(ctx, field, inner.Interface(), selection)
-					if err != nil {
-						ctx.addErr(selection.Loc, err)
-						fields[selection.Alias] = nil
-						return
-					}
-					fields[selection.Alias] = resolved
-				}
-			}()
-		}
-
-		for _, fragment := range selectionSet.Fragments {
-			func() {
-				ctx.updatePath(true, fragment.Fragment.Name)
-				defer func() {
-					ctx.updatePath(false)
-				}()
-				if fragment.Fragment.On != typString && fragment.Fragment.On != typ.Name {
-					if _, ok := object.Interfaces[fragment.Fragment.On]; !ok {
-						return
-					}
-				}
-				resolved, err := e.executeObject(ctx, object, inner.Interface(), fragment.Fragment.SelectionSet)
-				if err != nil {
-					ctx.addErr(fragment.Loc, err)
-					return
-				}
-
-				for k, v := range resolved.(map[string]interface{}) {
-					fields[k] = v
-				}
-				return
-			}()
+		object, err := typ.TypeResolve(ctx.Context, source)
+		if err != nil {
+			return nil, fmt.Errorf("can not resolve the type for union %s: %w", typ.Name, err)
+		}
+		return e.executeObject(ctx, object, source, selectionSet)
+	}
+	if value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Struct {
+		value = value.Elem()
+	}
 
+	var possibleTypes []string
+	var object *internal.Object
+	var inner reflect.Value
+	for typString, possibleType := range typ.Types {
+		field := *schemabuilder.GetField(value, typString)
+		if field.IsNil() {
+			continue
 		}
+		possibleTypes = append(possibleTypes, possibleType.String())
+		object, inner = possibleType, field
 	}
 
 	if len(possibleTypes) > 1 {
 		return nil, fmt.Errorf("union type field should only return one value, but received: %s", strings.Join(possibleTypes, " "))
 	}
-	return fields, nil
+	if object == nil {
+		return map[string]interface{}{}, nil
+	}
+	// the fields selected on the union and on the fragments applying to the member are collected
+	// together, so that every response key is resolved once
+	return e.executeObject(ctx, object, inner.Interface(), selectionSet)
 }
 
 func (e *Executor) executeObject(ctx *exeContext, typ *internal.Object, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
-	if value.Kind() == reflect.Ptr && value.IsNil() {
+	if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Map) && value.IsNil() {
 		return nil, nil
 	}
+	if namer, ok := source.(TypeNamer); ok && namer.GraphQLTypeName() != typ.Name {
+		return nil, fmt.Errorf("%T is named %q, it can not be a %s", source, namer.GraphQLTypeName(), typ.Name)
+	}
 
-	selections, err := Flatten(selectionSet)
+	selections, err := ctx.flatten(typ, selectionSet)
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.enterObject(value); err != nil {
+		return nil, err
+	}
+	defer ctx.leaveObject()
 
-	fields := make(map[string]interface{})
+	ctx.budget.charge(objectOverhead)
+	if ctx.state.slots != nil && len(selections) > 1 {
+		return e.executeFieldsConcurrently(ctx, typ, source, selections)
+	}
 
+	fields := make(map[string]interface{})
+	ctx.recordObject(fields, typ)
+	// the fields after a null non-null field are still resolved, and report their errors, but the
+	// object is null
+	var null bool
 	// for every selection, resolve the value and store it in the output object
 	for _, selection := range selections {
-		func() {
-			ctx.updatePath(true, selection.Alias)
-			defer func() {
-				ctx.updatePath(false)
-			}()
-			field := typ.Fields[selection.Name]
-			if len(selection.Directives) > 0 {
-				for _, directive := range selection.Directives {
-					next, result, err := directive.FnResolve(ctx, directive.ArgVals, field.Resolve, source, selection.Args)
-					if err != nil {
-						ctx.addErr(directive.Loc, err)
-						return
-					}
-					fields[selection.Alias] = result
-					if !next {
-						break
-					}
-				}
-				return
-			}
+		if !ctx.startField(selection) {
+			break
+		}
+		ctx.enterSlot(fieldSlot(fields, typ, selection))
+		value, ok, err := e.executeField(ctx, typ, source, selection)
+		ctx.leaveSlot()
+		if err != nil {
+			null = true
+		} else if ok {
+			setField(fields, selection.Alias, value)
+		}
+	}
+	if null {
+		return nil, errNull
+	}
+	return fields, nil
+}
 
-			if selection.Name == "__typename" {
-				fields[selection.Alias] = typ.Name
-				return
-			}
+// flatten is flatten for an object of type typ, with the selections of selectionSet flattened once
+// for the operation: the objects of a list, and the fields spreading the same fragment, reuse them
+// instead of merging their selections again.
+func (e *exeContext) flatten(typ *internal.Object, selectionSet *internal.SelectionSet) ([]*internal.Selection, error) {
+	key := flattenKey{typ: typ, selectionSet: selectionSet}
+	e.state.mu.Lock()
+	selections, ok := e.state.flattened[key]
+	e.state.mu.Unlock()
+	if ok {
+		return selections, nil
+	}
+	selections, err := flatten(typ, selectionSet, e.maxObjectFields)
+	if err != nil {
+		return nil, err
+	}
+	e.state.mu.Lock()
+	if e.state.flattened == nil {
+		e.state.flattened = make(map[flattenKey][]*internal.Selection)
+	}
+	e.state.flattened[key] = selections
+	e.state.mu.Unlock()
+	return selections, nil
+}
 
-			if field != nil {
-				resolved, err := e.resolveAndExecute(ctx, field, source, selection)
-				if err != nil {
-					ctx.addErr(selection.Loc, err)
-					fields[selection.Alias] = nil
-					return
-				}
-				fields[selection.Alias] = resolved
-			}
-			return
-		}()
+// startField reports whether the field of selection is resolved: the fields are not resolved once a
+// fail fast execution failed, the result exceeded its memory budget, or the context is done, in which
+// case the first field which is not resolved reports why.
+func (e *exeContext) startField(selection *internal.Selection) bool {
+	if e.stopped() {
+		return false
 	}
-	return fields, nil
+	if err := e.done(); err != nil {
+		e.updatePath(true, selection.Alias)
+		e.addErr(selection.Loc, err)
+		e.updatePath(false)
+		return false
+	}
+	e.budget.charge(fieldOverhead + int64(len(selection.Alias)))
+	return true
 }
 
-func (e *Executor) resolveAndExecute(ctx *exeContext, field *internal.Field, source interface{},
+// executeField returns the value of the field of selection on the object source of type typ, with
+// false when the object has no such field. The errors of the field are added to ctx, and make it null,
+// or make the object null when the field is non-null: executeField then returns errNull.
+func (e *Executor) executeField(ctx *exeContext, typ *internal.Object, source interface{},
+	selection *internal.Selection) (interface{}, bool, error) {
+	ctx.updatePath(true, selection.Alias)
+	defer func() {
+		ctx.updatePath(false)
+	}()
+	field := typ.Fields[selection.Name]
+	if field == nil {
+		field = selection.MetaField
+	}
+
+	if selection.Name == "__typename" {
+		return typ.Name, true, nil
+	}
+	if field == nil {
+		return nil, false, nil
+	}
+
+	resolved, err := e.resolveAndExecute(ctx, typ, field, source, selection)
+	if err == nil {
+		return resolved, true, nil
+	}
+	if directiveErr, ok := err.(*directiveError); ok {
+		ctx.addErr(directiveErr.loc, directiveErr.err)
+	} else if err != errNull {
+		ctx.addErr(selection.Loc, err)
+	}
+	if _, ok := field.Type.(*internal.NonNull); ok {
+		return nil, true, errNull
+	}
+	return nil, true, nil
+}
+
+// resolveAndExecute resolves field with the directives of selection, see withDirectives, and completes
+// the value against the type of field, or returns a *pendingField when the resolver returned a thunk.
+// The errors of the directives are *directiveError.
+func (e *Executor) resolveAndExecute(ctx *exeContext, typ *internal.Object, field *internal.Field, source interface{},
 	selection *internal.Selection) (interface{}, error) {
-	value, err := safeExecuteResolver(ctx.Context, field, source, selection.Args)
+	var start time.Time
+	if ctx.state.slowFields != nil {
+		start = e.now()
+	}
+	value, err := safeExecuteResolver(ctx.resolveContext(field), withDirectives(selection.Directives, e.resolver(typ, field)), source, selection.Args)
+	if ctx.state.slowFields != nil {
+		ctx.state.slowFields.record(ctx.path, e.now().Sub(start))
+	}
 	if err != nil {
 		return nil, err
 	}
-	return e.execute(ctx, field.Type, value, selection.SelectionSet)
+	if thunk, ok := value.(internal.Thunk); ok {
+		return ctx.deferField(typ, field, selection, thunk), nil
+	}
+	return e.completeField(ctx, typ, field, selection, value)
+}
+
+// resolveContext returns the context the resolver of field gets, which holds the path of the field when
+// the field asks for it, see internal.WithPath.
+func (e *exeContext) resolveContext(field *internal.Field) context.Context {
+	if !field.ResolvePath {
+		return e.Context
+	}
+	return internal.WithPath(e.Context, append([]interface{}(nil), e.path...))
+}
+
+// completeField completes value, resolved for field, against the type of field.
+func (e *Executor) completeField(ctx *exeContext, typ *internal.Object, field *internal.Field,
+	selection *internal.Selection, value interface{}) (interface{}, error) {
+	if value == nil {
+		if _, ok := field.Type.(*internal.NonNull); ok {
+			return nil, nonNullFieldError(typ, field)
+		}
+		return nil, nil
+	}
+	dynamic := ctx.dynamic
+	ctx.dynamic = field.DynamicOutput
+	result, err := e.execute(ctx, field.Type, value, selection.SelectionSet)
+	ctx.dynamic = dynamic
+	if null, ok := err.(*nullError); ok {
+		if nonNull, ok := field.Type.(*internal.NonNull); ok && null.typ == nonNull.Type {
+			return nil, nonNullFieldError(typ, field)
+		}
+	}
+	return result, err
+}
+
+// resolver returns the resolver of field. A field without one, such as the fields of a schema
+// written in SDL, resolves to the value of the key of the map, or of the struct field, named after
+// it in the value its object resolved to.
+func (e *Executor) resolver(typ *internal.Object, field *internal.Field) internal.FieldResolve {
+	if field.Resolve != nil {
+		return field.Resolve
+	}
+	return func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		value, ok := sourceValue(source, field.Name)
+		if !ok {
+			if _, nonNull := field.Type.(*internal.NonNull); nonNull {
+				return nil, nonNullFieldError(typ, field)
+			}
+			if e.strictFields {
+				return nil, fmt.Errorf("%s.%s is missing from the resolved value", typ.Name, field.Name)
+			}
+		}
+		return value, nil
+	}
 }
 
-func safeExecuteResolver(ctx context.Context, field *internal.Field, source, args interface{}) (result interface{}, err error) {
+// sourceValue returns the value of the key name of the map source, or of its struct field name,
+// and whether source has one.
+func sourceValue(source interface{}, name string) (interface{}, bool) {
+	value := reflect.ValueOf(source)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		field := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		if !field.IsValid() {
+			return nil, false
+		}
+		return field.Interface(), true
+	case reflect.Struct:
+		if field := schemabuilder.GetField(value, name); field != nil && field.CanInterface() {
+			return field.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+func safeExecuteResolver(ctx context.Context, resolve internal.FieldResolve, source, args interface{}) (result interface{}, err error) {
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
 			const size = 64 << 10
@@ -287,45 +849,76 @@ func safeExecuteResolver(ctx context.Context, field *internal.Field, source, arg
 			result, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
 		}
 	}()
-	return field.Resolve(ctx, source, args)
+	return resolve(ctx, source, args)
 }
 
 // executeList executes a set query
 func (e *Executor) executeList(ctx *exeContext, typ *internal.List, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, error) {
-	if reflect.ValueOf(source).IsNil() {
+	slice := reflect.ValueOf(source)
+	if slice.Kind() == reflect.Ptr && !slice.IsNil() {
+		slice = slice.Elem()
+	}
+	if slice.IsNil() {
 		return nil, nil
 	}
 
 	// iterate over arbitrary slice types using reflect
-	slice := reflect.ValueOf(source)
 	items := make([]interface{}, slice.Len())
+	ctx.budget.charge(listOverhead + itemOverhead*int64(len(items)))
 
+	// the items after a null item of [T!] are still resolved, and report their errors, but the list is null
+	var null bool
 	// resolve every element in the slice
 	for i := 0; i < slice.Len(); i++ {
+		if ctx.stopped() {
+			break
+		}
 		value := slice.Index(i)
+		ctx.updatePath(true, i)
+		ctx.enterSlot(itemSlot(items, i, typ))
 		resolved, err := e.execute(ctx, typ.Type, value.Interface(), selectionSet)
+		ctx.leaveSlot()
+		ctx.updatePath(false)
+		if err == errNull {
+			// a null item of [T!] makes the list null, the items of [T] may be null
+			_, nonNull := typ.Type.(*internal.NonNull)
+			null = null || nonNull
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
 		items[i] = resolved
 	}
 
+	if null {
+		return nil, errNull
+	}
 	return items, nil
 }
 
 // executeInterface resolves an interface query
 func (e *Executor) executeInterface(ctx *exeContext, typ *internal.Interface, source interface{},
 	selectionSet *internal.SelectionSet) (interface{}, error) {
-	value := reflect.ValueOf(source)
-	if value.Kind() == reflect.Ptr && value.IsNil() {
+	// a nil value, or an interface holding a nil pointer, is null without resolving its type
+	if unwrap(source) == nil {
 		return nil, nil
 	}
 
-	var object *internal.Object
-	if typ.TypeResolve != nil {
-		object = typ.TypeResolve(ctx, source)
-	} else {
+	object, named, err := namedObject(source, typ.PossibleTypes, "interface", typ.Name)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case named:
+		// source named its object
+	case typ.TypeResolve != nil:
+		object, err = typ.TypeResolve(ctx.Context, source)
+		if err != nil {
+			return nil, fmt.Errorf("can not resolve the type for interface %s: %w", typ.Name, err)
+		}
+	default:
 		sourceTyp := reflect.TypeOf(source)
 		if sourceTyp.Kind() == reflect.Ptr {
 			sourceTyp = sourceTyp.Elem()
@@ -345,22 +938,60 @@ func (e *Executor) executeInterface(ctx *exeContext, typ *internal.Interface, so
 		return nil, fmt.Errorf("can not find the type for interface %s", typ.Name)
 	}
 
-	typString, graphqlTyp := object.Name, object
+	// executeObject only collects the fragments applying to object
+	return e.executeObject(ctx, object, source, selectionSet)
+}
 
-	// modifiedSelectionSet selection set contains fragments on typString
-	modifiedSelectionSet := &internal.SelectionSet{
-		Selections: selectionSet.Selections,
-		Fragments:  []*internal.FragmentSpread{},
-	}
-	for _, f := range selectionSet.Fragments {
-		if f.Fragment.On == typString {
-			modifiedSelectionSet.Fragments = append(modifiedSelectionSet.Fragments, f)
-		} else if _, ok := graphqlTyp.Interfaces[f.Fragment.On]; ok {
-			modifiedSelectionSet.Fragments = append(modifiedSelectionSet.Fragments, f)
+// directiveError is an error a directive of a field returned, located at the directive.
+type directiveError struct {
+	err error
+	loc errors.Location
+}
+
+func (e *directiveError) Error() string {
+	return e.err.Error()
+}
+
+func (e *directiveError) Unwrap() error {
+	return e.err
+}
+
+// withDirectives wraps resolve with the directives of a field but skip and include, which flatten
+// already applied so that the fields they exclude run neither their directives nor their resolver.
+//
+// The directives run in the order of the document around the resolver, the first one outermost:
+// each is called with the next one, or the resolver, as the function resolving the field, which it
+// may call or not, and the value it returns is the value of the field. The errors a directive returns
+// are located at the directive, unless they are the error the function it called returned.
+func withDirectives(directives []*internal.Directive, resolve internal.FieldResolve) internal.FieldResolve {
+	for i := len(directives) - 1; i >= 0; i-- {
+		directive, next := directives[i], resolve
+		if directive.Name == "skip" || directive.Name == "include" {
+			continue
+		}
+		resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			var nextErr error
+			_, value, err := directive.FnResolve(ctx, directive.ArgVals, func(ctx context.Context, source, args interface{}) (interface{}, error) {
+				value, err := next(ctx, source, args)
+				if thunk, ok := value.(internal.Thunk); ok && err == nil {
+					// directives get the value of the field
+					value, err = thunk()
+				}
+				nextErr = err
+				return value, err
+			}, source, args)
+			if err != nil && !sameError(err, nextErr) {
+				err = &directiveError{err: err, loc: directive.Loc}
+			}
+			return value, err
 		}
 	}
+	return resolve
+}
 
-	return e.executeObject(ctx, object, source, modifiedSelectionSet)
+// sameError reports whether err and target are the same error, errors of uncomparable types never are.
+func sameError(err, target error) bool {
+	return target != nil && reflect.TypeOf(err) == reflect.TypeOf(target) && reflect.TypeOf(err).Comparable() && err == target
 }
 
 func findDirectiveWithName(directives []*internal.Directive, name string) *internal.Directive {
@@ -2,13 +2,23 @@ package execution_test
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"github.com/shyptr/graphql/errors"
 	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/introspection"
 	"github.com/shyptr/graphql/schemabuilder"
 	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type Pet interface {
@@ -29,16 +39,99 @@ type Cat struct {
 	Meows bool   `graphql:"meows"`
 }
 
+type Account struct {
+	ID    int    `graphql:"id"`
+	Email string `graphql:"email"`
+}
+
+type PersonRef int
+
+type Post struct {
+	CreatedAt time.Time  `graphql:"createdAt"`
+	UpdatedAt *time.Time `graphql:"updatedAt"`
+}
+
+type personRow struct {
+	name    string
+	friends []PersonRef
+}
+
 func (c Cat) GetName() string {
 	return c.Name
 }
 
+type Vehicle interface {
+	GraphQLType() string
+}
+
+type Car struct {
+	Wheels int `graphql:"wheels"`
+}
+
+func (Car) GraphQLType() string {
+	return "Car"
+}
+
+type Boat struct {
+	Sails int `graphql:"sails"`
+}
+
+func (Boat) GraphQLType() string {
+	return "Boat"
+}
+
+type Plane struct{}
+
+func (Plane) GraphQLType() string {
+	return "Plane"
+}
+
+type Listing interface {
+	ListingID() int
+}
+
+type CarListing struct {
+	ID     int `graphql:"id"`
+	Wheels int `graphql:"wheels"`
+}
+
+func (c CarListing) ListingID() int {
+	return c.ID
+}
+
+type BoatListing struct {
+	ID    int `graphql:"id"`
+	Sails int `graphql:"sails"`
+}
+
+func (b BoatListing) ListingID() int {
+	return b.ID
+}
+
+// AmphibianListing is listed as a car or as a boat depending on the tenant.
+type AmphibianListing struct {
+	ID     int `graphql:"id"`
+	Wheels int `graphql:"wheels"`
+	Sails  int `graphql:"sails"`
+}
+
+func (a *AmphibianListing) ListingID() int {
+	return a.ID
+}
+
 type Human struct {
 	Name string `graphql:"name"`
 }
 
 var Nil *errors.GraphQLError
 
+// UUID is stored in the database as a string, it is not a nullable wrapper.
+type UUID string
+
+func (u UUID) Value() (driver.Value, error) {
+	return "uuid:" + string(u), nil
+}
+
 func TestExecutor_Execute(t *testing.T) {
 	t.Run("Execute: Handles execution of abstract types", func(t *testing.T) {
 		t.Run("isTypeOf used to resolve runtime type for Interface", func(t *testing.T) {
@@ -395,6 +488,41 @@ func TestExecutor_Execute(t *testing.T) {
 		})
 	})
 
+	t.Run("Execute: handles directive arguments", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		wrap := build.Directive("wrap", []string{"FIELD"}, func(args struct {
+			With *string `graphql:"with"`
+		}, fn schemabuilder.DirectiveFn) (bool, interface{}, error) {
+			result, err := fn()
+			if err != nil {
+				return false, nil, err
+			}
+			return false, *args.With + result.(string) + *args.With, nil
+		})
+		wrap.FieldDefault("with", "*")
+		build.Query().FieldFunc("a", func() string { return "a" }, "")
+		schema := build.MustBuild()
+
+		t.Run("does not leak argument values between requests", func(t *testing.T) {
+			result, err := execution.Do(schema, execution.Params{Query: `{ a @wrap(with: "#") }`})
+			assert.Equal(t, errors.MultiError(nil), err)
+			marshal, err2 := json.Marshal(result)
+			assert.NoError(t, err2)
+			assert.JSONEq(t, `{"a":"#a#"}`, string(marshal))
+
+			result, err = execution.Do(schema, execution.Params{Query: `{ a @wrap }`})
+			assert.Equal(t, errors.MultiError(nil), err)
+			marshal, err2 = json.Marshal(result)
+			assert.NoError(t, err2)
+			assert.JSONEq(t, `{"a":"*a*"}`, string(marshal))
+		})
+
+		t.Run("requires non-null arguments", func(t *testing.T) {
+			_, err := execution.Do(schema, execution.Params{Query: `{ a @include }`})
+			assert.EqualError(t, err, `[graphql: Directive "@include" argument "if" of type "Boolean!" is required, but it was not provided. (1:5)]`)
+		})
+	})
+
 	t.Run("Execute: Handles basic execution tasks", func(t *testing.T) {
 		t.Run("throws on invalid variables", func(t *testing.T) {
 			build := schemabuilder.NewSchema()
@@ -578,6 +706,541 @@ func TestExecutor_Execute(t *testing.T) {
 		})
 	})
 
+	t.Run("Execute: handles masked fields", func(t *testing.T) {
+		isSelf := func(ctx context.Context, source interface{}) bool {
+			return ctx.Value("viewer") == source.(*Account).ID
+		}
+		accounts := []*Account{{ID: 1, Email: "a@example.com"}, {ID: 2, Email: "b@example.com"}}
+		buildSchema := func(policy ...schemabuilder.MaskPolicy) *internal.Schema {
+			build := schemabuilder.NewSchema()
+			account := build.Object("Account", Account{})
+			account.FieldOption("email", schemabuilder.Masked(isSelf, policy...))
+			account.FieldFunc("phone", func(a *Account) *string {
+				phone := fmt.Sprintf("555-000%d", a.ID)
+				return &phone
+			}, schemabuilder.Masked(isSelf))
+			build.Query().FieldFunc("accounts", func() []*Account { return accounts })
+			return build.MustBuild()
+		}
+		ctx := context.WithValue(context.Background(), "viewer", 1)
+
+		t.Run("masks nullable fields in list elements", func(t *testing.T) {
+			result, err := execution.Do(buildSchema(), execution.Params{Query: "{ accounts { id phone } }", Context: ctx})
+			assert.Equal(t, errors.MultiError(nil), err)
+			marshal, err2 := json.Marshal(result)
+			assert.NoError(t, err2)
+			assert.JSONEq(t, `{"accounts":[{"id":1,"phone":"555-0001"},{"id":2,"phone":null}]}`, string(marshal))
+		})
+
+		t.Run("reports masked non-null fields", func(t *testing.T) {
+			result, err := execution.Do(buildSchema(), execution.Params{Query: "{ accounts { id email } }", Context: ctx})
+			assert.Len(t, err, 1)
+			assert.Equal(t, []interface{}{"accounts", 1, "email"}, err[0].Path)
+			marshal, err2 := json.Marshal(result)
+			assert.NoError(t, err2)
+			// the null of the non-null email propagates to the account
+			assert.JSONEq(t, `{"accounts":[{"id":1,"email":"a@example.com"},null]}`, string(marshal))
+		})
+
+		t.Run("makes masked non-null fields nullable", func(t *testing.T) {
+			schema := buildSchema(schemabuilder.MaskNullable)
+			assert.Equal(t, "String", schema.TypeMap["Account"].(*internal.Object).Fields["email"].Type.String())
+			result, err := execution.Do(schema, execution.Params{Query: "{ accounts { id email } }", Context: ctx})
+			assert.Equal(t, errors.MultiError(nil), err)
+			marshal, err2 := json.Marshal(result)
+			assert.NoError(t, err2)
+			assert.JSONEq(t, `{"accounts":[{"id":1,"email":"a@example.com"},{"id":2,"email":null}]}`, string(marshal))
+		})
+	})
+
+	t.Run("Execute: unwraps sql nullable types", func(t *testing.T) {
+		now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		tests := []struct {
+			name     string
+			fn       interface{}
+			expected interface{}
+		}{
+			{"valid NullString", func() sql.NullString { return sql.NullString{String: "a", Valid: true} }, "a"},
+			{"invalid NullString", func() sql.NullString { return sql.NullString{String: "a"} }, nil},
+			{"valid NullInt64", func() sql.NullInt64 { return sql.NullInt64{Int64: 1, Valid: true} }, int64(1)},
+			{"invalid NullInt64", func() sql.NullInt64 { return sql.NullInt64{Int64: 1} }, nil},
+			{"valid NullTime", func() sql.NullTime { return sql.NullTime{Time: now, Valid: true} }, "2020-01-02T03:04:05Z"},
+			{"invalid NullTime", func() sql.NullTime { return sql.NullTime{Time: now} }, nil},
+			{"valid NullBool", func() sql.NullBool { return sql.NullBool{Bool: true, Valid: true} }, true},
+			{"invalid NullBool", func() sql.NullBool { return sql.NullBool{Bool: true} }, nil},
+			{"valid NullFloat64", func() sql.NullFloat64 { return sql.NullFloat64{Float64: 1.5, Valid: true} }, 1.5},
+			{"invalid NullFloat64", func() sql.NullFloat64 { return sql.NullFloat64{Float64: 1.5} }, nil},
+			{"valid pointer", func() *sql.NullString { return &sql.NullString{String: "a", Valid: true} }, "a"},
+			{"valid untyped", func() interface{} { return sql.NullString{String: "a", Valid: true} }, "a"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				build := schemabuilder.NewSchema()
+				build.Query().FieldFunc("value", tt.fn)
+				result, err := execution.Do(build.MustBuild(), execution.Params{Query: "{ value }"})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{"value": tt.expected}, result)
+			})
+		}
+	})
+
+	t.Run("Execute: serializes other valuers as they are", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		var serialized []interface{}
+		build.Scalar("UUID", UUID(""), func(value interface{}, dest reflect.Value) error { return nil }).
+			Serialize = func(value interface{}) (interface{}, error) {
+			serialized = append(serialized, value)
+			return string(value.(UUID)), nil
+		}
+		build.Query().FieldFunc("id", func() UUID { return "a" })
+		build.Query().FieldFunc("ids", func() []*UUID { id := UUID("b"); return []*UUID{&id, nil} })
+		schema := build.MustBuild()
+		assert.Equal(t, &internal.NonNull{Type: schema.TypeMap["UUID"]}, schema.Query.(*internal.Object).Fields["id"].Type)
+		result, err := execution.Do(schema, execution.Params{Query: "{ id ids }"})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"id": "a", "ids": []interface{}{"b", nil}}, result)
+		assert.ElementsMatch(t, []interface{}{UUID("a"), UUID("b")}, serialized)
+	})
+
+	t.Run("Execute: keys the response by alias", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Object("Dog", Dog{})
+		build.Query().FieldFunc("dog", func() Dog { return Dog{Name: "Odie"} })
+		schema := build.MustBuild()
+
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"aliased meta-fields", `{ kind: __typename dog { type: __typename } }`, `{"kind":"Query","dog":{"type":"Dog"}}`},
+			{"meta-fields aliased to themselves", `{ __typename: __typename }`, `{"__typename":"Query"}`},
+			{"fields aliased to response keys", `{ data: dog { errors: name } errors: __typename }`, `{"data":{"errors":"Odie"},"errors":"Query"}`},
+			{"fields aliased to each other", `{ dog { name: __typename __typename } }`, `{"dog":{"name":"Dog","__typename":"Dog"}}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := execution.Do(schema, execution.Params{Query: tt.query})
+				assert.Equal(t, errors.MultiError(nil), err)
+				marshal, err2 := json.Marshal(result)
+				assert.NoError(t, err2)
+				assert.JSONEq(t, tt.expected, string(marshal))
+			})
+		}
+
+		t.Run("rejects aliases reserved for introspection", func(t *testing.T) {
+			_, err := execution.Do(schema, execution.Params{Query: `{ __typename: dog { name } }`})
+			assert.EqualError(t, err, `[graphql: Alias "__typename" of field "dog" must not begin with "__", which is reserved by GraphQL introspection. (1:3)]`)
+			_, err = execution.Do(schema, execution.Params{Query: `{ dog { __name: name } }`})
+			assert.EqualError(t, err, `[graphql: Alias "__name" of field "name" must not begin with "__", which is reserved by GraphQL introspection. (1:9)]`)
+		})
+	})
+
+	t.Run("Execute: collates fields per runtime type", func(t *testing.T) {
+		type PetUnion struct {
+			*Dog
+			*Cat
+		}
+		var barks int
+		build := schemabuilder.NewSchema()
+		build.Union("PetUnion", PetUnion{}, "")
+		build.Object("Dog", Dog{}).FieldFunc("bark", func(d Dog) string {
+			barks++
+			return d.Name + " barks"
+		})
+		build.Object("Cat", Cat{})
+		build.Query().FieldFunc("pets", func() []PetUnion {
+			return []PetUnion{{Dog: &Dog{"Odie", true}}, {Cat: &Cat{"Garfield", false}}}
+		})
+		schema := build.MustBuild()
+
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+			barks    int
+		}{
+			{"same alias on exclusive types", `{ pets { ... on Dog { sound: woofs } ... on Cat { sound: meows } } }`,
+				`{"pets":[{"sound":true},{"sound":false}]}`, 0},
+			{"nested fragments", `{ pets { ... on PetUnion { ... on Dog { key: name } ... on Cat { key: name } } } }`,
+				`{"pets":[{"key":"Odie"},{"key":"Garfield"}]}`, 0},
+			{"duplicated fields", `{ pets { __typename ... on Dog { bark } ... on Dog { bark name } ... on PetUnion { __typename } } }`,
+				`{"pets":[{"__typename":"Dog","bark":"Odie barks","name":"Odie"},{"__typename":"Cat"}]}`, 1},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				barks = 0
+				result, err := execution.Do(schema, execution.Params{Query: tt.query})
+				assert.Equal(t, errors.MultiError(nil), err)
+				marshal, err2 := json.Marshal(result)
+				assert.NoError(t, err2)
+				assert.JSONEq(t, tt.expected, string(marshal))
+				assert.Equal(t, tt.barks, barks)
+			})
+		}
+
+		t.Run("interfaces", func(t *testing.T) {
+			build := schemabuilder.NewSchema()
+			pet := build.Interface("Pet", new(Pet), nil)
+			pet.FieldFunc("name", "GetName")
+			build.Object("Dog", Dog{}).InterfaceList(pet)
+			build.Object("Cat", Cat{}).InterfaceList(pet)
+			build.Query().FieldFunc("pets", func() []Pet {
+				return []Pet{Dog{"Odie", true}, Cat{"Garfield", false}}
+			})
+			result, err := execution.Do(build.MustBuild(), execution.Params{
+				Query: `{ pets { ... on Pet { ... on Dog { flag: woofs } ... on Cat { flag: meows } } ... on Dog { name } } }`,
+			})
+			assert.Equal(t, errors.MultiError(nil), err)
+			marshal, err2 := json.Marshal(result)
+			assert.NoError(t, err2)
+			assert.JSONEq(t, `{"pets":[{"flag":true,"name":"Odie"},{"flag":false}]}`, string(marshal))
+		})
+	})
+
+	t.Run("Execute: handles interface type resolvers", func(t *testing.T) {
+		buildSchema := func(typeResolve interface{}, vehicles []Vehicle) (*internal.Schema, error) {
+			build := schemabuilder.NewSchema()
+			vehicle := build.Interface("Vehicle", new(Vehicle), typeResolve)
+			vehicle.FieldFunc("kind", "GraphQLType")
+			car := build.Object("Car", Car{})
+			car.FieldFunc("kind", Car.GraphQLType)
+			car.InterfaceList(vehicle)
+			boat := build.Object("Boat", Boat{})
+			boat.FieldFunc("kind", Boat.GraphQLType)
+			boat.InterfaceList(vehicle)
+			build.Query().FieldFunc("vehicles", func() []Vehicle { return vehicles })
+			return build.Build()
+		}
+		query := "{ vehicles { __typename kind } }"
+
+		t.Run("resolves type names returned by a method", func(t *testing.T) {
+			schema, err := buildSchema("GraphQLType", []Vehicle{Car{Wheels: 4}, Boat{Sails: 2}})
+			assert.NoError(t, err)
+			result, errs := execution.Do(schema, execution.Params{Query: query})
+			assert.Equal(t, errors.MultiError(nil), errs)
+			marshal, err := json.Marshal(result)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"vehicles":[{"__typename":"Car","kind":"Car"},{"__typename":"Boat","kind":"Boat"}]}`, string(marshal))
+		})
+
+		t.Run("propagates resolver errors", func(t *testing.T) {
+			schema, err := buildSchema(func(ctx context.Context, v Vehicle) (interface{}, error) {
+				if boat, ok := v.(Boat); ok {
+					return nil, fmt.Errorf("boat with %d sails is docked", boat.Sails)
+				}
+				return v, nil
+			}, []Vehicle{Car{Wheels: 4}, Boat{Sails: 2}})
+			assert.NoError(t, err)
+			_, errs := execution.Do(schema, execution.Params{Query: query})
+			assert.Len(t, errs, 1)
+			assert.Equal(t, "can not resolve the type for interface Vehicle: boat with 2 sails is docked", errs[0].Message)
+			assert.Equal(t, []interface{}{"vehicles"}, errs[0].Path)
+		})
+
+		t.Run("resolves the objects of values with the types built", func(t *testing.T) {
+			vehicles := []Vehicle{Car{Wheels: 4}, Boat{Sails: 2}, &Car{Wheels: 3}}
+			schema, err := buildSchema(func(v Vehicle) interface{} { return v }, vehicles)
+			assert.NoError(t, err)
+			// the objects are looked up in the types of the built schema, which executions share
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, errs := execution.Do(schema, execution.Params{Query: query})
+					assert.Equal(t, errors.MultiError(nil), errs)
+					assert.Len(t, result["vehicles"], 3)
+				}()
+			}
+			wg.Wait()
+
+			schema, err = buildSchema(func(v Vehicle) interface{} { return v }, []Vehicle{Plane{}})
+			assert.NoError(t, err)
+			_, errs := execution.Do(schema, execution.Params{Query: query})
+			assert.Len(t, errs, 1)
+			assert.Equal(t, "can not resolve the type for interface Vehicle: type *execution_test.Plane is not an object", errs[0].Message)
+		})
+
+		t.Run("reports unknown type names", func(t *testing.T) {
+			schema, err := buildSchema("GraphQLType", []Vehicle{Plane{}})
+			assert.NoError(t, err)
+			_, errs := execution.Do(schema, execution.Params{Query: query})
+			assert.Len(t, errs, 1)
+			assert.Equal(t, `can not resolve the type for interface Vehicle: type "Plane" is not a possible type`, errs[0].Message)
+		})
+
+		t.Run("resolves types from the request context", func(t *testing.T) {
+			type tenantKey struct{}
+			build := schemabuilder.NewSchema()
+			listing := build.Interface("Listing", new(Listing), func(ctx context.Context, l *Listing) string {
+				if ctx.Value(tenantKey{}) == "marina" {
+					return "BoatListing"
+				}
+				return "CarListing"
+			})
+			listing.FieldFunc("id", "ListingID")
+			build.Object("CarListing", CarListing{}).InterfaceList(listing)
+			build.Object("BoatListing", BoatListing{}).InterfaceList(listing)
+			build.Query().FieldFunc("listings", func() []Listing {
+				return []Listing{&AmphibianListing{ID: 1, Wheels: 4, Sails: 2}, (*AmphibianListing)(nil)}
+			})
+			schema := build.MustBuild()
+			query := "{ listings { __typename id ... on CarListing { wheels } ... on BoatListing { sails } } }"
+
+			for tenant, expected := range map[string]string{
+				"garage": `{"listings":[{"__typename":"CarListing","id":1,"wheels":4},null]}`,
+				"marina": `{"listings":[{"__typename":"BoatListing","id":1,"sails":2},null]}`,
+			} {
+				ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+				result, errs := execution.Do(schema, execution.Params{Query: query, Context: ctx})
+				assert.Equal(t, errors.MultiError(nil), errs)
+				marshal, err := json.Marshal(result)
+				assert.NoError(t, err)
+				assert.JSONEq(t, expected, string(marshal))
+			}
+		})
+
+		t.Run("rejects invalid resolvers when building", func(t *testing.T) {
+			_, err := buildSchema("Wheels", nil)
+			assert.EqualError(t, err, "object schemabuilder.Query field vehicles parse error:Wheels should be method of execution_test.Vehicle")
+			_, err = buildSchema(func(v Vehicle) int { return 0 }, nil)
+			assert.EqualError(t, err, "object schemabuilder.Query field vehicles parse error:interface typeResolve func should return an object, not Int")
+			_, err = buildSchema(func(v Vehicle) (Car, string) { return Car{}, "" }, nil)
+			assert.EqualError(t, err, "object schemabuilder.Query field vehicles parse error:interface typeResolve func should return a value, optionally followed by an error")
+		})
+	})
+
+	t.Run("Execute: serializes time fields", func(t *testing.T) {
+		created := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+		shanghai := time.FixedZone("CST", 8*60*60)
+		tests := []struct {
+			name     string
+			opts     []schemabuilder.SchemaOption
+			expected string
+		}{
+			{"default format", nil,
+				`{"null":"2020-01-02T03:04:05.0000006Z","post":{"createdAt":"2020-01-02T03:04:05.0000006Z","updatedAt":"2020-01-02T03:04:05.0000006Z"}}`},
+			{"custom layout", []schemabuilder.SchemaOption{schemabuilder.TimeOutputFormat("2006-01-02 15:04:05", nil)},
+				`{"null":"2020-01-02 03:04:05","post":{"createdAt":"2020-01-02 03:04:05","updatedAt":"2020-01-02 03:04:05"}}`},
+			{"custom location", []schemabuilder.SchemaOption{schemabuilder.TimeOutputFormat(time.RFC3339, shanghai)},
+				`{"null":"2020-01-02T11:04:05+08:00","post":{"createdAt":"2020-01-02T11:04:05+08:00","updatedAt":"2020-01-02T11:04:05+08:00"}}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				build := schemabuilder.NewSchema(tt.opts...)
+				build.Object("Post", Post{})
+				build.Query().FieldFunc("post", func() Post { return Post{CreatedAt: created, UpdatedAt: &created} })
+				build.Query().FieldFunc("null", func() sql.NullTime { return sql.NullTime{Time: created, Valid: true} })
+				result, err := execution.Do(build.MustBuild(), execution.Params{Query: "{ post { createdAt updatedAt } null }"})
+				assert.Equal(t, errors.MultiError(nil), err)
+				marshal, err2 := json.Marshal(result)
+				assert.NoError(t, err2)
+				assert.Equal(t, tt.expected, string(marshal))
+			})
+		}
+	})
+
+	t.Run("Execute: handles objects over non-struct types", func(t *testing.T) {
+		rows := map[PersonRef]personRow{
+			1: {name: "Luke", friends: []PersonRef{2, 3}},
+			2: {name: "Han", friends: []PersonRef{1}},
+			3: {name: "Leia"},
+		}
+		var loads []PersonRef
+		load := func(ref PersonRef) personRow {
+			loads = append(loads, ref)
+			return rows[ref]
+		}
+		build := schemabuilder.NewSchema()
+		person := build.Object("Person", PersonRef(0))
+		person.FieldFunc("id", func(ref PersonRef) int { return int(ref) })
+		person.FieldFunc("name", func(ref PersonRef) string { return load(ref).name })
+		person.FieldFunc("friends", func(ref *PersonRef) []PersonRef { return load(*ref).friends })
+		build.Query().FieldFunc("person", func(args struct{ ID int }) *PersonRef {
+			ref := PersonRef(args.ID)
+			return &ref
+		})
+		schema := build.MustBuild()
+		assert.Equal(t, "Person", schema.TypeMap["Person"].(*internal.Object).Name)
+
+		result, err := execution.Do(schema, execution.Params{Query: "{ person(ID: 1) { id friends { id name } } }"})
+		assert.Equal(t, errors.MultiError(nil), err)
+		marshal, err2 := json.Marshal(result)
+		assert.NoError(t, err2)
+		assert.JSONEq(t, `{"person":{"id":1,"friends":[{"id":2,"name":"Han"},{"id":3,"name":"Leia"}]}}`, string(marshal))
+		assert.ElementsMatch(t, []PersonRef{1, 2, 3}, loads)
+
+		t.Run("requires a FieldFunc", func(t *testing.T) {
+			build := schemabuilder.NewSchema()
+			build.Object("Person", PersonRef(0))
+			_, err := build.Build()
+			assert.EqualError(t, err, "object Person over execution_test.PersonRef should had at least one FieldFunc")
+		})
+	})
+
+	t.Run("Execute: fails fast", func(t *testing.T) {
+		var calls int
+		build := schemabuilder.NewSchema()
+		var fields []string
+		for i := 0; i < 100; i++ {
+			name := fmt.Sprintf("f%d", i)
+			fields = append(fields, name)
+			build.Query().FieldFunc(name, func(ctx context.Context) (*string, error) {
+				calls++
+				if calls == 1 {
+					return nil, fmt.Errorf("failed")
+				}
+				return &name, ctx.Err()
+			})
+		}
+		schema := build.MustBuild()
+		query := "{ " + strings.Join(fields, " ") + " }"
+
+		result, err := execution.Do(schema, execution.Params{Query: query}, execution.FailFast())
+		assert.Equal(t, 1, calls)
+		assert.Len(t, err, 1)
+		assert.Equal(t, "failed", err[0].Message)
+		assert.Len(t, result, 1)
+
+		calls = 0
+		_, err = execution.Do(schema, execution.Params{Query: query})
+		assert.Equal(t, 100, calls)
+		assert.Len(t, err, 1)
+	})
+
+	t.Run("Execute: memoizes fields per request", func(t *testing.T) {
+		type Viewer struct {
+			Name string `graphql:"name"`
+		}
+		var calls int
+		viewer := &Viewer{Name: "alice"}
+		build := schemabuilder.NewSchema()
+		build.Object("Viewer", Viewer{}).FieldFunc("permissions", func(v *Viewer, args struct {
+			Scope string `graphql:"scope"`
+		}) []string {
+			calls++
+			return []string{v.Name + ":" + args.Scope}
+		}, schemabuilder.MemoizePerRequest())
+		build.Query().FieldFunc("viewer", func() *Viewer { return viewer })
+		schema := build.MustBuild()
+
+		result, err := execution.Do(schema, execution.Params{Query: `
+			{ viewer { ...A ...B ...C } }
+			fragment A on Viewer { a: permissions(scope: "read") }
+			fragment B on Viewer { b: permissions(scope: "read") }
+			fragment C on Viewer { c: permissions(scope: "read") }`})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, map[string]interface{}{"viewer": map[string]interface{}{
+			"a": []interface{}{"alice:read"},
+			"b": []interface{}{"alice:read"},
+			"c": []interface{}{"alice:read"},
+		}}, result)
+
+		calls = 0
+		_, err = execution.Do(schema, execution.Params{Query: `
+			{ viewer { ...A ...B } }
+			fragment A on Viewer { a: permissions(scope: "read") }
+			fragment B on Viewer { b: permissions(scope: "write") }`})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, 2, calls)
+
+		// the memo does not outlive a request
+		_, err = execution.Do(schema, execution.Params{Query: `{ viewer { permissions(scope: "write") } }`})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Execute: limits the concurrency of fields", func(t *testing.T) {
+		var running, highWater int64
+		build := schemabuilder.NewSchema()
+		build.Query().FieldFunc("quote", func(args struct {
+			ID int `graphql:"id"`
+		}) int {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				max := atomic.LoadInt64(&highWater)
+				if n <= max || atomic.CompareAndSwapInt64(&highWater, max, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return args.ID
+		}, schemabuilder.ConcurrencyLimit(5))
+		schema := build.MustBuild()
+
+		// the fields of a request are resolved one at a time, the calls come from concurrent requests
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				result, err := execution.Do(schema, execution.Params{Query: fmt.Sprintf("{ quote(id: %d) }", i)})
+				assert.Equal(t, errors.MultiError(nil), err)
+				assert.Equal(t, map[string]interface{}{"quote": i}, result)
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, int64(5), highWater)
+
+		blocking := func(scope ...schemabuilder.ConcurrencyScope) (*internal.Schema, chan struct{}, chan struct{}) {
+			started, release := make(chan struct{}), make(chan struct{})
+			build := schemabuilder.NewSchema()
+			build.Query().FieldFunc("wait", func() bool {
+				started <- struct{}{}
+				<-release
+				return true
+			}, schemabuilder.ConcurrencyLimit(1, scope...))
+			return build.MustBuild(), started, release
+		}
+
+		t.Run("fails calls whose context is done while waiting", func(t *testing.T) {
+			schema, started, release := blocking()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				execution.Do(schema, execution.Params{Query: "{ wait }"})
+			}()
+			<-started
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := execution.Do(schema, execution.Params{Query: "{ wait }", Context: ctx})
+			if assert.Len(t, err, 1) {
+				assert.Equal(t, "context deadline exceeded", err[0].Message)
+			}
+			close(release)
+			<-done
+		})
+
+		t.Run("limits each request on its own", func(t *testing.T) {
+			schema, started, release := blocking(schemabuilder.LimitPerRequest)
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					execution.Do(schema, execution.Params{Query: "{ wait }"})
+				}()
+			}
+			for i := 0; i < 2; i++ {
+				select {
+				case <-started:
+				case <-time.After(time.Second):
+					t.Fatal("the requests share the limit")
+				}
+			}
+			close(release)
+			wg.Wait()
+		})
+
+		build = schemabuilder.NewSchema()
+		build.Query().FieldFunc("zero", func() int { return 0 }, schemabuilder.ConcurrencyLimit(0))
+		_, err := build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field zero parse error:concurrency limit must be at least 1, not 0")
+	})
+
 	t.Run("Execute: Accepts any iterable as list value", func(t *testing.T) {
 		t.Run("Accepts a Set as a List value", func(t *testing.T) {
 			testData := []string{"apple", "banana", "apple", "coconut"}
@@ -637,3 +1300,720 @@ func check(t *testing.T, testType interface{}, testData interface{}, expected in
 	assert.Equal(t, errors.MultiError(nil), err)
 	assert.Equal(t, expected, result)
 }
+
+func TestExecutor_MissingFields(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("string", func() string { return "" })
+	str := build.MustBuild().TypeMap["String"]
+	person := &internal.Object{
+		Name: "Person",
+		Fields: map[string]*internal.Field{
+			"name":     {Name: "name", Type: &internal.NonNull{Type: str}},
+			"nickname": {Name: "nickname", Type: str},
+		},
+	}
+	var source interface{}
+	query := &internal.Object{
+		Name: "Query",
+		Fields: map[string]*internal.Field{
+			"person": {
+				Name: "person",
+				Type: person,
+				Resolve: func(ctx context.Context, _, _ interface{}) (interface{}, error) {
+					return source, nil
+				},
+			},
+		},
+	}
+	schema := &internal.Schema{
+		TypeMap: map[string]internal.NamedType{"Query": query, "Person": person, "String": str},
+		Query:   query,
+	}
+	do := func(t *testing.T, opts ...execution.Option) (interface{}, errors.MultiError) {
+		return execution.Do(schema, execution.Params{Query: "{ person { name nickname } }"}, opts...)
+	}
+
+	t.Run("resolves the keys of maps", func(t *testing.T) {
+		source = map[string]interface{}{"name": "Ann", "nickname": "A"}
+		data, err := do(t, execution.StrictFields())
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"person": map[string]interface{}{"name": "Ann", "nickname": "A"}}, data)
+	})
+
+	t.Run("resolves missing nullable fields to null", func(t *testing.T) {
+		source = map[string]interface{}{"name": "Ann"}
+		data, err := do(t)
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"person": map[string]interface{}{"name": "Ann", "nickname": nil}}, data)
+	})
+
+	t.Run("fails missing non-null fields", func(t *testing.T) {
+		for _, s := range []interface{}{
+			map[string]interface{}{"nickname": "A"},
+			map[string]interface{}{"name": nil, "nickname": "A"},
+			struct {
+				Nickname string `graphql:"nickname"`
+			}{"A"},
+		} {
+			source = s
+			data, err := do(t)
+			// the null of the non-null name propagates to the person
+			assert.Equal(t, map[string]interface{}{"person": nil}, data)
+			if assert.Len(t, err, 1) {
+				assert.Equal(t, "Cannot return null for non-nullable field Person.name.", err[0].Message)
+				assert.Equal(t, []interface{}{"person", "name"}, err[0].Path)
+			}
+		}
+	})
+
+	t.Run("fails missing nullable fields in strict mode", func(t *testing.T) {
+		source = map[string]interface{}{"name": "Ann", "nick": "A"}
+		data, err := do(t, execution.StrictFields())
+		assert.Equal(t, map[string]interface{}{"person": map[string]interface{}{"name": "Ann", "nickname": nil}}, data)
+		if assert.Len(t, err, 1) {
+			assert.Equal(t, "Person.nickname is missing from the resolved value", err[0].Message)
+			assert.Equal(t, []interface{}{"person", "nickname"}, err[0].Path)
+		}
+	})
+}
+
+var ErrPersonNotFound = stderrors.New("person not found")
+
+type PersonError struct {
+	ID int64
+}
+
+func (e *PersonError) Error() string { return fmt.Sprintf("person %d failed", e.ID) }
+
+func TestExecutor_ErrorChains(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("person", func() (string, error) {
+		return "", fmt.Errorf("loading person: %w", ErrPersonNotFound)
+	})
+	build.Query().FieldFunc("people", func() ([]string, error) {
+		return nil, fmt.Errorf("loading people: %w", &PersonError{ID: 2})
+	})
+	schema := build.MustBuild()
+
+	_, errs := execution.Do(schema, execution.Params{Query: "{ person people }"})
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "loading person: person not found", errs[0].Message)
+		assert.True(t, stderrors.Is(errs[0], ErrPersonNotFound))
+		assert.False(t, stderrors.Is(errs[1], ErrPersonNotFound))
+	}
+	assert.True(t, stderrors.Is(errs, ErrPersonNotFound))
+	var personErr *PersonError
+	if assert.True(t, stderrors.As(errs, &personErr)) {
+		assert.Equal(t, int64(2), personErr.ID)
+	}
+	assert.False(t, stderrors.Is(errors.MultiError{errors.New("not found")}, ErrPersonNotFound))
+}
+
+func TestExecutor_MetaFields(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string { return "world" })
+	schema := build.MustBuild()
+
+	health := schemabuilder.NewSchema()
+	health.Query().FieldFunc("_health", func(args struct {
+		Verbose *bool `graphql:"verbose"`
+	}) string {
+		if args.Verbose != nil && *args.Verbose {
+			return "ok, all checks passed"
+		}
+		return "ok"
+	})
+	schema.AddMetaField(health.MustBuild().Query.(*internal.Object).Fields["_health"])
+	introspection.AddIntrospectionToSchema(schema)
+
+	data, errs := execution.Do(schema, execution.Params{
+		Query: `{ hello _health verbose: _health(verbose: true) __typename __schema { queryType { name } } }`,
+	})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"hello":      "world",
+		"_health":    "ok",
+		"verbose":    "ok, all checks passed",
+		"__typename": "Query",
+		"__schema":   map[string]interface{}{"queryType": map[string]interface{}{"name": "Query"}},
+	}, data)
+	assert.Len(t, schema.Query.(*internal.Object).Fields, 1)
+
+	// meta fields are only selectable on the query root type
+	build = schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string { return "world" })
+	build.Mutation().FieldFunc("hello", func() string { return "world" })
+	mutations := build.MustBuild()
+	mutations.AddMetaField(health.MustBuild().Query.(*internal.Object).Fields["_health"])
+	_, errs = execution.Do(mutations, execution.Params{Query: `mutation { _health }`})
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, `Cannot query field "_health" on type "Mutation".`, errs[0].Message)
+	}
+}
+
+func TestExecutor_DirectiveOrder(t *testing.T) {
+	var calls []string
+	build := schemabuilder.NewSchema()
+	trace := func(name string) interface{} {
+		return func(next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+			calls = append(calls, "enter "+name)
+			value, err := next()
+			calls = append(calls, "leave "+name)
+			if err != nil {
+				return false, nil, err
+			}
+			return true, fmt.Sprintf("%s(%v)", name, value), nil
+		}
+	}
+	build.Directive("first", []string{"FIELD"}, trace("first"))
+	build.Directive("second", []string{"FIELD"}, trace("second"))
+	build.Directive("cached", []string{"FIELD"}, func(next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+		calls = append(calls, "cached")
+		return false, "cached", nil
+	})
+	build.Directive("fail", []string{"FIELD"}, func(next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+		return false, nil, stderrors.New("directive failed")
+	})
+	build.Query().FieldFunc("a", func() string {
+		calls = append(calls, "resolve a")
+		return "a"
+	})
+	build.Query().FieldFunc("broken", func() (string, error) {
+		calls = append(calls, "resolve broken")
+		return "", stderrors.New("resolver failed")
+	})
+	schema := build.MustBuild()
+
+	run := func(query string) (interface{}, errors.MultiError) {
+		calls = nil
+		return execution.Do(schema, execution.Params{Query: query})
+	}
+
+	t.Run("runs directives in document order around the resolver", func(t *testing.T) {
+		data, errs := run(`{ a @first @second }`)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"a": "first(second(a))"}, data)
+		assert.Equal(t, []string{"enter first", "enter second", "resolve a", "leave second", "leave first"}, calls)
+
+		data, errs = run(`{ a @second @first }`)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"a": "second(first(a))"}, data)
+	})
+
+	t.Run("excludes fields with skip and include first", func(t *testing.T) {
+		data, errs := run(`{ a @first @skip(if: true) other: a @include(if: false) @fail }`)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{}, data)
+		assert.Empty(t, calls)
+
+		data, errs = run(`{ a @first @include(if: true) }`)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"a": "first(a)"}, data)
+	})
+
+	t.Run("short-circuits the directives and the resolver", func(t *testing.T) {
+		data, errs := run(`{ a @first @cached @second }`)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"a": "first(cached)"}, data)
+		assert.Equal(t, []string{"enter first", "cached", "leave first"}, calls)
+	})
+
+	t.Run("locates errors at the directive returning them", func(t *testing.T) {
+		data, errs := run(`{ a @first @fail broken @first }`)
+		// the fields are non-null, the siblings of a failed one are still resolved
+		assert.Nil(t, data)
+		if assert.Len(t, errs, 2) {
+			assert.Equal(t, "directive failed", errs[0].Message)
+			assert.Equal(t, []errors.Location{{Line: 1, Column: 12}}, errs[0].Locations)
+			assert.Equal(t, []interface{}{"a"}, errs[0].Path)
+			// the error of the resolver is passed through by the directive
+			assert.Equal(t, "resolver failed", errs[1].Message)
+			assert.Equal(t, []errors.Location{{Line: 1, Column: 18}}, errs[1].Locations)
+		}
+	})
+}
+
+type roleKey struct{}
+
+func TestExecutor_CustomDirectives(t *testing.T) {
+	type Account struct {
+		Name   string `graphql:"name"`
+		Secret string `graphql:"secret"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Directive("uppercase", []string{"FIELD"}, func(next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+		value, err := next()
+		if err != nil {
+			return false, nil, err
+		}
+		return true, strings.ToUpper(value.(string)), nil
+	}, "turns the string of the field to upper case")
+	build.Directive("auth", []string{"FIELD"}, func(ctx context.Context, args struct {
+		Role string `graphql:"role"`
+	}, next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+		if role, _ := ctx.Value(roleKey{}).(string); role != args.Role {
+			return false, nil, fmt.Errorf("the field needs the role %s", args.Role)
+		}
+		value, err := next()
+		return true, value, err
+	})
+	build.Object("Account", Account{})
+	build.Query().FieldFunc("accounts", func() []Account {
+		return []Account{{Name: "alice", Secret: "a1"}, {Name: "bob", Secret: "b2"}}
+	})
+	schema := build.MustBuild()
+	assert.Equal(t, "turns the string of the field to upper case", schema.Directives["uppercase"].Desc)
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ accounts { name @uppercase } }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"accounts": []interface{}{
+		map[string]interface{}{"name": "ALICE"},
+		map[string]interface{}{"name": "BOB"},
+	}}, data)
+
+	query := `{ accounts { name secret @auth(role: "admin") @uppercase } }`
+	data, errs = execution.Do(schema, execution.Params{Query: query})
+	// the null of the non-null secret propagates to the list of non-null accounts
+	assert.Equal(t, map[string]interface{}{"accounts": nil}, data)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "the field needs the role admin", errs[0].Message)
+		assert.Equal(t, []errors.Location{{Line: 1, Column: 26}}, errs[0].Locations)
+		assert.Equal(t, []interface{}{"accounts", 0, "secret"}, errs[0].Path)
+		assert.Equal(t, []interface{}{"accounts", 1, "secret"}, errs[1].Path)
+	}
+
+	ctx := context.WithValue(context.Background(), roleKey{}, "admin")
+	data, errs = execution.Do(schema, execution.Params{Query: query, Context: ctx})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"accounts": []interface{}{
+		map[string]interface{}{"name": "alice", "secret": "A1"},
+		map[string]interface{}{"name": "bob", "secret": "B2"},
+	}}, data)
+}
+
+func TestExecutor_ExcludedFragments(t *testing.T) {
+	type User struct {
+		Name string `graphql:"name"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("User", User{})
+	resolved := 0
+	build.Query().FieldFunc("user", func() User {
+		resolved++
+		return User{Name: "alice"}
+	})
+	schema := build.MustBuild()
+
+	const fragments = ` fragment Details on User { ...Name } fragment Name on User { name }`
+	alice := map[string]interface{}{"id": "User", "name": "alice"}
+	typename := map[string]interface{}{"id": "User"}
+	for _, c := range []struct {
+		query              string
+		included, excluded map[string]interface{}
+	}{
+		{
+			query:    `query Q($flag: Boolean!) { user { id: __typename ...Details @include(if: $flag) } }`,
+			included: map[string]interface{}{"user": alice},
+			excluded: map[string]interface{}{"user": typename},
+		},
+		{
+			query:    `query Q($flag: Boolean!) { user { id: __typename ... @include(if: $flag) { ...Details } } }`,
+			included: map[string]interface{}{"user": alice},
+			excluded: map[string]interface{}{"user": typename},
+		},
+		{
+			query:    `query Q($flag: Boolean!) { user { id: __typename } other: user @include(if: $flag) { id: __typename ...Details } }`,
+			included: map[string]interface{}{"user": typename, "other": alice},
+			excluded: map[string]interface{}{"user": typename},
+		},
+	} {
+		query := c.query + fragments
+		// fragments spread behind skip or include are used, whatever the value of their condition
+		doc, err := internal.Parse(query)
+		assert.NoError(t, err)
+		assert.NoError(t, execution.ValidateDocument(schema, doc), query)
+
+		data, errs := execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"flag": true}})
+		assert.Empty(t, errs, query)
+		assert.Equal(t, c.included, data, query)
+
+		resolved = 0
+		data, errs = execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"flag": false}})
+		assert.Empty(t, errs, query)
+		assert.Equal(t, c.excluded, data, query)
+		assert.Equal(t, 1, resolved, query)
+	}
+}
+
+func TestExecutor_MaxDepth(t *testing.T) {
+	type Node struct {
+		Name string `graphql:"name"`
+	}
+	build := schemabuilder.NewSchema()
+	node := build.Object("Node", Node{})
+	node.FieldFunc("next", func(n Node) Node { return Node{Name: n.Name + "+"} })
+	build.Query().FieldFunc("node", func() Node { return Node{Name: "n"} })
+	schema := build.MustBuild()
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ node { next { name } } }`}, execution.MaxDepth(3))
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"next": map[string]interface{}{"name": "n+"}}}, data)
+
+	data, errs = execution.Do(schema, execution.Params{Query: `{ node { next { next { name } } } }`}, execution.MaxDepth(3))
+	assert.Nil(t, data)
+	assert.EqualError(t, errs, "[graphql: Field \"name\" has depth 4, more than the maximum depth 3. (1:24)]")
+
+	// the fields of fragments count where they are spread
+	_, errs = execution.Do(schema, execution.Params{Query: `{ node { ...Next } } fragment Next on Node { next { deep: next { name } } }`}, execution.MaxDepth(3))
+	assert.EqualError(t, errs, "[graphql: Field \"name\" has depth 4, more than the maximum depth 3. (1:66)]")
+
+	_, errs = execution.Do(schema, execution.Params{Query: `{ node { next { next { name } } } }`})
+	assert.Empty(t, errs)
+}
+
+func TestExecutor_MaxObjectFields(t *testing.T) {
+	type Node struct {
+		Name string `graphql:"name"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("Node", Node{})
+	build.Query().FieldFunc("node", func() *Node { return &Node{Name: "n"} })
+	schema := build.MustBuild()
+
+	// the aliases stacked by inline fragments are counted once the fragments are expanded
+	var query strings.Builder
+	query.WriteString("{ node { ... on Node {")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&query, " a%d: name", i)
+	}
+	query.WriteString(" } } }")
+	start := time.Now()
+	data, errs := execution.Do(schema, execution.Params{Query: query.String()})
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	assert.Equal(t, map[string]interface{}{"node": nil}, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "more than 500 fields are selected on an object of type Node", errs[0].Message)
+		assert.Equal(t, []interface{}{"node"}, errs[0].Path)
+	}
+
+	// the fields excluded by skip and include are not counted, nor keys selected twice
+	query.Reset()
+	query.WriteString(`{ node { a: name b: name ...F c: name @skip(if: true) } } fragment F on Node { a: name }`)
+	data, errs = execution.Do(schema, execution.Params{Query: query.String()}, execution.MaxObjectFields(2))
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"a": "n", "b": "n"}}, data)
+
+	_, errs = execution.Do(schema, execution.Params{Query: `{ node { a: name b: name c: name } }`}, execution.MaxObjectFields(2))
+	assert.EqualError(t, errs, "[graphql: more than 2 fields are selected on an object of type Node (1:8) path: [node]]")
+}
+
+func TestExecutor_NullPropagation(t *testing.T) {
+	type Item struct {
+		ID int `graphql:"id"`
+	}
+	type Group struct {
+		// Items is [Item!]! and MaybeItems [Item]! with StrictNullability
+		Items      []Item  `graphql:"items"`
+		MaybeItems []*Item `graphql:"maybeItems"`
+	}
+	build := schemabuilder.NewSchema(schemabuilder.StrictNullability())
+	item := build.Object("Item", Item{})
+	item.FieldFunc("age", func(i Item) (int, error) {
+		if i.ID == 2 {
+			return 0, fmt.Errorf("no age for %d", i.ID)
+		}
+		return 30 + i.ID, nil
+	})
+	item.FieldFunc("note", func(i Item) (*string, error) {
+		if i.ID == 2 {
+			return nil, fmt.Errorf("no note for %d", i.ID)
+		}
+		note := fmt.Sprint("note ", i.ID)
+		return &note, nil
+	})
+	build.Object("Group", Group{})
+	build.Query().FieldFunc("group", func() *Group {
+		return &Group{Items: []Item{{ID: 0}, {ID: 1}, {ID: 2}}, MaybeItems: []*Item{{ID: 0}, {ID: 1}, {ID: 2}}}
+	})
+	build.Query().FieldFunc("item", func() *Item { return &Item{ID: 2} })
+	build.Query().FieldFunc("ok", func() string { return "ok" })
+	build.Query().FieldFunc("failing", func() (string, error) { return "", fmt.Errorf("failed") })
+	schema := build.MustBuild()
+	paths := func(errs errors.MultiError) [][]interface{} {
+		var paths [][]interface{}
+		for _, err := range errs {
+			paths = append(paths, err.Path)
+		}
+		return paths
+	}
+
+	// a nullable leaf which fails is null
+	data, errs := execution.Do(schema, execution.Params{Query: `{ item { id note } ok }`})
+	assert.Equal(t, map[string]interface{}{"item": map[string]interface{}{"id": 2, "note": nil}, "ok": "ok"}, data)
+	assert.Equal(t, [][]interface{}{{"item", "note"}}, paths(errs))
+
+	// a non-null leaf which fails makes its nullable object null
+	data, errs = execution.Do(schema, execution.Params{Query: `{ item { id age } ok }`})
+	assert.Equal(t, map[string]interface{}{"item": nil, "ok": "ok"}, data)
+	assert.EqualError(t, errs, "[graphql: no age for 2 (1:13) path: [item age]]")
+
+	// the item of [Item] is null, the list of [Item!]! is, up to the nullable group
+	data, errs = execution.Do(schema, execution.Params{Query: `{ group { maybeItems { age } } }`})
+	assert.Equal(t, map[string]interface{}{"group": map[string]interface{}{"maybeItems": []interface{}{
+		map[string]interface{}{"age": 30}, map[string]interface{}{"age": 31}, nil,
+	}}}, data)
+	assert.Equal(t, [][]interface{}{{"group", "maybeItems", 2, "age"}}, paths(errs))
+	data, errs = execution.Do(schema, execution.Params{Query: `{ group { items { age } } ok }`})
+	assert.Equal(t, map[string]interface{}{"group": nil, "ok": "ok"}, data)
+	assert.Equal(t, [][]interface{}{{"group", "items", 2, "age"}}, paths(errs))
+
+	// the siblings of a null field are still resolved and report their errors, a non-null root field
+	// makes the data null
+	data, errs = execution.Do(schema, execution.Params{Query: `{ failing group { items { age } } item { age } }`})
+	assert.Nil(t, data)
+	assert.Equal(t, [][]interface{}{{"failing"}, {"group", "items", 2, "age"}, {"item", "age"}}, paths(errs))
+
+	// the fields resolved concurrently propagate their nulls the same way
+	data, errs = execution.Do(schema, execution.Params{Query: `{ group { items { id age } maybeItems { id age } } ok }`}, execution.Concurrency(4))
+	assert.Equal(t, map[string]interface{}{"group": nil, "ok": "ok"}, data)
+	assert.Len(t, errs, 2)
+}
+
+func TestExecutor_MaxExpandedFields(t *testing.T) {
+	type Node struct {
+		ID int `graphql:"id"`
+	}
+	build := schemabuilder.NewSchema()
+	node := build.Object("Node", Node{})
+	node.FieldFunc("next", func(n Node) Node { return Node{ID: n.ID + 1} })
+	build.Query().FieldFunc("node", func() Node { return Node{} })
+	schema := build.MustBuild()
+	// bomb spreads every fragment twice in the one before it, levels deep
+	bomb := func(levels int, spread string) string {
+		var query strings.Builder
+		query.WriteString("{ node { ...F0 } }\n")
+		for i := 0; i < levels; i++ {
+			fmt.Fprintf(&query, "fragment F%d on Node { id %s }\n", i, strings.Replace(spread, "NEXT", fmt.Sprint("F", i+1), -1))
+		}
+		fmt.Fprintf(&query, "fragment F%d on Node { id }\n", levels)
+		return query.String()
+	}
+
+	// the fragments spread in two aliased fields expand to 2^30 fields, rejected before any resolver runs
+	start := time.Now()
+	data, errs := execution.Do(schema, execution.Params{Query: bomb(30, "a: next { ...NEXT } b: next { ...NEXT }")})
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+	assert.Nil(t, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "The operation expands to more than 10000 fields once its fragments are spread.", errs[0].Message)
+		assert.Equal(t, "MaxExpandedFieldsExceeded", errs[0].Rule)
+	}
+	_, err := execution.Explain(schema, execution.Params{Query: bomb(30, "a: next { ...NEXT } b: next { ...NEXT }")})
+	assert.Error(t, err)
+
+	// the identical spreads, on the object or in fields of the same key, are merged
+	for _, spread := range []string{"...NEXT ...NEXT", "next { ...NEXT } next { ...NEXT }"} {
+		start = time.Now()
+		_, errs = execution.Do(schema, execution.Params{Query: bomb(30, spread)})
+		assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+		assert.Empty(t, errs)
+	}
+
+	// the ceiling is configurable
+	data, errs = execution.Do(schema, execution.Params{Query: bomb(4, "a: next { ...NEXT } b: next { ...NEXT }")}, execution.MaxExpandedFields(100))
+	assert.Empty(t, errs)
+	assert.NotNil(t, data)
+	_, errs = execution.Do(schema, execution.Params{Query: bomb(4, "a: next { ...NEXT } b: next { ...NEXT }")}, execution.MaxExpandedFields(50))
+	assert.EqualError(t, errs, "[graphql: The operation expands to more than 50 fields once its fragments are spread. (1:1)]")
+}
+
+func TestExecutor_Cancellation(t *testing.T) {
+	type User struct {
+		Name string `graphql:"name"`
+	}
+	var resolved []string
+	ctx, cancel := context.WithCancel(context.Background())
+	build := schemabuilder.NewSchema()
+	user := build.Object("User", User{})
+	user.FieldFunc("slow", func() *string {
+		resolved = append(resolved, "slow")
+		cancel()
+		slow := "slow"
+		return &slow
+	})
+	user.FieldFunc("after", func() string {
+		resolved = append(resolved, "after")
+		return "after"
+	})
+	build.Query().FieldFunc("user", func() User { return User{Name: "ann"} })
+	build.Query().FieldFunc("other", func() string {
+		resolved = append(resolved, "other")
+		return "other"
+	})
+	schema := build.MustBuild()
+
+	// the field being resolved when the context is cancelled fails, its siblings and the fields of its
+	// ancestors are not resolved
+	data, errs := execution.Do(schema, execution.Params{Query: `{ user { name slow after } other }`, Context: ctx})
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"name": "ann", "slow": nil}}, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "context canceled", errs[0].Message)
+		assert.Equal(t, []interface{}{"user", "slow"}, errs[0].Path)
+		assert.Equal(t, context.Canceled, errs[0].ResolverError)
+	}
+	assert.Equal(t, []string{"slow"}, resolved)
+
+	// an operation past its deadline before it starts resolves nothing
+	resolved = nil
+	ctx, stop := context.WithTimeout(context.Background(), -time.Second)
+	defer stop()
+	data, errs = execution.Do(schema, execution.Params{Query: `{ other user { name } }`, Context: ctx})
+	assert.Nil(t, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, context.DeadlineExceeded, errs[0].ResolverError)
+		assert.Empty(t, errs[0].Path)
+	}
+	assert.Empty(t, resolved)
+}
+
+func TestExecutor_ScalarOutput(t *testing.T) {
+	type Person struct {
+		Name string
+	}
+	build := schemabuilder.NewSchema()
+	query := build.Query()
+	query.FieldFunc("name", func() *string { return nil })
+	query.FieldFunc("names", func() []string { return nil })
+	query.FieldFunc("settings", func() string { return "" }, schemabuilder.DynamicOutput())
+	query.FieldFunc("raw", func() string { return "" })
+	schema := build.MustBuild()
+	// resolvers returning another type than the one their field was declared with, as after a refactoring
+	fields := schema.Query.(*internal.Object).Fields
+	fields["name"].Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		return &Person{Name: "alice"}, nil
+	}
+	fields["names"].Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		return []interface{}{"bob", Person{Name: "alice"}}, nil
+	}
+	fields["settings"].Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		return map[string]interface{}{"theme": "dark"}, nil
+	}
+	fields["raw"].Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		return []byte("raw"), nil
+	}
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ name names settings raw }`})
+	assert.Equal(t, map[string]interface{}{
+		"name": nil,
+		// the items of [String!] are non-null, so the error nulls the list
+		"names":    nil,
+		"settings": `{"theme":"dark"}`,
+		"raw":      "raw",
+	}, data)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "expected String, resolver returned execution_test.Person", errs[0].Message)
+		assert.Equal(t, []interface{}{"name"}, errs[0].Path)
+		assert.Equal(t, "expected String, resolver returned execution_test.Person", errs[1].Message)
+		assert.Equal(t, []interface{}{"names"}, errs[1].Path)
+	}
+}
+
+// upstream is the proxy of an object of an upstream server, which names its type.
+type upstream map[string]interface{}
+
+func (u upstream) GraphQLTypeName() string {
+	return u["type"].(string)
+}
+
+func TestExecutor_TypeNamer(t *testing.T) {
+	sdl := schemabuilder.FromSDL(`
+		type User { name: String }
+		type Post { title: String }
+		union SearchResult = User | Post
+		interface Node { id: ID }
+		type Comment implements Node { id: ID }
+		type Query { search: [SearchResult] node: Node me: User }
+	`)
+	results := []interface{}{
+		upstream{"type": "User", "name": "ada"},
+		upstream{"type": "Post", "title": "notes"},
+	}
+	sdl.Resolve("Query", "search", func() []interface{} { return results })
+	sdl.Resolve("Query", "node", func() interface{} { return upstream{"type": "Comment", "id": "c1"} })
+	sdl.Resolve("Query", "me", func() interface{} { return upstream{"type": "Post", "title": "notes"} })
+	schema := sdl.MustBuild()
+
+	// the members of the union are the objects the proxies name, without a __typename key
+	data, errs := execution.Do(schema, execution.Params{Query: `{
+		search { __typename ... on User { name } ... on Post { title } }
+		node { __typename id }
+	}`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"search": []interface{}{
+			map[string]interface{}{"__typename": "User", "name": "ada"},
+			map[string]interface{}{"__typename": "Post", "title": "notes"},
+		},
+		"node": map[string]interface{}{"__typename": "Comment", "id": "c1"},
+	}, data)
+
+	// the names which are not possible types fail
+	results = []interface{}{upstream{"type": "Comment", "id": "c1"}}
+	data, errs = execution.Do(schema, execution.Params{Query: `{ search { __typename } me { name } }`})
+	assert.Equal(t, map[string]interface{}{"search": nil, "me": nil}, data)
+	assert.EqualError(t, errs, `[graphql: execution_test.upstream is named "Comment", which is not a possible type of union SearchResult (1:10) path: [search]`+"\n"+
+		`graphql: execution_test.upstream is named "Post", it can not be a User (1:28) path: [me]]`)
+}
+
+type codeError struct {
+	message, code string
+}
+
+func (e codeError) Error() string { return e.message }
+
+func (e codeError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func TestExecutor_ErrorExtensions(t *testing.T) {
+	type Person struct {
+		ID int `graphql:"id"`
+	}
+	build := schemabuilder.NewSchema()
+	person := build.Object("Person", Person{})
+	person.FieldFunc("age", func(p Person) (*int, error) {
+		switch p.ID {
+		case 2:
+			return nil, codeError{"not allowed to see the age of 2", "UNAUTHENTICATED"}
+		case 3:
+			return nil, fmt.Errorf("age of 3: %w", codeError{"backend down", "UNAVAILABLE"})
+		case 4:
+			return nil, fmt.Errorf("no age for 4")
+		}
+		age := 30 + p.ID
+		return &age, nil
+	})
+	build.Query().FieldFunc("all", func() []Person { return []Person{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}} })
+	schema := build.MustBuild()
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ all { id age } }`})
+	all := data["all"].([]interface{})
+	assert.NotNil(t, all[1].(map[string]interface{})["age"])
+	assert.Equal(t, map[string]interface{}{"id": 2, "age": nil}, all[2])
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, []interface{}{"all", 2, "age"}, errs[0].Path)
+		assert.Equal(t, map[string]interface{}{"code": "UNAUTHENTICATED"}, errs[0].Extensions)
+		// the extensions of the wrapped errors are added too
+		assert.Equal(t, map[string]interface{}{"code": "UNAVAILABLE"}, errs[1].Extensions)
+		assert.Nil(t, errs[2].Extensions)
+	}
+
+	// the response only has the keys of the errors which are set
+	encoded, err := json.Marshal(errs)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"message": "not allowed to see the age of 2", "locations": [{"line": 1, "column": 12}], "path": ["all", 2, "age"], "extensions": {"code": "UNAUTHENTICATED"}},
+		{"message": "age of 3: backend down", "locations": [{"line": 1, "column": 12}], "path": ["all", 3, "age"], "extensions": {"code": "UNAVAILABLE"}},
+		{"message": "no age for 4", "locations": [{"line": 1, "column": 12}], "path": ["all", 4, "age"]}
+	]`, string(encoded))
+}
@@ -0,0 +1,250 @@
+package execution
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"sort"
+	"strings"
+)
+
+// PlanNode is a field the execution of an operation resolves, as planned by Explain. The root node
+// stands for the operation, its Type is the root type and its children are the root fields.
+type PlanNode struct {
+	// ParentType is the object the field is resolved on.
+	ParentType string `json:"parentType,omitempty"`
+	Field      string `json:"field,omitempty"`
+	// Alias is the key of the field in the response, set when it differs from Field.
+	Alias string `json:"alias,omitempty"`
+	// Type is the type of the field, with its list and non-null wrappers.
+	Type     string                `json:"type"`
+	Resolver internal.ResolverKind `json:"resolver,omitempty"`
+	// Args are the arguments the resolver gets, with the default values of omitted arguments.
+	Args map[string]interface{} `json:"args,omitempty"`
+	// Directives are the directives applied to the field, as written in the document with their
+	// arguments, skip and include excluded the fields they leave out of the plan.
+	Directives []string `json:"directives,omitempty"`
+	// Cost is the number of fields resolved for one value of the field, itself included, counting the
+	// fields of lists once as their length is only known when they are resolved.
+	Cost     int         `json:"cost"`
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// Explain plans the execution of the operation of params against schema without calling any
+// resolver, to tell what a query does, for example why it is slow:
+//
+//   plan, err := execution.Explain(schema, execution.Params{Query: query, Variables: variables})
+//   fmt.Print(plan)
+//
+// The query is validated as Do validates it. The fields of interfaces and unions are planned for
+// every possible object type, the children of such a field have the object as ParentType.
+func Explain(schema *internal.Schema, params Params) (*PlanNode, error) {
+	doc, err := internal.Parse(params.Query)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDocument(schema, doc); err != nil {
+		return nil, err
+	}
+	operationType, selectionSet, err := ApplySelectionSet(schema, doc, params.OperationName, params.Variables)
+	if err != nil {
+		return nil, err
+	}
+	if errs := ValidateRules(schema, doc, params.OperationName, params.Variables, params.Rules...); len(errs) > 0 {
+		return nil, errs
+	}
+	root := schema.Query
+	switch operationType {
+	case ast.Mutation:
+		root = schema.Mutation
+	case ast.Subscription:
+		root = schema.Subscription
+	}
+	object, ok := root.(*internal.Object)
+	if !ok {
+		return nil, errors.New("schema has no %s type", strings.ToLower(string(operationType)))
+	}
+	children, err := explainSelectionSet(object, selectionSet)
+	if err != nil {
+		return nil, err
+	}
+	node := &PlanNode{Type: object.Name, Children: children}
+	for _, child := range children {
+		node.Cost += child.Cost
+	}
+	return node, nil
+}
+
+// explainSelectionSet plans the fields of selectionSet selected on an object of type typ.
+func explainSelectionSet(typ *internal.Object, selectionSet *internal.SelectionSet) ([]*PlanNode, error) {
+	if selectionSet == nil {
+		return nil, nil
+	}
+	selections, err := flatten(typ, selectionSet, DefaultMaxObjectFields)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*PlanNode
+	for _, selection := range selections {
+		node := &PlanNode{ParentType: typ.Name, Field: selection.Name, Cost: 1}
+		if selection.Alias != selection.Name {
+			node.Alias = selection.Alias
+		}
+		if selection.Name == "__typename" {
+			node.Type, node.Resolver = "String!", internal.MetaResolver
+			nodes = append(nodes, node)
+			continue
+		}
+		field := typ.Fields[selection.Name]
+		var resolver internal.ResolverKind
+		switch {
+		case field == nil:
+			field, resolver = selection.MetaField, internal.MetaResolver
+		case field.Resolve == nil:
+			resolver = internal.DefaultResolver
+		case field.ResolverKind != "":
+			resolver = field.ResolverKind
+		default:
+			resolver = internal.FuncResolver
+		}
+		if field == nil {
+			return nil, errors.New("field %s.%s does not exist", typ.Name, selection.Name)
+		}
+		node.Type, node.Resolver = field.Type.String(), resolver
+		node.Args = explainArgs(field, selection.Args)
+		for _, directive := range selection.Directives {
+			if directive.Name != "skip" && directive.Name != "include" {
+				node.Directives = append(node.Directives, explainDirective(directive))
+			}
+		}
+
+		named, err := unwrapType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		var objects []*internal.Object
+		switch named := named.(type) {
+		case *internal.Object:
+			objects = []*internal.Object{named}
+		case *internal.Interface:
+			for _, name := range sortedObjectNames(named.PossibleTypes) {
+				objects = append(objects, named.PossibleTypes[name])
+			}
+		case *internal.Union:
+			for _, name := range sortedObjectNames(named.Types) {
+				objects = append(objects, named.Types[name])
+			}
+		}
+		for _, object := range objects {
+			children, err := explainSelectionSet(object, selection.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			// a value is of one of the possible objects, the costliest one is counted
+			cost := 0
+			for _, child := range children {
+				cost += child.Cost
+			}
+			if node.Cost < cost+1 {
+				node.Cost = cost + 1
+			}
+			node.Children = append(node.Children, children...)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// explainArgs returns the arguments of selection, with the default values of omitted arguments of field.
+func explainArgs(field *internal.Field, selectionArgs interface{}) map[string]interface{} {
+	args := map[string]interface{}{}
+	if selected, ok := selectionArgs.(map[string]interface{}); ok {
+		for name, value := range selected {
+			args[name] = value
+		}
+	}
+	for name, arg := range field.Args {
+		if _, ok := args[name]; !ok && arg.DefaultValue != nil {
+			args[name] = arg.DefaultValue
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// explainDirective formats directive as it is applied, such as @cached(ttl: 60).
+func explainDirective(directive *internal.Directive) string {
+	return "@" + directive.Name + explainArgList(directive.ArgVals)
+}
+
+// explainArgList formats args in parentheses, sorted by name, or returns "" when there are none.
+func explainArgList(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = name + ": " + explainValue(args[name])
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+// explainValue formats a JSON value, json.Marshal sorts the keys of maps.
+func explainValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func sortedObjectNames(objects map[string]*internal.Object) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String renders the plan as an indented tree, one field per line:
+//
+//   Query (cost 4)
+//     Query.hero(episode: "EMPIRE"): Character [func] (cost 3)
+//       Droid.name: String! [struct field] (cost 1)
+//       Human.name: String! [struct field] (cost 1)
+func (n *PlanNode) String() string {
+	var buf bytes.Buffer
+	n.write(&buf, "")
+	return buf.String()
+}
+
+func (n *PlanNode) write(buf *bytes.Buffer, indent string) {
+	buf.WriteString(indent)
+	if n.Field == "" {
+		fmt.Fprintf(buf, "%s (cost %d)\n", n.Type, n.Cost)
+	} else {
+		if n.Alias != "" {
+			buf.WriteString(n.Alias + ": ")
+		}
+		buf.WriteString(n.ParentType + "." + n.Field + explainArgList(n.Args))
+		fmt.Fprintf(buf, ": %s [%s]", n.Type, n.Resolver)
+		for _, directive := range n.Directives {
+			buf.WriteString(" " + directive)
+		}
+		fmt.Fprintf(buf, " (cost %d)\n", n.Cost)
+	}
+	for _, child := range n.Children {
+		child.write(buf, indent+"  ")
+	}
+}
@@ -0,0 +1,67 @@
+package execution_test
+
+import (
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	pet := build.Interface("Pet", new(Pet), nil)
+	pet.FieldFunc("name", "GetName")
+	build.Object("Dog", Dog{}).InterfaceList(pet)
+	build.Object("Cat", Cat{}).InterfaceList(pet)
+	build.Object("Human", Human{})
+	build.Directive("upper", []string{"FIELD"}, func(args struct {
+		Locale *string `graphql:"locale"`
+	}, next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+		panic("directives are not called")
+	})
+	build.Query().FieldFunc("pets", func(args struct {
+		First  int64   `graphql:"first"`
+		Filter *string `graphql:"filter"`
+	}) []Pet {
+		panic("resolvers are not called")
+	})
+	build.Query().FieldFunc("owner", func() Human {
+		panic("resolvers are not called")
+	})
+	schema := build.MustBuild()
+
+	plan, err := execution.Explain(schema, execution.Params{
+		Query: `
+			query Pets($filter: String) {
+				pets(first: 10, filter: $filter) {
+					__typename
+					name @upper(locale: "en")
+					... on Dog { woofs }
+					... on Cat { meows @skip(if: true) }
+				}
+				person: owner { name }
+			}`,
+		Variables: map[string]interface{}{"filter": "odie"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `Query (cost 6)
+  Query.pets(filter: "odie", first: 10): [Pet] [func] (cost 4)
+    Cat.__typename: String! [meta] (cost 1)
+    Cat.name: String! [struct field] @upper(locale: "en") (cost 1)
+    Dog.__typename: String! [meta] (cost 1)
+    Dog.name: String! [struct field] @upper(locale: "en") (cost 1)
+    Dog.woofs: Boolean! [struct field] (cost 1)
+  person: Query.owner: Human! [func] (cost 2)
+    Human.name: String! [struct field] (cost 1)
+`, plan.String())
+	assert.Equal(t, internal.FuncResolver, plan.Children[0].Resolver)
+	assert.Equal(t, "person", plan.Children[1].Alias)
+
+	t.Run("rejects invalid operations", func(t *testing.T) {
+		_, err := execution.Explain(schema, execution.Params{Query: `{ owner { color } }`})
+		assert.EqualError(t, err, `graphql: Cannot query field "color" on type "Human". (1:11)`)
+	})
+}
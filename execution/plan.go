@@ -0,0 +1,159 @@
+package execution
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/printer"
+	"sync"
+)
+
+// PlanKey identifies the plan of a query for a version of a schema.
+type PlanKey struct {
+	// Schema is the SchemaHash of the schema the query is planned for.
+	Schema string
+	// Query is the hex encoded sha256 of the query.
+	Query string
+}
+
+// Plan is what is known about a query before its variables are: the parsed document and the result
+// of its validation against the schema. Plans are shared by requests and must not be modified.
+type Plan struct {
+	Document *internal.Document
+	// Err is the error parsing or validating the document, if any.
+	Err error
+}
+
+// PlanCache stores plans, an Executor looks its plans up before parsing and validating a query.
+// A PlanCache is used concurrently.
+type PlanCache interface {
+	Get(key PlanKey) (*Plan, bool)
+	Add(key PlanKey, plan *Plan)
+}
+
+// WithPlanCache makes the executor store its plans in cache instead of a cache of its own.
+// Executors over different versions of a schema can share a cache, as plans are keyed by the schema hash.
+func WithPlanCache(cache PlanCache) Option {
+	return func(e *Executor) {
+		e.plans = cache
+	}
+}
+
+// DefaultPlanCacheSize is the number of plans kept by the cache of an executor created without WithPlanCache.
+const DefaultPlanCacheSize = 1000
+
+// NewPlanCache returns a PlanCache keeping the size most recently used plans, which counts its
+// lookups, see StatsCache.
+func NewPlanCache(size int) PlanCache {
+	return &lruPlanCache{size: size, plans: list.New(), keys: map[PlanKey]*list.Element{}}
+}
+
+type lruPlanCache struct {
+	mu    sync.Mutex
+	size  int
+	plans *list.List
+	keys  map[PlanKey]*list.Element
+	stats CacheStats
+}
+
+type lruEntry struct {
+	key  PlanKey
+	plan *Plan
+}
+
+func (c *lruPlanCache) Get(key PlanKey) (*Plan, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.keys[key]; ok {
+		c.plans.MoveToFront(elem)
+		c.stats.Hits++
+		return elem.Value.(*lruEntry).plan, true
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *lruPlanCache) Add(key PlanKey, plan *Plan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.keys[key]; ok {
+		elem.Value.(*lruEntry).plan = plan
+		c.plans.MoveToFront(elem)
+		return
+	}
+	c.keys[key] = c.plans.PushFront(&lruEntry{key: key, plan: plan})
+	for c.plans.Len() > c.size {
+		oldest := c.plans.Back()
+		c.plans.Remove(oldest)
+		delete(c.keys, oldest.Value.(*lruEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruPlanCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.plans.Len()
+	return stats
+}
+
+// SchemaHash returns the hex encoded sha256 of the SDL of schema, directives and builtin scalars included.
+// Schemas printed the same way share their plans, whatever their resolvers are.
+func SchemaHash(schema *internal.Schema) string {
+	sdl := printer.Options{
+		IncludeDescriptions:   true,
+		IncludeBuiltinScalars: true,
+		DirectiveFilter:       func(string) bool { return true },
+	}.Print(schema)
+	return hash(sdl)
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// version is a schema served by an executor, with its hash.
+type version struct {
+	schema *internal.Schema
+	hash   string
+}
+
+// plan returns the plan of query for the schema of v, parsing and validating query only when the
+// cache of the executor does not hold its plan yet.
+func (e *Executor) plan(v *version, query string) *Plan {
+	key := PlanKey{Schema: v.hash, Query: hash(query)}
+	if plan, ok := e.plans.Get(key); ok {
+		return plan
+	}
+	plan := &Plan{}
+	if plan.Document, plan.Err = internal.Parse(query); plan.Err == nil {
+		// the fields dropped are logged when the operation is applied
+		drop := e.dropUnknownInputField()
+		if drop != nil {
+			drop = func(*internal.InputObject, string, errors.Location) {}
+		}
+		plan.Err = validateDocument(v.schema, plan.Document, drop, e.maxExpandedFields)
+	}
+	e.plans.Add(key, plan)
+	return plan
+}
+
+// Document returns the document of query, parsed once for the plans of the executor, so that the
+// handlers executing operations with ApplySelectionSet and Execute share the plan cache of Do.
+// Executors which are not created by NewExecutor parse query every time. The document is shared by
+// requests and must not be modified.
+func (e *Executor) Document(query string) (*internal.Document, error) {
+	v, ok := e.current.Load().(*version)
+	if !ok || e.plans == nil {
+		return internal.Parse(query)
+	}
+	plan := e.plan(v, query)
+	if plan.Document == nil {
+		return nil, plan.Err
+	}
+	return plan.Document, nil
+}
@@ -0,0 +1,114 @@
+package execution_test
+
+import (
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type countingPlanCache struct {
+	execution.PlanCache
+	mu   sync.Mutex
+	adds []execution.PlanKey
+}
+
+func (c *countingPlanCache) Add(key execution.PlanKey, plan *execution.Plan) {
+	c.mu.Lock()
+	c.adds = append(c.adds, key)
+	c.mu.Unlock()
+	c.PlanCache.Add(key, plan)
+}
+
+func TestExecutor_Swap(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	v1 := func() *internal.Schema {
+		build := schemabuilder.NewSchema()
+		build.Query().FieldFunc("version", func() string { return "v1" })
+		build.Query().FieldFunc("slow", func() string {
+			close(started)
+			<-release
+			return "v1"
+		})
+		return build.MustBuild()
+	}()
+	v2 := func() *internal.Schema {
+		build := schemabuilder.NewSchema()
+		build.Query().FieldFunc("version", func() string { return "v2" })
+		build.Query().FieldFunc("added", func() string { return "v2" })
+		return build.MustBuild()
+	}()
+	assert.NotEqual(t, execution.SchemaHash(v1), execution.SchemaHash(v2))
+
+	cache := &countingPlanCache{PlanCache: execution.NewPlanCache(10)}
+	executor := execution.NewExecutor(v1, execution.WithPlanCache(cache))
+
+	result, errs := executor.Do(execution.Params{Query: "{ version }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	assert.Equal(t, map[string]interface{}{"version": "v1"}, result)
+	_, errs = executor.Do(execution.Params{Query: "{ added }"})
+	assert.Len(t, errs, 1)
+
+	// a request in flight finishes against the schema it started with
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result, errs := executor.Do(execution.Params{Query: "{ slow }"})
+		assert.Equal(t, errors.MultiError(nil), errs)
+		assert.Equal(t, map[string]interface{}{"slow": "v1"}, result)
+	}()
+	<-started
+	executor.Swap(v2)
+	assert.Equal(t, v2, executor.Schema())
+	close(release)
+	wg.Wait()
+
+	// the plans of v1 do not leak into v2
+	result, errs = executor.Do(execution.Params{Query: "{ added }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	assert.Equal(t, map[string]interface{}{"added": "v2"}, result)
+	result, errs = executor.Do(execution.Params{Query: "{ version }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	assert.Equal(t, map[string]interface{}{"version": "v2"}, result)
+	_, errs = executor.Do(execution.Params{Query: "{ slow }"})
+	assert.Len(t, errs, 1)
+
+	// and are still there for executors of v1
+	adds := len(cache.adds)
+	old := execution.NewExecutor(v1, execution.WithPlanCache(cache))
+	_, errs = old.Do(execution.Params{Query: "{ added }"})
+	assert.Len(t, errs, 1)
+	result, _ = old.Do(execution.Params{Query: "{ version }"})
+	assert.Equal(t, map[string]interface{}{"version": "v1"}, result)
+	assert.Equal(t, adds, len(cache.adds))
+
+	for _, key := range cache.adds {
+		assert.Contains(t, []string{execution.SchemaHash(v1), execution.SchemaHash(v2)}, key.Schema)
+	}
+	assert.Len(t, cache.adds, 6)
+}
+
+func TestPlanCache(t *testing.T) {
+	cache := execution.NewPlanCache(2)
+	plans := []*execution.Plan{{}, {}, {}}
+	cache.Add(execution.PlanKey{Schema: "s", Query: "0"}, plans[0])
+	cache.Add(execution.PlanKey{Schema: "s", Query: "1"}, plans[1])
+	// using a plan keeps it
+	plan, ok := cache.Get(execution.PlanKey{Schema: "s", Query: "0"})
+	assert.True(t, ok)
+	assert.Equal(t, plans[0], plan)
+	cache.Add(execution.PlanKey{Schema: "s", Query: "2"}, plans[2])
+
+	_, ok = cache.Get(execution.PlanKey{Schema: "s", Query: "1"})
+	assert.False(t, ok)
+	_, ok = cache.Get(execution.PlanKey{Schema: "s", Query: "0"})
+	assert.True(t, ok)
+	_, ok = cache.Get(execution.PlanKey{Schema: "other", Query: "2"})
+	assert.False(t, ok)
+	assert.Equal(t, execution.CacheStats{Hits: 2, Misses: 2, Evictions: 1, Size: 2}, cache.(execution.StatsCache).Stats())
+}
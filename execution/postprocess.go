@@ -0,0 +1,172 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"log"
+	"reflect"
+)
+
+// ResponsePostProcessor processes the data of an operation once its fields are completed, before it is
+// encoded, such as to scrub the values of some fields whatever the resolvers producing them. It may
+// change the values of data, see ResponseData.Walk.
+type ResponsePostProcessor func(ctx context.Context, schema *internal.Schema, data *ResponseData) error
+
+// WithResponsePostProcessor runs processor on the data of the operations, after the post-processors
+// added before it. An error of a post-processor is logged with the log package, and replaces the
+// response with a generic error, so that data which could not be processed is never sent:
+//
+//   execution.WithResponsePostProcessor(func(ctx context.Context, schema *internal.Schema, data *execution.ResponseData) error {
+//     return data.Walk(func(value *execution.ResponseValue) error {
+//       if value.Coordinate == "User.ssn" {
+//         value.Set(nil)
+//       }
+//       return nil
+//     })
+//   })
+func WithResponsePostProcessor(processor ResponsePostProcessor) Option {
+	return func(e *Executor) {
+		e.postProcessors = append(e.postProcessors, processor)
+	}
+}
+
+// ResponseData is the data of an operation, with the selections and the types its values were
+// completed with.
+type ResponseData struct {
+	// Data is the result of the root fields of the operation.
+	Data         map[string]interface{}
+	root         *internal.Object
+	selectionSet *internal.SelectionSet
+	ctx          *exeContext
+}
+
+// ResponseValue is the value of a field in the data.
+type ResponseValue struct {
+	// Coordinate is the schema coordinate of the field, such as User.ssn, with the object type the value
+	// was completed for when the field is selected on an interface or a union.
+	Coordinate string
+	// Type is the declared type of the field.
+	Type  internal.Type
+	Path  []interface{}
+	Value interface{}
+
+	object   map[string]interface{}
+	alias    string
+	replaced bool
+}
+
+// Set replaces the value of the field in the data by value, the fields of the value it replaces are
+// not walked. A non-null field should not be set to null.
+func (v *ResponseValue) Set(value interface{}) {
+	v.object[v.alias] = value
+	v.Value = value
+	v.replaced = true
+}
+
+// Walk calls fn with the values of the fields of the data, in the order of the selections, a field
+// before the fields of its value. The null objects and the fields which are not in the data, such as
+// those of the objects left out by an interrupted execution, are not walked. An error of fn stops the
+// walk and is returned.
+func (d *ResponseData) Walk(fn func(value *ResponseValue) error) error {
+	if d.Data == nil {
+		return nil
+	}
+	return d.walkObject(fn, d.root, d.Data, d.selectionSet, nil)
+}
+
+func (d *ResponseData) walkObject(fn func(value *ResponseValue) error, typ *internal.Object,
+	object map[string]interface{}, selectionSet *internal.SelectionSet, path []interface{}) error {
+	selections, err := d.ctx.flatten(typ, selectionSet)
+	if err != nil {
+		return err
+	}
+	for _, selection := range selections {
+		value, ok := object[selection.Alias]
+		if !ok {
+			continue
+		}
+		field := typ.Fields[selection.Name]
+		if field == nil {
+			field = selection.MetaField
+		}
+		if field == nil {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], selection.Alias)
+		response := &ResponseValue{Coordinate: typ.Name + "." + selection.Name, Type: field.Type, Path: fieldPath,
+			Value: value, object: object, alias: selection.Alias}
+		if err := fn(response); err != nil {
+			return err
+		}
+		if response.replaced {
+			continue
+		}
+		if err := d.walkValue(fn, field.Type, value, selection.SelectionSet, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *ResponseData) walkValue(fn func(value *ResponseValue) error, typ internal.Type, value interface{},
+	selectionSet *internal.SelectionSet, path []interface{}) error {
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return d.walkValue(fn, typ.Type, value, selectionSet, path)
+	case *internal.List:
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			if err := d.walkValue(fn, typ.Type, item, selectionSet, append(path[:len(path):len(path)], i)); err != nil {
+				return err
+			}
+		}
+	case *internal.Object, *internal.Interface, *internal.Union:
+		object, _ := value.(map[string]interface{})
+		if objectType := d.ctx.objectType(object); objectType != nil {
+			return d.walkObject(fn, objectType, object, selectionSet, path)
+		}
+	}
+	return nil
+}
+
+// recordObject records typ as the object type of fields, the result of an object, for the
+// post-processors walking the data.
+func (e *exeContext) recordObject(fields map[string]interface{}, typ *internal.Object) {
+	// the map is made before the execution, when the executor has post-processors
+	if e.state.objectTypes == nil {
+		return
+	}
+	e.state.mu.Lock()
+	e.state.objectTypes[reflect.ValueOf(fields).Pointer()] = typ
+	e.state.mu.Unlock()
+}
+
+// objectType returns the object type recorded for object, nil when there is none.
+func (e *exeContext) objectType(object map[string]interface{}) *internal.Object {
+	if object == nil {
+		return nil
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.objectTypes[reflect.ValueOf(object).Pointer()]
+}
+
+// postProcess runs the post-processors of the executor on response, the data of an operation of type
+// typ, and returns the error replacing the response when one of them fails.
+func (e *Executor) postProcess(ctx *exeContext, typ internal.Type, response interface{},
+	selectionSet *internal.SelectionSet) *errors.GraphQLError {
+	data, _ := response.(map[string]interface{})
+	root, _ := typ.(*internal.Object)
+	if data == nil || root == nil {
+		return nil
+	}
+	responseData := &ResponseData{Data: data, root: root, selectionSet: selectionSet, ctx: ctx}
+	for _, processor := range e.postProcessors {
+		if err := processor(ctx.Context, e.Schema(), responseData); err != nil {
+			log.Printf("graphql: response post-processor failed: %v", err)
+			return errors.New("the response could not be processed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package execution_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExecutor_ResponsePostProcessor(t *testing.T) {
+	type Employee struct {
+		Name    string     `graphql:"name"`
+		SSN     *string    `graphql:"ssn"`
+		Reports []Employee `graphql:"reports"`
+	}
+	ssn := func(s string) *string { return &s }
+	build := schemabuilder.NewSchema()
+	build.Object("Employee", Employee{})
+	build.Query().FieldFunc("teams", func() [][]Employee {
+		return [][]Employee{
+			{{Name: "ada", SSN: ssn("1"), Reports: []Employee{{Name: "alan", SSN: ssn("2")}}}},
+			{{Name: "grace", SSN: ssn("3")}},
+		}
+	})
+	build.Query().FieldFunc("ssn", func() string { return "root" })
+	schema := build.MustBuild()
+
+	// the scrubber nulls the ssn of the employees, whatever their alias and the list they are in
+	var scrubbed []string
+	scrub := execution.WithResponsePostProcessor(func(ctx context.Context, schema *internal.Schema, data *execution.ResponseData) error {
+		return data.Walk(func(value *execution.ResponseValue) error {
+			if value.Coordinate == "Employee.ssn" {
+				scrubbed = append(scrubbed, fmt.Sprint(value.Path))
+				value.Set(nil)
+			}
+			return nil
+		})
+	})
+	query := `{ teams { name id: ssn reports { name ssn } } }`
+	data, errs := execution.Do(schema, execution.Params{Query: query}, scrub)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"teams": []interface{}{
+		[]interface{}{map[string]interface{}{
+			"name": "ada", "id": nil,
+			"reports": []interface{}{map[string]interface{}{"name": "alan", "ssn": nil}},
+		}},
+		[]interface{}{map[string]interface{}{"name": "grace", "id": nil, "reports": nil}},
+	}}, data)
+	assert.Equal(t, []string{"[teams 0 0 id]", "[teams 0 0 reports 0 ssn]", "[teams 1 0 id]"}, scrubbed)
+
+	// the fields resolved concurrently are walked as well
+	scrubbed = nil
+	data, errs = execution.Do(schema, execution.Params{Query: query}, scrub, execution.Concurrency(4))
+	assert.Empty(t, errs)
+	assert.Len(t, scrubbed, 3)
+	assert.Nil(t, data["teams"].([]interface{})[1].([]interface{})[0].(map[string]interface{})["id"])
+
+	// the post-processors run in the order they were added in, their error replaces the response
+	var calls []string
+	record := func(name string, err error) execution.Option {
+		return execution.WithResponsePostProcessor(func(ctx context.Context, schema *internal.Schema, data *execution.ResponseData) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+	data, errs = execution.Do(schema, execution.Params{Query: `{ ssn }`},
+		record("first", nil), record("second", fmt.Errorf("leaked")), record("third", nil))
+	assert.Nil(t, data)
+	assert.EqualError(t, errs, "[graphql: the response could not be processed]")
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
@@ -0,0 +1,117 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"sort"
+)
+
+// ResolveField calls the resolver of the field fieldName of the object typeName of schema with args, as
+// an operation selecting the field would, for example to run Query.report from a scheduled job without
+// writing a query:
+//
+//   report, err := execution.ResolveField(ctx, schema, "Query", "report", map[string]interface{}{"id": id}, nil)
+//
+// args hold the JSON values of the arguments, as variables do: unknown arguments, and omitted or null
+// arguments of non-null types without default value are rejected, the default values of omitted arguments
+// and input object fields are filled in. The resolver runs with the options of the field, such as its
+// middlewares, and the fields of Query are resolved read-only.
+//
+// When selectionSet is nil the value the resolver returned is returned as is, otherwise the value is
+// completed against selectionSet, which may be made by ApplySelectionSet, the errors of its fields are
+// returned as an errors.MultiError along with the partial result.
+func ResolveField(ctx context.Context, schema *internal.Schema, typeName, fieldName string, args map[string]interface{},
+	selectionSet *internal.SelectionSet, opts ...Option) (interface{}, error) {
+	e := &Executor{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	typ, ok := schema.TypeMap[typeName].(*internal.Object)
+	if !ok {
+		for _, root := range []internal.Type{schema.Query, schema.Mutation, schema.Subscription} {
+			if root, ok := root.(*internal.Object); ok && root.Name == typeName {
+				typ = root
+			}
+		}
+	}
+	if typ == nil {
+		return nil, errors.New("object %s does not exist", typeName)
+	}
+	field, ok := typ.Fields[fieldName]
+	if !ok {
+		return nil, errors.New("field %s.%s does not exist", typeName, fieldName)
+	}
+	coerced, err := coerceArguments(typ, field, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if typ == schema.Query {
+		ctx = WithReadOnly(ctx)
+	}
+	exeCtx := &exeContext{Context: internal.WithMemo(ctx), path: []interface{}{fieldName}, state: &operationState{}}
+	if e.failFast {
+		exeCtx.Context, exeCtx.cancel = context.WithCancel(exeCtx.Context)
+		defer exeCtx.cancel()
+	}
+	if selectionSet == nil {
+		value, err := safeExecuteResolver(exeCtx.resolveContext(field), e.resolver(typ, field), nil, coerced)
+		if thunk, ok := value.(internal.Thunk); ok && err == nil {
+			return thunk()
+		}
+		return value, err
+	}
+	result, err := e.resolveAndExecute(exeCtx, typ, field, nil, &internal.Selection{
+		Name:         fieldName,
+		Alias:        fieldName,
+		Args:         coerced,
+		SelectionSet: selectionSet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.completeThunks(exeCtx)
+	exeCtx.reportWarnings()
+	if pending, ok := result.(*pendingField); ok {
+		result = pending.value
+	}
+	if len(exeCtx.errs) > 0 {
+		return result, exeCtx.errs
+	}
+	return result, nil
+}
+
+// coerceArguments checks args against the arguments of field, and returns a copy of them with the
+// default values of input object fields filled in, those of omitted arguments are filled in when args
+// are decoded for the resolver.
+func coerceArguments(typ *internal.Object, field *internal.Field, args map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		def, ok := field.Args[name]
+		if !ok {
+			return nil, errors.New("unknown argument %q of field %s.%s", name, typ.Name, field.Name)
+		}
+		coerced[name] = coerceValue(value, def.Type)
+	}
+	names := make([]string, 0, len(field.Args))
+	for name := range field.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := field.Args[name]
+		if _, nonNull := def.Type.(*internal.NonNull); !nonNull {
+			continue
+		}
+		if value, ok := coerced[name]; ok && value == nil {
+			return nil, errors.New("argument %q of non-null type %q of field %s.%s must not be null", name, def.Type.String(), typ.Name, field.Name)
+		} else if !ok && def.DefaultValue == nil {
+			return nil, errors.New("argument %q of type %q of field %s.%s is required, but it was not provided", name, def.Type.String(), typ.Name, field.Name)
+		}
+	}
+	return coerced, nil
+}
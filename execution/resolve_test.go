@@ -0,0 +1,57 @@
+package execution_test
+
+import (
+	"context"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type ReportArgs struct {
+	ID    int64 `graphql:"id"`
+	Limit int64 `graphql:"limit"`
+}
+
+type Report struct {
+	ID    int64 `graphql:"id"`
+	Limit int64 `graphql:"limit"`
+}
+
+func TestResolveField(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.InputObject("ReportArgs", ReportArgs{}).FieldDefault("limit", float64(10))
+	build.Object("Report", Report{})
+	build.Query().FieldFunc("report", func(ctx context.Context, args ReportArgs) (Report, error) {
+		return Report{ID: args.ID, Limit: args.Limit}, nil
+	})
+	schema := build.MustBuild()
+
+	report, err := execution.ResolveField(context.Background(), schema, "Query", "report", map[string]interface{}{"id": 1.0}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Report{ID: 1, Limit: 10}, report)
+
+	doc, err := internal.Parse(`{ report(id: 0) { limit } }`)
+	assert.NoError(t, err)
+	_, selectionSet, err := execution.ApplySelectionSet(schema, doc, "", nil)
+	assert.NoError(t, err)
+	report, err = execution.ResolveField(context.Background(), schema, "Query", "report", map[string]interface{}{"id": 2.0, "limit": 5.0},
+		selectionSet.Selections[0].SelectionSet)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"limit": int64(5)}, report)
+
+	for args, message := range map[*map[string]interface{}]string{
+		{}:                        `argument "id" of type "Int64!" of field Query.report is required, but it was not provided`,
+		{"id": nil}:               `argument "id" of non-null type "Int64!" of field Query.report must not be null`,
+		{"id": 1.0, "other": 1.0}: `unknown argument "other" of field Query.report`,
+		{"id": "one"}:             `not a number`,
+	} {
+		_, err := execution.ResolveField(context.Background(), schema, "Query", "report", *args, nil)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+	_, err = execution.ResolveField(context.Background(), schema, "Query", "reports", nil, nil)
+	assert.EqualError(t, err, "graphql: field Query.reports does not exist")
+}
@@ -0,0 +1,110 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+)
+
+// DocumentRewriter returns the document a request is validated and executed as, from the document of
+// the request. It may change doc in place and return it.
+type DocumentRewriter func(ctx context.Context, doc *ast.Document) (*ast.Document, error)
+
+// WithDocumentRewriter rewrites the document of every request with rewriter after it is parsed, before
+// it is validated, for example to add the fields the resolvers of a type always need, see WalkSelectionSets,
+// or to reject the operations selecting a banned field:
+//
+//   execution.WithDocumentRewriter(func(ctx context.Context, doc *ast.Document) (*ast.Document, error) {
+//     execution.WalkSelectionSets(schema, doc, func(typ internal.NamedType, set *ast.SelectionSet) {
+//       if typ.TypeName() == "AuditLog" {
+//         set.Selections = append(set.Selections, &ast.Field{Kind: kinds.Field, Name: &ast.Name{Name: "id"}})
+//       }
+//     })
+//     return doc, nil
+//   })
+//
+// The rewritten document, printed by ast.Normalize, replaces the query of the request from then on: it is
+// the key of the plan cache, the query the operation is validated and executed as, and so the one the
+// locations of the errors refer to. The operation fails when rewriter returns an error, with the message
+// of the error and the code GRAPHQL_VALIDATION_FAILED in its extensions.
+func WithDocumentRewriter(rewriter DocumentRewriter) Option {
+	return func(e *Executor) {
+		e.rewriter = rewriter
+	}
+}
+
+// RewriteQuery returns query rewritten by the DocumentRewriter of the executor and printed by
+// ast.Normalize, or query when it has none. Do calls it before the query is planned.
+func (e *Executor) RewriteQuery(ctx context.Context, query string) (string, error) {
+	if e.rewriter == nil {
+		return query, nil
+	}
+	doc, parseErr := internal.ParseDocument(query)
+	if parseErr != nil {
+		return "", parseErr
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rewritten, err := e.rewriter(ctx, doc)
+	if err != nil {
+		return "", &errors.GraphQLError{
+			Message:       err.Error(),
+			ResolverError: err,
+			Extensions:    map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+		}
+	}
+	return ast.Normalize(rewritten), nil
+}
+
+// WalkSelectionSets calls fn with the selection sets of the operations and fragments of doc, after those
+// holding them, and the type of schema they select fields of. The selection sets of fields and fragments
+// whose type is not in schema are not walked, the validation of the document reports them.
+func WalkSelectionSets(schema *internal.Schema, doc *ast.Document, fn func(typ internal.NamedType, selectionSet *ast.SelectionSet)) {
+	roots := map[ast.OperationType]internal.Type{ast.Query: schema.Query, ast.Mutation: schema.Mutation, ast.Subscription: schema.Subscription}
+	for _, definition := range doc.Definition {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if root, ok := roots[definition.Operation].(internal.NamedType); ok {
+				walkSelectionSet(schema, root, definition.SelectionSet, fn)
+			}
+		case *ast.FragmentDefinition:
+			if typ, ok := schema.TypeMap[definition.TypeCondition.Name.Name]; ok {
+				walkSelectionSet(schema, typ, definition.SelectionSet, fn)
+			}
+		}
+	}
+}
+
+func walkSelectionSet(schema *internal.Schema, typ internal.NamedType, selectionSet *ast.SelectionSet, fn func(typ internal.NamedType, selectionSet *ast.SelectionSet)) {
+	if selectionSet == nil {
+		return
+	}
+	fn(typ, selectionSet)
+	var fields map[string]*internal.Field
+	switch typ := typ.(type) {
+	case *internal.Object:
+		fields = typ.Fields
+	case *internal.Interface:
+		fields = typ.Fields
+	}
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if field, ok := fields[selection.Name.Name]; ok {
+				if named, err := unwrapType(field.Type); err == nil && named != nil {
+					walkSelectionSet(schema, named, selection.SelectionSet, fn)
+				}
+			}
+		case *ast.InlineFragment:
+			condition := typ
+			if selection.TypeCondition != nil {
+				condition = schema.TypeMap[selection.TypeCondition.Name.Name]
+			}
+			if condition != nil {
+				walkSelectionSet(schema, condition, selection.SelectionSet, fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package execution_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/kinds"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExecutor_DocumentRewriter(t *testing.T) {
+	type AuditLog struct {
+		ID     int    `graphql:"id"`
+		Action string `graphql:"action"`
+	}
+	type User struct {
+		Name string `graphql:"name"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("AuditLog", AuditLog{})
+	build.Object("User", User{})
+	build.Query().FieldFunc("logs", func() []AuditLog { return []AuditLog{{ID: 1, Action: "login"}} })
+	build.Query().FieldFunc("user", func() User { return User{Name: "ann"} })
+	schema := build.MustBuild()
+
+	var rewritten []string
+	requireID := execution.WithDocumentRewriter(func(ctx context.Context, doc *ast.Document) (*ast.Document, error) {
+		execution.WalkSelectionSets(schema, doc, func(typ internal.NamedType, set *ast.SelectionSet) {
+			if typ.TypeName() != "AuditLog" {
+				return
+			}
+			for _, selection := range set.Selections {
+				if field, ok := selection.(*ast.Field); ok && field.Name.Name == "id" {
+					return
+				}
+			}
+			set.Selections = append(set.Selections, &ast.Field{Kind: kinds.Field, Name: &ast.Name{Kind: kinds.Name, Name: "id"}})
+		})
+		rewritten = append(rewritten, ast.Normalize(doc))
+		return doc, nil
+	})
+	data, errs := execution.Do(schema, execution.Params{Query: `{ logs { action } user { name } }`}, requireID)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"logs": []interface{}{map[string]interface{}{"action": "login", "id": 1}},
+		"user": map[string]interface{}{"name": "ann"},
+	}, data)
+
+	data, errs = execution.Do(schema, execution.Params{Query: `{ logs { ...Log } } fragment Log on AuditLog { id action }`}, requireID)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"logs": []interface{}{map[string]interface{}{"id": 1, "action": "login"}}}, data)
+	assert.Equal(t, []string{
+		"{ logs { action id } user { name } }",
+		// the selection set of logs gets id, as the fragment it spreads is not walked from it
+		"{ logs { ...Log id } } fragment Log on AuditLog { id action }",
+	}, rewritten)
+
+	banUser := execution.WithDocumentRewriter(func(ctx context.Context, doc *ast.Document) (*ast.Document, error) {
+		var err error
+		execution.WalkSelectionSets(schema, doc, func(typ internal.NamedType, set *ast.SelectionSet) {
+			for _, selection := range set.Selections {
+				if field, ok := selection.(*ast.Field); ok && typ.TypeName() == "Query" && field.Name.Name == "user" {
+					err = fmt.Errorf("Query.user may not be queried")
+				}
+			}
+		})
+		return doc, err
+	})
+	data, errs = execution.Do(schema, execution.Params{Query: `{ logs { id } user { name } }`}, banUser)
+	assert.Nil(t, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "Query.user may not be queried", errs[0].Message)
+		assert.Equal(t, map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"}, errs[0].Extensions)
+	}
+	_, errs = execution.Do(schema, execution.Params{Query: `{ logs { id } }`}, banUser)
+	assert.Empty(t, errs)
+}
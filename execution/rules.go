@@ -0,0 +1,276 @@
+package execution
+
+import (
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/utils"
+	"sort"
+)
+
+// Rule is an optional validation run against an operation after the document has been validated.
+// It returns a *errors.GraphQLError or errors.MultiError for the problems it finds.
+type Rule func(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}) error
+
+// ValidateRules runs rules against the operation named operationName.
+func ValidateRules(schema *internal.Schema, document *internal.Document, operationName string, vars map[string]interface{}, rules ...Rule) errors.MultiError {
+	if len(rules) == 0 {
+		return nil
+	}
+	op, err := GetOperation(document, operationName)
+	if err != nil {
+		return errors.MultiError{err.(*errors.GraphQLError)}
+	}
+	var errs errors.MultiError
+	for _, rule := range rules {
+		switch err := rule(schema, document, op, vars).(type) {
+		case nil:
+		case errors.MultiError:
+			errs = append(errs, err...)
+		case *errors.GraphQLError:
+			errs = append(errs, err)
+		default:
+			errs = append(errs, errors.New("%s", err.Error()))
+		}
+	}
+	return errs
+}
+
+// NoUndeclaredVariableValues reports the values of variables which the operation does not declare,
+// values which are otherwise ignored.
+func NoUndeclaredVariableValues() Rule {
+	return func(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}) error {
+		declared := make(map[string]bool, len(op.Vars))
+		for _, v := range op.Vars {
+			declared[v.Var.Name.Name] = true
+		}
+		var names []string
+		for name := range vars {
+			if !declared[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var errs errors.MultiError
+		for _, name := range names {
+			errs = append(errs, printErr(op.Loc, "NoUndeclaredVariableValues", "Variable \"$%s\" is provided but not declared by the operation.", name).(*errors.GraphQLError))
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+}
+
+// NoSchemaIntrospection reports the __schema and __type fields selected by the operation, fragments
+// included, so that an endpoint, such as one serving persisted operations, cannot be used to scrape
+// the schema. __typename is allowed.
+func NoSchemaIntrospection() Rule {
+	return func(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}) error {
+		fragments := make(map[string]*ast.FragmentDefinition, len(document.Fragments))
+		for _, fragment := range document.Fragments {
+			fragments[fragment.Name.Name] = fragment
+		}
+
+		var errs errors.MultiError
+		visited := make(map[string]bool)
+		var checkSelectionSet func(selectionSet *ast.SelectionSet)
+		checkSelectionSet = func(selectionSet *ast.SelectionSet) {
+			if selectionSet == nil {
+				return
+			}
+			for _, selection := range selectionSet.Selections {
+				switch selection := selection.(type) {
+				case *ast.Field:
+					if name := selection.Name.Name; name == "__schema" || name == "__type" {
+						errs = append(errs, printErr(selection.Name.Loc, "NoSchemaIntrospection", "GraphQL introspection field \"%s\" is not allowed here, query the introspection endpoint instead.", name).(*errors.GraphQLError))
+						continue
+					}
+					checkSelectionSet(selection.SelectionSet)
+				case *ast.InlineFragment:
+					checkSelectionSet(selection.SelectionSet)
+				case *ast.FragmentSpread:
+					fragment, ok := fragments[selection.Name.Name]
+					if !ok || visited[fragment.Name.Name] {
+						continue
+					}
+					visited[fragment.Name.Name] = true
+					checkSelectionSet(fragment.SelectionSet)
+				}
+			}
+		}
+		checkSelectionSet(op.SelectionSet)
+
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+}
+
+// NoDeprecated reports every usage of a deprecated field, argument or enum value in the operation.
+// Enum values passed through variables are reported at the variable definition.
+func NoDeprecated() Rule {
+	return func(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}) error {
+		var errs errors.MultiError
+		report := func(loc errors.Location, format string, a ...interface{}) {
+			errs = append(errs, printErr(loc, "NoDeprecated", format, a...).(*errors.GraphQLError))
+		}
+
+		fragments := make(map[string]*ast.FragmentDefinition, len(document.Fragments))
+		for _, fragment := range document.Fragments {
+			fragments[fragment.Name.Name] = fragment
+		}
+
+		var checkValue func(value ast.Value, typ internal.Type)
+		checkValue = func(value ast.Value, typ internal.Type) {
+			switch typ := typ.(type) {
+			case *internal.NonNull:
+				checkValue(value, typ.Type)
+			case *internal.List:
+				if list, ok := value.(*ast.ListValue); ok {
+					for _, item := range list.Values {
+						checkValue(item, typ.Type)
+					}
+				} else {
+					checkValue(value, typ.Type)
+				}
+			case *internal.Enum:
+				if enum, ok := value.(*ast.EnumValue); ok {
+					if reason, ok := typ.Deprecated[enum.Value]; ok {
+						report(enum.Loc, "The enum value \"%s.%s\" is deprecated. %s", typ.Name, enum.Value, reason)
+					}
+				}
+			case *internal.InputObject:
+				if object, ok := value.(*ast.ObjectValue); ok {
+					for _, field := range object.Fields {
+						if f, ok := typ.Fields[field.Name.Name.Name]; ok {
+							if f.IsDeprecated {
+								report(field.Loc, "The input field \"%s.%s\" is deprecated. %s", typ.Name, f.Name, f.DeprecationReason)
+							}
+							checkValue(field.Value, f.Type)
+						}
+					}
+				}
+			}
+		}
+
+		var checkJson func(loc errors.Location, value interface{}, typ internal.Type)
+		checkJson = func(loc errors.Location, value interface{}, typ internal.Type) {
+			switch typ := typ.(type) {
+			case *internal.NonNull:
+				checkJson(loc, value, typ.Type)
+			case *internal.List:
+				if list, ok := value.([]interface{}); ok {
+					for _, item := range list {
+						checkJson(loc, item, typ.Type)
+					}
+				} else {
+					checkJson(loc, value, typ.Type)
+				}
+			case *internal.Enum:
+				if enum, ok := value.(string); ok {
+					if reason, ok := typ.Deprecated[enum]; ok {
+						report(loc, "The enum value \"%s.%s\" is deprecated. %s", typ.Name, enum, reason)
+					}
+				}
+			case *internal.InputObject:
+				if object, ok := value.(map[string]interface{}); ok {
+					names := make([]string, 0, len(object))
+					for name := range object {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					for _, name := range names {
+						v := object[name]
+						if f, ok := typ.Fields[name]; ok {
+							if f.IsDeprecated {
+								report(loc, "The input field \"%s.%s\" is deprecated. %s", typ.Name, f.Name, f.DeprecationReason)
+							}
+							checkJson(loc, v, f.Type)
+						}
+					}
+				}
+			}
+		}
+
+		for _, v := range op.Vars {
+			typ, err := utils.TypeFromAst(schema, v.Type)
+			if err != nil {
+				continue
+			}
+			if v.DefaultValue != nil {
+				checkValue(v.DefaultValue, typ)
+			}
+			checkJson(v.Loc, vars[v.Var.Name.Name], typ)
+		}
+
+		visited := make(map[string]bool)
+		var checkSelectionSet func(t internal.NamedType, selectionSet *ast.SelectionSet)
+		checkSelectionSet = func(t internal.NamedType, selectionSet *ast.SelectionSet) {
+			if selectionSet == nil {
+				return
+			}
+			for _, selection := range selectionSet.Selections {
+				switch selection := selection.(type) {
+				case *ast.Field:
+					f := fields(t)[selection.Name.Name]
+					if f == nil {
+						continue
+					}
+					if f.IsDeprecated {
+						report(selection.Name.Loc, "The field \"%s.%s\" is deprecated. %s", t.TypeName(), f.Name, f.DeprecationReason)
+					}
+					for _, arg := range selection.Arguments {
+						a, ok := f.Args[arg.Name.Name]
+						if !ok {
+							continue
+						}
+						if a.IsDeprecated {
+							report(arg.Loc, "The argument \"%s.%s(%s:)\" is deprecated. %s", t.TypeName(), f.Name, a.Name, a.DeprecationReason)
+						}
+						checkValue(arg.Value, a.Type)
+					}
+					if named, err := unwrapType(f.Type); err == nil && named != nil {
+						checkSelectionSet(named, selection.SelectionSet)
+					}
+				case *ast.InlineFragment:
+					on := t
+					if selection.TypeCondition != nil {
+						if typ, ok := schema.TypeMap[selection.TypeCondition.Name.Name]; ok {
+							on = typ
+						}
+					}
+					checkSelectionSet(on, selection.SelectionSet)
+				case *ast.FragmentSpread:
+					fragment, ok := fragments[selection.Name.Name]
+					if !ok || visited[fragment.Name.Name] {
+						continue
+					}
+					visited[fragment.Name.Name] = true
+					if typ, ok := schema.TypeMap[fragment.TypeCondition.Name.Name]; ok {
+						checkSelectionSet(typ, fragment.SelectionSet)
+					}
+				}
+			}
+		}
+
+		var root internal.Type
+		switch op.Operation {
+		case ast.Query:
+			root = schema.Query
+		case ast.Mutation:
+			root = schema.Mutation
+		case ast.Subscription:
+			root = schema.Subscription
+		}
+		if named, err := unwrapType(root); err == nil && named != nil {
+			checkSelectionSet(named, op.SelectionSet)
+		}
+
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+}
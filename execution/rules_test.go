@@ -0,0 +1,156 @@
+package execution_test
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+type Color int
+
+func TestNoDeprecated(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Color", Color(0), map[string]interface{}{"RED": Color(0), "CRIMSON": Color(1)})
+	build.Query().FieldFunc("paint", func(args struct {
+		Color  Color  `graphql:"color"`
+		Colour *Color `graphql:"colour"`
+	}) string {
+		return ""
+	})
+	build.Query().FieldFunc("oldPaint", func() string { return "" })
+	schema := build.MustBuild()
+
+	query := schema.Query.(*internal.Object)
+	query.Fields["oldPaint"].IsDeprecated = true
+	query.Fields["oldPaint"].DeprecationReason = "Use paint."
+	query.Fields["paint"].Args["colour"].IsDeprecated = true
+	query.Fields["paint"].Args["colour"].DeprecationReason = "Use color."
+	schema.TypeMap["Color"].(*internal.Enum).Deprecated = map[string]string{"CRIMSON": "Use RED."}
+
+	rules := []execution.Rule{execution.NoDeprecated()}
+
+	t.Run("allows deprecated usages by default", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: "{ oldPaint }"})
+		assert.Equal(t, errors.MultiError(nil), err)
+	})
+
+	t.Run("reports deprecated fields", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: "{ oldPaint }", Rules: rules})
+		assert.EqualError(t, err, `[graphql: The field "Query.oldPaint" is deprecated. Use paint. (1:3)]`)
+	})
+
+	t.Run("reports deprecated arguments", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: "{ paint(color: RED, colour: RED) }", Rules: rules})
+		assert.EqualError(t, err, `[graphql: The argument "Query.paint(colour:)" is deprecated. Use color. (1:21)]`)
+	})
+
+	t.Run("reports deprecated enum values", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: "{ paint(color: CRIMSON) }", Rules: rules})
+		assert.EqualError(t, err, `[graphql: The enum value "Color.CRIMSON" is deprecated. Use RED. (1:16)]`)
+
+		_, err = execution.Do(schema, execution.Params{
+			Query:     "query ($c: Color!) { paint(color: $c) }",
+			Variables: map[string]interface{}{"c": "CRIMSON"},
+			Rules:     rules,
+		})
+		assert.EqualError(t, err, `[graphql: The enum value "Color.CRIMSON" is deprecated. Use RED. (1:8)]`)
+	})
+}
+
+func TestNoSchemaIntrospection(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("ok", func() string { return "fine" })
+	schema := build.MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+	rules := []execution.Rule{execution.NoSchemaIntrospection()}
+
+	t.Run("allows __typename", func(t *testing.T) {
+		result, err := execution.Do(schema, execution.Params{Query: "{ ok __typename }", Rules: rules})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"ok": "fine", "__typename": "Query"}, result)
+	})
+
+	t.Run("reports __schema and __type", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: `{ __schema { queryType { name } } __type(name: "Query") { name } }`, Rules: rules})
+		assert.EqualError(t, err, `[graphql: GraphQL introspection field "__schema" is not allowed here, query the introspection endpoint instead. (1:3)`+"\n"+
+			`graphql: GraphQL introspection field "__type" is not allowed here, query the introspection endpoint instead. (1:35)]`)
+	})
+
+	t.Run("reports fields behind fragments", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: `
+			{ ...Scrape ... on Query { ...Scrape } }
+			fragment Scrape on Query { ... { __schema { types { name } } } }`, Rules: rules})
+		assert.EqualError(t, err, `[graphql: GraphQL introspection field "__schema" is not allowed here, query the introspection endpoint instead. (3:37)]`)
+	})
+}
+
+type Friend struct {
+	Name string `graphql:"name"`
+}
+
+func TestMaxComplexity(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	calls := 0
+	friend := build.Object("Friend", Friend{})
+	friend.FieldFunc("friends", func(args struct {
+		First *int64 `graphql:"first"`
+	}) []Friend {
+		calls++
+		return []Friend{{Name: "bob"}}
+	})
+	build.Query().FieldFunc("all", func(args struct {
+		Limit int64 `graphql:"limit"`
+	}) []Friend {
+		calls++
+		return []Friend{{Name: "ann"}}
+	})
+	build.Query().FieldFunc("search", func() []Friend { return nil }, schemabuilder.Cost(10))
+	build.Query().FieldFunc("report", func(args struct {
+		Pages int64 `graphql:"pages"`
+	}) string {
+		return "done"
+	}, schemabuilder.CostFunc(func(args map[string]interface{}, childComplexity int) int {
+		pages, _ := strconv.Atoi(fmt.Sprint(args["pages"]))
+		return pages * 5
+	}))
+	schema := build.MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+	complexity := func(max int, query string, variables map[string]interface{}) error {
+		_, errs := execution.Do(schema, execution.Params{Query: query, Variables: variables, Rules: []execution.Rule{execution.MaxComplexity(max)}})
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	}
+
+	// all counts 1 + 10 * (name 1 + friends (1 + 5 * name 1))
+	const query = `{ all(limit: 10) { name friends(first: 5) { name } } }`
+	assert.NoError(t, complexity(71, query, nil))
+	assert.Equal(t, 2, calls)
+	calls = 0
+	err := complexity(70, query, nil)
+	assert.EqualError(t, err, "[graphql: The operation is more complex than the maximum complexity of 70. (1:1)]")
+	assert.Equal(t, "MaxComplexityExceeded", err.(errors.MultiError)[0].Rule)
+	assert.Equal(t, 0, calls)
+
+	// the sizes may be variables, a list without them is counted once
+	assert.Error(t, complexity(70, `query($n: Int64!) { all(limit: $n) { name friends(first: 5) { name } } }`, map[string]interface{}{"n": float64(10)}))
+	assert.NoError(t, complexity(70, `query($n: Int64!) { all(limit: $n) { name friends(first: 5) { name } } }`, map[string]interface{}{"n": float64(9)}))
+	assert.NoError(t, complexity(4, `{ all(limit: 1) { friends { name } } }`, nil))
+
+	// huge sizes do not overflow
+	assert.Error(t, complexity(1000, `{ all(limit: 9223372036854775807) { friends(first: 9223372036854775807) { name } } }`, nil))
+
+	// the costs of the fields replace their count of 1, introspection counts nothing
+	assert.NoError(t, complexity(11, `{ search { name } }`, nil))
+	assert.Error(t, complexity(10, `{ search { name } }`, nil))
+	assert.NoError(t, complexity(15, `{ report(pages: 3) }`, nil))
+	assert.Error(t, complexity(14, `{ report(pages: 3) }`, nil))
+	assert.NoError(t, complexity(0, `{ __typename __schema { types { name fields { name } } } }`, nil))
+}
@@ -3,7 +3,10 @@ package execution
 import (
 	"fmt"
 	"github.com/shyptr/graphql/internal"
+	"log"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/shyptr/graphql/ast"
 	"github.com/shyptr/graphql/errors"
@@ -20,20 +23,108 @@ func printErr(loc errors.Location, rule string, format string, a ...interface{})
 
 func ApplySelectionSet(schema *internal.Schema, document *internal.Document, operationName string, vars map[string]interface{}) (
 	ast.OperationType, *internal.SelectionSet, error) {
+	return (&Executor{}).ApplySelectionSet(schema, document, operationName, vars)
+}
 
-	if document == nil {
-		return "", nil, errors.New("must provide document")
+// ApplySelectionSet is like the ApplySelectionSet function, with the options of the executor, such as
+// AllowUnknownInputFields.
+func (e *Executor) ApplySelectionSet(schema *internal.Schema, document *internal.Document, operationName string, vars map[string]interface{}) (
+	ast.OperationType, *internal.SelectionSet, error) {
+	op, err := GetOperation(document, operationName)
+	if err != nil {
+		return "", nil, err
 	}
-	if len(document.Operations) == 0 {
-		return "", nil, errors.New("no operations in query document")
+	vars, err = validateVariables(schema, op, vars, e.dropUnknownInputField())
+	if err != nil {
+		return "", nil, err
+	}
+	return applySelectionSet(schema, document, op, vars, e.dropUnknownInputField(), e.maxExpandedFields)
+}
+
+// dropFunc is called with the fields of input object values which their type does not define, before
+// they are dropped. The fields are rejected instead when the dropFunc is nil.
+type dropFunc func(typ *internal.InputObject, name string, loc errors.Location)
+
+// dropUnknownInputField returns the dropFunc of the executor, which logs the fields dropped.
+func (e *Executor) dropUnknownInputField() dropFunc {
+	if !e.allowUnknownInputFields {
+		return nil
+	}
+	return func(typ *internal.InputObject, name string, loc errors.Location) {
+		log.Printf("graphql: dropped field %q at %d:%d, which input object %s does not define", name, loc.Line, loc.Column, typ.Name)
+	}
+}
+
+// ApplyCoercedSelectionSet is like ApplySelectionSet, but trusts vars to be the result of an earlier
+// ValidateVariables call for the same operation, so the variable values are not validated twice.
+func ApplyCoercedSelectionSet(schema *internal.Schema, document *internal.Document, operationName string, vars map[string]interface{}) (
+	ast.OperationType, *internal.SelectionSet, error) {
+	return (&Executor{}).ApplyCoercedSelectionSet(schema, document, operationName, vars)
+}
+
+// ApplyCoercedSelectionSet is like the ApplyCoercedSelectionSet function, with the options of the
+// executor, such as MaxExpandedFields and AllowUnknownInputFields. vars should be the result of the
+// ValidateVariables method of the executor.
+func (e *Executor) ApplyCoercedSelectionSet(schema *internal.Schema, document *internal.Document, operationName string, vars map[string]interface{}) (
+	ast.OperationType, *internal.SelectionSet, error) {
+	op, err := GetOperation(document, operationName)
+	if err != nil {
+		return "", nil, err
 	}
 	if vars == nil {
 		vars = make(map[string]interface{})
 	}
+	return applySelectionSet(schema, document, op, vars, e.dropUnknownInputField(), e.maxExpandedFields)
+}
+
+// ValidateDocument runs the structural checks of every operation in document against schema, without looking at any
+// variable values. It is meant for tooling validating persisted operations ahead of time; the values of the variables
+// still have to be checked by ValidateVariables for every request.
+func ValidateDocument(schema *internal.Schema, document *internal.Document) error {
+	return (&Executor{}).ValidateDocument(schema, document)
+}
+
+// ValidateDocument is like the ValidateDocument function, with the options of the executor: the fields
+// input objects do not define are dropped with AllowUnknownInputFields, so that a document validated
+// ahead of time is checked as the executor checks it when it is run.
+func (e *Executor) ValidateDocument(schema *internal.Schema, document *internal.Document) error {
+	return validateDocument(schema, document, e.dropUnknownInputField(), e.maxExpandedFields)
+}
+
+// validateDocument is ValidateDocument, dropping the fields input objects do not define with drop
+// instead of rejecting them when it is not nil, and rejecting the operations expanding to more than
+// maxFields fields, see MaxExpandedFields.
+func validateDocument(schema *internal.Schema, document *internal.Document, drop dropFunc, maxFields int) error {
+	if document == nil {
+		return errors.New("must provide document")
+	}
+	if len(document.Operations) == 0 {
+		return errors.New("no operations in query document")
+	}
+	for _, op := range document.Operations {
+		if err := validateVariableDefinitions(schema, op.Vars); err != nil {
+			return err
+		}
+		// nil vars tell the checks needing variable values that they are not known
+		if _, _, err := applySelectionSet(schema, document, op, nil, drop, maxFields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOperation returns the operation of document which should be executed for operationName.
+func GetOperation(document *internal.Document, operationName string) (*ast.OperationDefinition, error) {
+	if document == nil {
+		return nil, errors.New("must provide document")
+	}
+	if len(document.Operations) == 0 {
+		return nil, errors.New("no operations in query document")
+	}
 	var op *ast.OperationDefinition
 	if operationName == "" {
 		if len(document.Operations) > 1 {
-			return "", nil, errors.New("more than one operation in query document and no operation name given")
+			return nil, errors.New("more than one operation in query document and no operation name given")
 		}
 		for _, p := range document.Operations {
 			// return the one and only operation
@@ -43,17 +134,267 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 	} else {
 		op = utils.GetOperation(document.Operations, operationName)
 		if op == nil {
-			return "", nil, errors.New("no operation with name %q", operationName)
+			return nil, errors.New("no operation with name %q", operationName)
 		}
 	}
 	if op == nil {
-		return "", nil, errors.New("no operation")
+		return nil, errors.New("no operation")
+	}
+	return op, nil
+}
+
+// ValidateVariables checks vars against the variable definitions of op and returns the variables coerced for
+// execution: default values of the operation are applied to variables omitted or provided as null, the default
+// values of input object fields are filled in for fields missing from the provided objects, and the names of
+// enum values are mapped to their Go values. The input map is not modified. The result is meant for
+// ApplyCoercedSelectionSet, not for ApplySelectionSet, which validates the variables again.
+//
+// A variable of a non-null type without default value must be provided and not be null, other variables may be
+// omitted, the arguments using them are then omitted as well. Values of variables the operation does not declare
+// are ignored, the NoUndeclaredVariableValues rule reports them.
+func ValidateVariables(schema *internal.Schema, op *ast.OperationDefinition, vars map[string]interface{}) (map[string]interface{}, error) {
+	return (&Executor{}).ValidateVariables(schema, op, vars)
+}
+
+// ValidateVariables is like the ValidateVariables function, dropping the fields input objects do
+// not define when the executor has the AllowUnknownInputFields option.
+func (e *Executor) ValidateVariables(schema *internal.Schema, op *ast.OperationDefinition, vars map[string]interface{}) (map[string]interface{}, error) {
+	coerced, err := validateVariables(schema, op, vars, e.dropUnknownInputField())
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range op.Vars {
+		if value, ok := coerced[v.Var.Name.Name]; ok {
+			vTyp, _ := utils.TypeFromAst(schema, v.Type)
+			coerced[v.Var.Name.Name] = mapEnums(value, vTyp)
+		}
+	}
+	return coerced, nil
+}
+
+// mapEnums returns val, a value of type typ, with the names of its enum values replaced by their Go
+// values. The lists and input objects holding enum values are copied.
+func mapEnums(val interface{}, typ internal.Type) interface{} {
+	if val == nil {
+		return nil
+	}
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return mapEnums(val, typ.Type)
+	case *internal.List:
+		list, ok := val.([]interface{})
+		if !ok {
+			return mapEnums(val, typ.Type)
+		}
+		if named, _ := unwrapType(typ); named != nil {
+			if _, ok := named.(*internal.Scalar); ok {
+				return list
+			}
+		}
+		res := make([]interface{}, len(list))
+		for i, item := range list {
+			res[i] = mapEnums(item, typ.Type)
+		}
+		return res
+	case *internal.InputObject:
+		in, ok := val.(map[string]interface{})
+		if !ok {
+			return val
+		}
+		res := make(map[string]interface{}, len(in))
+		for name, v := range in {
+			if f, ok := typ.Fields[name]; ok {
+				v = mapEnums(v, f.Type)
+			}
+			res[name] = v
+		}
+		return res
+	case *internal.Enum:
+		if name, ok := val.(string); ok {
+			if value, ok := typ.ReverseMap[name]; ok {
+				return value
+			}
+		}
+	}
+	return val
+}
+
+// validateVariables is ValidateVariables, leaving the enum values in their serialized (name) form, which
+// the argument decoders map to Go values, and dropping the fields the input objects of vars do not define
+// with drop instead of rejecting them when it is not nil.
+func validateVariables(schema *internal.Schema, op *ast.OperationDefinition, vars map[string]interface{}, drop dropFunc) (map[string]interface{}, error) {
+	if err := validateVariableDefinitions(schema, op.Vars); err != nil {
+		return nil, err
+	}
+	coerced := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		coerced[k] = v
+	}
+	for _, v := range op.Vars {
+		variableName := v.Var.Name.Name
+		vTyp, _ := utils.TypeFromAst(schema, v.Type)
+		if value, ok := coerced[variableName]; !ok && v.DefaultValue == nil {
+			if _, required := vTyp.(*internal.NonNull); required {
+				return nil, printErr(v.Loc, "VariablesOfCorrectType", "Variable \"$%s\" of required type \"%s\" was not provided.", variableName, vTyp.String())
+			}
+			continue
+		} else if value == nil && v.DefaultValue != nil {
+			value, err := internal.ValueToJson(v.DefaultValue, nil)
+			if err != nil {
+				return nil, printErr(v.Loc, "DefaultValuesOfCorrectType", err.Error())
+			}
+			coerced[variableName] = value
+		}
+		// coerceValue only keeps the fields the input objects define
+		for _, unknown := range unknownInputFields(coerced[variableName], vTyp) {
+			if drop == nil {
+				return nil, printErr(v.Loc, "VariablesOfCorrectType", "Variable \"$%s\" got invalid value %v; Field %q is not defined by type %q.%s",
+					variableName, coerced[variableName], unknown.name, unknown.typ.Name, makeSuggestion("Did you mean", fieldNames(unknown.typ), unknown.name))
+			}
+			drop(unknown.typ, unknown.name, v.Loc)
+		}
+		coerced[variableName] = coerceValue(coerced[variableName], vTyp)
+		if err := validateValue(v, coerced[variableName], vTyp); err != nil {
+			return nil, err
+		}
+	}
+	return coerced, nil
+}
+
+// validateVariableDefinitions checks the variable definitions themselves: names are unique,
+// and every type is known and an input type.
+func validateVariableDefinitions(schema *internal.Schema, defs []*ast.VariableDefinition) error {
+	varset := make(map[string]struct{})
+	for _, v := range defs {
+		variableName := v.Var.Name.Name
+		if _, ok := varset[variableName]; ok {
+			return printErr(v.Loc, "Variable Uniqueness", "duplicate variable name %s", variableName)
+		}
+		varset[variableName] = struct{}{}
+		if err := knownTypeName(schema, v.Type); err != nil {
+			return err
+		}
+		vTyp, err := utils.TypeFromAst(schema, v.Type)
+		if err != nil {
+			return printErr(v.Loc, "ValuesOfCorrectType", err.Error())
+		}
+		if vTyp != nil && !internal.IsInputType(vTyp) {
+			return printErr(v.Loc, "Variables Are Input Types", `Variable "$%s" cannot be non-input type "%s".`, variableName, v.Type.String())
+		}
+		if err := validateDirectives(schema, "VARIABLE_DEFINITION", v.Directives); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coerceValue returns a copy of val with the default values of input object fields
+// filled in for the fields that are missing from val.
+func coerceValue(val interface{}, typ internal.Type) interface{} {
+	if val == nil {
+		return nil
 	}
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return coerceValue(val, typ.Type)
+	case *internal.List:
+		list, ok := val.([]interface{})
+		if !ok {
+			return coerceValue(val, typ.Type)
+		}
+		// the elements are kept as they are, the list can be shared with the input
+		if leafList(typ) {
+			return list
+		}
+		res := make([]interface{}, len(list))
+		for i, item := range list {
+			res[i] = coerceValue(item, typ.Type)
+		}
+		return res
+	case *internal.InputObject:
+		in, ok := val.(map[string]interface{})
+		if !ok {
+			return val
+		}
+		res := make(map[string]interface{}, len(typ.Fields))
+		for name, f := range typ.Fields {
+			if v, ok := in[name]; ok {
+				res[name] = coerceValue(v, f.Type)
+			} else if f.DefaultValue != nil {
+				res[name] = f.DefaultValue
+			}
+		}
+		return res
+	}
+	return val
+}
+
+// unknownField is a field of an input object value which its type does not define.
+type unknownField struct {
+	typ  *internal.InputObject
+	name string
+}
+
+// unknownInputFields returns the fields of the input objects of val, of type typ, which their type does
+// not define, in order.
+func unknownInputFields(val interface{}, typ internal.Type) []unknownField {
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return unknownInputFields(val, typ.Type)
+	case *internal.List:
+		if leafList(typ) {
+			return nil
+		}
+		list, ok := val.([]interface{})
+		if !ok {
+			return unknownInputFields(val, typ.Type)
+		}
+		var unknown []unknownField
+		for _, item := range list {
+			unknown = append(unknown, unknownInputFields(item, typ.Type)...)
+		}
+		return unknown
+	case *internal.InputObject:
+		in, ok := val.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		names := make([]string, 0, len(in))
+		for name := range in {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var unknown []unknownField
+		for _, name := range names {
+			if f, ok := typ.Fields[name]; ok {
+				unknown = append(unknown, unknownInputFields(in[name], f.Type)...)
+			} else {
+				unknown = append(unknown, unknownField{typ: typ, name: name})
+			}
+		}
+		return unknown
+	}
+	return nil
+}
+
+// fieldNames returns the names of the fields of typ, in order.
+func fieldNames(typ *internal.InputObject) []string {
+	names := make([]string, 0, len(typ.Fields))
+	for name := range typ.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func applySelectionSet(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, vars map[string]interface{}, drop dropFunc,
+	maxFields int) (
+	ast.OperationType, *internal.SelectionSet, error) {
 	var opName string
 	if op.Name != nil {
-		opName = opName
+		opName = op.Name.Name
 	}
-	if op.Operation == "subscription" && len(op.SelectionSet.Selections) != 1 {
+	if op.Operation == ast.Subscription && len(op.SelectionSet.Selections) != 1 {
 		if opName != "" {
 			return "", nil, printErr(op.Loc, "Single root field", `Subscription "%s" must select only one top level field.`, opName)
 		} else {
@@ -86,36 +427,13 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 		}
 	}
 
-	// set default value
+	if err := validateVariableUsages(schema, document, op, obj); err != nil {
+		return "", nil, err
+	}
+
 	varset := make(map[string]struct{})
 	for _, v := range op.Vars {
-		variableName := v.Var.Name.Name
-		if _, ok := varset[variableName]; ok {
-			return "", nil, printErr(v.Loc, "Variable Uniqueness", "duplicate variable name %s", variableName)
-		}
-		varset[variableName] = struct{}{}
-		vTyp, err := utils.TypeFromAst(schema, v.Type)
-		if err != nil {
-			return "", nil, printErr(v.Loc, "ValuesOfCorrectType", err.Error())
-		}
-		if vTyp != nil && !internal.IsInputType(vTyp) {
-			return "", nil, printErr(v.Loc, "Variables Are Input Types", `Variable "$%s" cannot be non-input type "%s".`, variableName, v.Type.String())
-		}
-		if value, ok := vars[variableName]; !ok {
-			return "", nil, printErr(v.Loc, "NoUndefinedVariables", "Variable %q is not defined%s.", variableName, opName)
-		} else if ok && value == nil {
-			if v.DefaultValue != nil {
-				value, err := internal.ValueToJson(v.DefaultValue, nil)
-				if err != nil {
-					return "", nil, printErr(v.Loc, "DefaultValuesOfCorrectType", err.Error())
-				} else {
-					vars[variableName] = value
-				}
-			}
-		}
-		if err := validateValue(v, vars[variableName], vTyp); err != nil {
-			return "", nil, err
-		}
+		varset[v.Var.Name.Name] = struct{}{}
 	}
 
 	for _, fragment := range document.Fragments {
@@ -126,6 +444,9 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 				return "", nil, printErr(v.Loc, "Variable Uniqueness", "duplicate variable name %s", variableName)
 			}
 			varset[variableName] = struct{}{}
+			if err := knownTypeName(schema, v.Type); err != nil {
+				return "", nil, err
+			}
 			vTyp, err := utils.TypeFromAst(schema, v.Type)
 			if err != nil {
 				return "", nil, printErr(v.Loc, "ValuesOfCorrectType", err.Error())
@@ -133,6 +454,9 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 			if vTyp != nil && !internal.IsInputType(vTyp) {
 				return "", nil, printErr(v.Loc, "Variables Are Input Types", `Variable "$%s" cannot be non-input type "%s".`, variableName, v.Type.String())
 			}
+			if err := validateDirectives(schema, "VARIABLE_DEFINITION", v.Directives); err != nil {
+				return "", nil, err
+			}
 			if value, ok := vars[variableName]; !ok {
 				return "", nil, printErr(v.Loc, "NoUndefinedVariables", "Variable %q is not defined%s.", variableName, opName)
 			} else if ok && value == nil && v.DefaultValue != nil {
@@ -148,6 +472,9 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 			}
 		}
 
+		if err := knownTypeName(schema, fragment.TypeCondition); err != nil {
+			return "", nil, err
+		}
 		vtyp, err := utils.TypeFromAst(schema, fragment.TypeCondition)
 		if err != nil {
 			return "", nil, printErr(fragment.Loc, "FragmentsOnCompositeTypes", err.Error())
@@ -161,10 +488,11 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 			return "", nil, printErr(fragment.TypeCondition.Loc, "FragmentsOnCompositeTypes", "Fragment %q cannot condition on non composite type %q.", fragment.Name.Name, t)
 		}
 
-		selectionSet, err := parseSelectionSet(schema, t, fragment.SelectionSet, globalFragments, vars)
+		selectionSet, err := parseSelectionSet(schema, t, fragment.SelectionSet, globalFragments, vars, drop)
 		if err != nil {
 			return "", rv, err
 		}
+		globalFragments[fragment.Name.Name].Type = t
 		globalFragments[fragment.Name.Name].SelectionSet = selectionSet
 	}
 
@@ -172,7 +500,7 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 		return "", nil, err
 	}
 
-	selectionSet, err := parseSelectionSet(schema, obj, op.SelectionSet, globalFragments, vars)
+	selectionSet, err := parseSelectionSet(schema, obj, op.SelectionSet, globalFragments, vars, drop)
 	if err != nil {
 		return "", rv, err
 	}
@@ -181,6 +509,13 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 		return "", rv, err
 	}
 
+	if maxFields <= 0 {
+		maxFields = DefaultMaxExpandedFields
+	}
+	if expandedFields(selectionSet, maxFields) > maxFields {
+		return "", rv, printErr(op.Loc, "MaxExpandedFieldsExceeded", "The operation expands to more than %d fields once its fragments are spread.", maxFields)
+	}
+
 	if err := detectConflicts(selectionSet); err != nil {
 		return "", rv, err
 	}
@@ -192,7 +527,7 @@ func ApplySelectionSet(schema *internal.Schema, document *internal.Document, ope
 
 // parseSelectionSet takes a grapqhl-go selection set and converts it to a simplified *SelectionSet, bindings vars
 func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast.SelectionSet, globalFragments map[string]*internal.FragmentDefinition,
-	vars map[string]interface{}) (*internal.SelectionSet, error) {
+	vars map[string]interface{}, drop dropFunc) (*internal.SelectionSet, error) {
 	if input == nil {
 		return nil, nil
 	}
@@ -206,10 +541,16 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 			if selection.Alias != nil {
 				alias = selection.Alias.Name
 			}
+			// names beginning with "__" are reserved for introspection, so an alias can not
+			// make a field look like a meta-field in the response
+			if alias != selection.Name.Name && strings.HasPrefix(alias, "__") {
+				return nil, printErr(selection.Alias.Loc, "ReservedAlias", "Alias %q of field %q must not begin with \"__\", which is reserved by GraphQL introspection.", alias, selection.Name.Name)
+			}
 
-			if alias == "__typename" {
+			// meta-fields are recognized by their name, the response is keyed by the alias
+			if selection.Name.Name == "__typename" {
 				selections = append(selections, &internal.Selection{
-					Name:  alias,
+					Name:  selection.Name.Name,
 					Alias: alias,
 					Loc:   selection.Loc,
 				})
@@ -217,6 +558,14 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 			}
 
 			f := fields(t)[selection.Name.Name]
+			var metaField *internal.Field
+			if f == nil && schema != nil && t == schema.Query {
+				metaField = schema.MetaFields[selection.Name.Name]
+				f = metaField
+			}
+			if f == nil && (selection.Name.Name == "__schema" || selection.Name.Name == "__type") {
+				return nil, printErr(selection.Alias.Loc, "FieldsOnCorrectType", "Cannot query field %q on type %q: introspection is not enabled for this schema, call introspection.AddIntrospectionToSchema on it, or serve it with the graphql.WithAutoIntrospection(true) handler option.", selection.Name.Name, t)
+			}
 			if f == nil {
 				var names []string
 				for name := range fields(t) {
@@ -230,8 +579,17 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 			if err != nil {
 				return nil, err
 			}
+			if err := checkArguments(f, selection, args, vars); err != nil {
+				return nil, err
+			}
+			if err := validateArgumentTypes(f.Args, selection.Arguments); err != nil {
+				return nil, err
+			}
+			if err := parseLiterals(f.Args, selection.Arguments, args, drop); err != nil {
+				return nil, err
+			}
 
-			directives, err := parseDirectives(schema, "FIELD", selection.Directives, vars)
+			directives, err := parseDirectives(schema, "FIELD", selection.Directives, vars, drop)
 			if err != nil {
 				return nil, err
 			}
@@ -250,7 +608,7 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 			}
 			var selectionSet *internal.SelectionSet
 			if namedType != nil && selection.SelectionSet != nil {
-				selectionSet, err = parseSelectionSet(schema, namedType, selection.SelectionSet, globalFragments, vars)
+				selectionSet, err = parseSelectionSet(schema, namedType, selection.SelectionSet, globalFragments, vars, drop)
 				if err != nil {
 					return nil, err
 				}
@@ -263,6 +621,7 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 				SelectionSet: selectionSet,
 				Directives:   directives,
 				Loc:          selection.Loc,
+				MetaField:    metaField,
 			})
 
 		case *ast.FragmentSpread:
@@ -273,7 +632,7 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 				return nil, errors.New("unknown fragment")
 			}
 
-			directives, err := parseDirectives(schema, "FRAGMENT_SPREAD", selection.Directives, vars)
+			directives, err := parseDirectives(schema, "FRAGMENT_SPREAD", selection.Directives, vars, drop)
 			if err != nil {
 				return nil, err
 			}
@@ -288,16 +647,26 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 
 		case *ast.InlineFragment:
 			var on string
+			var condition internal.NamedType
+			fragmentType := t
 			if selection.TypeCondition != nil {
 				on = selection.TypeCondition.Name.Name
+				if err := knownTypeName(schema, selection.TypeCondition); err != nil {
+					return nil, err
+				}
+				fragmentType = schema.TypeMap[on]
+				condition = fragmentType
+				if !canBeFragment(fragmentType) {
+					return nil, printErr(selection.TypeCondition.Loc, "FragmentsOnCompositeTypes", "Fragment cannot condition on non composite type %q.", on)
+				}
 			}
 
-			directives, err := parseDirectives(schema, "INLINE_FRAGMENT", selection.Directives, vars)
+			directives, err := parseDirectives(schema, "INLINE_FRAGMENT", selection.Directives, vars, drop)
 			if err != nil {
 				return nil, err
 			}
 
-			selectionSet, err := parseSelectionSet(schema, t, selection.SelectionSet, globalFragments, vars)
+			selectionSet, err := parseSelectionSet(schema, fragmentType, selection.SelectionSet, globalFragments, vars, drop)
 			if err != nil {
 				return nil, err
 			}
@@ -305,6 +674,7 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 			fragments = append(fragments, &internal.FragmentSpread{
 				Fragment: &internal.FragmentDefinition{
 					On:           on,
+					Type:         condition,
 					SelectionSet: selectionSet,
 					Loc:          selection.Loc,
 				},
@@ -321,14 +691,48 @@ func parseSelectionSet(schema *internal.Schema, t internal.NamedType, input *ast
 	return selectionSet, nil
 }
 
-// argsToJson converts a graphql-go ast argument list to a json.Marshal-style map[string]interface{}
+// knownTypeName checks that the named type at the bottom of node is defined by schema,
+// suggesting the closest type names when it is not.
+func knownTypeName(schema *internal.Schema, node ast.Type) error {
+	for {
+		wrapping, ok := node.(ast.WrappingType)
+		if !ok {
+			break
+		}
+		node = wrapping.OfType()
+	}
+	named, ok := node.(*ast.Named)
+	if !ok {
+		return nil
+	}
+	if _, ok := schema.TypeMap[named.Name.Name]; ok {
+		return nil
+	}
+	names := make([]string, 0, len(schema.TypeMap))
+	for name := range schema.TypeMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	suggestion := makeSuggestion("Did you mean", names, named.Name.Name)
+	return printErr(named.Loc, "KnownTypeNames", "Unknown type %q.%s", named.Name.Name, suggestion)
+}
+
+// argsToJson converts a graphql-go ast argument list to a json.Marshal-style map[string]interface{}.
+// Arguments given a variable which is not provided are left out, as if they were omitted.
 func argsToJson(input []*ast.Argument, vars map[string]interface{}) (map[string]interface{}, error) {
 	args := make(map[string]interface{})
+	seen := make(map[string]bool, len(input))
 	for _, arg := range input {
 		name := arg.Name.Name
-		if _, found := args[name]; found {
+		if seen[name] {
 			return nil, errors.New("duplicate arg")
 		}
+		seen[name] = true
+		if variable, ok := arg.Value.(*ast.Variable); ok {
+			if _, provided := vars[variable.Name.Name]; !provided {
+				continue
+			}
+		}
 		value, err := internal.ValueToJson(arg.Value, vars)
 		if err != nil {
 			return nil, err
@@ -338,6 +742,438 @@ func argsToJson(input []*ast.Argument, vars map[string]interface{}) (map[string]
 	return args, nil
 }
 
+// checkArguments checks the arguments of field f selected by selection, following CoerceArgumentValues
+// of the specification: an argument of a non-null type must not be null, and must be provided unless
+// it has a default value. Omitted arguments stay absent from args, their default values are filled in
+// when args are decoded for the resolver. Arguments given variables are not checked when vars is nil,
+// as when validating a document without its variables.
+func checkArguments(f *internal.Field, selection *ast.Field, args map[string]interface{}, vars map[string]interface{}) error {
+	provided := make(map[string]bool, len(args))
+	for name := range args {
+		provided[name] = true
+	}
+	for _, arg := range selection.Arguments {
+		if _, ok := arg.Value.(*ast.Variable); ok && vars == nil {
+			provided[arg.Name.Name] = true
+		}
+	}
+	for _, arg := range selection.Arguments {
+		def, ok := f.Args[arg.Name.Name]
+		if !ok {
+			continue
+		}
+		if value, ok := args[arg.Name.Name]; ok && value == nil {
+			if _, nonNull := def.Type.(*internal.NonNull); nonNull {
+				return printErr(arg.Loc, "ArgumentsOfCorrectType", "Argument %q of non-null type %q must not be null.", arg.Name.Name, def.Type.String())
+			}
+		}
+	}
+	names := make([]string, 0, len(f.Args))
+	for name := range f.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def := f.Args[name]
+		if provided[name] || def.DefaultValue != nil {
+			continue
+		}
+		if _, nonNull := def.Type.(*internal.NonNull); nonNull {
+			return printErr(selection.Loc, "ProvidedNonNullArguments", "Field %q argument %q of type %q is required, but it was not provided.", selection.Name.Name, name, def.Type.String())
+		}
+	}
+	return nil
+}
+
+// validateVariableUsages checks that the variables of op are used in positions allowing their types,
+// following the VariablesInAllowedPosition rule of the specification: a nullable variable may only be
+// used in a non-null position when the variable or the position has a default value, and the variable
+// type must otherwise be compatible with the type of the position. The fragments spread by op are
+// checked with the variables of op. Unknown fields and arguments are left to the other checks.
+func validateVariableUsages(schema *internal.Schema, document *internal.Document, op *ast.OperationDefinition, root internal.NamedType) error {
+	u := &variableUsages{
+		schema:    schema,
+		defs:      make(map[string]*ast.VariableDefinition),
+		fragments: make(map[string]*ast.FragmentDefinition),
+		visited:   make(map[string]bool),
+	}
+	for _, fragment := range document.Fragments {
+		u.fragments[fragment.Name.Name] = fragment
+		for _, v := range fragment.VariableDefinitions {
+			u.defs[v.Var.Name.Name] = v
+		}
+	}
+	for _, v := range op.Vars {
+		u.defs[v.Var.Name.Name] = v
+	}
+	if err := u.directives(op.Directives); err != nil {
+		return err
+	}
+	return u.selectionSet(root, op.SelectionSet)
+}
+
+// variableUsages walks the selections of an operation for validateVariableUsages.
+type variableUsages struct {
+	schema    *internal.Schema
+	defs      map[string]*ast.VariableDefinition
+	fragments map[string]*ast.FragmentDefinition
+	visited   map[string]bool
+}
+
+func (u *variableUsages) selectionSet(t internal.NamedType, input *ast.SelectionSet) error {
+	if input == nil {
+		return nil
+	}
+	for _, selection := range input.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if err := u.directives(selection.Directives); err != nil {
+				return err
+			}
+			f := fields(t)[selection.Name.Name]
+			if f == nil && u.schema != nil && t == u.schema.Query {
+				f = u.schema.MetaFields[selection.Name.Name]
+			}
+			if f == nil {
+				continue
+			}
+			if err := u.arguments(f.Args, selection.Arguments); err != nil {
+				return err
+			}
+			if namedType, _ := unwrapType(f.Type); namedType != nil {
+				if err := u.selectionSet(namedType, selection.SelectionSet); err != nil {
+					return err
+				}
+			}
+		case *ast.FragmentSpread:
+			if err := u.directives(selection.Directives); err != nil {
+				return err
+			}
+			fragment, ok := u.fragments[selection.Name.Name]
+			if !ok || u.visited[fragment.Name.Name] {
+				continue
+			}
+			u.visited[fragment.Name.Name] = true
+			if err := u.directives(fragment.Directives); err != nil {
+				return err
+			}
+			if on, ok := u.schema.TypeMap[fragment.TypeCondition.Name.Name]; ok {
+				if err := u.selectionSet(on, fragment.SelectionSet); err != nil {
+					return err
+				}
+			}
+		case *ast.InlineFragment:
+			if err := u.directives(selection.Directives); err != nil {
+				return err
+			}
+			on := t
+			if selection.TypeCondition != nil {
+				var ok bool
+				if on, ok = u.schema.TypeMap[selection.TypeCondition.Name.Name]; !ok {
+					continue
+				}
+			}
+			if err := u.selectionSet(on, selection.SelectionSet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *variableUsages) directives(directives []*ast.Directive) error {
+	for _, directive := range directives {
+		if dir, ok := u.schema.Directives[directive.Name.Name]; ok {
+			if err := u.arguments(dir.Args, directive.Args); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *variableUsages) arguments(defs map[string]*internal.InputField, input []*ast.Argument) error {
+	for _, arg := range input {
+		if def, ok := defs[arg.Name.Name]; ok {
+			if err := u.value(def.Type, def.DefaultValue != nil, arg.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value checks the variables used in literal, a value of a position of type typ, which has a default
+// value when hasDefault is true.
+func (u *variableUsages) value(typ internal.Type, hasDefault bool, literal ast.Value) error {
+	switch literal := literal.(type) {
+	case *ast.Variable:
+		v, ok := u.defs[literal.Name.Name]
+		if !ok {
+			return nil
+		}
+		vTyp, err := utils.TypeFromAst(u.schema, v.Type)
+		if err != nil || vTyp == nil {
+			return nil
+		}
+		locationType := typ
+		if nonNull, ok := typ.(*internal.NonNull); ok {
+			if _, ok := vTyp.(*internal.NonNull); !ok {
+				_, nullDefault := v.DefaultValue.(*ast.NullValue)
+				if (v.DefaultValue != nil && !nullDefault) || hasDefault {
+					locationType = nonNull.Type
+				}
+			}
+		}
+		if !typesCompatible(vTyp, locationType) {
+			return &errors.GraphQLError{
+				Message:   fmt.Sprintf("Variable \"$%s\" of type \"%s\" used in position expecting type \"%s\".", literal.Name.Name, vTyp.String(), typ.String()),
+				Locations: []errors.Location{v.Loc, literal.Loc},
+				Rule:      "VariablesInAllowedPosition",
+			}
+		}
+	case *ast.ListValue:
+		item := typ
+		if nonNull, ok := item.(*internal.NonNull); ok {
+			item = nonNull.Type
+		}
+		if list, ok := item.(*internal.List); ok {
+			item = list.Type
+		}
+		for _, value := range literal.Values {
+			if err := u.value(item, false, value); err != nil {
+				return err
+			}
+		}
+	case *ast.ObjectValue:
+		named, _ := unwrapType(typ)
+		object, ok := named.(*internal.InputObject)
+		if !ok {
+			return nil
+		}
+		for _, field := range literal.Fields {
+			if f, ok := object.Fields[field.Name.Name.Name]; ok {
+				if err := u.value(f.Type, f.DefaultValue != nil, field.Value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// typesCompatible tells whether a variable of type varType can be used in a position of type
+// locationType: the non-null and list wrappers of the position must wrap the variable type as well,
+// the variable type may be non-null in a nullable position.
+func typesCompatible(varType, locationType internal.Type) bool {
+	if nonNull, ok := locationType.(*internal.NonNull); ok {
+		varNonNull, ok := varType.(*internal.NonNull)
+		return ok && typesCompatible(varNonNull.Type, nonNull.Type)
+	}
+	if nonNull, ok := varType.(*internal.NonNull); ok {
+		return typesCompatible(nonNull.Type, locationType)
+	}
+	if list, ok := locationType.(*internal.List); ok {
+		varList, ok := varType.(*internal.List)
+		return ok && typesCompatible(varList.Type, list.Type)
+	}
+	if _, ok := varType.(*internal.List); ok {
+		return false
+	}
+	return varType.String() == locationType.String()
+}
+
+// validateArgumentTypes checks that the literals of input are values of the types of their arguments in
+// defs: enums take one of their values, input objects an object literal, and the built-in scalars the
+// literals they parse. Variables are checked against their own type, and custom scalars by ParseLiteral.
+func validateArgumentTypes(defs map[string]*internal.InputField, input []*ast.Argument) error {
+	for _, arg := range input {
+		def, ok := defs[arg.Name.Name]
+		if !ok {
+			continue
+		}
+		if reason := literalError(def.Type, arg.Value); reason != "" {
+			return printErr(arg.Value.Location(), "ArgumentsOfCorrectType", "Argument %q has invalid value %s.\n%s", arg.Name.Name, ast.PrintValue(arg.Value), reason)
+		}
+	}
+	return nil
+}
+
+// literalError tells why literal is not a value of type typ, or returns "" when it is one.
+func literalError(typ internal.Type, literal ast.Value) string {
+	if _, ok := literal.(*ast.Variable); ok {
+		return ""
+	}
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		if _, ok := literal.(*ast.NullValue); ok {
+			return fmt.Sprintf("Expected %q, found null.", typ.String())
+		}
+		typ = nonNull.Type
+	}
+	if _, ok := literal.(*ast.NullValue); ok {
+		return ""
+	}
+	switch typ := typ.(type) {
+	case *internal.List:
+		list, ok := literal.(*ast.ListValue)
+		if !ok {
+			// a single value is coerced to a list
+			return literalError(typ.Type, literal)
+		}
+		for i, item := range list.Values {
+			if reason := literalError(typ.Type, item); reason != "" {
+				return fmt.Sprintf("In element #%d: %s", i, reason)
+			}
+		}
+	case *internal.InputObject:
+		object, ok := literal.(*ast.ObjectValue)
+		if !ok {
+			return fmt.Sprintf("Expected %q, found not an object.", typ.Name)
+		}
+		for _, field := range object.Fields {
+			// unknown fields are rejected or dropped by parseLiterals
+			if f, ok := typ.Fields[field.Name.Name.Name]; ok {
+				if reason := literalError(f.Type, field.Value); reason != "" {
+					return fmt.Sprintf("In field %q: %s", f.Name, reason)
+				}
+			}
+		}
+	case *internal.Enum:
+		if enum, ok := literal.(*ast.EnumValue); !ok || !enumHasValue(typ, enum.Value) {
+			return fmt.Sprintf("Expected type %q, found %s.", typ.Name, ast.PrintValue(literal))
+		}
+	case *internal.Scalar:
+		valid := true
+		switch typ.Name {
+		case "String":
+			_, valid = literal.(*ast.StringValue)
+		case "Boolean":
+			_, valid = literal.(*ast.BooleanValue)
+		case "Int":
+			_, valid = literal.(*ast.IntValue)
+		case "Float":
+			switch literal.(type) {
+			case *ast.IntValue, *ast.FloatValue:
+			default:
+				valid = false
+			}
+		case "ID":
+			switch literal.(type) {
+			case *ast.IntValue, *ast.StringValue:
+			default:
+				valid = false
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("Expected type %q, found %s.", typ.Name, ast.PrintValue(literal))
+		}
+	}
+	return ""
+}
+
+// parseLiterals replaces the values in args of the scalars written as literals in input by the values
+// their ParseLiteral function parses, so that resolvers receive the same values for literals and variables.
+// Scalars without ParseLiteral keep their JSON value, which is parsed with ParseValue.
+//
+// Fields of input object literals which their type does not define are rejected, like those of variables,
+// unless drop is not nil, they are then dropped.
+func parseLiterals(defs map[string]*internal.InputField, input []*ast.Argument, args map[string]interface{}, drop dropFunc) error {
+	for _, arg := range input {
+		def, ok := defs[arg.Name.Name]
+		if !ok {
+			continue
+		}
+		if value, ok := args[arg.Name.Name]; ok {
+			parsed, err := parseLiteral(def.Type, arg.Value, value, drop)
+			if err, ok := err.(*errors.GraphQLError); ok {
+				return err
+			}
+			if err != nil {
+				return printErr(arg.Loc, "ArgumentsOfCorrectType", "Argument %q has invalid value: %s", arg.Name.Name, err.Error())
+			}
+			args[arg.Name.Name] = parsed
+		}
+	}
+	return nil
+}
+
+// parseLiteral returns value, the JSON value of literal of type typ, with its scalar literals parsed.
+func parseLiteral(typ internal.Type, literal ast.Value, value interface{}, drop dropFunc) (interface{}, error) {
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return parseLiteral(typ.Type, literal, value, drop)
+	case *internal.List:
+		list, ok := literal.(*ast.ListValue)
+		if !ok {
+			// a single value is coerced to a list
+			return parseLiteral(typ.Type, literal, value, drop)
+		}
+		items, ok := value.([]interface{})
+		if !ok || len(items) != len(list.Values) {
+			return value, nil
+		}
+		for i, item := range list.Values {
+			parsed, err := parseLiteral(typ.Type, item, items[i], drop)
+			if _, ok := err.(*errors.GraphQLError); ok {
+				return nil, err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("In element #%d: %s", i, err.Error())
+			}
+			items[i] = parsed
+		}
+		return items, nil
+	case *internal.InputObject:
+		object, ok := literal.(*ast.ObjectValue)
+		fields, isMap := value.(map[string]interface{})
+		if !ok || !isMap {
+			return value, nil
+		}
+		for _, field := range object.Fields {
+			name := field.Name.Name.Name
+			f, ok := typ.Fields[name]
+			if !ok {
+				if drop == nil {
+					return nil, printErr(field.Loc, "ValuesOfCorrectType", "Field %q is not defined by type %q.%s", name, typ.Name,
+						makeSuggestion("Did you mean", fieldNames(typ), name))
+				}
+				drop(typ, name, field.Loc)
+				delete(fields, name)
+				continue
+			}
+			if _, provided := fields[name]; !provided {
+				continue
+			}
+			parsed, err := parseLiteral(f.Type, field.Value, fields[name], drop)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = parsed
+		}
+		return fields, nil
+	case *internal.Scalar:
+		switch literal.(type) {
+		case *ast.Variable, *ast.NullValue:
+			return value, nil
+		}
+		if typ.ParseLiteral == nil {
+			// the value is parsed again for the resolver, it is parsed here for the error to locate it
+			if customScalar(typ) {
+				if _, err := typ.ParseValue(value); err != nil {
+					return nil, err
+				}
+			}
+			return value, nil
+		}
+		parsed, err := typ.ParseLiteral(literal)
+		if err != nil {
+			return nil, err
+		}
+		return internal.LiteralValue{Value: parsed}, nil
+	}
+	return value, nil
+}
+
 type visitState int
 
 const (
@@ -346,7 +1182,7 @@ const (
 	visited
 )
 
-func parseDirectives(schema *internal.Schema, loc string, directives []*ast.Directive, vars map[string]interface{}) ([]*internal.Directive, error) {
+func parseDirectives(schema *internal.Schema, loc string, directives []*ast.Directive, vars map[string]interface{}, drop dropFunc) ([]*internal.Directive, error) {
 	if err := validateDirectives(schema, loc, directives); err != nil {
 		return nil, err
 	}
@@ -356,14 +1192,56 @@ func parseDirectives(schema *internal.Schema, loc string, directives []*ast.Dire
 		if err != nil {
 			return nil, err
 		}
-		dir := schema.Directives[directive.Name.Name]
+		// copy the schema directive, it is shared by every request
+		dir := *schema.Directives[directive.Name.Name]
+		if err := validateArgumentTypes(dir.Args, directive.Args); err != nil {
+			return nil, err
+		}
+		if err := parseLiterals(dir.Args, directive.Args, args, drop); err != nil {
+			return nil, err
+		}
+		for name, arg := range dir.Args {
+			if _, ok := args[name]; !ok && arg.DefaultValue != nil {
+				args[name] = arg.DefaultValue
+			}
+		}
 		dir.ArgVals = args
-		d = append(d, dir)
+		dir.Loc = directive.Loc
+		d = append(d, &dir)
 	}
 	return d, nil
 }
 
+// checkDepth checks that the fields of selectionSet, at depth depth, and their subfields are not deeper
+// than max. Fragments can not contain themselves, so the recursion ends.
+func checkDepth(selectionSet *internal.SelectionSet, max, depth int) *errors.GraphQLError {
+	if selectionSet == nil {
+		return nil
+	}
+	for _, selection := range selectionSet.Selections {
+		if depth > max {
+			return &errors.GraphQLError{
+				Message:   fmt.Sprintf("Field %q has depth %d, more than the maximum depth %d.", selection.Alias, depth, max),
+				Locations: []errors.Location{selection.Loc},
+				Rule:      "MaxDepth",
+			}
+		}
+		if err := checkDepth(selection.SelectionSet, max, depth+1); err != nil {
+			return err
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		if err := checkDepth(fragment.Fragment.SelectionSet, max, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // detectCyclesAndUnusedFragments finds cycles in fragments that include eachother as well as fragments that don't appear anywhere
+//
+// A fragment is used when it is spread anywhere in the operation, even under a field or a spread which
+// skip or include leave out: those directives are only evaluated by flatten, when the operation executes.
 func detectCyclesAndUnusedFragments(selectionSet *internal.SelectionSet, globalFragments map[string]*internal.FragmentDefinition) error {
 	state := make(map[*internal.FragmentDefinition]visitState)
 
@@ -439,9 +1317,15 @@ func detectConflicts(selectionSet *internal.SelectionSet) error {
 		state[selectionSet] = visited
 
 		selections := make(map[string]*internal.Selection)
+		// a fragment spread many times is compared once
+		siblings := make(map[*internal.SelectionSet]bool)
 
 		var visitSibling func(*internal.SelectionSet) error
 		visitSibling = func(selectionSet *internal.SelectionSet) error {
+			if siblings[selectionSet] {
+				return nil
+			}
+			siblings[selectionSet] = true
 			for _, selection := range selectionSet.Selections {
 				if other, found := selections[selection.Alias]; found {
 					if other.Name != selection.Name {
@@ -493,8 +1377,24 @@ func detectConflicts(selectionSet *internal.SelectionSet) error {
 //
 // Flatten does _not_ flatten out the inner queries, so the name above does not
 // get flattened out yet.
+//
+// Flatten fails when the selection set holds more than DefaultMaxObjectFields response keys.
 func Flatten(selectionSet *internal.SelectionSet) ([]*internal.Selection, error) {
+	return flatten(nil, selectionSet, DefaultMaxObjectFields)
+}
+
+// flatten is like Flatten, but only keeps the fragments applying to objects of type typ, unless typ is nil.
+// Fields of fragments on other types, which may reuse the same response keys, are never collected
+// for an object of type typ. It fails as soon as more than max response keys are collected, or
+// DefaultMaxObjectFields when max is not positive.
+func flatten(typ *internal.Object, selectionSet *internal.SelectionSet, max int) ([]*internal.Selection, error) {
+	if max <= 0 {
+		max = DefaultMaxObjectFields
+	}
 	grouped := make(map[string][]*internal.Selection)
+	// the response keys in the order they first appear in, so that fields are resolved, and their
+	// errors reported, in the order of the document
+	var aliases []string
 
 	state := make(map[*internal.SelectionSet]visitState)
 	var visit func(*internal.SelectionSet) error
@@ -504,9 +1404,24 @@ func Flatten(selectionSet *internal.SelectionSet) ([]*internal.Selection, error)
 		}
 
 		for _, selection := range selectionSet.Selections {
+			// skip and include exclude a field before its other directives and its resolver run
+			if ok, err := shouldIncludeNode(selection.Directives); err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+			if _, ok := grouped[selection.Alias]; !ok {
+				if len(aliases) == max {
+					return tooManyFields(typ, max)
+				}
+				aliases = append(aliases, selection.Alias)
+			}
 			grouped[selection.Alias] = append(grouped[selection.Alias], selection)
 		}
 		for _, fragment := range selectionSet.Fragments {
+			if typ != nil && !fragment.Fragment.AppliesTo(typ) {
+				continue
+			}
 			if ok, err := shouldIncludeNode(fragment.Directives); err != nil {
 				return err
 
@@ -528,7 +1443,8 @@ func Flatten(selectionSet *internal.SelectionSet) ([]*internal.Selection, error)
 	}
 
 	var flattened []*internal.Selection
-	for _, selections := range grouped {
+	for _, alias := range aliases {
+		selections := grouped[alias]
 		if len(selections) == 1 || selections[0].SelectionSet == nil {
 			flattened = append(flattened, selections[0])
 			continue
@@ -545,34 +1461,159 @@ func Flatten(selectionSet *internal.SelectionSet) ([]*internal.Selection, error)
 			Alias:        selections[0].Alias,
 			Args:         selections[0].Args,
 			SelectionSet: merged,
+			Directives:   selections[0].Directives,
 			Loc:          selections[0].Loc,
+			MetaField:    selections[0].MetaField,
 		})
 	}
 
 	return flattened, nil
 }
 
-func validateValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Type, names ...string) error {
-	name := v.Var.Name.Name
-	if len(names) > 0 {
-		name = names[0]
+// expandedFields returns the number of fields of selectionSet once its fragments are spread and the
+// fields of the same response key merged, counting the fields of the types of every fragment, or a
+// number more than max as soon as it is known to be. The count of the selection sets merged for a
+// response key is memoized, so that the fragments spread in many fields are only walked once.
+func expandedFields(selectionSet *internal.SelectionSet, max int) int {
+	memo := make(map[string]int)
+	var count func(selectionSets []*internal.SelectionSet) int
+	count = func(selectionSets []*internal.SelectionSet) int {
+		var key strings.Builder
+		for _, selectionSet := range selectionSets {
+			fmt.Fprintf(&key, "%p,", selectionSet)
+		}
+		if n, ok := memo[key.String()]; ok {
+			return n
+		}
+
+		grouped := make(map[string][]*internal.SelectionSet)
+		var aliases []string
+		seen := make(map[*internal.SelectionSet]bool)
+		var collect func(*internal.SelectionSet)
+		collect = func(selectionSet *internal.SelectionSet) {
+			if selectionSet == nil || seen[selectionSet] {
+				return
+			}
+			seen[selectionSet] = true
+			for _, selection := range selectionSet.Selections {
+				children, ok := grouped[selection.Alias]
+				if !ok {
+					aliases = append(aliases, selection.Alias)
+				}
+				if selection.SelectionSet != nil {
+					children = append(children, selection.SelectionSet)
+				}
+				grouped[selection.Alias] = children
+			}
+			for _, fragment := range selectionSet.Fragments {
+				collect(fragment.Fragment.SelectionSet)
+			}
+		}
+		for _, selectionSet := range selectionSets {
+			collect(selectionSet)
+		}
+
+		n := 0
+		for _, alias := range aliases {
+			n++
+			if children := grouped[alias]; len(children) > 0 {
+				n += count(children)
+			}
+			if n > max {
+				break
+			}
+		}
+		memo[key.String()] = n
+		return n
+	}
+	return count([]*internal.SelectionSet{selectionSet})
+}
+
+// tooManyFields is the error of the objects of type typ selected with more than max fields.
+func tooManyFields(typ *internal.Object, max int) error {
+	if typ == nil {
+		return fmt.Errorf("more than %d fields are selected on one object", max)
+	}
+	return fmt.Errorf("more than %d fields are selected on an object of type %s", max, typ.Name)
+}
+
+// valuePath names the value validateValue checks in its errors: the variable, a field of an input
+// object, or an element of a list, whose index is only formatted when the element is invalid.
+type valuePath struct {
+	parent *valuePath
+	field  string
+	index  int
+}
+
+func (p *valuePath) format(v *ast.VariableDefinition) string {
+	switch {
+	case p == nil:
+		return v.Var.Name.Name
+	case p.field != "":
+		return p.field
+	default:
+		return fmt.Sprintf("%s[%d]", p.parent.format(v), p.index)
+	}
+}
+
+// leafList reports whether the elements of the list typ are scalars or enums, which validateValue
+// checks in a single loop, and coerceValue and unknownInputFields need not visit.
+func leafList(typ *internal.List) bool {
+	elem := typ.Type
+	if nonNull, ok := elem.(*internal.NonNull); ok {
+		elem = nonNull.Type
 	}
+	switch elem.(type) {
+	case *internal.Scalar, *internal.Enum:
+		return true
+	}
+	return false
+}
+
+func validateValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Type) error {
+	return validatePathValue(v, val, vtyp, nil)
+}
+
+func validatePathValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Type, path *valuePath) error {
 	switch vtyp := vtyp.(type) {
 	case *internal.NonNull:
 		if val == nil {
-			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid value null.\nExpected type \"%s\", found null.", name, vtyp.String())
+			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid value null.\nExpected type \"%s\", found null.", path.format(v), vtyp.String())
 		}
-		return validateValue(v, val, vtyp.Type)
+		return validatePathValue(v, val, vtyp.Type, path)
 	case *internal.List:
 		if val == nil {
 			return nil
 		}
 		vv, ok := val.([]interface{})
 		if !ok {
-			return validateValue(v, val, vtyp.Type)
+			return validatePathValue(v, val, vtyp.Type, path)
+		}
+		if leafList(vtyp) {
+			// built-in scalars are only checked to be non-null, enums to be one of their values, and
+			// custom scalars to be parsed by their ParseValue
+			elem := vtyp.Type
+			nonNull, required := elem.(*internal.NonNull)
+			if required {
+				elem = nonNull.Type
+			}
+			enum, _ := elem.(*internal.Enum)
+			scalar, _ := elem.(*internal.Scalar)
+			custom := scalar != nil && customScalar(scalar)
+			for index, vi := range vv {
+				if vi == nil && !required {
+					continue
+				}
+				if vi == nil || enum != nil && !enumHasValue(enum, vi) || custom {
+					if err := validatePathValue(v, vi, vtyp.Type, &valuePath{parent: path, index: index}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
 		}
 		for index, vi := range vv {
-			if err := validateValue(v, vi, vtyp.Type, fmt.Sprintf("%s[%d]", v.Var.Name.Name, index)); err != nil {
+			if err := validatePathValue(v, vi, vtyp.Type, &valuePath{parent: path, index: index}); err != nil {
 				return err
 			}
 		}
@@ -582,29 +1623,32 @@ func validateValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Typ
 		}
 		e, ok := val.(string)
 		if !ok {
-			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid type %T.\nExpected type \"%s\", found %v.", name, val, vtyp, val)
+			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid type %T.\nExpected type \"%s\", found %v.", path.format(v), val, vtyp, val)
 		}
-		for _, option := range vtyp.Values {
-			if option == e {
-				return nil
-			}
+		if enumHasValue(vtyp, e) {
+			return nil
 		}
-		return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid value %s.\nExpected type \"%s\", found %s.", name, e, vtyp.String(), e)
+		return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid value %s.\nExpected type \"%s\", found %s.", path.format(v), e, vtyp.String(), e)
 	case *internal.Scalar:
-
+		if val == nil || !customScalar(vtyp) {
+			return nil
+		}
+		if _, err := vtyp.ParseValue(val); err != nil {
+			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid value %v.\n%s", path.format(v), val, err.Error())
+		}
 	case *internal.InputObject:
 		if val == nil {
 			return nil
 		}
 		in, ok := val.(map[string]interface{})
 		if !ok {
-			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid type %T.\nExpected type \"%s\", found %s.", name, val, vtyp, val)
+			return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" has invalid type %T.\nExpected type \"%s\", found %s.", path.format(v), val, vtyp, val)
 		}
 		for argName, arg := range in {
 			if f, ok := vtyp.Fields[argName]; !ok {
-				return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" got invalid value %v; Field %q is not defined by type %q", name, val, argName, vtyp.Name)
+				return printErr(v.Loc, "VariablesOfCorrectType", "Variable \"%s\" got invalid value %v; Field %q is not defined by type %q", path.format(v), val, argName, vtyp.Name)
 			} else {
-				if err := validateValue(v, arg, f.Type, f.Name); err != nil {
+				if err := validatePathValue(v, arg, f.Type, &valuePath{field: f.Name}); err != nil {
 					return err
 				}
 			}
@@ -612,7 +1656,7 @@ func validateValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Typ
 		}
 		for fname, f := range vtyp.Fields {
 			if _, ok := in[fname]; !ok {
-				if err := validateValue(v, nil, f.Type, fname); err != nil {
+				if err := validatePathValue(v, nil, f.Type, &valuePath{field: fname}); err != nil {
 					return err
 				}
 			}
@@ -621,6 +1665,22 @@ func validateValue(v *ast.VariableDefinition, val interface{}, vtyp internal.Typ
 	return nil
 }
 
+// customScalar reports whether the values of typ are parsed by its own ParseValue rather than by one of
+// the scalars of the specification, whose values the resolvers check.
+func customScalar(typ *internal.Scalar) bool {
+	return typ.ParseValue != nil && !builtinScalars[typ.Name]
+}
+
+// enumHasValue reports whether val is the name of one of the values of enum.
+func enumHasValue(enum *internal.Enum, val interface{}) bool {
+	for _, option := range enum.Values {
+		if option == val {
+			return true
+		}
+	}
+	return false
+}
+
 func unwrapType(t internal.Type) (internal.NamedType, error) {
 	if t == nil {
 		return nil, nil
@@ -679,6 +1739,15 @@ func validateDirectives(schema *internal.Schema, loc string, directives []*ast.D
 		if !locOK {
 			return printErr(d.Name.Loc, "KnownDirectives", "Directive %q may not be used on %s.", dirName, loc)
 		}
+
+		for argName, arg := range dd.Args {
+			if _, ok := argNames[argName]; ok || arg.DefaultValue != nil {
+				continue
+			}
+			if _, ok := arg.Type.(*internal.NonNull); ok {
+				return printErr(d.Loc, "ProvidedNonNullArguments", "Directive \"@%s\" argument %q of type %q is required, but it was not provided.", dirName, argName, arg.Type.String())
+			}
+		}
 	}
 	return nil
 }
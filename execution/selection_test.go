@@ -0,0 +1,703 @@
+package execution_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+type Identity int
+
+const (
+	Student Identity = iota
+	Teacher
+)
+
+type PersonFilter struct {
+	Name     *string  `graphql:"name"`
+	Identity Identity `graphql:"identity"`
+	Limit    int      `graphql:"limit"`
+}
+
+func TestValidateVariables(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Identity", Identity(0), map[string]interface{}{
+		"STUDENT": Student,
+		"TEACHER": Teacher,
+	})
+	filter := build.InputObject("PersonFilter", PersonFilter{})
+	filter.FieldDefault("limit", float64(10))
+	build.Query().FieldFunc("count", func(args struct {
+		Filter *PersonFilter `graphql:"filter"`
+		Kind   *Identity     `graphql:"kind"`
+	}) int {
+		return args.Filter.Limit
+	})
+	schema := build.MustBuild()
+
+	const query = `
+      query Count($filter: PersonFilter, $kind: Identity = TEACHER) {
+        count(filter: $filter, kind: $kind)
+      }
+    `
+	doc, err := internal.Parse(query)
+	assert.NoError(t, err)
+	assert.NoError(t, execution.ValidateDocument(schema, doc))
+
+	op, err := execution.GetOperation(doc, "Count")
+	assert.NoError(t, err)
+
+	t.Run("applies defaults and keeps the input untouched", func(t *testing.T) {
+		vars := map[string]interface{}{
+			"filter": map[string]interface{}{"name": "john", "identity": "STUDENT"},
+			"kind":   nil,
+		}
+		coerced, err := execution.ValidateVariables(schema, op, vars)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"filter": map[string]interface{}{"name": "john", "identity": Student, "limit": float64(10)},
+			"kind":   Teacher,
+		}, coerced)
+		assert.Nil(t, vars["kind"])
+		assert.NotContains(t, vars["filter"], "limit")
+		assert.Equal(t, "STUDENT", vars["filter"].(map[string]interface{})["identity"])
+
+		_, selectionSet, err := execution.ApplyCoercedSelectionSet(schema, doc, "Count", coerced)
+		assert.NoError(t, err)
+		result, errs := (&execution.Executor{}).Execute(context.Background(), schema.Query, nil, selectionSet)
+		assert.Equal(t, errors.MultiError(nil), errs)
+		marshal, err := json.Marshal(result)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"count":10}`, string(marshal))
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		_, err := execution.ValidateVariables(schema, op, map[string]interface{}{
+			"filter": map[string]interface{}{"identity": "NOBODY"},
+			"kind":   nil,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("applies the options of the executor", func(t *testing.T) {
+		vars := map[string]interface{}{"filter": map[string]interface{}{"identity": "STUDENT", "extra": true}}
+		_, err := execution.ValidateVariables(schema, op, vars)
+		assert.Error(t, err)
+
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(os.Stderr)
+		executor := execution.NewExecutor(schema, execution.AllowUnknownInputFields(), execution.MaxExpandedFields(1))
+		coerced, err := executor.ValidateVariables(schema, op, vars)
+		assert.NoError(t, err)
+		assert.NotContains(t, coerced["filter"], "extra")
+		_, _, err = executor.ApplyCoercedSelectionSet(schema, doc, "Count", coerced)
+		assert.NoError(t, err)
+
+		wide, err := internal.Parse(`{ a: count b: count }`)
+		assert.NoError(t, err)
+		assert.NoError(t, execution.ValidateDocument(schema, wide))
+		assert.EqualError(t, executor.ValidateDocument(schema, wide),
+			"graphql: The operation expands to more than 1 fields once its fragments are spread. (1:1)")
+		_, _, err = executor.ApplyCoercedSelectionSet(schema, wide, "", nil)
+		assert.EqualError(t, err, "graphql: The operation expands to more than 1 fields once its fragments are spread. (1:1)")
+	})
+}
+
+func TestValidateDocument_VariableDirectives(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Directive("deprecatedVar", []string{"VARIABLE_DEFINITION"},
+		func(ctx context.Context, args struct{}, fn schemabuilder.DirectiveFn) (bool, interface{}, error) {
+			return true, nil, nil
+		})
+	build.Query().FieldFunc("echo", func(args struct{ Text *string }) *string { return args.Text })
+	schema := build.MustBuild()
+
+	validate := func(query string) error {
+		doc, err := internal.Parse(query)
+		assert.NoError(t, err)
+		return execution.ValidateDocument(schema, doc)
+	}
+
+	assert.NoError(t, validate(`query ($text: String @deprecatedVar) { echo(text: $text) }`))
+	assert.EqualError(t, validate(`query ($text: String @unknown) { echo(text: $text) }`),
+		`graphql: Unknown directive "unknown". (1:22)`)
+	assert.EqualError(t, validate(`query ($text: String @skip(if: true)) { echo(text: $text) }`),
+		`graphql: Directive "skip" may not be used on VARIABLE_DEFINITION. (1:22)`)
+}
+
+func TestValidateDocument_KnownTypeNames(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Object("Person", Human{})
+	build.Query().FieldFunc("me", func() *Human { return &Human{Name: "john"} })
+	build.Query().FieldFunc("echo", func(args struct{ Text *string }) *string { return args.Text })
+	schema := build.MustBuild()
+
+	validate := func(query string) error {
+		doc, err := internal.Parse(query)
+		assert.NoError(t, err)
+		return execution.ValidateDocument(schema, doc)
+	}
+
+	assert.NoError(t, validate(`{ me { ... on Person { name } } }`))
+	assert.EqualError(t, validate(`{ me { ... on Persn { name } } }`),
+		`graphql: Unknown type "Persn". Did you mean "Person"? (1:15)`)
+	assert.EqualError(t, validate(`{ me { ... on Persn { nme } } }`),
+		`graphql: Unknown type "Persn". Did you mean "Person"? (1:15)`)
+	assert.EqualError(t, validate(`{ me { ... on String { name } } }`),
+		`graphql: Fragment cannot condition on non composite type "String". (1:15)`)
+	assert.EqualError(t, validate(`{ me { ...PersonFields } } fragment PersonFields on Persn { name }`),
+		`graphql: Unknown type "Persn". Did you mean "Person"? (1:53)`)
+	assert.EqualError(t, validate(`query ($text: [Strin!]) { echo(text: $text) }`),
+		`graphql: Unknown type "Strin". Did you mean "String"? (1:16)`)
+	assert.EqualError(t, validate(`query ($text: Unknown) { echo(text: $text) }`),
+		`graphql: Unknown type "Unknown". (1:15)`)
+}
+
+func TestValidateVariables_Presence(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("greet", func(args struct {
+		Name  *string `graphql:"name"`
+		Title *string `graphql:"title"`
+	}) string {
+		greeting := "hello"
+		if args.Title != nil {
+			greeting += " " + *args.Title
+		}
+		if args.Name != nil {
+			greeting += " " + *args.Name
+		}
+		return greeting
+	})
+	schema := build.MustBuild()
+	const query = `query ($name: String, $title: String!) { greet(name: $name, title: $title) }`
+
+	t.Run("omitted nullable variables are omitted arguments", func(t *testing.T) {
+		result, err := execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"title": "dr"}})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"greet": "hello dr"}, result)
+	})
+
+	t.Run("omitted non-null variables are an error", func(t *testing.T) {
+		_, err := execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"name": "john"}})
+		assert.EqualError(t, err, `[graphql: Variable "$title" of required type "String!" was not provided. (1:23)]`)
+	})
+
+	t.Run("null is a value of nullable variables only", func(t *testing.T) {
+		result, err := execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"name": nil, "title": "dr"}})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"greet": "hello dr"}, result)
+
+		_, err = execution.Do(schema, execution.Params{Query: query, Variables: map[string]interface{}{"name": "john", "title": nil}})
+		assert.Len(t, err, 1)
+	})
+
+	t.Run("undeclared variables are ignored unless a rule reports them", func(t *testing.T) {
+		vars := map[string]interface{}{"title": "dr", "name": "john", "extra": 1, "other": nil}
+		result, err := execution.Do(schema, execution.Params{Query: query, Variables: vars})
+		assert.Equal(t, errors.MultiError(nil), err)
+		assert.Equal(t, map[string]interface{}{"greet": "hello dr john"}, result)
+
+		_, err = execution.Do(schema, execution.Params{Query: query, Variables: vars, Rules: []execution.Rule{execution.NoUndeclaredVariableValues()}})
+		assert.EqualError(t, err, "[graphql: Variable \"$extra\" is provided but not declared by the operation. (1:1)\n"+
+			"graphql: Variable \"$other\" is provided but not declared by the operation. (1:1)]")
+	})
+}
+
+type PositionInput struct {
+	Required string `graphql:"required"`
+}
+
+type PositionArgs struct {
+	Required  int            `graphql:"required"`
+	Defaulted int            `graphql:"defaulted"`
+	Optional  *int           `graphql:"optional"`
+	List      []int          `graphql:"list"`
+	Input     *PositionInput `graphql:"input"`
+}
+
+func TestVariablesInAllowedPosition(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.InputObject("PositionInput", PositionInput{})
+	build.InputObject("PositionArgs", PositionArgs{}).FieldDefault("defaulted", 1)
+	build.Query().FieldFunc("position", func(args PositionArgs) int { return args.Required })
+	schema := build.MustBuild()
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{
+			name:  "nullable variable in a non-null position",
+			query: `query ($a: Int) { position(required: $a) }`,
+			err:   `graphql: Variable "$a" of type "Int" used in position expecting type "Int!". (1:8) (1:38)`,
+		},
+		{
+			name:  "non-null variable in a nullable position",
+			query: `query ($a: Int!) { position(required: $a, optional: $a) }`,
+		},
+		{
+			name:  "nullable variable with a default value",
+			query: `query ($a: Int = 1) { position(required: $a) }`,
+		},
+		{
+			name:  "nullable variable with a null default value",
+			query: `query ($a: Int = null) { position(required: $a) }`,
+			err:   `graphql: Variable "$a" of type "Int" used in position expecting type "Int!". (1:8) (1:45)`,
+		},
+		{
+			name:  "nullable variable in a non-null position with a default value",
+			query: `query ($a: Int, $r: Int!) { position(required: $r, defaulted: $a) }`,
+		},
+		{
+			name:  "variable of another type",
+			query: `query ($a: String!) { position(required: $a) }`,
+			err:   `graphql: Variable "$a" of type "String!" used in position expecting type "Int!". (1:8) (1:42)`,
+		},
+		{
+			name:  "nullable list items",
+			query: `query ($r: Int!, $l: [Int]!) { position(required: $r, list: $l) }`,
+			err:   `graphql: Variable "$l" of type "[Int]!" used in position expecting type "[Int!]". (1:18) (1:61)`,
+		},
+		{
+			name:  "list item",
+			query: `query ($r: Int!, $a: Int) { position(required: $r, list: [$r, $a]) }`,
+			err:   `graphql: Variable "$a" of type "Int" used in position expecting type "Int!". (1:18) (1:63)`,
+		},
+		{
+			name:  "input object field",
+			query: `query ($r: Int!, $a: String) { position(required: $r, input: {required: $a}) }`,
+			err:   `graphql: Variable "$a" of type "String" used in position expecting type "String!". (1:18) (1:73)`,
+		},
+		{
+			name:  "fragment",
+			query: `query ($a: Int) { ...F } fragment F on Query { position(required: $a) }`,
+			err:   `graphql: Variable "$a" of type "Int" used in position expecting type "Int!". (1:8) (1:67)`,
+		},
+		{
+			name:  "directive",
+			query: `query ($r: Int!, $b: Boolean) { position(required: $r) @include(if: $b) }`,
+			err:   `graphql: Variable "$b" of type "Boolean" used in position expecting type "Boolean!". (1:18) (1:69)`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			document, err := internal.Parse(tc.query)
+			assert.NoError(t, err)
+			// the rule is checked with the document, before the values of the variables are known
+			err = execution.ValidateDocument(schema, document)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type CoerceArgs struct {
+	Required  string  `graphql:"required"`
+	Defaulted string  `graphql:"defaulted"`
+	Optional  *string `graphql:"optional"`
+}
+
+func TestCoerceArguments(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.InputObject("CoerceArgs", CoerceArgs{}).FieldDefault("defaulted", "d")
+	build.Query().FieldFunc("coerce", func(args CoerceArgs) string {
+		optional := "<nil>"
+		if args.Optional != nil {
+			optional = *args.Optional
+		}
+		return args.Required + "," + args.Defaulted + "," + optional
+	})
+	schema := build.MustBuild()
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		vars   map[string]interface{}
+		result string
+		args   map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "literals",
+			query:  `{ coerce(required: "r", defaulted: "x", optional: "o") }`,
+			result: "r,x,o",
+			args:   map[string]interface{}{"required": "r", "defaulted": "x", "optional": "o"},
+		},
+		{
+			name:   "omitted literals take their default or stay absent",
+			query:  `{ coerce(required: "r") }`,
+			result: "r,d,<nil>",
+			args:   map[string]interface{}{"required": "r"},
+		},
+		{
+			name:  "omitted non-null literal",
+			query: `{ coerce(defaulted: "x") }`,
+			err:   `graphql: Field "coerce" argument "required" of type "String!" is required, but it was not provided. (1:3)`,
+		},
+		{
+			name:  "null non-null literal",
+			query: `{ coerce(required: null) }`,
+			err:   `graphql: Argument "required" of non-null type "String!" must not be null. (1:10)`,
+		},
+		{
+			name:  "null non-null literal with a default",
+			query: `{ coerce(required: "r", defaulted: null) }`,
+			err:   `graphql: Argument "defaulted" of non-null type "String!" must not be null. (1:25)`,
+		},
+		{
+			name:   "provided variables",
+			query:  `query ($r: String!, $d: String, $o: String) { coerce(required: $r, defaulted: $d, optional: $o) }`,
+			vars:   map[string]interface{}{"r": "r", "d": "x", "o": "o"},
+			result: "r,x,o",
+			args:   map[string]interface{}{"required": "r", "defaulted": "x", "optional": "o"},
+		},
+		{
+			name:   "omitted variables take the default or stay absent",
+			query:  `query ($r: String!, $d: String, $o: String) { coerce(required: $r, defaulted: $d, optional: $o) }`,
+			vars:   map[string]interface{}{"r": "r"},
+			result: "r,d,<nil>",
+			args:   map[string]interface{}{"required": "r"},
+		},
+		{
+			name:   "null nullable variable",
+			query:  `query ($r: String!, $o: String) { coerce(required: $r, optional: $o) }`,
+			vars:   map[string]interface{}{"r": "r", "o": nil},
+			result: "r,d,<nil>",
+			args:   map[string]interface{}{"required": "r", "optional": nil},
+		},
+		{
+			name:  "omitted non-null variable",
+			query: `query ($r: String!) { coerce(required: $r) }`,
+			err:   `graphql: Variable "$r" of required type "String!" was not provided. (1:8)`,
+		},
+		{
+			name:  "null non-null variable",
+			query: `query ($r: String!) { coerce(required: $r) }`,
+			vars:  map[string]interface{}{"r": nil},
+			err:   "graphql: Variable \"r\" has invalid value null.\nExpected type \"String!\", found null. (1:8)",
+		},
+		{
+			name:  "nullable variable in a non-null position",
+			query: `query ($r: String) { coerce(required: $r) }`,
+			vars:  map[string]interface{}{"r": "r"},
+			err:   `graphql: Variable "$r" of type "String" used in position expecting type "String!". (1:8) (1:39)`,
+		},
+		{
+			name:   "nullable variable with a default in a non-null position",
+			query:  `query ($r: String = "v") { coerce(required: $r) }`,
+			result: "v,d,<nil>",
+			args:   map[string]interface{}{"required": "v"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			document, err := internal.Parse(tc.query)
+			assert.NoError(t, err)
+			// literals are checked with the document, variables once their values are known
+			var selectionSet *internal.SelectionSet
+			if err = execution.ValidateDocument(schema, document); err == nil {
+				_, selectionSet, err = execution.ApplySelectionSet(schema, document, "", tc.vars)
+			}
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.args, selectionSet.Selections[0].Args)
+
+			result, errs := execution.Do(schema, execution.Params{Query: tc.query, Variables: tc.vars})
+			assert.Equal(t, errors.MultiError(nil), errs)
+			assert.Equal(t, map[string]interface{}{"coerce": tc.result}, result)
+		})
+	}
+}
+
+type Cents int64
+
+func TestParseLiteral(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	// variables are sent in cents, literals are written in units
+	cents := build.Scalar("Cents", Cents(0), func(value interface{}, dest reflect.Value) error {
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("cents must be a number")
+		}
+		dest.SetInt(int64(n))
+		return nil
+	})
+	cents.LiteralValueFunc(func(value ast.Value) (interface{}, error) {
+		units, err := strconv.ParseInt(value.GetValue().(string), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return Cents(units * 100), nil
+	})
+	build.Query().FieldFunc("price", func(args struct {
+		Amounts []Cents `graphql:"amounts"`
+	}) []Cents {
+		return args.Amounts
+	})
+	schema := build.MustBuild()
+
+	literal, errs := execution.Do(schema, execution.Params{Query: "{ price(amounts: [1, 2]) }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	variable, errs := execution.Do(schema, execution.Params{
+		Query:     "query ($a: [Cents!]!) { price(amounts: $a) }",
+		Variables: map[string]interface{}{"a": []interface{}{float64(100), float64(200)}},
+	})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	assert.Equal(t, map[string]interface{}{"price": []interface{}{"100", "200"}}, literal)
+	assert.Equal(t, literal, variable)
+
+	_, errs = execution.Do(schema, execution.Params{Query: `{ price(amounts: "x") }`})
+	assert.EqualError(t, errs, `[graphql: Argument "amounts" has invalid value: strconv.ParseInt: parsing "x": invalid syntax (1:9)]`)
+}
+
+type UnknownFieldsFilter struct {
+	Name  string                `graphql:"name"`
+	Inner *UnknownFieldsFilter  `graphql:"inner"`
+	Tags  []UnknownFieldsFilter `graphql:"tags"`
+}
+
+func TestUnknownInputFields(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.InputObject("Filter", UnknownFieldsFilter{})
+	build.Query().FieldFunc("users", func(args struct {
+		Filter *UnknownFieldsFilter `graphql:"filter"`
+	}) string {
+		return fmt.Sprint(args.Filter.Name, args.Filter.Inner != nil)
+	})
+	schema := build.MustBuild()
+
+	const variableQuery = `query($f: Filter) { users(filter: $f) }`
+	for _, tc := range []struct {
+		name    string
+		literal string
+		value   map[string]interface{}
+		err     string
+	}{
+		{
+			name:    "top level",
+			literal: `{ users(filter: {name: "a", nme: "b"}) }`,
+			value:   map[string]interface{}{"name": "a", "nme": "b"},
+			err:     `Field "nme" is not defined by type "Filter". Did you mean "name"?`,
+		},
+		{
+			name:    "nested",
+			literal: `{ users(filter: {name: "a", inner: {name: "b", extra: 1}}) }`,
+			value:   map[string]interface{}{"name": "a", "inner": map[string]interface{}{"name": "b", "extra": 1.0}},
+			err:     `Field "extra" is not defined by type "Filter".`,
+		},
+		{
+			name:    "in a list",
+			literal: `{ users(filter: {name: "a", tags: [{name: "t"}, {name: "u", nam: "u"}]}) }`,
+			value:   map[string]interface{}{"name": "a", "tags": []interface{}{map[string]interface{}{"name": "t"}, map[string]interface{}{"name": "u", "nam": "u"}}},
+			err:     `Field "nam" is not defined by type "Filter". Did you mean "name"?`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errs := execution.Do(schema, execution.Params{Query: tc.literal})
+			if assert.Len(t, errs, 1) {
+				assert.Equal(t, tc.err, errs[0].Message)
+				assert.Equal(t, "ValuesOfCorrectType", errs[0].Rule)
+			}
+			_, errs = execution.Do(schema, execution.Params{Query: variableQuery, Variables: map[string]interface{}{"f": tc.value}})
+			if assert.Len(t, errs, 1) {
+				assert.Contains(t, errs[0].Message, `Variable "$f" got invalid value`)
+				assert.Contains(t, errs[0].Message, tc.err)
+			}
+
+			var logged bytes.Buffer
+			log.SetOutput(&logged)
+			defer log.SetOutput(os.Stderr)
+			data, errs := execution.Do(schema, execution.Params{Query: tc.literal}, execution.AllowUnknownInputFields())
+			assert.Empty(t, errs)
+			variableData, errs := execution.Do(schema, execution.Params{Query: variableQuery, Variables: map[string]interface{}{"f": tc.value}},
+				execution.AllowUnknownInputFields())
+			assert.Empty(t, errs)
+			assert.Equal(t, data, variableData)
+			assert.Equal(t, 2, strings.Count(logged.String(), "graphql: dropped"))
+		})
+	}
+
+	_, errs := execution.Do(schema, execution.Params{Query: `{ users(filter: {name: "a",
+      nme: "b"}) }`})
+	if assert.Len(t, errs, 1) {
+		// the location of the field
+		assert.Equal(t, []errors.Location{{Line: 2, Column: 7}}, errs[0].Locations)
+	}
+}
+
+func TestValidateVariables_Lists(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Identity", Identity(0), map[string]interface{}{
+		"STUDENT": Student,
+		"TEACHER": Teacher,
+	})
+	build.Query().FieldFunc("sum", func(args struct {
+		Values     []int64      `graphql:"values"`
+		Identities [][]Identity `graphql:"identities"`
+	}) int64 {
+		var sum int64
+		for _, value := range args.Values {
+			sum += value
+		}
+		return sum
+	})
+	schema := build.MustBuild()
+	doc, err := internal.Parse(`query Sum($values: [Int64!]!, $identities: [[Identity!]!]) { sum(values: $values, identities: $identities) }`)
+	assert.NoError(t, err)
+	op, err := execution.GetOperation(doc, "Sum")
+	assert.NoError(t, err)
+
+	values := []interface{}{float64(1), float64(2), float64(3)}
+	coerced, err := execution.ValidateVariables(schema, op, map[string]interface{}{"values": values})
+	assert.NoError(t, err)
+	assert.Equal(t, values, coerced["values"])
+
+	_, err = execution.ValidateVariables(schema, op, map[string]interface{}{"values": []interface{}{float64(1), nil, float64(3)}})
+	assert.EqualError(t, err, "graphql: Variable \"values[1]\" has invalid value null.\nExpected type \"Int64!\", found null. (1:11)")
+
+	_, err = execution.ValidateVariables(schema, op, map[string]interface{}{
+		"values":     values,
+		"identities": []interface{}{[]interface{}{"STUDENT"}, []interface{}{"TEACHER", "NOBODY"}},
+	})
+	assert.EqualError(t, err, "graphql: Variable \"identities[1][1]\" has invalid value NOBODY.\nExpected type \"Identity\", found NOBODY. (1:31)")
+}
+
+func TestLeafListArguments(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Identity", Identity(0), map[string]interface{}{
+		"STUDENT": Student,
+		"TEACHER": Teacher,
+	})
+	build.Query().FieldFunc("identities", func(args struct {
+		Identities []Identity `graphql:"identities"`
+	}) []Identity {
+		return args.Identities
+	})
+	build.Query().FieldFunc("days", func(args struct {
+		Times []time.Time `graphql:"times"`
+	}) []int {
+		days := make([]int, len(args.Times))
+		for i, t := range args.Times {
+			days[i] = t.Day()
+		}
+		return days
+	})
+	schema := build.MustBuild()
+	do := func(query string, variables map[string]interface{}) (interface{}, errors.MultiError) {
+		return execution.Do(schema, execution.Params{Query: query, Variables: variables})
+	}
+
+	// literals and variables give the resolver the same values, a single value is a list of it
+	for _, variables := range []map[string]interface{}{
+		nil,
+		{"identities": []interface{}{"STUDENT", "TEACHER"}, "times": []interface{}{"2020-01-02T00:00:00Z", "2020-01-03T00:00:00Z"}},
+	} {
+		query := `{ identities(identities: [STUDENT, TEACHER]) days(times: ["2020-01-02T00:00:00Z", "2020-01-03T00:00:00Z"]) }`
+		if variables != nil {
+			query = `query($identities: [Identity!], $times: [Time!]) { identities(identities: $identities) days(times: $times) }`
+		}
+		data, errs := do(query, variables)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"identities": []interface{}{"STUDENT", "TEACHER"}, "days": []interface{}{2, 3}}, data)
+	}
+	data, errs := do(`{ identities(identities: TEACHER) days(times: "2020-01-02T00:00:00Z") }`, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"identities": []interface{}{"TEACHER"}, "days": []interface{}{2}}, data)
+	data, errs = do(`query($identity: [Identity!], $time: [Time!]) { identities(identities: $identity) days(times: $time) }`,
+		map[string]interface{}{"identity": "TEACHER", "time": "2020-01-02T00:00:00Z"})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"identities": []interface{}{"TEACHER"}, "days": []interface{}{2}}, data)
+
+	// the errors tell the invalid element
+	_, errs = do(`{ days(times: ["2020-01-02T00:00:00Z", "2nd of January"]) }`, nil)
+	assert.EqualError(t, errs, `[graphql: Argument "times" has invalid value: In element #1: parsing time "2nd of January" as "2006-01-02T15:04:05Z07:00": cannot parse "2nd of January" as "2006" (1:8)]`)
+	_, errs = do(`query($times: [Time!]) { days(times: $times) }`, map[string]interface{}{"times": []interface{}{"2020-01-02T00:00:00Z", "2nd of January"}})
+	assert.EqualError(t, errs, "[graphql: Variable \"times[1]\" has invalid value 2nd of January.\n"+
+		`parsing time "2nd of January" as "2006-01-02T15:04:05Z07:00": cannot parse "2nd of January" as "2006" (1:7)]`)
+	_, errs = do(`query($identities: [Identity!]) { identities(identities: $identities) }`, map[string]interface{}{"identities": []interface{}{"STUDENT", "NOBODY"}})
+	assert.EqualError(t, errs, "[graphql: Variable \"identities[1]\" has invalid value NOBODY.\nExpected type \"Identity\", found NOBODY. (1:7)]")
+}
+
+func TestValidateArgumentTypes(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Identity", Identity(0), map[string]interface{}{
+		"STUDENT": Student,
+		"TEACHER": Teacher,
+	})
+	build.InputObject("PersonFilter", PersonFilter{})
+	build.Query().FieldFunc("count", func(args struct {
+		Filter *PersonFilter `graphql:"filter"`
+		Names  []string      `graphql:"names"`
+	}) int {
+		return len(args.Names)
+	})
+	schema := build.MustBuild()
+
+	for query, expected := range map[string]string{
+		`{ count(names: ["a", "b"], filter: {identity: TEACHER, limit: 1}) }`: "",
+		`{ count(names: "a") }`:                            "",
+		`{ count(names: ["a", 1]) }`:                       "[graphql: Argument \"names\" has invalid value [\"a\", 1].\nIn element #1: Expected type \"String\", found 1. (1:16)]",
+		`{ count(filter: ["a"]) }`:                         "[graphql: Argument \"filter\" has invalid value [\"a\"].\nExpected \"PersonFilter\", found not an object. (1:17)]",
+		`{ count(filter: {identity: "TEACHER"}) }`:         "[graphql: Argument \"filter\" has invalid value {identity: \"TEACHER\"}.\nIn field \"identity\": Expected type \"Identity\", found \"TEACHER\". (1:17)]",
+		`{ count(filter: {name: "a", identity: NOBODY}) }`: "[graphql: Argument \"filter\" has invalid value {name: \"a\", identity: NOBODY}.\nIn field \"identity\": Expected type \"Identity\", found NOBODY. (1:17)]",
+	} {
+		_, err := execution.Do(schema, execution.Params{Query: query})
+		if expected == "" {
+			assert.Equal(t, errors.MultiError(nil), err, query)
+		} else {
+			assert.EqualError(t, err, expected, query)
+		}
+	}
+}
+
+func BenchmarkValidateVariables_List(b *testing.B) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("count", func(args struct {
+		Values []int64 `graphql:"values"`
+	}) int {
+		return len(args.Values)
+	})
+	schema := build.MustBuild()
+	doc, err := internal.Parse(`query Count($values: [Int!]!) { count(values: $values) }`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	op, err := execution.GetOperation(doc, "Count")
+	if err != nil {
+		b.Fatal(err)
+	}
+	values := make([]interface{}, 500000)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	vars := map[string]interface{}{"values": values}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := execution.ValidateVariables(schema, op, vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
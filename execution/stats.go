@@ -0,0 +1,156 @@
+package execution
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// CacheStats count the lookups of a cache since it was created.
+type CacheStats struct {
+	// Hits and Misses are the numbers of lookups which found an entry and which did not.
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Evictions is the number of entries removed to make room for others.
+	Evictions int64 `json:"evictions"`
+	// Size is the number of entries the cache holds.
+	Size int `json:"size"`
+}
+
+// StatsCache is a cache counting its lookups, such as the caches returned by NewPlanCache and
+// graphql.NewQueryCache.
+type StatsCache interface {
+	Stats() CacheStats
+}
+
+// LoaderStats count the loads of dataloaders, see dataloader.Stats.
+type LoaderStats struct {
+	// Loads is the number of keys given to Load, Hits the number of them whose value was cached or
+	// already queued.
+	Loads int `json:"loads"`
+	Hits  int `json:"hits"`
+	// Batches is the number of calls of the BatchFunc, Keys the number of keys they loaded.
+	Batches int `json:"batches"`
+	Keys    int `json:"keys"`
+}
+
+// AverageBatchSize returns the average number of keys loaded by a batch, 0 when there was none.
+func (s LoaderStats) AverageBatchSize() float64 {
+	if s.Batches == 0 {
+		return 0
+	}
+	return float64(s.Keys) / float64(s.Batches)
+}
+
+// HitRatio returns the part of the loads whose value was cached or already queued, 0 when there was none.
+func (s LoaderStats) HitRatio() float64 {
+	if s.Loads == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Loads)
+}
+
+// MarshalJSON encodes the counts with the average batch size and the hit ratio.
+func (s LoaderStats) MarshalJSON() ([]byte, error) {
+	type counts LoaderStats
+	return json.Marshal(struct {
+		counts
+		AverageBatchSize float64 `json:"averageBatchSize"`
+		HitRatio         float64 `json:"hitRatio"`
+	}{counts(s), s.AverageBatchSize(), s.HitRatio()})
+}
+
+func (s LoaderStats) add(other LoaderStats) LoaderStats {
+	s.Loads += other.Loads
+	s.Hits += other.Hits
+	s.Batches += other.Batches
+	s.Keys += other.Keys
+	return s
+}
+
+// Stats are the counts aggregated by a StatsCollector.
+type Stats struct {
+	// Caches are the stats of the caches by their names, such as "plans" for the plan caches of the
+	// executors, summed up when several caches have the same name.
+	Caches map[string]CacheStats `json:"caches"`
+	// Loaders are the stats of the dataloaders of the requests by their names, summed up.
+	Loaders map[string]LoaderStats `json:"loaders"`
+}
+
+// StatsCollector aggregates the stats of the caches and the dataloaders of executors created
+// WithStatsCollector. It is safe for concurrent use.
+type StatsCollector struct {
+	mu      sync.Mutex
+	caches  map[string][]StatsCache
+	loaders map[string]LoaderStats
+}
+
+// NewStatsCollector returns an empty StatsCollector.
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{caches: map[string][]StatsCache{}, loaders: map[string]LoaderStats{}}
+}
+
+// WithStatsCollector makes the executor report the stats of its plan cache, as "plans", to
+// collector, for the handlers of the executor to record the stats of the loaders of their requests.
+func WithStatsCollector(collector *StatsCollector) Option {
+	return func(e *Executor) {
+		e.stats = collector
+	}
+}
+
+// AddCache reports the stats of cache as name, once however many times it is added.
+func (c *StatsCollector) AddCache(name string, cache StatsCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	comparable := reflect.TypeOf(cache).Comparable()
+	for _, added := range c.caches[name] {
+		if comparable && added == cache {
+			return
+		}
+	}
+	c.caches[name] = append(c.caches[name], cache)
+}
+
+// RecordLoaders adds the stats of the loaders of a request to those of the loaders of the same names.
+func (c *StatsCollector) RecordLoaders(stats map[string]LoaderStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, loader := range stats {
+		c.loaders[name] = c.loaders[name].add(loader)
+	}
+}
+
+// Snapshot returns the stats of the caches and the loaders so far.
+func (c *StatsCollector) Snapshot() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := Stats{Caches: make(map[string]CacheStats, len(c.caches)), Loaders: make(map[string]LoaderStats, len(c.loaders))}
+	for name, caches := range c.caches {
+		var sum CacheStats
+		for _, cache := range caches {
+			cacheStats := cache.Stats()
+			sum.Hits += cacheStats.Hits
+			sum.Misses += cacheStats.Misses
+			sum.Evictions += cacheStats.Evictions
+			sum.Size += cacheStats.Size
+		}
+		stats.Caches[name] = sum
+	}
+	for name, loader := range c.loaders {
+		stats.Loaders[name] = loader
+	}
+	return stats
+}
+
+// StatsCollector returns the collector the executor was created with, nil when there is none.
+func (e *Executor) StatsCollector() *StatsCollector {
+	return e.stats
+}
+
+// CacheStats returns the stats of the plan cache of the executor, zero when it does not count them.
+func (e *Executor) CacheStats() CacheStats {
+	if cache, ok := e.plans.(StatsCache); ok {
+		return cache.Stats()
+	}
+	return CacheStats{}
+}
@@ -0,0 +1,48 @@
+package execution_test
+
+import (
+	"encoding/json"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatsCollector(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string { return "world" })
+	schema := build.MustBuild()
+
+	collector := execution.NewStatsCollector()
+	shared := execution.NewPlanCache(10)
+	executor := execution.NewExecutor(schema, execution.WithStatsCollector(collector), execution.WithPlanCache(shared))
+	// the executors sharing a plan cache report it once
+	execution.NewExecutor(schema, execution.WithStatsCollector(collector), execution.WithPlanCache(shared))
+	other := execution.NewExecutor(schema, execution.WithStatsCollector(collector))
+	assert.Equal(t, collector, executor.StatsCollector())
+
+	for i := 0; i < 3; i++ {
+		_, errs := executor.Do(execution.Params{Query: "{ hello }"})
+		assert.Equal(t, errors.MultiError(nil), errs)
+	}
+	_, errs := other.Do(execution.Params{Query: "{ hello }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+	assert.Equal(t, execution.CacheStats{Hits: 2, Misses: 1, Size: 1}, executor.CacheStats())
+
+	collector.RecordLoaders(map[string]execution.LoaderStats{"friends": {Loads: 4, Hits: 1, Batches: 1, Keys: 3}})
+	collector.RecordLoaders(map[string]execution.LoaderStats{"friends": {Loads: 2, Hits: 2}})
+	stats := collector.Snapshot()
+	// the plan caches of the executors are summed up
+	assert.Equal(t, map[string]execution.CacheStats{"plans": {Hits: 2, Misses: 2, Size: 2}}, stats.Caches)
+	assert.Equal(t, map[string]execution.LoaderStats{"friends": {Loads: 6, Hits: 3, Batches: 1, Keys: 3}}, stats.Loaders)
+
+	encoded, err := json.Marshal(stats.Loaders["friends"])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"loads": 6, "hits": 3, "batches": 1, "keys": 3, "averageBatchSize": 3, "hitRatio": 0.5}`, string(encoded))
+
+	// an executor without a collector reports the stats of its plan cache
+	executor = execution.NewExecutor(schema)
+	assert.Nil(t, executor.StatsCollector())
+	assert.Equal(t, execution.CacheStats{}, executor.CacheStats())
+}
@@ -0,0 +1,158 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/internal"
+)
+
+type dispatchKey struct{}
+
+// WithDispatch returns a copy of ctx whose operations call dispatch once the resolvers of every level
+// of fields returned, before the thunks some of them returned are called. A resolver returns a thunk
+// by returning a func resolving its value, such as func() (*User, error), the fields of the objects
+// of the thunks completed at a level make the next one. Loaders which queue the keys requested by
+// the resolvers of a level load them in dispatch with one batch, see the dataloader package.
+//
+// The dispatch functions of ctx are called in the order they were added in.
+func WithDispatch(ctx context.Context, dispatch func(ctx context.Context)) context.Context {
+	dispatchers, _ := ctx.Value(dispatchKey{}).([]func(ctx context.Context))
+	return context.WithValue(ctx, dispatchKey{}, append(dispatchers[:len(dispatchers):len(dispatchers)], dispatch))
+}
+
+// nullSlot is a position of the result: the field alias of the object fields, or the item index of
+// the list items, which is nullable unless its type is non-null.
+type nullSlot struct {
+	fields   map[string]interface{}
+	alias    string
+	items    []interface{}
+	index    int
+	nullable bool
+}
+
+// fieldSlot returns the slot of the field of selection in fields, the result of an object of type typ.
+func fieldSlot(fields map[string]interface{}, typ *internal.Object, selection *internal.Selection) nullSlot {
+	slot := nullSlot{fields: fields, alias: selection.Alias, nullable: true}
+	if field := typ.Fields[selection.Name]; field != nil {
+		_, nonNull := field.Type.(*internal.NonNull)
+		slot.nullable = !nonNull
+	}
+	return slot
+}
+
+// itemSlot returns the slot of the item index of items, the result of a list of type typ.
+func itemSlot(items []interface{}, index int, typ *internal.List) nullSlot {
+	_, nonNull := typ.Type.(*internal.NonNull)
+	return nullSlot{items: items, index: index, nullable: !nonNull}
+}
+
+func (e *exeContext) enterSlot(slot nullSlot) {
+	e.slots = append(e.slots, slot)
+}
+
+func (e *exeContext) leaveSlot() {
+	e.slots = e.slots[:len(e.slots)-1]
+}
+
+// propagateNull makes the closest nullable slot of e null, or the data of the operation when there is
+// none. The null of a non-null field resolved synchronously propagates with errNull instead, the
+// objects holding a field whose resolver returned a thunk are in the result once it is completed.
+func (e *exeContext) propagateNull() {
+	for i := len(e.slots) - 1; i >= 0; i-- {
+		slot := e.slots[i]
+		if !slot.nullable {
+			continue
+		}
+		if slot.items != nil {
+			slot.items[slot.index] = nil
+		} else {
+			slot.fields[slot.alias] = nil
+		}
+		return
+	}
+	e.state.nullData = true
+}
+
+// pendingField is a field whose resolver returned a thunk, completed by completeThunks.
+type pendingField struct {
+	// ctx is a branch of the context of the field, with its path
+	ctx       *exeContext
+	typ       *internal.Object
+	field     *internal.Field
+	selection *internal.Selection
+	thunk     internal.Thunk
+	// fields is the result of the object of the field, which its value is stored in once completed
+	fields map[string]interface{}
+	value  interface{}
+}
+
+// deferField queues the field of selection, whose resolver returned thunk, until the fields of its
+// level are resolved.
+func (e *exeContext) deferField(typ *internal.Object, field *internal.Field, selection *internal.Selection,
+	thunk internal.Thunk) *pendingField {
+	pending := &pendingField{ctx: e.branch(), typ: typ, field: field, selection: selection, thunk: thunk}
+	e.state.mu.Lock()
+	e.state.pending = append(e.state.pending, pending)
+	e.state.mu.Unlock()
+	return pending
+}
+
+// setField stores value as the field alias of fields, or the value of the field once completed when
+// it is pending.
+func setField(fields map[string]interface{}, alias string, value interface{}) {
+	if pending, ok := value.(*pendingField); ok {
+		pending.fields = fields
+		value = nil
+	}
+	fields[alias] = value
+}
+
+// completeThunks completes the pending fields level after level: the dispatch functions of ctx are
+// called, then the thunks of the fields of the level, whose completion queues those of the next one.
+// The errors and warnings of the fields are added to ctx in the order the fields were queued in.
+func (e *Executor) completeThunks(ctx *exeContext) {
+	dispatchers, _ := ctx.Value(dispatchKey{}).([]func(ctx context.Context))
+	for {
+		ctx.state.mu.Lock()
+		level := ctx.state.pending
+		ctx.state.pending = nil
+		ctx.state.mu.Unlock()
+		if len(level) == 0 || ctx.stopped() {
+			return
+		}
+
+		for _, dispatch := range dispatchers {
+			dispatch(ctx.Context)
+		}
+		for _, pending := range level {
+			if ctx.stopped() {
+				return
+			}
+			if err := ctx.done(); err != nil {
+				pending.ctx.addErr(pending.selection.Loc, err)
+				ctx.errs = append(ctx.errs, pending.ctx.errs...)
+				return
+			}
+			value, err := safeExecuteResolver(pending.ctx, func(context.Context, interface{}, interface{}) (interface{}, error) {
+				return pending.thunk()
+			}, nil, nil)
+			if err == nil {
+				value, err = e.completeField(pending.ctx, pending.typ, pending.field, pending.selection, value)
+			}
+			if err != nil && err != errNull {
+				pending.ctx.addErr(pending.selection.Loc, err)
+			}
+			if err != nil {
+				value = nil
+			}
+			pending.value = value
+			if pending.fields != nil {
+				pending.fields[pending.selection.Alias] = value
+			}
+			if _, nonNull := pending.field.Type.(*internal.NonNull); nonNull && err != nil {
+				pending.ctx.propagateNull()
+			}
+			ctx.errs = append(ctx.errs, pending.ctx.errs...)
+			ctx.warnings = append(ctx.warnings, pending.ctx.warnings...)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package execution_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExecutor_Thunks(t *testing.T) {
+	type Item struct {
+		ID int `graphql:"id"`
+	}
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	build := schemabuilder.NewSchema()
+	item := build.Object("Item", Item{})
+	item.FieldFunc("label", func(i Item) func() (string, error) {
+		record(fmt.Sprint("resolve ", i.ID))
+		return func() (string, error) {
+			record(fmt.Sprint("thunk ", i.ID))
+			if i.ID == 2 {
+				return "", fmt.Errorf("no label for %d", i.ID)
+			}
+			return fmt.Sprint("item ", i.ID), nil
+		}
+	})
+	build.Query().FieldFunc("items", func() []Item { return []Item{{ID: 1}, {ID: 2}, {ID: 3}} })
+	build.Query().FieldFunc("nullableItems", func() []*Item { return []*Item{{ID: 1}, {ID: 2}, {ID: 3}} })
+	build.Query().FieldFunc("count", func() int {
+		record("count")
+		return 3
+	})
+	build.Directive("upper", []string{"FIELD"}, func(ctx context.Context, next func() (interface{}, error)) (interface{}, error) {
+		value, err := next()
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(value.(string)), nil
+	})
+	schema := build.MustBuild()
+
+	// the thunks are called once the resolvers of the level returned, after the dispatch functions
+	ctx := execution.WithDispatch(context.Background(), func(ctx context.Context) { record("dispatch") })
+	data, errs := execution.Do(schema, execution.Params{Query: `{ nullableItems { label } count }`, Context: ctx})
+	assert.Equal(t, map[string]interface{}{
+		"nullableItems": []interface{}{
+			map[string]interface{}{"label": "item 1"},
+			nil,
+			map[string]interface{}{"label": "item 3"},
+		},
+		"count": 3,
+	}, data)
+	assert.EqualError(t, errs, "[graphql: no label for 2 (1:19) path: [nullableItems 1 label]]")
+	assert.Equal(t, []string{"resolve 1", "resolve 2", "resolve 3", "count", "dispatch", "thunk 1", "thunk 2", "thunk 3"}, calls)
+
+	// the null of the label of a thunk propagates as the null of a label resolved synchronously
+	calls = nil
+	data, errs = execution.Do(schema, execution.Params{Query: `{ items { label } count }`, Context: ctx})
+	assert.Equal(t, map[string]interface{}{"items": nil, "count": 3}, data)
+	assert.EqualError(t, errs, "[graphql: no label for 2 (1:11) path: [items 1 label]]")
+
+	// directives get the value of the thunk
+	calls = nil
+	data, errs = execution.Do(schema, execution.Params{Query: `{ nullableItems { label @upper } }`})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "ITEM 3", data["nullableItems"].([]interface{})[2].(map[string]interface{})["label"])
+	assert.Equal(t, []string{"resolve 1", "thunk 1", "resolve 2", "thunk 2", "resolve 3", "thunk 3"}, calls)
+
+	// the thunks of the fields resolved concurrently are called once they all returned
+	calls = nil
+	data, errs = execution.Do(schema, execution.Params{Query: `{ nullableItems { id label } count }`, Context: ctx}, execution.Concurrency(4))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "item 1", data["nullableItems"].([]interface{})[0].(map[string]interface{})["label"])
+	assert.Nil(t, data["nullableItems"].([]interface{})[1])
+	assert.Equal(t, []string{"dispatch", "thunk 1", "thunk 2", "thunk 3"}, calls[4:])
+}
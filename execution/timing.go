@@ -0,0 +1,39 @@
+package execution
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FieldTiming is the time the resolver of a field took, see RecordSlowFields.
+type FieldTiming struct {
+	Path     []interface{} `json:"path"`
+	Duration time.Duration `json:"duration"`
+}
+
+type slowFieldsKey struct{}
+
+// slowFields collects the fields of the operations of a context whose resolver took threshold or longer.
+type slowFields struct {
+	threshold time.Duration
+	mu        sync.Mutex
+	fields    *[]FieldTiming
+}
+
+// RecordSlowFields returns a copy of ctx whose operations add to fields the fields whose resolver took
+// threshold or longer, as they return. A resolver returning a thunk is timed until it returns the
+// thunk. The operations executed with other contexts do not time their resolvers.
+func RecordSlowFields(ctx context.Context, threshold time.Duration, fields *[]FieldTiming) context.Context {
+	return context.WithValue(ctx, slowFieldsKey{}, &slowFields{threshold: threshold, fields: fields})
+}
+
+// record adds the field at path when its resolver took threshold or longer.
+func (s *slowFields) record(path []interface{}, took time.Duration) {
+	if took < s.threshold {
+		return
+	}
+	s.mu.Lock()
+	*s.fields = append(*s.fields, FieldTiming{Path: append([]interface{}(nil), path...), Duration: took})
+	s.mu.Unlock()
+}
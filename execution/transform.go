@@ -0,0 +1,58 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+)
+
+// VariableTransform returns the variables the operation op is executed with, from those of the request.
+// The variable definitions of op tell the types of the variables, see ast.NamedType.
+type VariableTransform func(ctx context.Context, op *ast.OperationDefinition, vars map[string]interface{}) (map[string]interface{}, error)
+
+// WithVariableTransform transforms the variables of every operation with transform after the query is
+// parsed, before the variables are validated and coerced to their types, for example to decrypt the
+// variables of a custom Encrypted scalar:
+//
+//   execution.WithVariableTransform(func(ctx context.Context, op *ast.OperationDefinition, vars map[string]interface{}) (map[string]interface{}, error) {
+//     for _, v := range op.Vars {
+//       if ast.NamedType(v.Type).Name.Name == "Encrypted" {
+//         ...
+//       }
+//     }
+//     return vars, nil
+//   })
+//
+// The operation fails when transform returns an error, with the message of the error and the code
+// BAD_USER_INPUT in its extensions.
+func WithVariableTransform(transform VariableTransform) Option {
+	return func(e *Executor) {
+		e.transform = transform
+	}
+}
+
+// TransformVariables returns the variables of the operation named operationName of document transformed
+// by the VariableTransform of the executor, or vars when it has none. Do calls it before ApplySelectionSet.
+func (e *Executor) TransformVariables(ctx context.Context, document *internal.Document, operationName string, vars map[string]interface{}) (map[string]interface{}, error) {
+	if e.transform == nil {
+		return vars, nil
+	}
+	op, err := GetOperation(document, operationName)
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	transformed, err := e.transform(ctx, op, vars)
+	if err != nil {
+		return nil, &errors.GraphQLError{
+			Message:       err.Error(),
+			Locations:     []errors.Location{op.Loc},
+			ResolverError: err,
+			Extensions:    map[string]interface{}{"code": "BAD_USER_INPUT"},
+		}
+	}
+	return transformed, nil
+}
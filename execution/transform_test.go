@@ -0,0 +1,85 @@
+package execution_test
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Encrypted string
+
+var errNotEncrypted = stderrors.New("ssn is not encrypted")
+
+// decrypt is a fake decryptor of the variables of type Encrypted, which reverses "enc:" prefixed strings.
+func decrypt(ctx context.Context, op *ast.OperationDefinition, vars map[string]interface{}) (map[string]interface{}, error) {
+	decrypted := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		decrypted[k] = v
+	}
+	for _, v := range op.Vars {
+		if ast.NamedType(v.Type).Name.Name != "Encrypted" {
+			continue
+		}
+		value, ok := vars[v.Var.Name.Name].(string)
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(value, "enc:") {
+			return nil, errNotEncrypted
+		}
+		runes := []rune(strings.TrimPrefix(value, "enc:"))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		decrypted[v.Var.Name.Name] = string(runes)
+	}
+	return decrypted, nil
+}
+
+func TestExecutor_VariableTransform(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Scalar("Encrypted", Encrypted(""), func(value interface{}, dest reflect.Value) error {
+		dest.SetString(value.(string))
+		return nil
+	})
+	build.Query().FieldFunc("person", func(args struct {
+		SSN  Encrypted `graphql:"ssn"`
+		Name string    `graphql:"name"`
+	}) string {
+		return args.Name + " " + string(args.SSN)
+	})
+	schema := build.MustBuild()
+	query := `query Person($ssn: Encrypted!, $name: String!) { person(ssn: $ssn, name: $name) }`
+
+	data, errs := execution.Do(schema, execution.Params{
+		Query:     query,
+		Variables: map[string]interface{}{"ssn": "enc:987", "name": "enc:bob"},
+	}, execution.WithVariableTransform(decrypt))
+	assert.Empty(t, errs)
+	// only the variable of type Encrypted is decrypted
+	assert.Equal(t, map[string]interface{}{"person": "enc:bob 789"}, data)
+
+	data, errs = execution.Do(schema, execution.Params{
+		Query:     query,
+		Variables: map[string]interface{}{"ssn": "789", "name": "bob"},
+	}, execution.WithVariableTransform(decrypt))
+	assert.Nil(t, data)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "ssn is not encrypted", errs[0].Message)
+		assert.Equal(t, map[string]interface{}{"code": "BAD_USER_INPUT"}, errs[0].Extensions)
+		assert.True(t, stderrors.Is(errs[0], errNotEncrypted))
+	}
+
+	data, errs = execution.Do(schema, execution.Params{
+		Query:     query,
+		Variables: map[string]interface{}{"ssn": "789", "name": "bob"},
+	})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"person": "bob 789"}, data)
+}
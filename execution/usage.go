@@ -0,0 +1,169 @@
+package execution
+
+import (
+	"encoding/json"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/internal/clock"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Coordinates returns the schema coordinates an operation touches, sorted: the fields it selects as
+// Type.field, the arguments it passes as Type.field(argument:), the fields of the input objects it
+// passes as Input.field and the enum values it passes as Enum.VALUE.
+//
+// The coordinates are read from the selection set returned by ApplySelectionSet, so fragments are
+// already resolved and variables already replaced by their values. Meta-fields are left out.
+func Coordinates(root internal.Type, selectionSet *internal.SelectionSet) []string {
+	coordinates := map[string]struct{}{}
+	visited := map[*internal.SelectionSet]map[string]bool{}
+	var visit func(typ internal.NamedType, selectionSet *internal.SelectionSet)
+	visit = func(typ internal.NamedType, selectionSet *internal.SelectionSet) {
+		if selectionSet == nil || typ == nil {
+			return
+		}
+		// a fragment spread many times is visited once for each type it is spread on
+		if visited[selectionSet] == nil {
+			visited[selectionSet] = map[string]bool{}
+		}
+		if visited[selectionSet][typ.String()] {
+			return
+		}
+		visited[selectionSet][typ.String()] = true
+
+		var fields map[string]*internal.Field
+		switch typ := typ.(type) {
+		case *internal.Object:
+			fields = typ.Fields
+		case *internal.Interface:
+			fields = typ.Fields
+		}
+		for _, selection := range selectionSet.Selections {
+			field := fields[selection.Name]
+			if field == nil || strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			coordinate := typ.String() + "." + selection.Name
+			coordinates[coordinate] = struct{}{}
+			args, _ := selection.Args.(map[string]interface{})
+			for name, value := range args {
+				if arg, ok := field.Args[name]; ok {
+					coordinates[coordinate+"("+name+":)"] = struct{}{}
+					inputCoordinates(arg.Type, value, coordinates)
+				}
+			}
+			namedType, _ := unwrapType(field.Type)
+			visit(namedType, selection.SelectionSet)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			on := fragment.Fragment.Type
+			if on == nil {
+				on = typ
+			}
+			visit(on, fragment.Fragment.SelectionSet)
+		}
+	}
+	if namedType, _ := unwrapType(root); namedType != nil {
+		visit(namedType, selectionSet)
+	}
+
+	sorted := make([]string, 0, len(coordinates))
+	for coordinate := range coordinates {
+		sorted = append(sorted, coordinate)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// inputCoordinates adds the coordinates of the input fields and enum values of value, of type typ.
+func inputCoordinates(typ internal.Type, value interface{}, coordinates map[string]struct{}) {
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		inputCoordinates(typ.Type, value, coordinates)
+	case *internal.List:
+		if values, ok := value.([]interface{}); ok {
+			for _, value := range values {
+				inputCoordinates(typ.Type, value, coordinates)
+			}
+		} else {
+			inputCoordinates(typ.Type, value, coordinates)
+		}
+	case *internal.Enum:
+		if name, ok := value.(string); ok {
+			coordinates[typ.Name+"."+name] = struct{}{}
+		}
+	case *internal.InputObject:
+		fields, _ := value.(map[string]interface{})
+		for name, value := range fields {
+			if field, ok := typ.Fields[name]; ok {
+				coordinates[typ.Name+"."+name] = struct{}{}
+				inputCoordinates(field.Type, value, coordinates)
+			}
+		}
+	}
+}
+
+// CoordinateUsage is how often a schema coordinate was used.
+type CoordinateUsage struct {
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// UsageSnapshot maps schema coordinates to their usage, see Coordinates.
+type UsageSnapshot map[string]CoordinateUsage
+
+// WriteJSON writes the snapshot as a JSON object keyed by the coordinates, in order.
+func (s UsageSnapshot) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// UsageCollector aggregates the schema coordinates used by the operations of executors created
+// WithUsageCollector. It is safe for concurrent use.
+type UsageCollector struct {
+	mu    sync.Mutex
+	usage UsageSnapshot
+}
+
+// NewUsageCollector returns an empty UsageCollector.
+func NewUsageCollector() *UsageCollector {
+	return &UsageCollector{usage: UsageSnapshot{}}
+}
+
+// WithUsageCollector makes the executor record the coordinates used by every operation it executes in collector.
+func WithUsageCollector(collector *UsageCollector) Option {
+	return func(e *Executor) {
+		e.usage = collector
+	}
+}
+
+// Record counts a use of each of coordinates.
+func (c *UsageCollector) Record(coordinates []string) {
+	c.record(coordinates, clock.Real.Now())
+}
+
+func (c *UsageCollector) record(coordinates []string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, coordinate := range coordinates {
+		usage := c.usage[coordinate]
+		usage.Count++
+		usage.LastSeen = now
+		c.usage[coordinate] = usage
+	}
+}
+
+// Snapshot returns a copy of the usage recorded so far.
+func (c *UsageCollector) Snapshot() UsageSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := make(UsageSnapshot, len(c.usage))
+	for coordinate, usage := range c.usage {
+		snapshot[coordinate] = usage
+	}
+	return snapshot
+}
@@ -0,0 +1,102 @@
+package execution_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/internal/clock"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type UsageRole int
+
+type UsageUser struct {
+	Name  string    `graphql:"name"`
+	Email string    `graphql:"email"`
+	Role  UsageRole `graphql:"role"`
+}
+
+type UsageFilter struct {
+	Role *UsageRole `graphql:"role"`
+}
+
+func TestCoordinates(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Role", UsageRole(0), map[string]interface{}{"ADMIN": UsageRole(0), "USER": UsageRole(1)})
+	build.InputObject("UserFilter", UsageFilter{})
+	user := build.Object("User", UsageUser{})
+	user.FieldFunc("friends", func(u UsageUser, args struct {
+		Filter *UsageFilter `graphql:"filter"`
+		First  *int         `graphql:"first"`
+	}) []UsageUser {
+		return []UsageUser{{Name: "bob"}}
+	})
+	build.Query().FieldFunc("user", func(args struct {
+		ID int `graphql:"id"`
+	}) UsageUser {
+		return UsageUser{Name: "alice"}
+	})
+	schema := build.MustBuild()
+
+	query := `
+		query($role: Role) {
+			user(id: 1) {
+				...Basic
+				__typename
+				friends(filter: {role: $role}) { ...Basic ... on User { role } }
+			}
+		}
+		fragment Basic on User { name }`
+	document, err := internal.Parse(query)
+	assert.NoError(t, err)
+	_, selectionSet, err := execution.ApplySelectionSet(schema, document, "", map[string]interface{}{"role": "ADMIN"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Query.user",
+		"Query.user(id:)",
+		"Role.ADMIN",
+		"User.friends",
+		"User.friends(filter:)",
+		"User.name",
+		"User.role",
+		"UserFilter.role",
+	}, execution.Coordinates(schema.Query, selectionSet))
+
+	collector := execution.NewUsageCollector()
+	fake := clock.NewFake(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	executor := execution.NewExecutor(schema, execution.WithUsageCollector(collector), execution.WithClock(fake))
+	for i := 0; i < 2; i++ {
+		_, errs := executor.Do(execution.Params{Query: query, Variables: map[string]interface{}{"role": "ADMIN"}})
+		assert.Equal(t, errors.MultiError(nil), errs)
+	}
+	fake.Advance(time.Minute)
+	_, errs := executor.Do(execution.Params{Query: "{ user(id: 2) { email } }"})
+	assert.Equal(t, errors.MultiError(nil), errs)
+
+	snapshot := collector.Snapshot()
+	assert.Len(t, snapshot, 9)
+	assert.Equal(t, int64(3), snapshot["Query.user"].Count)
+	assert.Equal(t, int64(2), snapshot["User.name"].Count)
+	assert.Equal(t, int64(1), snapshot["User.email"].Count)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC), snapshot["Query.user"].LastSeen)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), snapshot["User.name"].LastSeen)
+	assert.Equal(t, []string{"User.friends"}, introspection.UnusedFields(schema, execution.UsageSnapshot{
+		"Query.user": {}, "User.name": {}, "User.email": {}, "User.role": {},
+	}))
+	assert.Empty(t, introspection.UnusedFields(schema, snapshot))
+
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.WriteJSON(&buf))
+	var exported map[string]struct {
+		Count    int64     `json:"count"`
+		LastSeen time.Time `json:"lastSeen"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Equal(t, int64(2), exported["Role.ADMIN"].Count)
+}
@@ -0,0 +1,33 @@
+package execution
+
+import (
+	"context"
+	"github.com/shyptr/graphql/internal"
+)
+
+// Warning is a field which resolved to null instead of failing, such as a best-effort field out of
+// time, reported in the warnings extension of the response rather than in its errors.
+type Warning struct {
+	Path   []interface{} `json:"path"`
+	Reason string        `json:"reason"`
+}
+
+type warningsKey struct{}
+
+// CollectWarnings returns a copy of ctx whose operations add the warnings of their fields to warnings
+// once they are executed, in the order of the fields.
+func CollectWarnings(ctx context.Context, warnings *[]Warning) context.Context {
+	return context.WithValue(ctx, warningsKey{}, warnings)
+}
+
+// addWarning adds the warning of the field at the path of the context.
+func (e *exeContext) addWarning(warning *internal.Warning) {
+	e.warnings = append(e.warnings, Warning{Path: append([]interface{}(nil), e.path...), Reason: warning.Reason})
+}
+
+// reportWarnings adds the warnings of the operation to those collected by its context.
+func (e *exeContext) reportWarnings() {
+	if warnings, ok := e.Value(warningsKey{}).(*[]Warning); ok && len(e.warnings) > 0 {
+		*warnings = append(*warnings, e.warnings...)
+	}
+}
@@ -0,0 +1,49 @@
+package execution_test
+
+import (
+	"context"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBestEffort(t *testing.T) {
+	abandoned := make(chan error, 1)
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("recommendations", func(ctx context.Context) ([]string, error) {
+		<-ctx.Done()
+		abandoned <- ctx.Err()
+		return []string{"too late"}, nil
+	}, schemabuilder.BestEffort(20*time.Millisecond))
+	build.Query().FieldFunc("banners", func() []string { return []string{"sale"} }, schemabuilder.BestEffort(time.Second))
+	build.Query().FieldFunc("title", func() string { return "home" })
+	schema := build.MustBuild()
+
+	// the slow field is null with a warning, the others resolve
+	var warnings []execution.Warning
+	ctx := execution.CollectWarnings(context.Background(), &warnings)
+	data, errs := execution.Do(schema, execution.Params{Query: `{ title banners recommendations }`, Context: ctx})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"title": "home", "banners": []interface{}{"sale"}, "recommendations": nil}, data)
+	assert.Equal(t, []execution.Warning{{Path: []interface{}{"recommendations"}, Reason: "recommendations timed out after 20ms"}}, warnings)
+	// the context of the abandoned resolver is cancelled, its result is discarded
+	assert.Equal(t, context.DeadlineExceeded, <-abandoned)
+
+	// the operation running out of time fails the field
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	warnings = nil
+	_, errs = execution.Do(schema, execution.Params{Query: `{ recommendations }`, Context: execution.CollectWarnings(ctx, &warnings)})
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "context deadline exceeded", errs[0].Message)
+	}
+	assert.Empty(t, warnings)
+	<-abandoned
+
+	build = schemabuilder.NewSchema()
+	build.Query().FieldFunc("title", func() string { return "home" }, schemabuilder.BestEffort(time.Second))
+	_, err := build.Build()
+	assert.EqualError(t, err, "object schemabuilder.Query field title parse error:best-effort field must be nullable, not String!")
+}
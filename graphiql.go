@@ -5,27 +5,24 @@ import (
 	"net/http"
 )
 
+// URL is the endpoint queried by the GraphiQL handlers created without one.
 var URL = "query"
 
+var graphiQLTemplate = template.Must(template.New("GraphiQL").Parse(page))
+
+// GraphiQLHandler serves GraphiQL querying the endpoint url, URL when it is not passed.
 func GraphiQLHandler(url ...string) http.HandlerFunc {
+	endpoint := URL
 	if len(url) > 0 {
-		URL = url[0]
-	}
-	return graphiQLHandler
-}
-
-func graphiQLHandler(w http.ResponseWriter, r *http.Request) {
-	t := template.New("GraphiQL")
-	t, err := t.Parse(page)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		endpoint = url[0]
 	}
-	err = t.ExecuteTemplate(w, "index", struct {
-		URL string
-	}{URL: URL})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := graphiQLTemplate.ExecuteTemplate(w, "index", struct {
+			URL string
+		}{URL: endpoint})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 	}
 }
 
@@ -1,16 +1,23 @@
 package graphql
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/dataloader"
 	"github.com/shyptr/graphql/errors"
 	"github.com/shyptr/graphql/execution"
 	"github.com/shyptr/graphql/internal"
-	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/shyptr/graphql/introspection"
+	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
+// Use adds middlewares to the handlers created by HTTPHandler afterwards, see WithMiddleware to add
+// them to a single handler.
 func Use(mm ...HandlerFunc) {
 	Ctx.HandlersChain = append(Ctx.HandlersChain, mm...)
 }
@@ -18,135 +25,514 @@ func Use(mm ...HandlerFunc) {
 type Handler struct {
 	Schema   *internal.Schema
 	Executor *execution.Executor
-	ctx      *Context
+	// Rules are the optional validation rules run for every request.
+	Rules []execution.Rule
+	// StrictJSON rejects request bodies repeating a key in an object, see WithStrictJSON.
+	StrictJSON bool
+	// Encoder writes the results, EncodeResponse unless set by WithResponseEncoder.
+	Encoder ResponseEncoder
+	// Middlewares run in order around the execution of every request, see WithMiddleware.
+	Middlewares []HandlerFunc
+	// Logger and MaxDepth are those of the Context of every request.
+	Logger   *log.Logger
+	MaxDepth int
+	// ContextHook prepares the Context of every request before the middlewares run, see WithContextHook.
+	ContextHook func(ctx *Context)
+	// AutoIntrospection serves a clone of Schema with the introspection fields, see WithAutoIntrospection.
+	AutoIntrospection bool
+	// ETag answers queries with an ETag header, and conditional requests with 304, see WithETag.
+	ETag bool
+	// Explain answers requests asking for it with the plan of their operation, see WithExplain.
+	Explain bool
+	// UploadMaxMemory and UploadMaxSize limit the multipart requests uploading files, see WithUploadLimits.
+	UploadMaxMemory int64
+	UploadMaxSize   int64
+	// Selector picks the schema of every request instead of Schema, see HTTPHandlerFunc.
+	Selector func(r *http.Request) (*internal.Schema, error)
+	// Versions are the schemas of the API versions the requests choose from, see VersionedHandler.
+	Versions map[string]*internal.Schema
+	// PersistedQueries holds the automatic persisted queries, see WithPersistedQueries.
+	PersistedQueries QueryCache
+	// Allowlist holds the only queries the handler runs, see WithAllowlist.
+	Allowlist Allowlist
+	// SlowQueryThreshold, SlowFieldThreshold and SlowQueryLogger log the slow requests, see WithSlowQueryLog.
+	SlowQueryThreshold time.Duration
+	SlowFieldThreshold time.Duration
+	SlowQueryLogger    SlowQueryLogger
+	// LoaderStats answers the requests with the stats of their dataloaders, see WithLoaderStats.
+	LoaderStats bool
+	// MaxBatch is the number of operations of the batch requests the handler answers, see WithBatching.
+	MaxBatch int
+	// Loaders and SharedLoaders attach dataloaders to the operations, see WithLoaders and WithSharedLoaders.
+	Loaders       func(ctx context.Context) map[string]*dataloader.Loader
+	SharedLoaders bool
+
+	introspectOnce sync.Once
+	introspected   *internal.Schema
+	// executorOptions configure the executors of the schemas picked by Selector.
+	executorOptions []execution.Option
+	variantsMu      sync.Mutex
+	variants        map[*internal.Schema]*variant
+}
+
+// variant is a schema picked by the Selector of a handler, with the executor running its requests.
+type variant struct {
+	schema   *internal.Schema
+	executor *execution.Executor
+	hash     string
+}
+
+// HandlerOption configures the handler returned by HTTPHandler.
+type HandlerOption func(*Handler)
+
+// WithRules runs the optional validation rules for every request.
+//
+// NoDeprecated can also be enabled for a single request with the request extension
+// {"extensions": {"noDeprecated": true}}, so that only CI clients enforce it.
+func WithRules(rules ...execution.Rule) HandlerOption {
+	return func(h *Handler) {
+		h.Rules = append(h.Rules, rules...)
+	}
+}
+
+// WithExecutorOptions executes the requests with an executor configured by opts, such as
+// execution.FailFast or execution.AllowUnknownInputFields.
+func WithExecutorOptions(opts ...execution.Option) HandlerOption {
+	return func(h *Handler) {
+		h.executorOptions = opts
+		if h.Schema != nil {
+			h.Executor = execution.NewExecutor(h.Schema, opts...)
+		}
+	}
+}
+
+// WithMiddleware adds middlewares to the handler, after those added by Use before it was created.
+func WithMiddleware(mm ...HandlerFunc) HandlerOption {
+	return func(h *Handler) {
+		h.Middlewares = append(h.Middlewares, mm...)
+	}
+}
+
+// WithLogger sets the Logger of the Context of every request, the one set by SetLogger by default.
+func WithLogger(logger *log.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.Logger = logger
+	}
+}
+
+// WithMaxDepth sets the MaxDepth of the Context of every request, the one set by MaxDepth by default.
+func WithMaxDepth(n int) HandlerOption {
+	return func(h *Handler) {
+		h.MaxDepth = n
+	}
+}
+
+// WithContextHook calls hook with the Context of every request before the middlewares run, for
+// example to copy what the middlewares of a router stored in the context of the request:
+//
+//   graphql.WithContextHook(func(ctx *graphql.Context) {
+//     ctx.Set(userKey{}, ctx.Request.Context().Value(userKey{}))
+//   })
+func WithContextHook(hook func(ctx *Context)) HandlerOption {
+	return func(h *Handler) {
+		h.ContextHook = hook
+	}
+}
+
+// WithAutoIntrospection makes the handler add the introspection fields to a clone of its schema
+// when it serves the first request, if enabled and the schema does not have them, so that GraphiQL
+// can show the documentation during development without calling introspection.AddIntrospectionToSchema.
+// The schema given to HTTPHandler is not changed.
+func WithAutoIntrospection(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.AutoIntrospection = enabled
+	}
+}
+
+// WithExplain makes the handler answer the requests with the extension {"explain": true} with the
+// plan of their operation, see execution.Explain, in the "plan" extension of the response, without
+// calling any resolver. It is meant for debugging, as the plan tells how the schema is implemented.
+func WithExplain(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.Explain = enabled
+	}
+}
+
+// schema returns the schema the requests are executed on.
+func (h *Handler) schema() *internal.Schema {
+	if !h.AutoIntrospection {
+		return h.Schema
+	}
+	h.introspectOnce.Do(func() {
+		h.introspected = withIntrospection(h.Schema)
+	})
+	return h.introspected
+}
+
+// withIntrospection returns schema, or a clone of it with the introspection fields when it does not have them.
+func withIntrospection(schema *internal.Schema) *internal.Schema {
+	if _, ok := schema.TypeMap["__Schema"]; ok {
+		return schema
+	}
+	clone := schema.Clone()
+	introspection.AddIntrospectionToSchema(clone)
+	return clone
 }
 
-// Resp represents a typical response of a GraphQL server. It may be encoded to JSON directly or
+// variant returns the schema the request of ctx is executed on with its executor and the hash of
+// the schema, which is empty for handlers without Selector or Versions. The executor of a schema
+// picked by Selector or Versions is created on its first request, with the options given to
+// WithExecutorOptions, and kept for the following ones, so that they share its plan cache.
+func (h *Handler) variant(ctx *Context) (*variant, error) {
+	var schema *internal.Schema
+	var err error
+	switch {
+	case h.Versions != nil:
+		ctx.APIVersion, err = requestVersion(ctx.Request, h.Versions)
+		schema = h.Versions[ctx.APIVersion]
+	case h.Selector != nil:
+		schema, err = h.Selector(ctx.Request)
+	default:
+		return &variant{schema: h.schema(), executor: h.Executor}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, errors.New("no schema for the request")
+	}
+	h.variantsMu.Lock()
+	defer h.variantsMu.Unlock()
+	if v, ok := h.variants[schema]; ok {
+		return v, nil
+	}
+	served := schema
+	if h.AutoIntrospection {
+		served = withIntrospection(schema)
+	}
+	v := &variant{
+		schema:   served,
+		executor: execution.NewExecutor(served, h.executorOptions...),
+		hash:     execution.SchemaHash(served),
+	}
+	h.addCacheStats(v.executor)
+	if h.variants == nil {
+		h.variants = map[*internal.Schema]*variant{}
+	}
+	h.variants[schema] = v
+	return v, nil
+}
+
+// Response represents a typical response of a GraphQL server. It may be encoded to JSON directly or
 // it may be further processed to a custom response type, for example to include custom error data.
-// Errors are intentionally serialized first based on the advice in https://github.com/facebook/graphql/commit/7b40390d48680b15cb93e02d46ac5eb249689876#diff-757cea6edf0288677a9eea4cfc801d87R107
-type Response struct {
-	Errors     []*errors.GraphQLError `json:"errors,omitempty"`
-	Data       interface{}            `json:"data,omitempty"`
-	Extensions map[string]interface{} `json:"extensions,omitempty"`
+type Response = execution.Result
+
+// ResponseEncoder writes the result of a request to w, see WithResponseEncoder.
+type ResponseEncoder func(w http.ResponseWriter, result *execution.Result) error
+
+// WithResponseEncoder makes the handler write results with encoder instead of EncodeResponse,
+// for clients expecting another envelope than the one of the GraphQL specification:
+//
+//   graphql.HTTPHandler(schema, graphql.WithResponseEncoder(func(w http.ResponseWriter, result *execution.Result) error {
+//     w.Header().Set("Content-Type", "application/json")
+//     w.WriteHeader(graphql.ResponseStatus(w, result))
+//     return json.NewEncoder(w).Encode(map[string]interface{}{
+//       "result":    result.Data,
+//       "errorList": result.Errors,
+//       "ok":        len(result.Errors) == 0,
+//     })
+//   }))
+//
+// The encoder chooses the status code of the response, ResponseStatus is the one EncodeResponse uses.
+// The errors of result unwrap to the errors their resolvers returned, the encoder can present them
+// with errors.Is and errors.As.
+// Requests failing before they are executed, such as those which are not POST requests, are not
+// written by the encoder.
+func WithResponseEncoder(encoder ResponseEncoder) HandlerOption {
+	return func(h *Handler) {
+		h.Encoder = encoder
+	}
 }
 
-// HTTPHandler implements the handler required for executing the graphql queries and mutations
-func HTTPHandler(schema *internal.Schema) http.Handler {
+// EncodeResponse is the default ResponseEncoder, it writes result as the JSON object described by the GraphQL specification.
+func EncodeResponse(w http.ResponseWriter, result *execution.Result) error {
+	responseJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ResponseStatus(w, result))
+	_, err = w.Write(responseJSON)
+	return err
+}
+
+// ResponseStatus returns the status code recommended for writing result to w: the status a handler
+// function already wrote to the Context, otherwise 200 even when result holds errors, as GraphQL over
+// HTTP asks for application/json responses.
+func ResponseStatus(w http.ResponseWriter, result *execution.Result) int {
+	if resp, ok := w.(*Resp); ok && resp.status != 0 {
+		return resp.status
+	}
+	return http.StatusOK
+}
+
+// HTTPHandler implements the handler required for executing the graphql queries and mutations.
+// The handler only depends on the package level settings, such as Use and SetLogger, as they are
+// when it is created, so handlers of different schemas and options can serve in one process.
+func HTTPHandler(schema *internal.Schema, opts ...HandlerOption) http.Handler {
 	h := &Handler{
-		Schema:   schema,
-		Executor: &execution.Executor{},
+		Schema:      schema,
+		Executor:    &execution.Executor{},
+		Middlewares: append([]HandlerFunc(nil), Ctx.HandlersChain...),
+		Logger:      Ctx.Logger,
+		MaxDepth:    Ctx.MaxDepth,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	h.addCacheStats(h.Executor)
 
 	return h
 }
 
+// HTTPHandlerFunc is HTTPHandler for servers serving several schemas, such as schemas filtered for
+// the tiers of their tenants: selector picks the schema of every request before its operation is
+// parsed, so that the operation is validated against that schema only and the fields it does not
+// have are unknown. selector returns one of a few schemas built up front, each of them gets its own
+// executor. Requests for which selector fails are answered with 400 and its error.
+//
+// The hash of the schema, see execution.SchemaHash, is the SchemaHash of the Context of the requests
+// for the logs of the middlewares.
+func HTTPHandlerFunc(selector func(r *http.Request) (*internal.Schema, error), opts ...HandlerOption) http.Handler {
+	h := HTTPHandler(nil, opts...).(*Handler)
+	h.Selector = selector
+	return h
+}
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := *Ctx
-	ctx.Writer, ctx.Request = &Resp{ResponseWriter: w}, r
-	h.ctx = &ctx
-	h.ctx.keys = make(map[interface{}]interface{})
-	h.ctx.HandlersChain = append(h.ctx.HandlersChain, execute(h))
-	h.ctx.Next()
+	ctx := &Context{
+		Request:               r,
+		Writer:                &Resp{ResponseWriter: w},
+		keys:                  map[interface{}]interface{}{responseHeadersKey{}: &responseHeaders{header: http.Header{}}},
+		MaxDepth:              h.MaxDepth,
+		Logger:                h.Logger,
+		useStringDescriptions: Ctx.useStringDescriptions,
+		index:                 -1,
+	}
+	ctx.HandlersChain = append(append(make([]HandlerFunc, 0, len(h.Middlewares)+1), h.Middlewares...), execute(h))
+	if h.ContextHook != nil {
+		h.ContextHook(ctx)
+	}
+	ctx.Next()
+}
+
+// requestError answers a request which can not be executed with status and a response holding msg.
+func requestError(ctx *Context, status int, msg string) {
+	err := errors.New("%s", msg)
+	ctx.Error = append(ctx.Error, err)
+	ctx.Writer.Header().Set("Content-Type", "application/json")
+	ctx.Writer.WriteHeader(status)
+	json.NewEncoder(ctx.Writer).Encode(&Response{Errors: errors.MultiError{err}})
 }
 
+// execute runs the operation of a POST request, given in its JSON body or in the operations field of
+// a multipart form with files, or of a GET request, given in its query, operationName, variables and
+// extensions URL parameters. GET requests only run queries, other operations are refused with 405.
+// The JSON body of a POST request may be an array of operations, see WithBatching.
 func execute(handler *Handler) HandlerFunc {
 	return func(ctx *Context) {
 		if ctx.Request.Method == http.MethodOptions {
 			return
 		}
-		if ctx.Request.Method != http.MethodPost {
-			ctx.ServerError("must be post", http.StatusBadRequest)
+		if ctx.Request.Method != http.MethodPost && ctx.Request.Method != http.MethodGet {
+			ctx.ServerError("must be post or get", http.StatusBadRequest)
 			return
 		}
+		start := time.Now()
+		variant, err := handler.variant(ctx)
+		if err != nil {
+			requestError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.SchemaHash = variant.hash
 		param := execution.Params{Context: ctx}
+		var batch []execution.Params
 
 		contentType := strings.SplitN(ctx.Request.Header.Get("Content-Type"), ";", 2)[0]
-		if contentType == "multipart/form-data" {
-			if err := ctx.Request.ParseMultipartForm(200); err != nil {
-				ctx.ServerError(err.Error(), http.StatusBadRequest)
+		if ctx.Request.Method == http.MethodGet {
+			if err := decodeQueryParams(ctx.Request.URL.Query(), &param, handler.StrictJSON); err != nil {
+				requestError(ctx, http.StatusBadRequest, err.Error())
 				return
 			}
-			if err := json.Unmarshal([]byte(ctx.Request.Form.Get("operations")), &param); err != nil {
-				ctx.ServerError(err.Error(), http.StatusBadRequest)
-				return
-			}
-			var fileMap = map[string][]string{}
-			if err := json.Unmarshal([]byte(ctx.Request.Form.Get("map")), &fileMap); err != nil {
-				ctx.ServerError(err.Error(), http.StatusBadRequest)
+		} else if contentType == "multipart/form-data" {
+			uploads, err := decodeMultipart(ctx.Request, &param, handler.StrictJSON, handler.UploadMaxMemory, handler.UploadMaxSize)
+			if err != nil {
+				requestError(ctx, err.(*uploadError).status, err.Error())
 				return
 			}
-			if param.Variables == nil {
-				param.Variables = make(map[string]interface{})
-			}
-			for key, path := range fileMap {
-				file, header, err := ctx.Request.FormFile(key)
-				if err != nil {
-					ctx.ServerError(err.Error(), http.StatusBadRequest)
-					return
-				}
-				varPath := strings.Split(path[0], ".")[1:]
-				var index int
-				for ; index < len(varPath); index++ {
-					if index < len(varPath)-1 {
-						param.Variables[varPath[index]] = make(map[string]interface{})
-					} else {
-						param.Variables[varPath[index]] = schemabuilder.Upload{
-							File:     file,
-							Filename: header.Filename,
-							Size:     header.Size,
-						}
-					}
-				}
-			}
+			defer closeUploads(uploads)
 		} else {
-			if err := json.NewDecoder(ctx.Request.Body).Decode(&param); err != nil {
+			if batch, err = decodeBody(ctx.Request.Body, &param, handler.StrictJSON, handler.MaxBatch); err != nil {
 				ctx.ServerError(err.Error(), http.StatusBadRequest)
 				return
 			}
 		}
 
-		ctx.OperationName = param.OperationName
-		var execute interface{}
-		var exeErr errors.MultiError
-		defer func() {
-			res := &Response{
-				Data:   execute,
-				Errors: exeErr,
+		if batch != nil {
+			handler.executeBatch(ctx, variant, batch)
+			return
+		}
+		res, loaders, slowFields := handler.run(ctx, variant, param, nil)
+		var versions []string
+		if headers, ok := ctx.Value(responseHeadersKey{}).(*responseHeaders); ok {
+			headers.writeTo(ctx.Writer)
+			versions = headers.versions
+		}
+		encode := handler.Encoder
+		if encode == nil {
+			encode = EncodeResponse
+		}
+		if handler.ETag && ctx.Method == ast.Query && len(res.Errors) == 0 {
+			if err := encodeWithETag(ctx, encode, res, param, versions); err != nil {
+				ctx.ServerError(err.Error(), http.StatusInternalServerError)
 			}
-			if len(exeErr) > 0 {
-				ctx.Error = append(ctx.Error, exeErr...)
+		} else if err := encode(ctx.Writer, res); err != nil {
+			ctx.ServerError(err.Error(), http.StatusInternalServerError)
+		}
+		handler.logSlowQuery(ctx, variant.schema, param, time.Since(start), loaders, slowFields)
+	}
+}
+
+// run executes the operation of param, and returns its response with the stats of its dataloaders
+// and the timings of its slow fields. The operations of a batch request share the loaders of scope.
+func (h *Handler) run(ctx *Context, variant *variant, param execution.Params, scope *loaderScope) (
+	res *Response, loaders map[string]dataloader.Stats, slowFields []execution.FieldTiming) {
+	ctx.OperationName = param.OperationName
+	var execute interface{}
+	var exeErr errors.MultiError
+	var extensions map[string]interface{}
+	var warnings []execution.Warning
+	var loadersCtx context.Context = ctx
+	var shared map[string]dataloader.Stats
+	defer func() {
+		loaders = h.loaderStats(loadersCtx, variant.executor, shared)
+		if loaders != nil && h.LoaderStats {
+			if extensions == nil {
+				extensions = map[string]interface{}{}
 			}
-			responseJSON, err := json.Marshal(res)
-			if err != nil {
-				ctx.ServerError(err.Error(), http.StatusInternalServerError)
-				return
+			extensions["loaders"] = loaders
+		}
+		if len(warnings) > 0 {
+			if extensions == nil {
+				extensions = map[string]interface{}{}
 			}
-			if ctx.Writer.status == 0 {
-				ctx.Writer.WriteHeader(http.StatusOK)
+			extensions["warnings"] = warnings
+		}
+		if ctx.APIVersion != "" {
+			if extensions == nil {
+				extensions = map[string]interface{}{}
 			}
-			ctx.Writer.Header().Set("Content-Type", "application/json")
-			ctx.Writer.Write(responseJSON)
-		}()
-		doc, parseErr := internal.Parse(param.Query)
-		if parseErr != nil {
-			exeErr = []*errors.GraphQLError{parseErr.(*errors.GraphQLError)}
+			extensions["version"] = ctx.APIVersion
+		}
+		res = &Response{
+			Data:       execute,
+			Errors:     exeErr,
+			Extensions: extensions,
+		}
+		if len(exeErr) > 0 {
+			ctx.Error = append(ctx.Error, exeErr...)
+		}
+	}()
+	if h.PersistedQueries != nil {
+		if err := persistedQuery(h.PersistedQueries, &param); err != nil {
+			exeErr = errors.MultiError{err}
 			return
 		}
-		//exeErr = validation.Validate(handler.Schema, doc, param.Variables, ctx.MaxDepth)
-		//if len(exeErr) > 0 {
-		//	return
-		//}
-
-		operationType, selectionSet, applyErr := execution.ApplySelectionSet(handler.Schema, doc, param.OperationName, param.Variables)
-		if applyErr != nil {
-			exeErr = []*errors.GraphQLError{applyErr.(*errors.GraphQLError)}
+	}
+	if h.Allowlist != nil {
+		if err := allowedQuery(h.Allowlist, param); err != nil {
+			exeErr = errors.MultiError{err}
 			return
 		}
-		ctx.Method = operationType
-		root := handler.Schema.Query
-		if operationType == ast.Mutation {
-			root = handler.Schema.Mutation
+	}
+	// the rewritten query is the one explained, executed and hashed for the ETag
+	query, rewriteErr := variant.executor.RewriteQuery(ctx, param.Query)
+	if rewriteErr != nil {
+		exeErr = errors.MultiError{rewriteErr.(*errors.GraphQLError)}
+		return
+	}
+	param.Query = query
+	if explain, _ := param.Extensions["explain"].(bool); explain && h.Explain {
+		plan, err := execution.Explain(variant.schema, param)
+		switch err := err.(type) {
+		case nil:
+			extensions = map[string]interface{}{"plan": plan}
+		case errors.MultiError:
+			exeErr = err
+		case *errors.GraphQLError:
+			exeErr = errors.MultiError{err}
+		default:
+			exeErr = errors.MultiError{errors.New("%s", err.Error())}
+		}
+		return
+	}
+	doc, parseErr := variant.executor.Document(param.Query)
+	if parseErr != nil {
+		exeErr = []*errors.GraphQLError{parseErr.(*errors.GraphQLError)}
+		return
+	}
+	// GET requests must be safe, so they only run queries
+	if ctx.Request.Method == http.MethodGet {
+		if op, err := execution.GetOperation(doc, param.OperationName); err == nil && op.Operation != ast.Query {
+			ctx.Writer.Header().Set("Allow", http.MethodPost)
+			ctx.Writer.Header().Set("Content-Type", "application/json")
+			ctx.Writer.WriteHeader(http.StatusMethodNotAllowed)
+			exeErr = errors.MultiError{errors.New("Can not execute a %s over GET, use POST.", strings.ToLower(string(op.Operation)))}
+			return
 		}
-		execute, exeErr = handler.Executor.Execute(ctx, root, nil, selectionSet)
 	}
+	//exeErr = validation.Validate(h.Schema, doc, param.Variables, ctx.MaxDepth)
+	//if len(exeErr) > 0 {
+	//	return
+	//}
+
+	schema := variant.schema
+	vars, transformErr := variant.executor.TransformVariables(ctx, doc, param.OperationName, param.Variables)
+	if transformErr != nil {
+		exeErr = []*errors.GraphQLError{transformErr.(*errors.GraphQLError)}
+		return
+	}
+	operationType, selectionSet, applyErr := variant.executor.ApplySelectionSet(schema, doc, param.OperationName, vars)
+	if applyErr != nil {
+		exeErr = []*errors.GraphQLError{applyErr.(*errors.GraphQLError)}
+		return
+	}
+	rules := h.Rules
+	if noDeprecated, _ := param.Extensions["noDeprecated"].(bool); noDeprecated {
+		rules = append(rules[:len(rules):len(rules)], execution.NoDeprecated())
+	}
+	if exeErr = execution.ValidateRules(schema, doc, param.OperationName, vars, rules...); len(exeErr) > 0 {
+		return
+	}
+	ctx.Method = operationType
+	root := schema.Query
+	if operationType == ast.Mutation {
+		root = schema.Mutation
+	}
+	loadersCtx, shared = h.attachLoaders(ctx, scope, operationType)
+	exeCtx := loadersCtx
+	if operationType == ast.Query {
+		exeCtx = execution.WithReadOnly(exeCtx)
+	}
+	exeCtx = execution.CollectWarnings(exeCtx, &warnings)
+	if h.SlowQueryThreshold > 0 {
+		exeCtx = execution.RecordSlowFields(exeCtx, h.SlowFieldThreshold, &slowFields)
+	}
+	execute, exeErr = variant.executor.Execute(exeCtx, root, nil, selectionSet)
+	if execute == nil {
+		// the null of a non-null root field makes the data null, which is in the response unlike
+		// the data of the requests rejected before their execution
+		execute = json.RawMessage("null")
+	}
+	return
 }
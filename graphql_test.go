@@ -0,0 +1,441 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPHandler_Variables(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("echo", func(args struct {
+		N int64 `graphql:"n"`
+	}) int64 {
+		return args.N
+	})
+	schema := build.MustBuild()
+
+	post := func(handler http.Handler, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("keeps the precision of large integers", func(t *testing.T) {
+		// 2^60 + 1 is rounded to 2^60 by a float64
+		res := post(graphql.HTTPHandler(schema), `{"query": "query($n: Int64!) { echo(n: $n) }", "variables": {"n": 1152921504606846977}}`)
+		assert.Equal(t, http.StatusOK, res.Code)
+		// JSONEq compares numbers as float64
+		assert.Equal(t, `{"data":{"echo":1152921504606846977}}`, res.Body.String())
+	})
+
+	t.Run("keeps the last of duplicate keys by default", func(t *testing.T) {
+		res := post(graphql.HTTPHandler(schema), `{"query": "query($n: Int64!) { echo(n: $n) }", "variables": {"n": 1, "n": 2}}`)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"echo": 2}}`, res.Body.String())
+	})
+
+	t.Run("rejects duplicate keys in strict mode", func(t *testing.T) {
+		handler := graphql.HTTPHandler(schema, graphql.WithStrictJSON())
+		res := post(handler, `{"query": "query($n: Int64!) { echo(n: $n) }", "variables": {"n": 1, "n": 2}}`)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Equal(t, "duplicate key \"n\" in \"variables\"\n", res.Body.String())
+
+		res = post(handler, `{"query": "{ echo(n: 1) }", "query": "{ echo(n: 2) }"}`)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Equal(t, "duplicate key \"query\" in the request body\n", res.Body.String())
+
+		res = post(handler, `{"query": "query($n: Int64!) { echo(n: $n) }", "variables": {"n": 3}}`)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"echo": 3}}`, res.Body.String())
+	})
+}
+
+func TestHTTPHandler_ResponseEncoder(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("ok", func() string { return "fine" })
+	build.Query().FieldFunc("fail", func() (*string, error) { return nil, fmt.Errorf("broken") })
+	schema := build.MustBuild()
+
+	var received *execution.Result
+	handler := graphql.HTTPHandler(schema, graphql.WithResponseEncoder(func(w http.ResponseWriter, result *execution.Result) error {
+		received = result
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(graphql.ResponseStatus(w, result))
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"result":    result.Data,
+			"errorList": result.Errors,
+			"ok":        len(result.Errors) == 0,
+		})
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ ok fail }"}`)))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]interface{}{"ok": "fine", "fail": nil}, received.Data)
+	if assert.Len(t, received.Errors, 1) {
+		assert.Equal(t, "broken", received.Errors[0].Message)
+		assert.Equal(t, []interface{}{"fail"}, received.Errors[0].Path)
+	}
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, false, body["ok"])
+	assert.Equal(t, map[string]interface{}{"ok": "fine", "fail": nil}, body["result"])
+	assert.Len(t, body["errorList"], 1)
+
+	// the default encoder writes the envelope of the specification
+	recorder = httptest.NewRecorder()
+	graphql.HTTPHandler(schema).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ ok fail }"}`)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": [{"message": "broken", "locations": [{"line": 1, "column": 6}], "path": ["fail"]}], "data": {"ok": "fine", "fail": null}}`, recorder.Body.String())
+}
+
+type geometry struct {
+	geoJSON string
+}
+
+func TestHTTPHandler_RawJSONScalar(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	scalar := build.Scalar("GeoJSON", geometry{}, func(value interface{}, dest reflect.Value) error {
+		return fmt.Errorf("GeoJSON is an output type")
+	})
+	scalar.Serialize = func(value interface{}) (interface{}, error) {
+		return json.RawMessage(value.(geometry).geoJSON), nil
+	}
+	build.Query().FieldFunc("area", func() geometry {
+		return geometry{geoJSON: `{"type": "Point", "coordinates": [4.35, 50.85]}`}
+	})
+	build.Query().FieldFunc("broken", func() geometry { return geometry{geoJSON: `{"type": "Point"`} })
+	// the default Serialize keeps raw JSON
+	build.Scalar("JSON", json.RawMessage{})
+	build.Query().FieldFunc("style", func() json.RawMessage { return json.RawMessage(`{"zoom": 3}`) })
+	handler := graphql.HTTPHandler(build.MustBuild())
+	post := func(query string) string {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(fmt.Sprintf(`{"query": %q}`, query))))
+		return recorder.Body.String()
+	}
+
+	// the serialized JSON is written as it is instead of as a string
+	body := post("{ area }")
+	assert.Contains(t, body, `"area":{"type":"Point","coordinates":[4.35,50.85]}`)
+	assert.JSONEq(t, `{"data": {"area": {"type": "Point", "coordinates": [4.35, 50.85]}}}`, body)
+
+	assert.JSONEq(t, `{"data": {"style": {"zoom": 3}}}`, post("{ style }"))
+
+	// invalid JSON fails its field, which is non-null, so the data is null
+	assert.JSONEq(t, `{
+		"errors": [{"message": "GeoJSON serialized to invalid JSON", "locations": [{"line": 1, "column": 8}], "path": ["broken"]}],
+		"data": null
+	}`, post("{ area broken }"))
+}
+
+func TestHTTPHandler_Warnings(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("recommendations", func(ctx context.Context) []string {
+		<-ctx.Done()
+		return nil
+	}, schemabuilder.BestEffort(10*time.Millisecond))
+	build.Query().FieldFunc("title", func() string { return "home" })
+	handler := graphql.HTTPHandler(build.MustBuild())
+
+	// the field out of time is null, with a warning instead of an error
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ title recommendations }"}`)))
+	assert.JSONEq(t, `{
+		"data": {"title": "home", "recommendations": null},
+		"extensions": {"warnings": [{"path": ["recommendations"], "reason": "recommendations timed out after 10ms"}]}
+	}`, recorder.Body.String())
+}
+
+func TestSetCookie(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("ok", func() string { return "fine" })
+	build.Mutation().FieldFunc("login", func(ctx context.Context, args struct {
+		Name string `graphql:"name"`
+	}) (string, error) {
+		if err := graphql.SetCookie(ctx, &http.Cookie{Name: "session", Value: args.Name}); err != nil {
+			return "", err
+		}
+		return args.Name, graphql.SetHeader(ctx, "Cache-Control", "no-store")
+	})
+	build.Mutation().FieldFunc("touch", func(ctx context.Context) error {
+		return graphql.SetHeader(ctx, "X-Touched", "yes")
+	})
+	schema := build.MustBuild()
+
+	recorder := httptest.NewRecorder()
+	graphql.HTTPHandler(schema).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query": "mutation { login(name: \"alice\") touch }"}`)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"login": "alice", "touch": true}}`, recorder.Body.String())
+	if cookies := recorder.Result().Cookies(); assert.Len(t, cookies, 1) {
+		assert.Equal(t, "session", cookies[0].Name)
+		assert.Equal(t, "alice", cookies[0].Value)
+	}
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "yes", recorder.Header().Get("X-Touched"))
+
+	// outside of an HTTP request there is no response to set headers on
+	assert.Equal(t, graphql.ErrHeadersUnavailable, graphql.SetHeader(context.Background(), "X-Touched", "no"))
+}
+
+func TestHTTPHandler_Isolation(t *testing.T) {
+	type userKey struct{}
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string { return "hello" })
+	public := build.MustBuild()
+	build = schemabuilder.NewSchema()
+	build.Query().FieldFunc("me", func(ctx context.Context) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return user
+	})
+	admin := build.MustBuild()
+
+	var calls []string
+	record := func(name string) graphql.HandlerFunc {
+		return func(ctx *graphql.Context) {
+			calls = append(calls, name)
+			ctx.Next()
+		}
+	}
+	publicHandler := graphql.HTTPHandler(public, graphql.WithMiddleware(record("public")))
+	adminHandler := graphql.HTTPHandler(admin, graphql.WithMiddleware(record("admin")), graphql.WithContextHook(func(ctx *graphql.Context) {
+		ctx.Set(userKey{}, ctx.Request.Context().Value(userKey{}))
+	}))
+
+	post := func(handler http.Handler, user, query string) string {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(fmt.Sprintf(`{"query": %q}`, query)))
+		request = request.WithContext(context.WithValue(request.Context(), userKey{}, user))
+		handler.ServeHTTP(recorder, request)
+		return recorder.Body.String()
+	}
+	for i := 0; i < 2; i++ {
+		assert.JSONEq(t, `{"data": {"hello": "hello"}}`, post(publicHandler, "", "{ hello }"))
+		assert.JSONEq(t, `{"data": {"me": "ann"}}`, post(adminHandler, "ann", "{ me }"))
+	}
+	assert.Contains(t, post(publicHandler, "", "{ me }"), `Cannot query field \"me\" on type \"Query\"`)
+	assert.Equal(t, []string{"public", "admin", "public", "admin", "public"}, calls)
+}
+
+func TestHTTPHandler_RequestContext(t *testing.T) {
+	type userKey struct{}
+	var cancel context.CancelFunc
+	var resolved []string
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("me", func(ctx context.Context) string {
+		resolved = append(resolved, "me")
+		return ctx.Value(userKey{}).(string)
+	})
+	build.Query().FieldFunc("leave", func() bool {
+		resolved = append(resolved, "leave")
+		cancel()
+		return true
+	})
+	build.Query().FieldFunc("after", func() bool {
+		resolved = append(resolved, "after")
+		return true
+	})
+	handler := graphql.HTTPHandler(build.MustBuild())
+
+	// the resolvers get the values and the cancellation of the context of the request, the field being
+	// resolved fails, and its null propagates to the data
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ me leave after }"}`))
+	ctx, stop := context.WithCancel(context.WithValue(request.Context(), userKey{}, "ann"))
+	defer stop()
+	cancel = stop
+	handler.ServeHTTP(recorder, request.WithContext(ctx))
+	assert.JSONEq(t, `{
+		"data": null,
+		"errors": [{"message": "context canceled", "locations": [{"line": 1, "column": 6}], "path": ["leave"]}]
+	}`, recorder.Body.String())
+	assert.Equal(t, []string{"me", "leave"}, resolved)
+}
+
+func TestAssertWritable(t *testing.T) {
+	var saved int
+	save := func(ctx context.Context) (int, error) {
+		if err := graphql.AssertWritable(ctx); err != nil {
+			return 0, err
+		}
+		saved++
+		return saved, nil
+	}
+	build := schemabuilder.NewSchema()
+	// a query field reaching code which writes
+	build.Query().FieldFunc("visits", save)
+	build.Mutation().FieldFunc("visit", save)
+	handler := graphql.HTTPHandler(build.MustBuild())
+
+	post := func(query string) string {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(fmt.Sprintf(`{"query": %q}`, query)))
+		handler.ServeHTTP(recorder, request)
+		return recorder.Body.String()
+	}
+	assert.JSONEq(t, `{"data": null, "errors": [{"message": "the operation is read-only, it can not write", "locations": [{"line": 1, "column": 3}], "path": ["visits"]}]}`, post("{ visits }"))
+	assert.JSONEq(t, `{"data": {"visit": 1}}`, post("mutation { visit }"))
+	assert.Equal(t, 1, saved)
+}
+
+func TestHTTPHandler_AutoIntrospection(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string { return "hello" })
+	schema := build.MustBuild()
+
+	post := func(handler http.Handler, query string) string {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(fmt.Sprintf(`{"query": %q}`, query)))
+		handler.ServeHTTP(recorder, request)
+		return recorder.Body.String()
+	}
+	auto := graphql.HTTPHandler(schema, graphql.WithAutoIntrospection(true))
+	for i := 0; i < 2; i++ {
+		assert.JSONEq(t, `{"data": {"__schema": {"queryType": {"name": "Query"}}, "hello": "hello"}}`, post(auto, "{ __schema { queryType { name } } hello }"))
+	}
+	assert.JSONEq(t, `{"data": {"__type": {"fields": [{"name": "hello"}]}}}`, post(auto, `{ __type(name: "Query") { fields { name } } }`))
+
+	// the schema given to the handler is not changed
+	assert.NotContains(t, schema.TypeMap, "__Schema")
+	assert.JSONEq(t, `{"errors": [{"message": "Cannot query field \"__schema\" on type \"Query\": introspection is not enabled for this schema, call introspection.AddIntrospectionToSchema on it, or serve it with the graphql.WithAutoIntrospection(true) handler option.", "locations": [{"line": 1, "column": 3}]}]}`, post(graphql.HTTPHandler(schema), "{ __schema { queryType { name } } }"))
+}
+
+func TestHTTPHandler_Explain(t *testing.T) {
+	called := false
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("hello", func() string {
+		called = true
+		return "world"
+	})
+	schema := build.MustBuild()
+	body := `{"query": "{ hello }", "extensions": {"explain": true}}`
+
+	recorder := httptest.NewRecorder()
+	graphql.HTTPHandler(schema, graphql.WithExplain(true)).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"extensions": {"plan": {"type": "Query", "cost": 1, "children": [
+		{"parentType": "Query", "field": "hello", "type": "String!", "resolver": "func", "cost": 1}
+	]}}}`, recorder.Body.String())
+	assert.False(t, called)
+
+	// without the option the extension is ignored
+	recorder = httptest.NewRecorder()
+	graphql.HTTPHandler(schema).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, recorder.Body.String())
+	assert.True(t, called)
+}
+
+func TestHTTPHandler_Get(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("echo", func(args struct {
+		N *int64 `graphql:"n"`
+	}) int64 {
+		if args.N == nil {
+			return -1
+		}
+		return *args.N
+	})
+	mutated := false
+	build.Mutation().FieldFunc("mutate", func() bool {
+		mutated = true
+		return true
+	})
+	handler := graphql.HTTPHandler(build.MustBuild())
+	get := func(params url.Values) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil))
+		return recorder
+	}
+
+	recorder := get(url.Values{"query": {"query Echo($n: Int64) { echo(n: $n) }"}, "variables": {`{"n": 7}`}, "operationName": {"Echo"}})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"echo": 7}}`, recorder.Body.String())
+
+	// empty variables are no variables
+	recorder = get(url.Values{"query": {"query Echo($n: Int64) { echo(n: $n) }"}, "variables": {""}})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"echo": -1}}`, recorder.Body.String())
+
+	recorder = get(url.Values{"query": {"{ echo }"}, "variables": {`{"n": `}})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"errors": [{"message": "variables are invalid JSON: unexpected EOF"}]}`, recorder.Body.String())
+
+	recorder = get(url.Values{"query": {"mutation { mutate }"}})
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, http.MethodPost, recorder.Header().Get("Allow"))
+	assert.JSONEq(t, `{"errors": [{"message": "Can not execute a mutation over GET, use POST."}]}`, recorder.Body.String())
+	assert.False(t, mutated)
+
+	// POST requests run mutations as before
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "mutation { mutate }"}`)))
+	assert.JSONEq(t, `{"data": {"mutate": true}}`, recorder.Body.String())
+	assert.True(t, mutated)
+}
+
+func TestHTTPHandlerFunc(t *testing.T) {
+	tenantSchema := func(enterprise bool) *schemabuilder.Schema {
+		build := schemabuilder.NewSchema()
+		build.Query().FieldFunc("name", func() string { return "acme" })
+		if enterprise {
+			build.Query().FieldFunc("auditLog", func() []string { return []string{"login"} })
+		}
+		return build
+	}
+	basic := tenantSchema(false).MustBuild()
+	enterprise := tenantSchema(true).MustBuild()
+	var hashes []string
+	handler := graphql.HTTPHandlerFunc(func(r *http.Request) (*internal.Schema, error) {
+		switch r.Header.Get("X-Tenant") {
+		case "basic":
+			return basic, nil
+		case "enterprise":
+			return enterprise, nil
+		}
+		return nil, fmt.Errorf("unknown tenant %q", r.Header.Get("X-Tenant"))
+	}, graphql.WithMiddleware(func(ctx *graphql.Context) {
+		ctx.Next()
+		hashes = append(hashes, ctx.SchemaHash)
+	}), graphql.WithExecutorOptions(execution.MaxDepth(5)))
+	post := func(tenant, query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(fmt.Sprintf(`{"query": %q}`, query)))
+		request.Header.Set("X-Tenant", tenant)
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := post("enterprise", "{ name auditLog }")
+	assert.JSONEq(t, `{"data": {"name": "acme", "auditLog": ["login"]}}`, recorder.Body.String())
+
+	// the field does not exist in the schema of the basic tier
+	recorder = post("basic", "{ name auditLog }")
+	assert.JSONEq(t, `{"errors": [{"message": "Cannot query field \"auditLog\" on type \"Query\".", "locations": [{"line": 1, "column": 8}]}]}`, recorder.Body.String())
+	recorder = post("basic", "{ name }")
+	assert.JSONEq(t, `{"data": {"name": "acme"}}`, recorder.Body.String())
+
+	assert.Equal(t, []string{execution.SchemaHash(enterprise), execution.SchemaHash(basic), execution.SchemaHash(basic)}, hashes)
+	assert.NotEqual(t, hashes[0], hashes[1])
+
+	recorder = post("free", "{ name }")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "unknown tenant \"free\""}]}`, recorder.Body.String())
+}
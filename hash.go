@@ -0,0 +1,20 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/internal"
+)
+
+// QueryHash returns the hex encoded sha256 of the normalized query, see ast.Normalize,
+// so that queries differing only by formatting share the same hash.
+// A query which can not be parsed is hashed as it is.
+func QueryHash(query string) string {
+	normalized := query
+	if doc, err := internal.ParseDocument(query); err == nil {
+		normalized = ast.Normalize(doc)
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
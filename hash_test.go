@@ -0,0 +1,37 @@
+package graphql_test
+
+import (
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQueryHash(t *testing.T) {
+	compact := `query Hero($ep: Episode = JEDI) { hero(episode: $ep, first: 1) { name ... on Droid { primaryFunction } } }`
+	formatted := `
+      # fetch the hero
+      query Hero(
+        $ep: Episode = JEDI,
+      ) {
+        hero(first: 1, episode: $ep) {
+          name,
+          ... on Droid {
+            primaryFunction
+          }
+        }
+      }
+    `
+
+	doc, err := internal.ParseDocument(formatted)
+	assert.Nil(t, err)
+	assert.Equal(t, compact, ast.Normalize(doc))
+
+	assert.Equal(t, graphql.QueryHash(compact), graphql.QueryHash(formatted))
+	assert.Equal(t, graphql.QueryHash("{ a(x: {b: 1, a: \"s\"}) }"), graphql.QueryHash("query {\n a(x: { a: \"s\" b: 1 })\n}"))
+
+	assert.NotEqual(t, graphql.QueryHash(compact), graphql.QueryHash(`query Hero($ep: Episode = JEDI) { hero(episode: $ep, first: 1) { name } }`))
+	assert.NotEqual(t, graphql.QueryHash("{ a b }"), graphql.QueryHash("{ b a }"))
+	assert.NotEqual(t, graphql.QueryHash("{ a"), graphql.QueryHash("{  a"))
+}
@@ -0,0 +1,72 @@
+package graphql
+
+import (
+	"context"
+	"github.com/shyptr/graphql/errors"
+	"net/http"
+	"sync"
+)
+
+// ErrHeadersUnavailable is returned by SetHeader and SetCookie when the response of the request can
+// not take headers anymore, or never could.
+var ErrHeadersUnavailable = errors.New("response headers can only be set while an HTTPHandler executes the request")
+
+type responseHeadersKey struct{}
+
+// responseHeaders collects the headers, cookies and ETag versions set by the resolvers of a request,
+// to be written with the response once execution completes.
+type responseHeaders struct {
+	mu       sync.Mutex
+	header   http.Header
+	cookies  []*http.Cookie
+	versions []string
+	written  bool
+}
+
+// SetHeader sets the header key of the HTTP response to value, replacing any value set before.
+// It is safe to call from resolvers running in parallel.
+//
+// The HTTP handler writes the response once every resolver has returned, which is what lets
+// resolvers set headers at all: the body is not streamed to the client as fields resolve. The
+// headers of streamed responses, such as subscriptions over websocket, are written before the first
+// resolver runs, so setting them there, or from a goroutine outliving the request, returns
+// ErrHeadersUnavailable.
+func SetHeader(ctx context.Context, key, value string) error {
+	return withResponseHeaders(ctx, func(headers *responseHeaders) {
+		headers.header.Set(key, value)
+	})
+}
+
+// SetCookie adds cookie to the HTTP response, under the same conditions as SetHeader.
+func SetCookie(ctx context.Context, cookie *http.Cookie) error {
+	return withResponseHeaders(ctx, func(headers *responseHeaders) {
+		headers.cookies = append(headers.cookies, cookie)
+	})
+}
+
+func withResponseHeaders(ctx context.Context, fn func(headers *responseHeaders)) error {
+	headers, _ := ctx.Value(responseHeadersKey{}).(*responseHeaders)
+	if headers == nil {
+		return ErrHeadersUnavailable
+	}
+	headers.mu.Lock()
+	defer headers.mu.Unlock()
+	if headers.written {
+		return ErrHeadersUnavailable
+	}
+	fn(headers)
+	return nil
+}
+
+// writeTo adds the collected headers and cookies to the headers of w, after which no more can be set.
+func (h *responseHeaders) writeTo(w http.ResponseWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.written = true
+	for key, values := range h.header {
+		w.Header()[key] = values
+	}
+	for _, cookie := range h.cookies {
+		http.SetCookie(w, cookie)
+	}
+}
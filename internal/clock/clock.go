@@ -0,0 +1,128 @@
+// Package clock abstracts the time source of the executor and the handlers, so that the features
+// depending on time, such as keep-alives and usage timestamps, can be tested without waiting.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of time.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a Timer sending the time on its channel once d elapsed.
+	NewTimer(d time.Duration) Timer
+	// Tick returns a channel receiving the time every d, and a function stopping the ticks.
+	Tick(d time.Duration) (ticks <-chan time.Time, stop func())
+}
+
+// Timer is a time.Timer of a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, it returns false if the timer already fired or was stopped.
+	Stop() bool
+}
+
+// Real is the Clock of the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) Tick(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// Fake is a Clock whose time only moves with Advance, for tests.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a timer, which fires once, or a ticker, which fires every period.
+type waiter struct {
+	next    time.Time
+	period  time.Duration
+	c       chan time.Time
+	stopped bool
+}
+
+// NewFake returns a Fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time of the clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a Timer firing once the clock advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return &fakeTimer{f, f.add(d, 0)}
+}
+
+// Tick returns a channel receiving the time every time the clock advanced by d.
+func (f *Fake) Tick(d time.Duration) (<-chan time.Time, func()) {
+	w := f.add(d, d)
+	return w.c, func() { f.stop(w) }
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers due. Like those of the time
+// package, a ticker whose previous tick was not received yet drops the tick.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, w := range f.waiters {
+		for !w.stopped && !w.next.After(f.now) {
+			select {
+			case w.c <- f.now:
+			default:
+			}
+			if w.period == 0 {
+				w.stopped = true
+			} else {
+				w.next = w.next.Add(w.period)
+			}
+		}
+	}
+}
+
+func (f *Fake) add(d, period time.Duration) *waiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{next: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+func (f *Fake) stop(w *waiter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stopped := w.stopped
+	w.stopped = true
+	return !stopped
+}
+
+type fakeTimer struct {
+	clock *Fake
+	w     *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTimer) Stop() bool { return t.clock.stop(t.w) }
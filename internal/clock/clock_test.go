@@ -0,0 +1,33 @@
+package clock_test
+
+import (
+	"github.com/shyptr/graphql/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	timer := fake.NewTimer(10 * time.Second)
+	stopped := fake.NewTimer(10 * time.Second)
+	ticks, stop := fake.Tick(4 * time.Second)
+
+	fake.Advance(5 * time.Second)
+	assert.Equal(t, start.Add(5*time.Second), fake.Now())
+	assert.Equal(t, start.Add(5*time.Second), <-ticks)
+	assert.Len(t, timer.C(), 0)
+	assert.True(t, stopped.Stop())
+
+	// the ticks due at 8s and 12s are received once, the channel holds a single tick
+	fake.Advance(7 * time.Second)
+	assert.Equal(t, start.Add(12*time.Second), <-timer.C())
+	assert.Equal(t, start.Add(12*time.Second), <-ticks)
+	assert.Len(t, stopped.C(), 0)
+	assert.False(t, timer.Stop())
+
+	stop()
+	fake.Advance(time.Minute)
+	assert.Len(t, ticks, 0)
+}
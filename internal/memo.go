@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+type memoKey struct{}
+
+// Memo holds the values memoized while executing one request.
+type Memo struct {
+	mu     sync.Mutex
+	values map[interface{}]*memoValue
+}
+
+type memoValue struct {
+	once   sync.Once
+	result interface{}
+	err    error
+}
+
+// WithMemo returns a copy of ctx holding a new Memo, the executor calls it once per request.
+func WithMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoKey{}, &Memo{values: map[interface{}]*memoValue{}})
+}
+
+// MemoFrom returns the Memo of the request executed with ctx, or nil outside of an execution.
+func MemoFrom(ctx context.Context) *Memo {
+	memo, _ := ctx.Value(memoKey{}).(*Memo)
+	return memo
+}
+
+// Do returns the result of fn memoized under key, calling fn only the first time key is seen.
+// key must be comparable.
+func (m *Memo) Do(key interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	m.mu.Lock()
+	value, ok := m.values[key]
+	if !ok {
+		value = &memoValue{}
+		m.values[key] = value
+	}
+	m.mu.Unlock()
+	value.once.Do(func() {
+		value.result, value.err = fn()
+	})
+	return value.result, value.err
+}
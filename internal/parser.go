@@ -68,7 +68,7 @@ func parseDocument(l *lexer) *ast.Document {
 		case "mutation":
 			doc.Definition = append(doc.Definition, parseOperationDefinition(l, ast.Mutation))
 		case "subscription":
-			doc.Definition = append(doc.Definition, parseOperationDefinition(l, ast.Mutation))
+			doc.Definition = append(doc.Definition, parseOperationDefinition(l, ast.Subscription))
 		case "fragment":
 			fragment := parseFragmentDefinition(l)
 			fragment.Loc = loc
@@ -459,6 +459,9 @@ func parseDirective(l *lexer) *ast.Directive {
 	return directive
 }
 
+// ValueToJson converts value to a json.Marshal-style value, replacing its variables by their values in
+// vars. A variable which is not provided is null, but the fields of object values given such a
+// variable are left out, as if they were omitted, so that they take their default values.
 func ValueToJson(value ast.Value, vars map[string]interface{}) (interface{}, *errors.GraphQLError) {
 	switch value := value.(type) {
 	case *ast.IntValue:
@@ -489,11 +492,18 @@ func ValueToJson(value ast.Value, vars map[string]interface{}) (interface{}, *er
 		return actual, nil
 	case *ast.ObjectValue:
 		obj := make(map[string]interface{})
+		seen := make(map[string]bool, len(value.Fields))
 		for _, field := range value.Fields {
 			name := field.Name.Name.Name
-			if _, found := obj[name]; found {
+			if seen[name] {
 				return nil, errors.New("duplicate field")
 			}
+			seen[name] = true
+			if variable, ok := field.Value.(*ast.Variable); ok {
+				if _, provided := vars[variable.Name.Name]; !provided {
+					continue
+				}
+			}
 			value, err := ValueToJson(field.Value, vars)
 			if err != nil {
 				return nil, err
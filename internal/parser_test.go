@@ -79,8 +79,12 @@ func TestParser(t *testing.T) {
 	})
 
 	t.Run("parses variable definition directives", func(t *testing.T) {
-		_, err := internal.ParseDocument("query Foo($x: Boolean = false @bar) { field }")
+		doc, err := internal.ParseDocument("query Foo($x: Boolean = false @bar, $y: Int @baz(a: 1)) { field }")
 		assert.Equal(t, NilGraphQLError, err)
+		vars := doc.Definition[0].(*ast.OperationDefinition).Vars
+		assert.Equal(t, "bar", vars[0].Directives[0].Name.Name)
+		assert.Equal(t, "baz", vars[1].Directives[0].Name.Name)
+		assert.Equal(t, "a", vars[1].Directives[0].Args[0].Name.Name)
 	})
 
 	t.Run(`does not accept fragments named "on"`, func(t *testing.T) {
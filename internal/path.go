@@ -0,0 +1,17 @@
+package internal
+
+import "context"
+
+type pathKey struct{}
+
+// WithPath returns a copy of ctx holding path, the path in the response of the field resolved with it,
+// such as [users 0 name].
+func WithPath(ctx context.Context, path []interface{}) context.Context {
+	return context.WithValue(ctx, pathKey{}, path)
+}
+
+// PathOf returns the path held by ctx, see WithPath, or nil.
+func PathOf(ctx context.Context) []interface{} {
+	path, _ := ctx.Value(pathKey{}).([]interface{})
+	return path
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+type readOnlyKey struct{}
+
+// ErrReadOnly is the error of code writing while a read-only operation executes.
+var ErrReadOnly = errors.New("the operation is read-only, it can not write")
+
+// WithReadOnly returns a copy of ctx marking the operation executed with it as read-only.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly reports whether the operation executed with ctx is read-only.
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/kinds"
+	"github.com/shyptr/graphql/token"
+	"io"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// ParseSchema parses a schema definition language document, holding every definition in the returned document.
+func ParseSchema(source string) (*ast.Document, error) {
+	doc := &ast.Document{Kind: kinds.Document}
+	err := ParseSchemaReader(strings.NewReader(source), func(def ast.TypeSystemDefinition) error {
+		doc.Definition = append(doc.Definition, def)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ParseSchemaReader parses the schema definition language document read from r and calls handler
+// with each type system definition as soon as it is parsed, so that very large documents can be
+// turned into types on the fly without holding the whole source or syntax tree in memory.
+//
+// The reader is tokenized through a small buffer; locations are tracked across refills and are
+// the same as when parsing the whole source. Parsing stops at the first syntax error, returned as
+// a *errors.GraphQLError, or at the first error returned by handler, returned as is.
+// Type system extensions are not supported.
+func ParseSchemaReader(r io.Reader, handler func(def ast.TypeSystemDefinition) error) error {
+	l := newReaderLexer(r)
+	var handlerErr error
+	err := l.catchSyntaxError(func() {
+		l.SkipWhitespace()
+		for l.peek() != token.EOF && handlerErr == nil {
+			handlerErr = handler(parseTypeSystemDefinition(l))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return handlerErr
+}
+
+func newReaderLexer(r io.Reader) *lexer {
+	scan := &scanner.Scanner{}
+	scan.Init(r)
+	scan.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings
+	scan.Error = func(_ *scanner.Scanner, msg string) {
+		panic(syntaxError(msg))
+	}
+	return &lexer{scan: scan}
+}
+
+/**
+ * TypeSystemDefinition :
+ *   - SchemaDefinition
+ *   - TypeDefinition
+ *   - DirectiveDefinition
+ */
+func parseTypeSystemDefinition(l *lexer) ast.TypeSystemDefinition {
+	loc := l.location()
+	desc := parseDescription(l)
+	if l.peek() == token.NAME {
+		switch l.scan.TokenText() {
+		case token.SCHEMA:
+			l.advanceKeyWord(token.SCHEMA)
+			return parseSchemaDefinition(l, &ast.SchemaDefinition{Kind: kinds.SchemaDefinition, Desc: desc, Loc: loc})
+		case token.SCALAR:
+			l.advanceKeyWord(token.SCALAR)
+			def := &ast.ScalarDefinition{Kind: kinds.ScalarDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Directives = parseDirectives(l)
+			return def
+		case token.TYPE:
+			l.advanceKeyWord(token.TYPE)
+			def := &ast.ObjectDefinition{Kind: kinds.ObjectDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Interfaces = parseImplementsInterfaces(l)
+			def.Directives = parseDirectives(l)
+			def.Fields = parseFieldDefinitions(l)
+			return def
+		case token.INTERFACE:
+			l.advanceKeyWord(token.INTERFACE)
+			def := &ast.InterfaceDefinition{Kind: kinds.InterfaceDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Interfaces = parseImplementsInterfaces(l)
+			def.Directives = parseDirectives(l)
+			def.Fields = parseFieldDefinitions(l)
+			return def
+		case token.UNION:
+			l.advanceKeyWord(token.UNION)
+			def := &ast.UnionDefinition{Kind: kinds.UnionDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Directives = parseDirectives(l)
+			def.Members = parseUnionMembers(l)
+			return def
+		case token.ENUM:
+			l.advanceKeyWord(token.ENUM)
+			def := &ast.EnumDefinition{Kind: kinds.EnumDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Directives = parseDirectives(l)
+			def.Values = parseEnumValueDefinitions(l)
+			return def
+		case token.INPUT:
+			l.advanceKeyWord(token.INPUT)
+			def := &ast.InputObjectDefinition{Kind: kinds.InputObjectDefinition, Desc: desc, Loc: loc}
+			def.Name = parseName(l)
+			def.Directives = parseDirectives(l)
+			if l.peek() == token.BRACE_L {
+				def.InputFields = parseInputValueDefinitions(l, token.BRACE_L, token.BRACE_R)
+			}
+			return def
+		case token.DIRECTIVE:
+			l.advanceKeyWord(token.DIRECTIVE)
+			return parseDirectiveDefinition(l, &ast.DirectiveDefinition{Kind: kinds.DirectiveDefinition, Desc: desc, Loc: loc})
+		case token.EXTEND:
+			l.SyntaxError("Type system extensions are not supported.")
+		}
+	}
+	l.SyntaxError(fmt.Sprintf("Unexpected %q.", l.scan.TokenText()))
+	return nil
+}
+
+/**
+ * Description : StringValue
+ */
+func parseDescription(l *lexer) *ast.StringValue {
+	if l.peek() != token.STRING {
+		return nil
+	}
+	loc := l.location()
+	value := l.scan.TokenText()
+	if value == `""` && l.scan.Peek() == '"' {
+		value = blockStringValue(l.readBlockString())
+	} else if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	} else {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	}
+	l.SkipWhitespace()
+	return &ast.StringValue{Kind: kinds.StringValue, Value: value, Loc: loc}
+}
+
+// readBlockString reads the raw content of a block string up to its closing quotes, which it consumes.
+// The scanner stops at the first two quotes of a block string, which are its current token.
+func (l *lexer) readBlockString() string {
+	var buf strings.Builder
+	start := l.scan.Position
+	l.scan.Next()
+	for {
+		next := l.scan.Next()
+		if next == scanner.EOF {
+			// Next invalidates the position of the token, the error is reported where the string starts
+			l.scan.Position = start
+			l.SyntaxError("Unterminated string.")
+		}
+		buf.WriteRune(next)
+		if next != '"' {
+			continue
+		}
+		raw := buf.String()
+		switch {
+		case strings.HasSuffix(raw, `\"""`):
+			buf.Reset()
+			buf.WriteString(raw[:len(raw)-4] + `"""`)
+		case strings.HasSuffix(raw, `"""`):
+			return raw[:len(raw)-3]
+		}
+	}
+}
+
+// blockStringValue removes the common indentation of the lines of a block string, as well as its
+// leading and trailing blank lines.
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.Replace(raw, "\r\n", "\n", -1), "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) < common {
+				lines[i] = ""
+			} else {
+				lines[i] = lines[i][common:]
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+/**
+ * SchemaDefinition : schema Directives? { OperationTypeDefinition+ }
+ *
+ * OperationTypeDefinition : OperationType : NamedType
+ */
+func parseSchemaDefinition(l *lexer, def *ast.SchemaDefinition) *ast.SchemaDefinition {
+	def.Directives = parseDirectives(l)
+	l.advance(token.BRACE_L)
+	for l.peek() != token.BRACE_R {
+		loc := l.location()
+		var operation ast.OperationType
+		switch name := l.scan.TokenText(); name {
+		case token.QUERY:
+			operation = ast.Query
+		case token.MUTATION:
+			operation = ast.Mutation
+		case token.SUBSCRIPTION:
+			operation = ast.Subscription
+		default:
+			l.SyntaxError(fmt.Sprintf("Unexpected %q.", name))
+		}
+		l.advance(token.NAME)
+		l.advance(token.COLON)
+		def.OperationTypes = append(def.OperationTypes, &ast.OperationTypeDefinition{
+			Kind:      kinds.OperationTypeDefinition,
+			Operation: operation,
+			Type:      parseNamed(l),
+			Loc:       loc,
+		})
+	}
+	l.advance(token.BRACE_R)
+	return def
+}
+
+/**
+ * ImplementsInterfaces :
+ *   - implements `&`? NamedType
+ *   - ImplementsInterfaces & NamedType
+ */
+func parseImplementsInterfaces(l *lexer) []*ast.Named {
+	if l.peek() != token.NAME || l.scan.TokenText() != "implements" {
+		return nil
+	}
+	l.advanceKeyWord("implements")
+	if l.peek() == token.AMP {
+		l.advance(token.AMP)
+	}
+	interfaces := []*ast.Named{parseNamed(l)}
+	for l.peek() == token.AMP {
+		l.advance(token.AMP)
+		interfaces = append(interfaces, parseNamed(l))
+	}
+	return interfaces
+}
+
+/**
+ * FieldsDefinition : { FieldDefinition+ }
+ *
+ * FieldDefinition : Description? Name ArgumentsDefinition? : Type Directives?
+ */
+func parseFieldDefinitions(l *lexer) []*ast.FieldDefinition {
+	if l.peek() != token.BRACE_L {
+		return nil
+	}
+	l.advance(token.BRACE_L)
+	var fields []*ast.FieldDefinition
+	for l.peek() != token.BRACE_R {
+		field := &ast.FieldDefinition{Kind: kinds.FieldDefinition, Loc: l.location()}
+		field.Desc = parseDescription(l)
+		field.Name = parseName(l)
+		if l.peek() == token.PAREN_L {
+			field.Argument = parseInputValueDefinitions(l, token.PAREN_L, token.PAREN_R)
+		}
+		l.advance(token.COLON)
+		field.Type = ParseType(l)
+		field.Directives = parseDirectives(l)
+		fields = append(fields, field)
+	}
+	l.advance(token.BRACE_R)
+	return fields
+}
+
+/**
+ * ArgumentsDefinition : ( InputValueDefinition+ )
+ *
+ * InputFieldsDefinition : { InputValueDefinition+ }
+ *
+ * InputValueDefinition : Description? Name : Type DefaultValue? Directives?
+ */
+func parseInputValueDefinitions(l *lexer, open, close rune) []*ast.InputValueDefinition {
+	l.advance(open)
+	var values []*ast.InputValueDefinition
+	for l.peek() != close {
+		value := &ast.InputValueDefinition{Kind: kinds.InputValueDefinition, Loc: l.location()}
+		value.Desc = parseDescription(l)
+		value.Name = parseName(l)
+		l.advance(token.COLON)
+		value.Type = ParseType(l)
+		if l.peek() == token.EQUALS {
+			l.advance(token.EQUALS)
+			value.DefaultValue = ParseValueLiteral(l, true)
+		}
+		value.Directives = parseDirectives(l)
+		values = append(values, value)
+	}
+	l.advance(close)
+	return values
+}
+
+/**
+ * UnionMemberTypes :
+ *   - = `|`? NamedType
+ *   - UnionMemberTypes | NamedType
+ */
+func parseUnionMembers(l *lexer) []*ast.Named {
+	if l.peek() != token.EQUALS {
+		return nil
+	}
+	l.advance(token.EQUALS)
+	if l.peek() == token.PIPE {
+		l.advance(token.PIPE)
+	}
+	members := []*ast.Named{parseNamed(l)}
+	for l.peek() == token.PIPE {
+		l.advance(token.PIPE)
+		members = append(members, parseNamed(l))
+	}
+	return members
+}
+
+/**
+ * EnumValuesDefinition : { EnumValueDefinition+ }
+ *
+ * EnumValueDefinition : Description? EnumValue Directives?
+ */
+func parseEnumValueDefinitions(l *lexer) []*ast.EnumValueDefinition {
+	if l.peek() != token.BRACE_L {
+		return nil
+	}
+	l.advance(token.BRACE_L)
+	var values []*ast.EnumValueDefinition
+	for l.peek() != token.BRACE_R {
+		value := &ast.EnumValueDefinition{Kind: kinds.EnumValueDefinition, Loc: l.location()}
+		value.Desc = parseDescription(l)
+		loc := l.location()
+		name := l.scan.TokenText()
+		l.advance(token.NAME)
+		value.Value = &ast.EnumValue{Kind: kinds.EnumValue, Value: name, Loc: loc}
+		value.Directives = parseDirectives(l)
+		values = append(values, value)
+	}
+	l.advance(token.BRACE_R)
+	return values
+}
+
+/**
+ * DirectiveDefinition : Description? directive @ Name ArgumentsDefinition? repeatable? on DirectiveLocations
+ *
+ * DirectiveLocations :
+ *   - `|`? DirectiveLocation
+ *   - DirectiveLocations | DirectiveLocation
+ */
+func parseDirectiveDefinition(l *lexer, def *ast.DirectiveDefinition) *ast.DirectiveDefinition {
+	l.advance(token.AT)
+	def.Name = parseName(l)
+	if l.peek() == token.PAREN_L {
+		def.Arguments = parseInputValueDefinitions(l, token.PAREN_L, token.PAREN_R)
+	}
+	// the syntax tree does not record repeatable directives
+	if l.peek() == token.NAME && l.scan.TokenText() == "repeatable" {
+		l.advanceKeyWord("repeatable")
+	}
+	l.advanceKeyWord("on")
+	if l.peek() == token.PIPE {
+		l.advance(token.PIPE)
+	}
+	def.Locations = append(def.Locations, parseName(l).Name)
+	for l.peek() == token.PIPE {
+		l.advance(token.PIPE)
+		def.Locations = append(def.Locations, parseName(l).Name)
+	}
+	return def
+}
@@ -0,0 +1,199 @@
+package internal_test
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/internal"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseSchema(t *testing.T) {
+	doc, err := internal.ParseSchema(`
+schema { query: Query mutation: Mutation }
+
+"""
+A node.
+
+  Indented.
+"""
+interface Node { id: ID! }
+
+type User implements Node & Named @key(fields: "id") {
+  id: ID!
+  "the name, \"quoted\""
+  name(upper: Boolean = false, format: [String!] = ["a"]): String @deprecated(reason: "no")
+}
+
+union Result = | User | Error
+
+enum Role { ADMIN "users" USER }
+
+input Filter { role: Role = ADMIN, limit: Int }
+
+scalar Time
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+`)
+	assert.NoError(t, err)
+	assert.Len(t, doc.Definition, 8)
+
+	schema := doc.Definition[0].(*ast.SchemaDefinition)
+	assert.Len(t, schema.OperationTypes, 2)
+	assert.Equal(t, ast.Mutation, schema.OperationTypes[1].Operation)
+	assert.Equal(t, "Mutation", schema.OperationTypes[1].Type.Name.Name)
+
+	node := doc.Definition[1].(*ast.InterfaceDefinition)
+	assert.Equal(t, "A node.\n\n  Indented.", node.Desc.Value)
+	assert.Equal(t, errors.Location{Line: 4, Column: 1}, node.Loc)
+	assert.Equal(t, "Node", node.Name.Name)
+
+	user := doc.Definition[2].(*ast.ObjectDefinition)
+	assert.Equal(t, errors.Location{Line: 11, Column: 1}, user.Loc)
+	assert.Len(t, user.Interfaces, 2)
+	assert.Equal(t, "key", user.Directives[0].Name.Name)
+	assert.Len(t, user.Fields, 2)
+	name := user.Fields[1]
+	assert.Equal(t, `the name, "quoted"`, name.Desc.Value)
+	assert.Equal(t, errors.Location{Line: 13, Column: 3}, name.Loc)
+	assert.Len(t, name.Argument, 2)
+	assert.Equal(t, "[String!]", fmt.Sprint(name.Argument[1].Type))
+	assert.IsType(t, &ast.ListValue{}, name.Argument[1].DefaultValue)
+	assert.Equal(t, "deprecated", name.Directives[0].Name.Name)
+
+	result := doc.Definition[3].(*ast.UnionDefinition)
+	assert.Len(t, result.Members, 2)
+
+	role := doc.Definition[4].(*ast.EnumDefinition)
+	assert.Len(t, role.Values, 2)
+	assert.Equal(t, "USER", role.Values[1].Value.Value)
+	assert.Equal(t, "users", role.Values[1].Desc.Value)
+
+	filter := doc.Definition[5].(*ast.InputObjectDefinition)
+	assert.Len(t, filter.InputFields, 2)
+	assert.Equal(t, &ast.EnumValue{Kind: "EnumValue", Value: "ADMIN", Loc: errors.Location{Line: 21, Column: 29}}, filter.InputFields[0].DefaultValue)
+
+	assert.Equal(t, "Time", doc.Definition[6].(*ast.ScalarDefinition).Name.Name)
+
+	key := doc.Definition[7].(*ast.DirectiveDefinition)
+	assert.Equal(t, []string{"OBJECT", "INTERFACE"}, key.Locations)
+	assert.Len(t, key.Arguments, 1)
+
+	_, err = internal.ParseSchema("type User {\n  name: String\n")
+	assert.Equal(t, &errors.GraphQLError{
+		Message:   `Syntax Error: Expected Ident, found "".`,
+		Locations: []errors.Location{{Line: 3, Column: 1}},
+	}, err)
+	_, err = internal.ParseSchema(`"""never closed`)
+	assert.EqualError(t, err, "graphql: Syntax Error: Unterminated string. (1:1)")
+	_, err = internal.ParseSchema("extend type User { age: Int }")
+	assert.EqualError(t, err, "graphql: Syntax Error: Type system extensions are not supported. (1:1)")
+}
+
+func TestParseSchemaReader(t *testing.T) {
+	sdl := generateSDL(200)
+
+	t.Run("tracks locations across buffer refills", func(t *testing.T) {
+		var locations []errors.Location
+		err := internal.ParseSchemaReader(iotest.HalfReader(strings.NewReader(sdl)), func(def ast.TypeSystemDefinition) error {
+			if object, ok := def.(*ast.ObjectDefinition); ok {
+				locations = append(locations, object.Loc, object.Fields[len(object.Fields)-1].Type.(*ast.Named).Loc)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, locations, 400)
+		// every type takes 11 lines, its last field is on its 9th
+		assert.Equal(t, errors.Location{Line: 11*150 + 1, Column: 1}, locations[300])
+		assert.Equal(t, errors.Location{Line: 11*150 + 9, Column: 11}, locations[301])
+
+		doc, err := internal.ParseSchema(sdl)
+		assert.NoError(t, err)
+		assert.Equal(t, locations[300], doc.Definition[150].(*ast.ObjectDefinition).Loc)
+	})
+
+	t.Run("stops at the first handler error", func(t *testing.T) {
+		calls := 0
+		err := internal.ParseSchemaReader(strings.NewReader(sdl), func(def ast.TypeSystemDefinition) error {
+			calls++
+			if calls == 3 {
+				return fmt.Errorf("stop")
+			}
+			return nil
+		})
+		assert.EqualError(t, err, "stop")
+		assert.Equal(t, 3, calls)
+	})
+}
+
+// generateSDL generates n object types of 11 lines each.
+func generateSDL(n int) string {
+	var buf strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "\"\"\"\nType number %d.\n\"\"\"\ntype Type%d implements Node {\n  id: ID!\n", i, i)
+		fmt.Fprintf(&buf, "  \"the name\"\n  name(upper: Boolean = false): String @deprecated\n")
+		fmt.Fprintf(&buf, "  friends(first: Int = 10, after: String): [Type%d!]!\n  parent: Type%d\n}\n\n", i, i)
+	}
+	return buf.String()
+}
+
+func BenchmarkParseSchema(b *testing.B) {
+	var sdl string
+	for n := 100; len(sdl) < 5<<20; n *= 2 {
+		sdl = generateSDL(n)
+	}
+	b.SetBytes(int64(len(sdl)))
+
+	// live-heap-B is the most memory held while parsing, over what was held before; it is
+	// measured with the timer stopped, as it collects garbage first
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak int64
+		for i := 0; i < b.N; i++ {
+			base := liveHeap(b)
+			doc, err := internal.ParseSchema(sdl)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if live := liveHeap(b) - base; live > peak {
+				peak = live
+			}
+			runtime.KeepAlive(doc)
+		}
+		b.ReportMetric(float64(peak), "live-heap-B")
+	})
+
+	b.Run("streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		var peak int64
+		for i := 0; i < b.N; i++ {
+			base := liveHeap(b)
+			count := 0
+			err := internal.ParseSchemaReader(strings.NewReader(sdl), func(def ast.TypeSystemDefinition) error {
+				if count++; count%1000 == 0 {
+					if live := liveHeap(b) - base; live > peak {
+						peak = live
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(peak), "live-heap-B")
+	})
+}
+
+func liveHeap(b *testing.B) int64 {
+	b.StopTimer()
+	defer b.StartTimer()
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
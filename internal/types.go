@@ -40,11 +40,19 @@ var _ NamedType = (*Union)(nil)
 // The leaf values of any request and input values to arguments are Scalars (or Enums)
 // and are defined with a name and a series of serialization functions used to ensure validity.
 type Scalar struct {
-	Name         string                                 `json:"name"`
-	Desc         string                                 `json:"description"`
-	Serialize    func(interface{}) (interface{}, error) `json:"-"`
-	ParseValue   func(interface{}) (interface{}, error) `json:"-"`
-	ParseLiteral func(value ast.Value) error            `json:"-"`
+	Name         string                                     `json:"name"`
+	Desc         string                                     `json:"description"`
+	Serialize    func(interface{}) (interface{}, error)     `json:"-"`
+	ParseValue   func(interface{}) (interface{}, error)     `json:"-"`
+	ParseLiteral func(value ast.Value) (interface{}, error) `json:"-"`
+	// SpecifiedByURL is the URL of the specification of the scalar, shown by introspection.
+	SpecifiedByURL string `json:"specifiedByURL"`
+}
+
+// LiteralValue is the value of a scalar argument parsed from its literal by ParseLiteral. Argument
+// decoders use it as is, instead of passing it to ParseValue like the values of variables.
+type LiteralValue struct {
+	Value interface{}
 }
 
 // Almost all of the GraphQL types you define will be object types.
@@ -77,6 +85,9 @@ type Union struct {
 	Name  string             `json:"name"`
 	Types map[string]*Object `json:"types"`
 	Desc  string             `json:"description"`
+	// TypeResolve returns the member of the union a value is, when it is nil the value is a struct with a
+	// pointer field per member, only one of them not nil.
+	TypeResolve TypeResolve `json:"-"`
 }
 
 // Some leaf values of requests and input values are Enums.
@@ -91,6 +102,12 @@ type Enum struct {
 	ReverseMap map[string]interface{} `json:"-"`
 	Map        map[interface{}]string `json:"-"`
 	Desc       string                 `json:"description"`
+	// Deprecated maps the deprecated values to their deprecation reason.
+	Deprecated map[string]string `json:"-"`
+	// OutputMapper converts the values of fields before they are looked up in Map, and InputMapper
+	// the values of ReverseMap before they reach resolvers, both may be nil.
+	OutputMapper func(value interface{}) (interface{}, error) `json:"-"`
+	InputMapper  func(value interface{}) (interface{}, error) `json:"-"`
 }
 
 // An input object defines a structured collection of fields which may be supplied to a field argument.
@@ -126,6 +143,33 @@ func (t *InputObject) String() string { return t.Name }
 func (t *List) String() string        { return fmt.Sprintf("[%s]", t.Type.String()) }
 func (t *NonNull) String() string     { return fmt.Sprintf("%s!", t.Type.String()) }
 
+// TypeRef renders typ the way introspection nests it, e.g. NON_NULL(LIST(OBJECT Person)).
+// It is meant for debugging; error messages should use the SDL form returned by String.
+func TypeRef(typ Type) string {
+	switch typ := typ.(type) {
+	case *NonNull:
+		return fmt.Sprintf("NON_NULL(%s)", TypeRef(typ.Type))
+	case *List:
+		return fmt.Sprintf("LIST(%s)", TypeRef(typ.Type))
+	case *Scalar:
+		return "SCALAR " + typ.Name
+	case *Object:
+		return "OBJECT " + typ.Name
+	case *Interface:
+		return "INTERFACE " + typ.Name
+	case *Union:
+		return "UNION " + typ.Name
+	case *Enum:
+		return "ENUM " + typ.Name
+	case *InputObject:
+		return "INPUT_OBJECT " + typ.Name
+	case nil:
+		return "<nil>"
+	default:
+		return fmt.Sprintf("%T", typ)
+	}
+}
+
 func (t *Scalar) IsType()      {}
 func (t *Object) IsType()      {}
 func (t *Interface) IsType()   {}
@@ -149,25 +193,57 @@ func (t *Union) Description() string       { return t.Desc }
 func (t *Enum) Description() string        { return t.Desc }
 func (t *InputObject) Description() string { return t.Desc }
 
-type TypeResolve func(ctx context.Context, value interface{}) *Object
+type TypeResolve func(ctx context.Context, value interface{}) (*Object, error)
 
 type FieldResolve func(ctx context.Context, source, args interface{}) (interface{}, error)
 
+// Thunk is a value a FieldResolve returns to be resolved later: the executor calls it once the
+// resolvers of the other fields of its level returned, so that they can batch their loads.
+type Thunk func() (interface{}, error)
+
 //type HandlerFunc func(ctx context.Context) error
 
 type Field struct {
-	Name    string                 `json:"name"`
-	Type    Type                   `json:"type"`
-	Args    map[string]*InputField `json:"arguments"`
-	Resolve FieldResolve           `json:"-"`
-	Desc    string                 `json:"desc"`
+	Name              string                 `json:"name"`
+	Type              Type                   `json:"type"`
+	Args              map[string]*InputField `json:"arguments"`
+	Resolve           FieldResolve           `json:"-"`
+	Desc              string                 `json:"desc"`
+	IsDeprecated      bool                   `json:"isDeprecated"`
+	DeprecationReason string                 `json:"deprecationReason"`
+	// ResolverKind tells what Resolve does, it may be empty for fields resolved by a function.
+	ResolverKind ResolverKind `json:"-"`
+	// DynamicOutput lets Resolve return any value for a field of a built-in scalar type, which the
+	// Serialize function of the scalar encodes, instead of a string, a number or a boolean.
+	DynamicOutput bool `json:"-"`
+	// Complexity returns the complexity of the field selected with args, given that of the fields selected
+	// on its values, for execution.MaxComplexity. Fields without one count 1 besides their selection.
+	Complexity func(args map[string]interface{}, childComplexity int) int `json:"-"`
+	// ResolvePath makes the executor pass the path of the field to Resolve in its context, see WithPath.
+	ResolvePath bool `json:"-"`
 }
 
+// ResolverKind tells how a field is resolved, as execution.Explain reports it.
+type ResolverKind string
+
+const (
+	// DefaultResolver reads the key or struct field named after the field, for fields without Resolve.
+	DefaultResolver ResolverKind = "default"
+	// StructFieldResolver reads a field of the struct the object resolved to.
+	StructFieldResolver ResolverKind = "struct field"
+	// FuncResolver calls a function, such as one registered by FieldFunc.
+	FuncResolver ResolverKind = "func"
+	// MetaResolver resolves __typename and the meta fields of the schema.
+	MetaResolver ResolverKind = "meta"
+)
+
 type InputField struct {
-	Name         string      `json:"name"`
-	Type         Type        `json:"type"`
-	Desc         string      `json:"description"`
-	DefaultValue interface{} `json:"defaultValue"`
+	Name              string      `json:"name"`
+	Type              Type        `json:"type"`
+	Desc              string      `json:"description"`
+	DefaultValue      interface{} `json:"defaultValue"`
+	IsDeprecated      bool        `json:"isDeprecated"`
+	DeprecationReason string      `json:"deprecationReason"`
 }
 
 //Schema used to validate and resolve the queries
@@ -177,6 +253,39 @@ type Schema struct {
 	Query        Type                  `json:"query"`
 	Mutation     Type                  `json:"mutation"`
 	Subscription Type                  `json:"subscription"`
+	// MetaFields are fields selectable on the query root type besides its own, which extensions
+	// such as introspection add to a built schema without changing its Query object, see AddMetaField.
+	MetaFields map[string]*Field `json:"-"`
+}
+
+// AddMetaField adds field to the MetaFields of s, the named types it refers to must be in the TypeMap
+// of s. A meta field named like a field of the query root type is never selected.
+func (s *Schema) AddMetaField(field *Field) {
+	if s.MetaFields == nil {
+		s.MetaFields = make(map[string]*Field)
+	}
+	s.MetaFields[field.Name] = field
+}
+
+// Clone returns a copy of the schema whose type, directive and meta field maps can be changed, such
+// as by introspection.AddIntrospectionToSchema, without changing those of s. The types are shared.
+func (s *Schema) Clone() *Schema {
+	clone := *s
+	clone.TypeMap = make(map[string]NamedType, len(s.TypeMap))
+	for name, typ := range s.TypeMap {
+		clone.TypeMap[name] = typ
+	}
+	clone.Directives = make(map[string]*Directive, len(s.Directives))
+	for name, directive := range s.Directives {
+		clone.Directives[name] = directive
+	}
+	if s.MetaFields != nil {
+		clone.MetaFields = make(map[string]*Field, len(s.MetaFields))
+		for name, field := range s.MetaFields {
+			clone.MetaFields[name] = field
+		}
+	}
+	return &clone
 }
 
 type DirectiveFn func(ctx context.Context, args interface{}, fieldFn FieldResolve, source interface{}, fieldArgs interface{}) (bool, interface{}, error)
@@ -238,20 +347,41 @@ type Selection struct {
 	SelectionSet *SelectionSet
 	Directives   []*Directive
 	Loc          errors.Location
+	// MetaField is the meta field of the schema the selection selects, nil for the fields of its type.
+	MetaField *Field
 }
 
 // A FragmentDefinition represents a reusable part of a GraphQL query
 //
 // The On part of a FragmentDefinition represents the type of source object for which
-// this FragmentDefinition should be used. That is not currently implemented in this
-// package.
+// this FragmentDefinition should be used, Type is that type when it is known.
 type FragmentDefinition struct {
 	Name         string
 	On           string
+	Type         NamedType
 	SelectionSet *SelectionSet
 	Loc          errors.Location
 }
 
+// AppliesTo reports whether the fragment selects fields of objects of type typ.
+func (f *FragmentDefinition) AppliesTo(typ *Object) bool {
+	switch on := f.Type.(type) {
+	case *Object:
+		return on.Name == typ.Name
+	case *Interface:
+		_, ok := typ.Interfaces[on.Name]
+		return ok
+	case *Union:
+		_, ok := on.Types[typ.Name]
+		return ok
+	}
+	if f.On == "" || f.On == typ.Name {
+		return true
+	}
+	_, ok := typ.Interfaces[f.On]
+	return ok
+}
+
 // FragmentSpread represents a usage of a FragmentDefinition. Alongside the information
 // about the fragment, it includes any directives used at that spread location.
 type FragmentSpread struct {
@@ -282,3 +412,11 @@ func IsBasicType(typ Type) bool {
 		return false
 	}
 }
+
+// Warning is the error of a FieldResolve resolving its field to null with a warning, which is reported
+// apart from the errors of the response.
+type Warning struct {
+	Reason string
+}
+
+func (w *Warning) Error() string { return w.Reason }
@@ -0,0 +1,33 @@
+package internal_test
+
+import (
+	"github.com/shyptr/graphql/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestType_String(t *testing.T) {
+	person := &internal.Object{Name: "Person"}
+	id := &internal.Scalar{Name: "ID"}
+
+	tests := []struct {
+		typ    internal.Type
+		sdl    string
+		typRef string
+	}{
+		{person, "Person", "OBJECT Person"},
+		{&internal.NonNull{Type: id}, "ID!", "NON_NULL(SCALAR ID)"},
+		{&internal.List{Type: person}, "[Person]", "LIST(OBJECT Person)"},
+		{&internal.NonNull{Type: &internal.List{Type: person}}, "[Person]!", "NON_NULL(LIST(OBJECT Person))"},
+		{&internal.List{Type: &internal.NonNull{Type: person}}, "[Person!]", "LIST(NON_NULL(OBJECT Person))"},
+		{
+			&internal.NonNull{Type: &internal.List{Type: &internal.NonNull{Type: &internal.List{Type: &internal.Enum{Name: "Episode"}}}}},
+			"[[Episode]!]!",
+			"NON_NULL(LIST(NON_NULL(LIST(ENUM Episode))))",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.sdl, tt.typ.String())
+		assert.Equal(t, tt.typRef, internal.TypeRef(tt.typ))
+	}
+}
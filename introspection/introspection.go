@@ -7,7 +7,9 @@ import (
 	"github.com/shyptr/graphql/execution"
 	"github.com/shyptr/graphql/internal"
 	"github.com/shyptr/graphql/schemabuilder"
+	"reflect"
 	"sort"
+	"strings"
 )
 
 // A GraphQL server supports introspection over its schema.
@@ -62,6 +64,20 @@ type introspection struct {
 	query        internal.Type
 	mutation     internal.Type
 	subscription internal.Type
+	// sortEnumValues lists the values of enums by name, see SortEnumValues.
+	sortEnumValues bool
+}
+
+// Option configures the introspection added by AddIntrospectionToSchema.
+type Option func(*introspection)
+
+// SortEnumValues lists the values of enums by name in __Type.enumValues, instead of in the order of the
+// Values of their Enum, which is the order of their Go values for the enums registered by
+// schemabuilder.Schema.Enum and the order of the document for those written in SDL.
+func SortEnumValues() Option {
+	return func(is *introspection) {
+		is.sortEnumValues = true
+	}
 }
 
 type DirectiveLocation string
@@ -74,6 +90,7 @@ const (
 	FragmentDefinition   DirectiveLocation = "FRAGMENT_DEFINITION"
 	FragmentSpread       DirectiveLocation = "FRAGMENT_SPREAD"
 	InlineFragment       DirectiveLocation = "INLINE_FRAGMENT"
+	VariableDefinition   DirectiveLocation = "VARIABLE_DEFINITION"
 	Schema               DirectiveLocation = "SCHEMA"
 	Scalar               DirectiveLocation = "SCALAR"
 	Object               DirectiveLocation = "OBJECT"
@@ -163,7 +180,7 @@ type __Type struct {
 //}
 
 func (s *introspection) registerType(schema *schemabuilder.Schema) {
-	schema.Enum("__TypeKind", TypeKind(0), map[string]interface{}{
+	schema.Enum("__TypeKind", TypeKind(""), map[string]interface{}{
 		string(OBJECT):       OBJECT,
 		string(UNION):        UNION,
 		string(SCALAR):       SCALAR,
@@ -218,51 +235,35 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		IncludeDeprecated *bool `graphql:"includeDeprecated"`
 	}) []__Field {
 		fields := make([]__Field, 0)
+		includeDeprecated := args.IncludeDeprecated != nil && *args.IncludeDeprecated
 
 		switch t := t.OfType.(type) {
 		case *internal.Object:
 			for name, field := range t.Fields {
-				args := make([]__InputValue, 0)
-				for name, arg := range field.Args {
-					var defaultValue string
-					if arg.DefaultValue != nil {
-						defaultValue = fmt.Sprintf("%v", arg.DefaultValue)
-					}
-					args = append(args, __InputValue{
-						Name:         name,
-						Desc:         arg.Desc,
-						DefaultValue: &defaultValue,
-						Type:         __Type{OfType: arg.Type},
-					})
+				if field.IsDeprecated && !includeDeprecated {
+					continue
 				}
-				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
 				fields = append(fields, __Field{
 					Name:              name,
 					Desc:              &field.Desc,
-					Args:              args,
+					Args:              field.Args,
 					Type:              __Type{OfType: field.Type},
-					IsDeprecated:      false,
-					DeprecationReason: "",
+					IsDeprecated:      field.IsDeprecated,
+					DeprecationReason: field.DeprecationReason,
 				})
 			}
 		case *internal.Interface:
 			for name, field := range t.Fields {
-				args := make([]__InputValue, 0)
-				for name, arg := range field.Args {
-					args = append(args, __InputValue{
-						Name: name,
-						Desc: arg.Desc,
-						Type: __Type{OfType: arg.Type},
-					})
+				if field.IsDeprecated && !includeDeprecated {
+					continue
 				}
-				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
 				fields = append(fields, __Field{
 					Name:              name,
 					Desc:              &field.Desc,
-					Args:              args,
+					Args:              field.Args,
 					Type:              __Type{OfType: field.Type},
-					IsDeprecated:      false,
-					DeprecationReason: "",
+					IsDeprecated:      field.IsDeprecated,
+					DeprecationReason: field.DeprecationReason,
 				})
 			}
 		}
@@ -312,41 +313,40 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 
 		switch t := t.OfType.(type) {
 		case *internal.Enum:
-			enumValues := make([]__EnumValue, 0)
-			for _, v := range t.Map {
+			enumValues := make([]__EnumValue, 0, len(t.Values))
+			for _, v := range t.Values {
 				desc := t.ValuesDesc[v]
+				reason, deprecated := t.Deprecated[v]
+				if deprecated && (args.IncludeDeprecated == nil || !*args.IncludeDeprecated) {
+					continue
+				}
 				enumValues = append(enumValues,
-					__EnumValue{Name: v, Desc: &desc, IsDeprecated: false, DeprecationReason: ""})
+					__EnumValue{Name: v, Desc: &desc, IsDeprecated: deprecated, DeprecationReason: reason})
+			}
+			if s.sortEnumValues {
+				sort.Slice(enumValues, func(i, j int) bool { return enumValues[i].Name < enumValues[j].Name })
 			}
-			sort.Slice(enumValues, func(i, j int) bool { return enumValues[i].Name < enumValues[j].Name })
 			return enumValues
 		}
 		return []__EnumValue{}
 	}, "should be non-null for ENUM only, must be null for the others")
 
-	object.FieldFunc("inputFields", func(t __Type) []__InputValue {
-		fields := make([]__InputValue, 0)
-
-		switch t := t.OfType.(type) {
-		case *internal.InputObject:
-			for name, f := range t.Fields {
-				var defaultValue string
-				if f.DefaultValue != nil {
-					defaultValue = fmt.Sprintf("%v", f.DefaultValue)
-				}
-				fields = append(fields, __InputValue{
-					Name:         name,
-					Type:         __Type{OfType: f.Type},
-					DefaultValue: &defaultValue,
-					Desc:         f.Desc,
-				})
-			}
+	object.FieldFunc("inputFields", func(t __Type, args struct {
+		IncludeDeprecated *bool `graphql:"includeDeprecated"`
+	}) []__InputValue {
+		if t, ok := t.OfType.(*internal.InputObject); ok {
+			return inputValues(t.Fields, args.IncludeDeprecated != nil && *args.IncludeDeprecated)
 		}
-
-		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
-		return fields
+		return []__InputValue{}
 	}, "should be non-null for INPUT_OBJECT only, must be null for the others")
 
+	object.FieldFunc("specifiedByURL", func(t __Type) *string {
+		if t, ok := t.OfType.(*internal.Scalar); ok && t.SpecifiedByURL != "" {
+			return &t.SpecifiedByURL
+		}
+		return nil
+	}, "the URL of the specification of a SCALAR, null when it has none and for the other kinds")
+
 	object.FieldFunc("ofType", func(t __Type) *__Type {
 		switch t := t.OfType.(type) {
 		case *internal.List:
@@ -361,24 +361,58 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 
 // The __Field type represents each field in an Object or Interface type.
 type __Field struct {
-	Name              string         `graphql:"name"`
-	Desc              *string        `graphql:"description"`
-	Args              []__InputValue `graphql:"args"`
-	Type              __Type         `graphql:"type"`
-	IsDeprecated      bool           `graphql:"isDeprecated"`
-	DeprecationReason string         `graphql:"deprecationReason"`
+	Name              string                          `graphql:"name"`
+	Desc              *string                         `graphql:"description"`
+	Args              map[string]*internal.InputField `graphql:"-" json:"-"`
+	Type              __Type                          `graphql:"type"`
+	IsDeprecated      bool                            `graphql:"isDeprecated"`
+	DeprecationReason string                          `graphql:"deprecationReason"`
 }
 
 func (s *introspection) registerField(schema *schemabuilder.Schema) {
-	schema.Object("__Field", __Field{}, "")
+	object := schema.Object("__Field", __Field{}, "")
+	object.FieldFunc("args", func(f __Field, args struct {
+		IncludeDeprecated *bool `graphql:"includeDeprecated"`
+	}) []__InputValue {
+		return inputValues(f.Args, args.IncludeDeprecated != nil && *args.IncludeDeprecated)
+	}, "")
 }
 
 // The __InputValue type represents field and directive arguments as well as the inputFields of an input object.
 type __InputValue struct {
-	Name         string  `graphql:"name"`
-	Desc         string  `graphql:"description"`
-	Type         __Type  `graphql:"type"`
-	DefaultValue *string `graphql:"defaultValue"`
+	Name string `graphql:"name"`
+	Desc string `graphql:"description"`
+	Type __Type `graphql:"type"`
+	// DefaultValue is the default value as a GraphQL literal, nil when there is none
+	DefaultValue      *string `graphql:"defaultValue"`
+	IsDeprecated      bool    `graphql:"isDeprecated"`
+	DeprecationReason string  `graphql:"deprecationReason"`
+}
+
+// inputValues returns the input values of fields sorted by name, without the deprecated ones unless
+// includeDeprecated is true.
+func inputValues(fields map[string]*internal.InputField, includeDeprecated bool) []__InputValue {
+	values := make([]__InputValue, 0, len(fields))
+	for name, field := range fields {
+		if field.IsDeprecated && !includeDeprecated {
+			continue
+		}
+		var defaultValue *string
+		if field.DefaultValue != nil {
+			value := formatDefaultValue(field.Type, field.DefaultValue)
+			defaultValue = &value
+		}
+		values = append(values, __InputValue{
+			Name:              name,
+			Desc:              field.Desc,
+			Type:              __Type{OfType: field.Type},
+			DefaultValue:      defaultValue,
+			IsDeprecated:      field.IsDeprecated,
+			DeprecationReason: field.DeprecationReason,
+		})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
 }
 
 func (s *introspection) registerInputValue(schema *schemabuilder.Schema) {
@@ -399,22 +433,31 @@ func (s *introspection) registerEnumValue(schema *schemabuilder.Schema) {
 
 // The __Directive type represents a Directive that a server supports.
 type __Directive struct {
-	Name         string              `graphql:"name"`
-	Desc         string              `graphql:"description"`
-	Locations    []DirectiveLocation `graphql:"locations"`
-	Args         []__InputValue      `graphql:"args"`
-	IsDeprecated bool                `graphql:"isDeprecated"`
+	Name         string                          `graphql:"name"`
+	Desc         string                          `graphql:"description"`
+	Locations    []DirectiveLocation             `graphql:"locations"`
+	Args         map[string]*internal.InputField `graphql:"-" json:"-"`
+	IsDeprecated bool                            `graphql:"isDeprecated"`
+	// IsRepeatable is always false, a directive can only be used once at a location, see the
+	// UniqueDirectivesPerLocation rule.
+	IsRepeatable bool `graphql:"isRepeatable"`
 }
 
 func (s *introspection) registerDirective(schema *schemabuilder.Schema) {
-	schema.Object("__Directive", __Directive{}, "")
-	schema.Enum("__DirectiveLocation ", DirectiveLocation("QUERY"), map[string]DirectiveLocation{
+	object := schema.Object("__Directive", __Directive{}, "")
+	object.FieldFunc("args", func(d __Directive, args struct {
+		IncludeDeprecated *bool `graphql:"includeDeprecated"`
+	}) []__InputValue {
+		return inputValues(d.Args, args.IncludeDeprecated != nil && *args.IncludeDeprecated)
+	}, "")
+	schema.Enum("__DirectiveLocation", DirectiveLocation("QUERY"), map[string]DirectiveLocation{
 		"QUERY":                  Query,
 		"MUTATION":               Mutation,
 		"FIELD":                  Field,
 		"FRAGMENT_DEFINITION":    FragmentDefinition,
 		"FRAGMENT_SPREAD":        FragmentSpread,
 		"INLINE_FRAGMENT":        InlineFragment,
+		"VARIABLE_DEFINITION":    VariableDefinition,
 		"SUBSCRIPTION":           Subscription,
 		"SCHEMA":                 Schema,
 		"SCALAR":                 Scalar,
@@ -563,7 +606,7 @@ func (s *introspection) schema() *internal.Schema {
 }
 
 // AddIntrospectionToSchema adds the introspection fields to existing schema
-func AddIntrospectionToSchema(schema *internal.Schema) {
+func AddIntrospectionToSchema(schema *internal.Schema, opts ...Option) {
 	types := make(map[string]internal.Type)
 	collectTypes(schema.Query, types)
 	collectTypes(schema.Mutation, types)
@@ -571,7 +614,16 @@ func AddIntrospectionToSchema(schema *internal.Schema) {
 	is := &introspection{
 		types: types,
 	}
-	for _, d := range schema.Directives {
+	for _, opt := range opts {
+		opt(is)
+	}
+	names := make([]string, 0, len(schema.Directives))
+	for name := range schema.Directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := schema.Directives[name]
 		is.directives = append(is.directives, __Directive{
 			Name: d.Name,
 			Desc: d.Desc,
@@ -582,59 +634,76 @@ func AddIntrospectionToSchema(schema *internal.Schema) {
 				}
 				return locs
 			}(),
-			Args: func() []__InputValue {
-				inputValues := make([]__InputValue, 0)
-				for _, arguemnt := range d.Args {
-					var defaultValue string
-					if arguemnt.DefaultValue != nil {
-						defaultValue = fmt.Sprintf("%s", defaultValue)
-					}
-					inputValues = append(inputValues, __InputValue{
-						Name:         arguemnt.Name,
-						Desc:         arguemnt.Desc,
-						Type:         __Type{OfType: arguemnt.Type},
-						DefaultValue: &defaultValue,
-					})
-				}
-				return inputValues
-			}(),
+			Args:         d.Args,
 			IsDeprecated: false,
 		})
 	}
 	isSchema := is.schema()
 
-	copyObject(schema.Query, isSchema.Query)
-	schema.Query = isSchema.Query
-	//copyObject(schema.Mutation, isSchema.Mutation)
-	//schema.Mutation = isSchema.Mutation
-	//copyObject(schema.Subscription, isSchema.Subscription)
-	//schema.Subscription = isSchema.Subscription
-
+	// the introspection fields are meta fields, the Query object of schema is left as it is
+	for _, name := range []string{"__schema", "__type"} {
+		field := isSchema.Query.(*internal.Object).Fields[name]
+		schema.AddMetaField(field)
+		// the introspection types are types of the schema as well
+		collectTypes(field.Type, types)
+	}
 	for k, v := range isSchema.TypeMap {
-		schema.TypeMap[k] = v
+		// the builtin scalars the introspection types refer to may not be used by schema
+		_, scalar := v.(*internal.Scalar)
+		if _, ok := schema.TypeMap[k]; strings.HasPrefix(k, "__") || scalar && !ok {
+			schema.TypeMap[k] = v
+		}
 	}
 
 	is.query, is.mutation, is.subscription = schema.Query, schema.Mutation, schema.Subscription
-	//is.types["Mutation"] = schema.Mutation
-	//is.types["Subscription"] = schema.Subscription
 }
 
-func copyObject(s internal.Type, d internal.Type) {
-	if s == nil {
-		return
+// formatDefaultValue returns the default value of an input value of type typ as shown by introspection,
+// with the values of enums by their name rather than their Go value.
+func formatDefaultValue(typ internal.Type, value interface{}) string {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		typ = nonNull.Type
 	}
-	if d == nil {
-		d = &internal.Object{}
+	switch typ := typ.(type) {
+	case *internal.Enum:
+		if name, ok := enumName(typ, value); ok {
+			return name
+		}
+	case *internal.List:
+		list := reflect.ValueOf(value)
+		if list.Kind() == reflect.Slice || list.Kind() == reflect.Array {
+			items := make([]string, list.Len())
+			for i := range items {
+				items[i] = formatDefaultValue(typ.Type, list.Index(i).Interface())
+			}
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// enumName returns the name of the value of enum, which may already be a name.
+func enumName(enum *internal.Enum, value interface{}) (string, bool) {
+	if value == nil || !reflect.TypeOf(value).Comparable() {
+		return "", false
+	}
+	if enum.OutputMapper != nil {
+		if mapped, err := enum.OutputMapper(value); err == nil {
+			value = mapped
+		}
+	}
+	if value == nil || !reflect.TypeOf(value).Comparable() {
+		return "", false
 	}
-	src := s.(*internal.Object)
-	dest := d.(*internal.Object)
-	dest.Name, dest.IsTypeOf, dest.Desc = src.Name, src.IsTypeOf, src.Desc
-	for k, v := range src.Fields {
-		dest.Fields[k] = v
+	if name, ok := enum.Map[value]; ok {
+		return name, true
 	}
-	for k, v := range src.Interfaces {
-		dest.Interfaces[k] = v
+	if name, ok := value.(string); ok {
+		if _, ok := enum.ReverseMap[name]; ok {
+			return name, true
+		}
 	}
+	return "", false
 }
 
 // ComputeSchemaJSON returns the result of executing a GraphQL introspection
@@ -1,9 +1,11 @@
 package introspection
 
-// Copied from https://github.com/graphql/graphiql/blob/master/src/utility/introspectionQueries.js
+// IntrospectionQuery is the query of getIntrospectionQuery of graphql-js with all its options, as sent by
+// GraphiQL and Apollo Sandbox.
 const IntrospectionQuery = `
 query IntrospectionQuery {
 	__schema {
+		description
 		queryType { name }
 		mutationType { name }
 		subscriptionType { name }
@@ -13,8 +15,9 @@ query IntrospectionQuery {
 		directives {
 			name
 			description
+			isRepeatable
 			locations
-			args {
+			args(includeDeprecated: true) {
 				...InputValue
 			}
 		}
@@ -24,10 +27,11 @@ fragment FullType on __Type {
 	kind
 	name
 	description
+	specifiedByURL
 	fields(includeDeprecated: true) {
 		name
 		description
-		args {
+		args(includeDeprecated: true) {
 			...InputValue
 		}
 		type {
@@ -36,7 +40,7 @@ fragment FullType on __Type {
 		isDeprecated
 		deprecationReason
 	}
-	inputFields {
+	inputFields(includeDeprecated: true) {
 		...InputValue
 	}
 	interfaces {
@@ -57,6 +61,8 @@ fragment InputValue on __InputValue {
 	description
 	type { ...TypeRef }
 	defaultValue
+	isDeprecated
+	deprecationReason
 }
 fragment TypeRef on __Type {
 	kind
@@ -0,0 +1,160 @@
+package introspection_test
+
+import (
+	"encoding/json"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type Size int
+
+const (
+	Small Size = iota
+	Medium
+	Large
+)
+
+type SizeArgs struct {
+	Size  Size   `graphql:"size"`
+	Sizes []Size `graphql:"sizes"`
+}
+
+func sizeSchema() *internal.Schema {
+	build := schemabuilder.NewSchema()
+	build.Enum("Size", Size(0), map[string]interface{}{
+		"SMALL":  Small,
+		"MEDIUM": schemabuilder.DescField{Field: Medium, Desc: "the usual size"},
+		"LARGE":  Large,
+	})
+	args := build.InputObject("SizeArgs", SizeArgs{})
+	args.FieldDefault("size", Medium)
+	args.FieldDefault("sizes", []Size{Small, Large})
+	build.Query().FieldFunc("fit", func(args SizeArgs) bool { return true })
+	return build.MustBuild()
+}
+
+// introspect returns the JSON of the result of query on schema.
+func introspect(t *testing.T, schema *internal.Schema, query string) string {
+	data, errs := execution.Do(schema, execution.Params{Query: query})
+	assert.Empty(t, errs)
+	result, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(result)
+}
+
+func TestAddIntrospectionToSchema_Enum(t *testing.T) {
+	schema := sizeSchema()
+	introspection.AddIntrospectionToSchema(schema)
+	// the values are in the order of their Go values, and the default values name them
+	assert.JSONEq(t, `{
+		"__type": {"enumValues": [
+			{"name": "SMALL", "description": ""},
+			{"name": "MEDIUM", "description": "the usual size"},
+			{"name": "LARGE", "description": ""}
+		]},
+		"__schema": {"queryType": {"fields": [{"args": [
+			{"name": "size", "defaultValue": "MEDIUM"},
+			{"name": "sizes", "defaultValue": "[SMALL, LARGE]"}
+		]}]}}
+	}`, introspect(t, schema, `{
+		__type(name: "Size") { enumValues { name description } }
+		__schema { queryType { fields { args { name defaultValue } } } }
+	}`))
+
+	schema = sizeSchema()
+	introspection.AddIntrospectionToSchema(schema, introspection.SortEnumValues())
+	assert.JSONEq(t, `{"__type": {"enumValues": [{"name": "LARGE"}, {"name": "MEDIUM"}, {"name": "SMALL"}]}}`,
+		introspect(t, schema, `{ __type(name: "Size") { enumValues { name } } }`))
+}
+
+func TestAddIntrospectionToSchema_EnumDeprecation(t *testing.T) {
+	schema := schemabuilder.FromSDL(`
+		enum Unit { METER FOOT @deprecated(reason: "use METER") }
+		type Query { unit: Unit }
+	`).MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+	// the values of an enum written in SDL are in the order of the document
+	assert.JSONEq(t, `{"__type": {"enumValues": [
+		{"name": "METER", "isDeprecated": false, "deprecationReason": ""},
+		{"name": "FOOT", "isDeprecated": true, "deprecationReason": "use METER"}
+	]}}`, introspect(t, schema, `{
+		__type(name: "Unit") { enumValues(includeDeprecated: true) { name isDeprecated deprecationReason } }
+	}`))
+}
+
+func TestAddIntrospectionToSchema_Deprecated(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Size", Size(0), map[string]interface{}{"SMALL": Small, "MEDIUM": Medium, "LARGE": Large},
+		schemabuilder.DeprecatedValues(map[string]string{"MEDIUM": "pick SMALL or LARGE"}))
+	build.Query().FieldFunc("size", func() Size { return Small })
+	build.Query().FieldFunc("sized", func() bool { return true }, schemabuilder.Deprecated("use size"))
+	schema := build.MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+
+	// the deprecated fields and values are only listed with includeDeprecated
+	assert.JSONEq(t, `{
+		"__type": {"enumValues": [{"name": "SMALL"}, {"name": "LARGE"}]},
+		"__schema": {"queryType": {"fields": [{"name": "size"}]}}
+	}`, introspect(t, schema, `{
+		__type(name: "Size") { enumValues { name } }
+		__schema { queryType { fields { name } } }
+	}`))
+	assert.JSONEq(t, `{
+		"__type": {"enumValues": [
+			{"name": "SMALL", "isDeprecated": false, "deprecationReason": ""},
+			{"name": "MEDIUM", "isDeprecated": true, "deprecationReason": "pick SMALL or LARGE"},
+			{"name": "LARGE", "isDeprecated": false, "deprecationReason": ""}
+		]},
+		"__schema": {"queryType": {"fields": [
+			{"name": "size", "isDeprecated": false, "deprecationReason": ""},
+			{"name": "sized", "isDeprecated": true, "deprecationReason": "use size"}
+		]}}
+	}`, introspect(t, schema, `{
+		__type(name: "Size") { enumValues(includeDeprecated: true) { name isDeprecated deprecationReason } }
+		__schema { queryType { fields(includeDeprecated: true) { name isDeprecated deprecationReason } } }
+	}`))
+}
+
+func TestAddIntrospectionToSchema_SpecifiedBy(t *testing.T) {
+	schema := schemabuilder.FromSDL(`
+		scalar Time @specifiedBy(url: "https://tools.ietf.org/html/rfc3339")
+		input Range { from: Time, until: Time @deprecated(reason: "use from") }
+		type Query { count(range: Range, unit: String @deprecated): Int }
+	`).MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+
+	// the deprecated arguments and input fields are only listed with includeDeprecated
+	assert.JSONEq(t, `{
+		"time": {"specifiedByURL": "https://tools.ietf.org/html/rfc3339"},
+		"range": {"specifiedByURL": null, "inputFields": [{"name": "from"}]},
+		"__schema": {"queryType": {"fields": [{"args": [{"name": "range"}]}]}}
+	}`, introspect(t, schema, `{
+		time: __type(name: "Time") { specifiedByURL }
+		range: __type(name: "Range") { specifiedByURL inputFields { name } }
+		__schema { queryType { fields { args { name } } } }
+	}`))
+	assert.JSONEq(t, `{
+		"__type": {"inputFields": [
+			{"name": "from", "defaultValue": null, "isDeprecated": false, "deprecationReason": ""},
+			{"name": "until", "defaultValue": null, "isDeprecated": true, "deprecationReason": "use from"}
+		]},
+		"__schema": {"queryType": {"fields": [{"args": [
+			{"name": "range", "isDeprecated": false, "deprecationReason": ""},
+			{"name": "unit", "isDeprecated": true, "deprecationReason": "No longer supported"}
+		]}]}}
+	}`, introspect(t, schema, `{
+		__type(name: "Range") { inputFields(includeDeprecated: true) { name defaultValue isDeprecated deprecationReason } }
+		__schema { queryType { fields { args(includeDeprecated: true) { name isDeprecated deprecationReason } } } }
+	}`))
+
+	// the directives can only be used once at a location, the introspection types are listed
+	data := introspect(t, schema, `{ __schema { directives { name isRepeatable } types { name } } }`)
+	assert.Contains(t, data, `{"isRepeatable":false,"name":"include"}`)
+	assert.Contains(t, data, `{"name":"__Type"}`)
+}
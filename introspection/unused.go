@@ -0,0 +1,35 @@
+package introspection
+
+import (
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"sort"
+	"strings"
+)
+
+// UnusedFields returns the coordinates, as Type.field, of the fields of the objects and interfaces of
+// schema which are not used in usage, sorted. They are the candidates for removal from the schema,
+// as long as usage covers a long enough period of traffic.
+func UnusedFields(schema *internal.Schema, usage execution.UsageSnapshot) []string {
+	var unused []string
+	for name, typ := range schema.TypeMap {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		var fields map[string]*internal.Field
+		switch typ := typ.(type) {
+		case *internal.Object:
+			fields = typ.Fields
+		case *internal.Interface:
+			fields = typ.Fields
+		}
+		for field := range fields {
+			coordinate := name + "." + field
+			if _, ok := usage[coordinate]; !ok && !strings.HasPrefix(field, "__") {
+				unused = append(unused, coordinate)
+			}
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"github.com/shyptr/graphql"
 	"net"
 	"net/http/httputil"
@@ -33,7 +34,7 @@ func Recovery() graphql.HandlerFunc {
 				if brokenPipe {
 					logger.Printf("error:%v request %s\n", err, httpRequest)
 				} else {
-					logger.Printf("error:%v [Recovery] %s panic recovered. %s\n", err, strings.Join(headers, "\r\n"))
+					logger.Printf("error:%v [Recovery] panic recovered. %s\n", err, strings.Join(headers, "\r\n"))
 				}
 			}
 		}()
@@ -54,8 +55,15 @@ func Logger() graphql.HandlerFunc {
 			if operationName == "" {
 				operationName = "query"
 			}
-			logger.Printf("status %d | latencyTime %d | ip %s | method %s | operationName %s", statusCode, time.Now().Sub(startTime), clientIP,
+			line := fmt.Sprintf("status %d | latencyTime %d | ip %s | method %s | operationName %s", statusCode, time.Now().Sub(startTime), clientIP,
 				reqMethod, operationName)
+			if ctx.SchemaHash != "" {
+				line += " | schema " + ctx.SchemaHash
+			}
+			if ctx.APIVersion != "" {
+				line += " | version " + ctx.APIVersion
+			}
+			logger.Print(line)
 		}()
 		ctx.Next()
 	}
@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"strings"
+	"sync"
+)
+
+// QueryCache stores the queries of automatic persisted queries by the hex encoded sha256 of their text,
+// see WithPersistedQueries. A QueryCache is used concurrently.
+type QueryCache interface {
+	Get(hash string) (string, bool)
+	Set(hash, query string)
+}
+
+// WithPersistedQueries makes the handler serve automatic persisted queries, as Apollo clients send
+// them: a request with the extension {"persistedQuery": {"version": 1, "sha256Hash": "..."}} and no
+// query runs the query cache holds under the hash, or fails with PersistedQueryNotFound so that the
+// client sends it again with the query, which is stored once its hash is checked.
+//
+//   graphql.HTTPHandler(schema, graphql.WithPersistedQueries(graphql.NewQueryCache(1000)))
+func WithPersistedQueries(cache QueryCache) HandlerOption {
+	return func(h *Handler) {
+		h.PersistedQueries = cache
+	}
+}
+
+// NewQueryCache returns a QueryCache keeping the size most recently used queries, which counts its
+// lookups, see execution.StatsCache.
+func NewQueryCache(size int) QueryCache {
+	return &lruQueryCache{size: size, queries: list.New(), hashes: map[string]*list.Element{}}
+}
+
+type lruQueryCache struct {
+	mu      sync.Mutex
+	size    int
+	queries *list.List
+	hashes  map[string]*list.Element
+	stats   execution.CacheStats
+}
+
+type queryEntry struct {
+	hash, query string
+}
+
+func (c *lruQueryCache) Get(hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.hashes[hash]; ok {
+		c.queries.MoveToFront(elem)
+		c.stats.Hits++
+		return elem.Value.(*queryEntry).query, true
+	}
+	c.stats.Misses++
+	return "", false
+}
+
+func (c *lruQueryCache) Set(hash, query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.hashes[hash]; ok {
+		elem.Value.(*queryEntry).query = query
+		c.queries.MoveToFront(elem)
+		return
+	}
+	c.hashes[hash] = c.queries.PushFront(&queryEntry{hash: hash, query: query})
+	for c.queries.Len() > c.size {
+		oldest := c.queries.Back()
+		c.queries.Remove(oldest)
+		delete(c.hashes, oldest.Value.(*queryEntry).hash)
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruQueryCache) Stats() execution.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.queries.Len()
+	return stats
+}
+
+// persistedQuery completes param with its persisted query, if it has the persistedQuery extension:
+// the query of a request with the hash alone is looked up in cache, that of a request with both is
+// stored in cache once the hash is checked.
+func persistedQuery(cache QueryCache, param *execution.Params) *errors.GraphQLError {
+	extension, ok := param.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if version := fmt.Sprint(extension["version"]); version != "1" {
+		return &errors.GraphQLError{
+			Message:    "PersistedQueryNotSupported",
+			Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_SUPPORTED"},
+		}
+	}
+	hash, _ := extension["sha256Hash"].(string)
+	if hash == "" {
+		return errors.New("the persistedQuery extension must have a sha256Hash")
+	}
+	hash = strings.ToLower(hash)
+	if param.Query == "" {
+		query, ok := cache.Get(hash)
+		if !ok {
+			return &errors.GraphQLError{
+				Message:    "PersistedQueryNotFound",
+				Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"},
+			}
+		}
+		param.Query = query
+		return nil
+	}
+	sum := sha256.Sum256([]byte(param.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return errors.New("provided sha does not match query")
+	}
+	cache.Set(hash, param.Query)
+	return nil
+}
@@ -0,0 +1,72 @@
+package graphql_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler_PersistedQueries(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	calls := 0
+	build.Query().FieldFunc("hello", func() string {
+		calls++
+		return "world"
+	})
+	handler := graphql.HTTPHandler(build.MustBuild(), graphql.WithPersistedQueries(graphql.NewQueryCache(10)))
+	post := func(body string) string {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+	const query = "{ hello }"
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	extensions := fmt.Sprintf(`{"persistedQuery": {"version": 1, "sha256Hash": %q}}`, hash)
+
+	// an unknown hash asks the client for the query, which is stored with its hash
+	assert.JSONEq(t, `{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`,
+		post(fmt.Sprintf(`{"extensions": %s}`, extensions)))
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, post(fmt.Sprintf(`{"query": %q, "extensions": %s}`, query, extensions)))
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, post(fmt.Sprintf(`{"extensions": %s}`, extensions)))
+	assert.Equal(t, 2, calls)
+
+	// a query not matching its hash is neither run nor stored
+	wrong := `{"persistedQuery": {"version": 1, "sha256Hash": "0000"}}`
+	assert.JSONEq(t, `{"errors": [{"message": "provided sha does not match query"}]}`,
+		post(fmt.Sprintf(`{"query": %q, "extensions": %s}`, query, wrong)))
+	assert.Contains(t, post(fmt.Sprintf(`{"extensions": %s}`, wrong)), "PersistedQueryNotFound")
+	assert.Equal(t, 2, calls)
+
+	assert.Contains(t, post(`{"extensions": {"persistedQuery": {"version": 2, "sha256Hash": "0000"}}}`), "PERSISTED_QUERY_NOT_SUPPORTED")
+
+	// GET requests give the hash in the extensions parameter
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{"extensions": {extensions}}.Encode(), nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"hello": "world"}}`, recorder.Body.String())
+}
+
+func TestQueryCache(t *testing.T) {
+	cache := graphql.NewQueryCache(2)
+	cache.Set("a", "{ a }")
+	cache.Set("b", "{ b }")
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	// the least recently used query makes room
+	cache.Set("c", "{ c }")
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	query, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "{ a }", query)
+}
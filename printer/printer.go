@@ -0,0 +1,416 @@
+// Package printer prints a built schema in the GraphQL schema definition language.
+//
+// The output is stable: types are grouped by kind and every group, as well as the fields,
+// arguments and enum values of a type, is sorted by name, so printing the same schema twice
+// gives the same text and schemas can be diffed or composed file by file.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/internal"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Options control what is printed.
+type Options struct {
+	// IncludeDescriptions prints the descriptions of types, fields, arguments and enum values.
+	IncludeDescriptions bool
+	// BlockDescriptions prints every description as a block string, as graphql-js does, instead of
+	// printing the descriptions of a single line as strings.
+	BlockDescriptions bool
+	// IncludeBuiltinScalars prints the scalars defined by the GraphQL specification:
+	// Int, Float, String, Boolean and ID.
+	IncludeBuiltinScalars bool
+	// DirectiveFilter reports whether the definition of the directive name is printed.
+	// When nil, every directive but the builtin include and skip is printed.
+	DirectiveFilter func(name string) bool
+	// IncludeMetaFields prints the meta fields of the schema in its query root type, but for the
+	// introspection fields.
+	IncludeMetaFields bool
+}
+
+// DefaultOptions are used by Print and WriteSplit.
+var DefaultOptions = Options{IncludeDescriptions: true}
+
+var builtinScalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+
+var builtinDirectives = map[string]bool{"include": true, "skip": true}
+
+// Print prints schema with DefaultOptions.
+func Print(schema *internal.Schema) string {
+	return DefaultOptions.Print(schema)
+}
+
+// Print prints schema as a single document.
+func (o Options) Print(schema *internal.Schema) string {
+	var parts []string
+	for _, group := range o.groups(schema) {
+		for _, file := range group.files {
+			parts = append(parts, file.content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// file is the printed definitions of a group, or of a single type of a group.
+type file struct {
+	name    string
+	content string
+}
+
+// group is a kind of definitions, printed in one file or in a directory with a file per type.
+type group struct {
+	name  string
+	dir   bool
+	files []file
+}
+
+// groups prints the definitions of schema, in the order they are printed in a single document.
+func (o Options) groups(schema *internal.Schema) []group {
+	roots := map[string]bool{}
+	var rootTypes []*internal.Object
+	var schemaDef bytes.Buffer
+	for _, root := range []struct {
+		operation string
+		typ       internal.Type
+	}{{"query", schema.Query}, {"mutation", schema.Mutation}, {"subscription", schema.Subscription}} {
+		typ := root.typ
+		if nonNull, ok := typ.(*internal.NonNull); ok {
+			typ = nonNull.Type
+		}
+		object, ok := typ.(*internal.Object)
+		if ok && root.operation == "query" && o.IncludeMetaFields && len(schema.MetaFields) > 0 {
+			object = withMetaFields(object, schema.MetaFields)
+		}
+		// roots without fields, like the Mutation of a schema defining none, are not printed
+		if !ok || len(printableFields(object.Fields)) == 0 {
+			continue
+		}
+		roots[object.Name] = true
+		rootTypes = append(rootTypes, object)
+		fmt.Fprintf(&schemaDef, "  %s: %s\n", root.operation, object.Name)
+	}
+
+	var scalars, enums, inputs, interfaces, unions, objects []internal.NamedType
+	for _, name := range sortedKeys(schema.TypeMap) {
+		typ := schema.TypeMap[name]
+		if strings.HasPrefix(name, "__") || roots[name] {
+			continue
+		}
+		switch typ.(type) {
+		case *internal.Scalar:
+			if o.IncludeBuiltinScalars || !builtinScalars[name] {
+				scalars = append(scalars, typ)
+			}
+		case *internal.Enum:
+			enums = append(enums, typ)
+		case *internal.InputObject:
+			inputs = append(inputs, typ)
+		case *internal.Interface:
+			interfaces = append(interfaces, typ)
+		case *internal.Union:
+			unions = append(unions, typ)
+		case *internal.Object:
+			objects = append(objects, typ)
+		}
+	}
+
+	var directives bytes.Buffer
+	for _, name := range sortedKeys(schema.Directives) {
+		if o.DirectiveFilter == nil && builtinDirectives[name] || o.DirectiveFilter != nil && !o.DirectiveFilter(name) {
+			continue
+		}
+		if directives.Len() > 0 {
+			directives.WriteByte('\n')
+		}
+		o.printDirective(&directives, schema.Directives[name])
+	}
+
+	var groups []group
+	single := func(name string, content string) {
+		if content != "" {
+			groups = append(groups, group{name: name, files: []file{{name: name, content: content}}})
+		}
+	}
+	single("directives.graphql", directives.String())
+	single("scalars.graphql", o.printTypes(scalars))
+	single("enums.graphql", o.printTypes(enums))
+	single("inputs.graphql", o.printTypes(inputs))
+	single("interfaces.graphql", o.printTypes(interfaces))
+	single("unions.graphql", o.printTypes(unions))
+	if len(objects) > 0 {
+		objectGroup := group{name: "objects", dir: true}
+		for _, object := range objects {
+			objectGroup.files = append(objectGroup.files, file{name: object.TypeName() + ".graphql", content: o.printTypes([]internal.NamedType{object})})
+		}
+		groups = append(groups, objectGroup)
+	}
+	if len(rootTypes) > 0 {
+		var buf bytes.Buffer
+		// the schema definition is only needed when the roots are not named by convention
+		for _, object := range rootTypes {
+			if object.Name != "Query" && object.Name != "Mutation" && object.Name != "Subscription" {
+				fmt.Fprintf(&buf, "schema {\n%s}\n\n", schemaDef.String())
+				break
+			}
+		}
+		for i, object := range rootTypes {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			o.printType(&buf, object)
+		}
+		single("roots.graphql", buf.String())
+	}
+	return groups
+}
+
+func (o Options) printTypes(types []internal.NamedType) string {
+	var buf bytes.Buffer
+	for i, typ := range types {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		o.printType(&buf, typ)
+	}
+	return buf.String()
+}
+
+func (o Options) printType(buf *bytes.Buffer, typ internal.NamedType) {
+	o.printDescription(buf, "", typ.Description())
+	switch typ := typ.(type) {
+	case *internal.Scalar:
+		fmt.Fprintf(buf, "scalar %s", typ.Name)
+		if typ.SpecifiedByURL != "" {
+			buf.WriteString(" @specifiedBy(url: " + ast.Quote(typ.SpecifiedByURL) + ")")
+		}
+		buf.WriteByte('\n')
+	case *internal.Enum:
+		values := make([]string, len(typ.Values))
+		copy(values, typ.Values)
+		sort.Strings(values)
+		fmt.Fprintf(buf, "enum %s {\n", typ.Name)
+		for _, value := range values {
+			o.printDescription(buf, "  ", typ.ValuesDesc[value])
+			buf.WriteString("  " + value)
+			if reason, ok := typ.Deprecated[value]; ok {
+				printDeprecated(buf, reason)
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("}\n")
+	case *internal.InputObject:
+		fmt.Fprintf(buf, "input %s {\n", typ.Name)
+		for _, name := range sortedKeys(typ.Fields) {
+			o.printDescription(buf, "  ", typ.Fields[name].Desc)
+			buf.WriteString("  ")
+			printInputValue(buf, typ.Fields[name])
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("}\n")
+	case *internal.Interface:
+		fmt.Fprintf(buf, "interface %s%s {\n", typ.Name, implements(typ.Interfaces))
+		o.printFields(buf, typ.Fields)
+		buf.WriteString("}\n")
+	case *internal.Union:
+		fmt.Fprintf(buf, "union %s = %s\n", typ.Name, strings.Join(sortedKeys(typ.Types), " | "))
+	case *internal.Object:
+		fmt.Fprintf(buf, "type %s%s {\n", typ.Name, implements(typ.Interfaces))
+		o.printFields(buf, typ.Fields)
+		buf.WriteString("}\n")
+	}
+}
+
+func implements(interfaces map[string]*internal.Interface) string {
+	if len(interfaces) == 0 {
+		return ""
+	}
+	return " implements " + strings.Join(sortedKeys(interfaces), " & ")
+}
+
+// withMetaFields returns a copy of object with metaFields added to its fields.
+func withMetaFields(object *internal.Object, metaFields map[string]*internal.Field) *internal.Object {
+	merged := *object
+	merged.Fields = make(map[string]*internal.Field, len(object.Fields)+len(metaFields))
+	for name, field := range metaFields {
+		merged.Fields[name] = field
+	}
+	for name, field := range object.Fields {
+		merged.Fields[name] = field
+	}
+	return &merged
+}
+
+// printableFields returns the names of fields in order, leaving out the introspection fields.
+func printableFields(fields map[string]*internal.Field) []string {
+	var names []string
+	for _, name := range sortedKeys(fields) {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (o Options) printFields(buf *bytes.Buffer, fields map[string]*internal.Field) {
+	for _, name := range printableFields(fields) {
+		field := fields[name]
+		o.printDescription(buf, "  ", field.Desc)
+		buf.WriteString("  " + name)
+		o.printArgs(buf, "  ", field.Args)
+		buf.WriteString(": " + field.Type.String())
+		if field.IsDeprecated {
+			printDeprecated(buf, field.DeprecationReason)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// printArgs prints args on one line, or one per line when any of them has a description to print.
+func (o Options) printArgs(buf *bytes.Buffer, indent string, args map[string]*internal.InputField) {
+	if len(args) == 0 {
+		return
+	}
+	names := sortedKeys(args)
+	multiline := false
+	for _, name := range names {
+		if o.IncludeDescriptions && args[name].Desc != "" {
+			multiline = true
+		}
+	}
+	buf.WriteByte('(')
+	for i, name := range names {
+		if multiline {
+			buf.WriteByte('\n')
+			o.printDescription(buf, indent+"  ", args[name].Desc)
+			buf.WriteString(indent + "  ")
+		} else if i > 0 {
+			buf.WriteString(", ")
+		}
+		printInputValue(buf, args[name])
+	}
+	if multiline {
+		buf.WriteString("\n" + indent)
+	}
+	buf.WriteByte(')')
+}
+
+func (o Options) printDirective(buf *bytes.Buffer, directive *internal.Directive) {
+	o.printDescription(buf, "", directive.Desc)
+	buf.WriteString("directive @" + directive.Name)
+	o.printArgs(buf, "", directive.Args)
+	buf.WriteString(" on " + strings.Join(directive.Locs, " | ") + "\n")
+}
+
+func printInputValue(buf *bytes.Buffer, value *internal.InputField) {
+	buf.WriteString(value.Name + ": " + value.Type.String())
+	if value.DefaultValue != nil {
+		buf.WriteString(" = " + printValue(value.Type, reflect.ValueOf(value.DefaultValue)))
+	}
+	if value.IsDeprecated {
+		printDeprecated(buf, value.DeprecationReason)
+	}
+}
+
+func printDeprecated(buf *bytes.Buffer, reason string) {
+	buf.WriteString(" @deprecated")
+	if reason != "" {
+		buf.WriteString("(reason: " + ast.Quote(reason) + ")")
+	}
+}
+
+// printValue prints the default value v of an input of type typ as a GraphQL literal.
+func printValue(typ internal.Type, v reflect.Value) string {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		typ = nonNull.Type
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	if enum, ok := typ.(*internal.Enum); ok && v.CanInterface() {
+		if name, ok := enum.Map[v.Interface()]; ok {
+			return name
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return ast.Quote(v.String())
+	case reflect.Slice, reflect.Array:
+		var item internal.Type
+		if list, ok := typ.(*internal.List); ok {
+			item = list.Type
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = printValue(item, v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		var fields map[string]*internal.InputField
+		if input, ok := typ.(*internal.InputObject); ok {
+			fields = input.Fields
+		}
+		keys := make([]string, 0, v.Len())
+		values := map[string]reflect.Value{}
+		for _, key := range v.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			keys = append(keys, name)
+			values[name] = v.MapIndex(key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			var field internal.Type
+			if f, ok := fields[key]; ok {
+				field = f.Type
+			}
+			items[i] = key + ": " + printValue(field, values[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// printDescription prints desc above a definition indented by indent.
+func (o Options) printDescription(buf *bytes.Buffer, indent string, desc string) {
+	if !o.IncludeDescriptions || desc == "" {
+		return
+	}
+	if !strings.Contains(desc, "\n") {
+		if !o.BlockDescriptions {
+			buf.WriteString(indent + ast.Quote(desc) + "\n")
+			return
+		}
+		// a quote or backslash ending the description would be read with the closing quotes
+		if !strings.HasSuffix(desc, `"`) && !strings.HasSuffix(desc, `\`) {
+			buf.WriteString(indent + `"""` + strings.Replace(desc, `"""`, `\"""`, -1) + `"""` + "\n")
+			return
+		}
+	}
+	buf.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(strings.Replace(desc, `"""`, `\"""`, -1), "\n") {
+		if line != "" {
+			buf.WriteString(indent + line)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(indent + `"""` + "\n")
+}
+
+// sortedKeys returns the keys of m, a map keyed by strings, in order.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
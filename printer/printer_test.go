@@ -0,0 +1,180 @@
+package printer_test
+
+import (
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/printer"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Role int
+
+type User struct {
+	Name string `graphql:"name"`
+	Role Role   `graphql:"role"`
+}
+
+type Team struct {
+	Name string `graphql:"name"`
+}
+
+type UserFilter struct {
+	Name string `graphql:"name"`
+	Role *Role  `graphql:"role"`
+}
+
+func buildSchema(t *testing.T, withTeam bool) *internal.Schema {
+	build := schemabuilder.NewSchema()
+	build.Enum("Role", Role(0), map[string]interface{}{"USER": Role(0), "ADMIN": Role(1)}, "user role")
+	build.Object("User", User{}, "a user")
+	build.InputObject("UserFilter", UserFilter{})
+	build.Query().FieldFunc("users", func(args struct {
+		Filter *UserFilter `graphql:"filter"`
+	}) []User {
+		return nil
+	}, "lists the users")
+	build.Query().FieldFunc("now", func() time.Time { return time.Time{} })
+	if withTeam {
+		build.Object("Team", Team{})
+		build.Query().FieldFunc("teams", func() []Team { return nil })
+	}
+	schema, err := build.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestPrint(t *testing.T) {
+	schema := buildSchema(t, false)
+	assert.Equal(t, `"time type"
+scalar Time
+
+"user role"
+enum Role {
+  ADMIN
+  USER
+}
+
+input UserFilter {
+  name: String!
+  role: Role
+}
+
+"a user"
+type User {
+  name: String!
+  role: Role!
+}
+
+type Query {
+  now: Time!
+  "lists the users"
+  users(filter: UserFilter): [User!]
+}
+`, printer.Print(schema))
+
+	printed := printer.Options{IncludeDescriptions: true, BlockDescriptions: true}.Print(schema)
+	assert.Contains(t, printed, "\"\"\"user role\"\"\"\nenum Role {\n")
+	assert.Contains(t, printed, "  \"\"\"lists the users\"\"\"\n  users(filter: UserFilter): [User!]\n")
+
+	opts := printer.Options{IncludeBuiltinScalars: true}
+	printed = opts.Print(schema)
+	assert.Contains(t, printed, "scalar String\n")
+	assert.NotContains(t, printed, "user role")
+	assert.NotContains(t, printed, "directive @include")
+
+	opts.DirectiveFilter = func(name string) bool { return name == "include" }
+	printed = opts.Print(schema)
+	assert.Contains(t, printed, "directive @include(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT\n")
+	assert.NotContains(t, printed, "directive @skip")
+
+	health := schemabuilder.NewSchema()
+	health.Query().FieldFunc("_health", func() string { return "ok" })
+	schema.AddMetaField(health.MustBuild().Query.(*internal.Object).Fields["_health"])
+	introspection.AddIntrospectionToSchema(schema)
+	assert.NotContains(t, printer.Print(schema), "_health")
+	printed = printer.Options{IncludeMetaFields: true}.Print(schema)
+	assert.Contains(t, printed, "type Query {\n  _health: String!\n  now: Time!\n")
+	assert.NotContains(t, printed, "__schema")
+
+	specified := schemabuilder.FromSDL(`
+		scalar Time @specifiedBy(url: "https://tools.ietf.org/html/rfc3339")
+		type Query { now: Time }
+	`).MustBuild()
+	assert.Contains(t, printer.Print(specified), "scalar Time @specifiedBy(url: \"https://tools.ietf.org/html/rfc3339\")\n")
+}
+
+func TestWriteSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "printer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := func() map[string]string {
+		contents := map[string]string{}
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				content, _ := ioutil.ReadFile(path)
+				rel, _ := filepath.Rel(dir, path)
+				contents[filepath.ToSlash(rel)] = string(content)
+			}
+			return err
+		})
+		return contents
+	}
+	names := func(contents map[string]string) []string {
+		var names []string
+		for name := range contents {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	schema := buildSchema(t, true)
+	assert.NoError(t, printer.WriteSplit(dir, schema))
+	first := files()
+	assert.Equal(t, []string{
+		"enums.graphql",
+		"index.graphql",
+		"inputs.graphql",
+		"objects/Team.graphql",
+		"objects/User.graphql",
+		"roots.graphql",
+		"scalars.graphql",
+	}, names(first))
+	assert.Equal(t, "type Team {\n  name: String!\n}\n", first["objects/Team.graphql"])
+
+	var concatenated []string
+	for _, name := range []string{"scalars.graphql", "enums.graphql", "inputs.graphql", "objects/Team.graphql", "objects/User.graphql", "roots.graphql"} {
+		assert.Contains(t, first["index.graphql"], "# "+name+"\n")
+		concatenated = append(concatenated, first[name])
+	}
+	assert.Equal(t, printer.Print(schema), strings.Join(concatenated, "\n"))
+
+	// a second run rewrites nothing
+	info, err := os.Stat(filepath.Join(dir, "roots.graphql"))
+	assert.NoError(t, err)
+	past := info.ModTime().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "roots.graphql"), past, past))
+	assert.NoError(t, printer.WriteSplit(dir, buildSchema(t, true)))
+	assert.Equal(t, first, files())
+	info, err = os.Stat(filepath.Join(dir, "roots.graphql"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	// removed types lose their files
+	assert.NoError(t, printer.WriteSplit(dir, buildSchema(t, false)))
+	assert.NotContains(t, files(), "objects/Team.graphql")
+	assert.Contains(t, files(), "objects/User.graphql")
+}
@@ -0,0 +1,105 @@
+package printer
+
+import (
+	"bytes"
+	"github.com/shyptr/graphql/internal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IndexFile lists the files written by WriteSplit, in the order they are printed in a single document.
+const IndexFile = "index.graphql"
+
+// groupFiles are the files WriteSplit may write for a group printed in a single file.
+var groupFiles = []string{
+	"directives.graphql",
+	"scalars.graphql",
+	"enums.graphql",
+	"inputs.graphql",
+	"interfaces.graphql",
+	"unions.graphql",
+	"roots.graphql",
+}
+
+// WriteSplit writes schema to dir with DefaultOptions.
+func WriteSplit(dir string, schema *internal.Schema) error {
+	return DefaultOptions.WriteSplit(dir, schema)
+}
+
+// WriteSplit writes schema to dir, a file per kind of definitions and a file per object type:
+//
+//	directives.graphql
+//	scalars.graphql
+//	enums.graphql
+//	inputs.graphql
+//	interfaces.graphql
+//	unions.graphql
+//	objects/<Name>.graphql
+//	roots.graphql
+//
+// Kinds without definitions have no file. IndexFile lists the files which were written.
+// Files are only written when their content changes, and the files of definitions which
+// are no longer in schema are removed, so writing the same schema again changes nothing.
+func (o Options) WriteSplit(dir string, schema *internal.Schema) error {
+	written := map[string]bool{}
+	var index bytes.Buffer
+	index.WriteString("# Generated, concatenating the files in this order gives the whole schema.\n")
+	for _, group := range o.groups(schema) {
+		for _, file := range group.files {
+			name := file.name
+			if group.dir {
+				name = group.name + "/" + file.name
+			}
+			if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), file.content); err != nil {
+				return err
+			}
+			written[name] = true
+			index.WriteString("# " + name + "\n")
+		}
+	}
+	if err := writeFile(filepath.Join(dir, IndexFile), index.String()); err != nil {
+		return err
+	}
+
+	for _, name := range groupFiles {
+		if err := removeStale(filepath.Join(dir, name), written[name]); err != nil {
+			return err
+		}
+	}
+	objects, err := ioutil.ReadDir(filepath.Join(dir, "objects"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, object := range objects {
+		if object.IsDir() || !strings.HasSuffix(object.Name(), ".graphql") {
+			continue
+		}
+		if err := removeStale(filepath.Join(dir, "objects", object.Name()), written["objects/"+object.Name()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes content to path, unless path already has that content.
+func writeFile(path string, content string) error {
+	if existing, err := ioutil.ReadFile(path); err == nil && string(existing) == content {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(content), 0644)
+}
+
+func removeStale(path string, written bool) error {
+	if written {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package graphql
+
+import (
+	"context"
+	"github.com/shyptr/graphql/internal"
+)
+
+// ErrReadOnly is returned by AssertWritable while a read-only operation executes.
+var ErrReadOnly = internal.ErrReadOnly
+
+// AssertWritable returns ErrReadOnly when the operation executed with ctx is read-only, as query
+// operations are, and nil otherwise. Resolvers and the storage layers they call check it before
+// writing, so that a query routed to a read replica never writes by accident:
+//
+//   func (s *Store) SaveUser(ctx context.Context, user *User) error {
+//     if err := graphql.AssertWritable(ctx); err != nil {
+//       return err
+//     }
+//     ...
+//   }
+func AssertWritable(ctx context.Context) error {
+	if internal.IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -10,16 +10,6 @@ import (
 	"testing"
 )
 
-func catch(f func()) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
-		}
-	}()
-	f()
-	return
-}
-
 type Scalar struct {
 	Value string
 }
@@ -65,9 +55,11 @@ func TestScalar(t *testing.T) {
 
 	t.Run("register a Scalar with non-parser", func(t *testing.T) {
 		Init()
-		assert.EqualError(t, catch(func() {
-			builder.Scalar("SomeScalar", ScalarNonParse{}, "")
-		}), "either UnmarshalFunc should be provided or the provided type should implement json.Unmarshaler interface")
+		builder.Scalar("NonParseScalar", ScalarNonParse{}, "")
+		_, err := builder.Build()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "scalar NonParseScalar: either UnmarshalFunc should be provided or the provided type should implement json.Unmarshaler interface")
+		}
 	})
 
 	t.Run("accepts a Scalar type giving parser", func(t *testing.T) {
@@ -0,0 +1,209 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"go/ast"
+	"reflect"
+	"unicode"
+)
+
+// WithEnum registers an enum when the schema is created, see Schema.Enum.
+func WithEnum(name string, val interface{}, enum interface{}, options ...interface{}) SchemaOption {
+	return func(s *Schema) {
+		s.Enum(name, val, enum, options...)
+	}
+}
+
+// WithScalar registers a scalar when the schema is created, see Schema.Scalar.
+func WithScalar(name string, tp interface{}, options ...interface{}) SchemaOption {
+	return func(s *Schema) {
+		s.Scalar(name, tp, options...)
+	}
+}
+
+// AutoSchema builds a whole schema from root, a struct whose fields named Query, Mutation and
+// Subscription hold the values of the root objects:
+//
+//   schema, err := AutoSchema(struct {
+//     Query    MyQuery
+//     Mutation MyMutation
+//   }{Query: MyQuery{db}, Mutation: MyMutation{db}})
+//
+// The exported methods of a root value become root fields named after the method, starting with
+// a lower case letter, and its exported fields become root fields named like the fields of objects.
+// The struct types reachable from the root fields are registered as objects named after their type,
+// and the struct types of arguments as input objects. Enums and scalars other than the builtin ones
+// are registered with options, usually WithEnum and WithScalar.
+//
+// Interfaces and unions can not be told from other types, a root field reaching one is an error
+// naming the path to the field. Prototypes which outgrow AutoSchema switch to registering
+// their types by hand, which builds the same schema.
+func AutoSchema(root interface{}, opts ...SchemaOption) (*internal.Schema, error) {
+	s := NewSchema(opts...)
+	a := &autoSchema{
+		schema:  s,
+		inputs:  map[reflect.Type]bool{},
+		outputs: map[reflect.Type]bool{},
+	}
+	value := reflect.ValueOf(root)
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("root must be a struct, not %s", value.Type())
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		var object *Object
+		switch field.Name {
+		case "Query":
+			object = s.Query()
+		case "Mutation":
+			object = s.Mutation()
+		case "Subscription":
+			object = s.Subscription()
+		default:
+			s.fail(field.Name, "root fields must be named Query, Mutation or Subscription")
+			continue
+		}
+		a.rootFields(object, value.Field(i), field.Name)
+	}
+	return s.Build()
+}
+
+// autoSchema registers the types of the fields of an AutoSchema.
+type autoSchema struct {
+	schema *Schema
+	// inputs and outputs are the struct types registered as input objects and objects
+	inputs  map[reflect.Type]bool
+	outputs map[reflect.Type]bool
+}
+
+// rootFields registers the exported fields and methods of value as fields of object.
+func (a *autoSchema) rootFields(object *Object, value reflect.Value, path string) {
+	typ := value.Type()
+	if typ.Kind() == reflect.Struct {
+		for i := 0; i < typ.NumField(); i++ {
+			skip, _, _, name, desc := parseFieldTag(typ.Field(i))
+			if skip {
+				continue
+			}
+			field := value.Field(i)
+			fn := reflect.MakeFunc(reflect.FuncOf(nil, []reflect.Type{field.Type()}, false), func([]reflect.Value) []reflect.Value {
+				return []reflect.Value{field}
+			})
+			object.FieldFunc(name, fn.Interface(), desc)
+			a.register(field.Type(), false, path+"."+name)
+		}
+	}
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if !ast.IsExported(method.Name) {
+			continue
+		}
+		name := lowerFirst(method.Name)
+		fn := value.Method(i)
+		object.FieldFunc(name, fn.Interface())
+		for j := 0; j < fn.Type().NumIn(); j++ {
+			if in := fn.Type().In(j); in != contextType {
+				a.arguments(in, path+"."+name)
+			}
+		}
+		if fn.Type().NumOut() > 0 && fn.Type().Out(0) != errType {
+			a.register(fn.Type().Out(0), false, path+"."+name)
+		}
+	}
+}
+
+// arguments registers the types of the fields of typ, the arguments of a root field.
+func (a *autoSchema) arguments(typ reflect.Type, path string) {
+	if typ.Kind() != reflect.Struct {
+		// the error is reported by Build
+		return
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if skip, _, _, name, _ := parseFieldTag(typ.Field(i)); !skip {
+			a.register(typ.Field(i).Type, true, path+"."+name)
+		}
+	}
+}
+
+// register registers the type of the value at path, and the types it contains.
+func (a *autoSchema) register(typ reflect.Type, input bool, path string) {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice && typ != reflect.TypeOf([]byte(nil)) {
+		typ = typ.Elem()
+	}
+	if a.known(typ) {
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		a.schema.fail(path, "interface %s can not be registered automatically, register it with Schema.Interface", typ)
+		return
+	case reflect.Struct:
+	default:
+		a.schema.fail(path, "%s is neither a builtin scalar nor a registered enum or scalar", typ)
+		return
+	}
+	if typ.Name() == "" {
+		a.schema.fail(path, "struct types of fields must be named")
+		return
+	}
+
+	registered, other := a.outputs, a.inputs
+	if input {
+		registered, other = a.inputs, a.outputs
+	}
+	if other[typ] {
+		a.schema.fail(path, "%s can not be both an object and an input object", typ)
+		return
+	}
+	if registered[typ] {
+		return
+	}
+	registered[typ] = true
+	if input {
+		a.schema.InputObject(typ.Name(), reflect.New(typ).Elem().Interface())
+	} else {
+		a.schema.Object(typ.Name(), reflect.New(typ).Elem().Interface())
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if skip, _, _, name, _ := parseFieldTag(typ.Field(i)); !skip {
+			a.register(typ.Field(i).Type, input, path+"."+name)
+		}
+	}
+}
+
+// known reports whether typ is a scalar or an enum of the schema.
+func (a *autoSchema) known(typ reflect.Type) bool {
+	// the any type is mapped to AnyScalar
+	if typ.Kind() == reflect.Interface && typ.NumMethod() == 0 {
+		return true
+	}
+	// maps with string keys are JSON objects
+	if typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String {
+		return true
+	}
+	for _, scalar := range a.schema.scalars {
+		if reflect.TypeOf(scalar.Type) == typ {
+			return true
+		}
+	}
+	for _, enum := range a.schema.enums {
+		if reflect.TypeOf(enum.Type) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerFirst lowers the leading upper case letters of name, but the last one when it starts a word:
+// Users is users, ID is id and HTTPStatus is httpStatus.
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
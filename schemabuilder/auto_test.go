@@ -0,0 +1,117 @@
+package schemabuilder_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/printer"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type AutoRole int
+
+type AutoAddress struct {
+	City string `graphql:"city"`
+}
+
+type AutoUser struct {
+	Name    string       `graphql:"name"`
+	Role    AutoRole     `graphql:"role"`
+	Address *AutoAddress `graphql:"address"`
+	Tags    []string     `graphql:"tags"`
+}
+
+type AutoUserFilter struct {
+	Role *AutoRole `graphql:"role"`
+}
+
+type AutoQuery struct {
+	Version string `graphql:"version;the api version"`
+	users   []AutoUser
+}
+
+func (q AutoQuery) Users(args struct {
+	Filter *AutoUserFilter `graphql:"filter"`
+}) []AutoUser {
+	var users []AutoUser
+	for _, user := range q.users {
+		if args.Filter == nil || args.Filter.Role == nil || *args.Filter.Role == user.Role {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+func (q AutoQuery) UserByID(ctx context.Context, args struct {
+	ID int `graphql:"id"`
+}) (*AutoUser, error) {
+	if args.ID < 0 || args.ID >= len(q.users) {
+		return nil, fmt.Errorf("no user %d", args.ID)
+	}
+	return &q.users[args.ID], nil
+}
+
+type AutoMutation struct{}
+
+func (AutoMutation) Rename(args struct {
+	Name string `graphql:"name"`
+}) AutoUser {
+	return AutoUser{Name: args.Name}
+}
+
+var autoRoles = map[string]interface{}{"ADMIN": AutoRole(0), "USER": AutoRole(1)}
+
+func TestAutoSchema(t *testing.T) {
+	query := AutoQuery{Version: "1.0", users: []AutoUser{
+		{Name: "alice", Role: 0, Address: &AutoAddress{City: "Paris"}},
+		{Name: "bob", Role: 1},
+	}}
+	schema, err := schemabuilder.AutoSchema(struct {
+		Query    AutoQuery
+		Mutation AutoMutation
+	}{Query: query}, schemabuilder.WithEnum("AutoRole", AutoRole(0), autoRoles))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("builds the schema registered by hand", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Enum("AutoRole", AutoRole(0), autoRoles)
+		build.Object("AutoUser", AutoUser{})
+		build.Object("AutoAddress", AutoAddress{})
+		build.InputObject("AutoUserFilter", AutoUserFilter{})
+		build.Query().FieldFunc("version", func() string { return query.Version }, "the api version")
+		build.Query().FieldFunc("users", query.Users)
+		build.Query().FieldFunc("userByID", query.UserByID)
+		build.Mutation().FieldFunc("rename", AutoMutation{}.Rename)
+		assert.Equal(t, printer.Print(build.MustBuild()), printer.Print(schema))
+	})
+
+	t.Run("resolves root fields", func(t *testing.T) {
+		result, errs := execution.Do(schema, execution.Params{
+			Query: `{ version users(filter: {role: USER}) { name } userByID(id: 0) { name address { city } } }`,
+		})
+		assert.Equal(t, 0, len(errs))
+		assert.Equal(t, map[string]interface{}{
+			"version":  "1.0",
+			"users":    []interface{}{map[string]interface{}{"name": "bob"}},
+			"userByID": map[string]interface{}{"name": "alice", "address": map[string]interface{}{"city": "Paris"}},
+		}, result)
+	})
+
+	t.Run("reports the paths to ambiguous types", func(t *testing.T) {
+		_, err := schemabuilder.AutoSchema(struct {
+			Query struct {
+				Name  fmt.Stringer `graphql:"name"`
+				Users []AutoUser   `graphql:"users"`
+			}
+		}{})
+		errs, ok := err.(schemabuilder.RegistrationErrors)
+		if assert.True(t, ok, "%T", err) && assert.Len(t, errs, 2) {
+			assert.EqualError(t, errs[0], "Query.name: interface fmt.Stringer can not be registered automatically, register it with Schema.Interface")
+			assert.EqualError(t, errs[1], "Query.users.role: schemabuilder_test.AutoRole is neither a builtin scalar nor a registered enum or scalar")
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/shyptr/graphql/internal"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -24,6 +25,19 @@ type schemaBuilder struct {
 	interfaces   map[reflect.Type]*Interface
 	scalars      map[reflect.Type]*Scalar
 	unions       map[reflect.Type]*Union
+	// json is the scalar of the maps with string keys, see JSONObject
+	json *Scalar
+	// strictNullability makes slices non-null lists, see StrictNullability
+	strictNullability bool
+	// requireNamedArgs rejects anonymous args structs, see RequireNamedArgStructs
+	requireNamedArgs bool
+	// inputNaming names the generated input objects, see GenerateInputObjects
+	inputNaming InputNaming
+	// manifest lists the fields of the objects built so far
+	manifest []ManifestField
+	// objectsByType maps the Go types of the objects to them once the schema is built, for the
+	// TypeResolve funcs of interfaces, which must not build types while operations are executed
+	objectsByType map[reflect.Type]*internal.Object
 }
 
 var Serialize = func(value interface{}) (interface{}, error) {
@@ -32,6 +46,8 @@ var Serialize = func(value interface{}) (interface{}, error) {
 		return v, nil
 	case *string, *float64, *int64, *bool, *int, *int8, *int16, *int32, *uint, *uint8, *uint16, *uint32, *uint64, *float32, *time.Time:
 		return v, nil
+	case json.RawMessage:
+		return v, nil
 	case []byte:
 		return string(v), nil
 	case *[]byte:
@@ -71,6 +87,10 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (internal.Type, error) {
 	// Scalar
 	if scalar := sb.getScalar(nodeType); scalar != nil {
 		sb.types[nodeType] = &internal.NonNull{Type: scalar}
+		// nullable wrappers such as sql.NullString are null when not valid
+		if sqlNullTypes[nodeType] {
+			sb.types[nodeType] = scalar
+		}
 		sb.types[reflect.PtrTo(nodeType)] = scalar
 		return sb.types[nodeType], nil
 	}
@@ -78,6 +98,9 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (internal.Type, error) {
 		if scalar := sb.getScalar(nodeType.Elem()); scalar != nil {
 			sb.types[nodeType] = scalar
 			sb.types[nodeType.Elem()] = &internal.NonNull{Type: scalar}
+			if sqlNullTypes[nodeType.Elem()] {
+				sb.types[nodeType.Elem()] = scalar
+			}
 			return sb.types[nodeType], nil // XXX: prefix typ with "*"
 		}
 	}
@@ -111,47 +134,137 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (internal.Type, error) {
 		return sb.types[nodeType], nil
 	}
 
+	// Object over a named type which is not a struct
+	if _, ok := sb.objects[nodeType]; ok {
+		if err := sb.buildStruct(nodeType); err != nil {
+			return nil, err
+		}
+		return sb.types[nodeType], nil
+	}
+	if nodeType.Kind() == reflect.Ptr {
+		if _, ok := sb.objects[nodeType.Elem()]; ok {
+			if err := sb.buildStruct(nodeType.Elem()); err != nil {
+				return nil, err
+			}
+			return sb.types[nodeType], nil
+		}
+	}
+
+	// Maps with string keys are JSON objects, which are nullable as slices are
+	if nodeType.Kind() == reflect.Map && nodeType.Key().Kind() == reflect.String && sb.json != nil {
+		scalar := &internal.Scalar{Name: sb.json.Name, Desc: sb.json.Desc, Serialize: sb.json.Serialize,
+			ParseValue: sb.json.ParseValue, ParseLiteral: sb.json.ParseLiteral, SpecifiedByURL: sb.json.SpecifiedByURL}
+		sb.types[nodeType] = scalar
+		if sb.strictNullability {
+			sb.types[nodeType] = &internal.NonNull{Type: scalar}
+		}
+		sb.types[reflect.PtrTo(nodeType)] = scalar
+		return sb.types[nodeType], nil
+	}
+	if nodeType.Kind() == reflect.Ptr && nodeType.Elem().Kind() == reflect.Map {
+		if _, err := sb.getType(nodeType.Elem()); err != nil {
+			return nil, err
+		}
+		return sb.types[nodeType], nil
+	}
+
 	if nodeType.Kind() == reflect.Slice {
 		elementType, err := sb.getType(nodeType.Elem())
 		if err != nil {
 			return nil, err
 		}
 		sb.types[nodeType] = &internal.List{Type: elementType}
+		if sb.strictNullability {
+			sb.types[nodeType] = &internal.NonNull{Type: &internal.List{Type: elementType}}
+		}
 		sb.types[reflect.PtrTo(nodeType)] = &internal.List{Type: elementType}
 		return sb.types[nodeType], nil
 	}
-	return nil, fmt.Errorf("bad type %s: should be a scalar, slice, or struct type", nodeType)
+	if nodeType.Kind() == reflect.Ptr && nodeType.Elem().Kind() == reflect.Slice {
+		if _, err := sb.getType(nodeType.Elem()); err != nil {
+			return nil, err
+		}
+		return sb.types[nodeType], nil
+	}
+	return nil, fmt.Errorf("bad type %s: should be a scalar, slice, map with string keys, or struct type", nodeType)
+}
+
+// tagNullability applies the null and nonnull tags of the field name to its type typ, built from src.
+// With strict nullability the tags may not contradict the nullability of src.
+func (sb *schemaBuilder) tagNullability(src reflect.Type, typ internal.Type, null, nonnull bool, name string) (internal.Type, error) {
+	nonNull, isNonNull := typ.(*internal.NonNull)
+	if sb.strictNullability {
+		if nonnull && !isNonNull {
+			return nil, fmt.Errorf("field %s: nonnull tag contradicts the nullable type %s", name, src)
+		}
+		if null && isNonNull {
+			return nil, fmt.Errorf("field %s: null tag contradicts the non-null type %s, use a pointer", name, src)
+		}
+	}
+	if nonnull && !isNonNull {
+		return &internal.NonNull{Type: typ}, nil
+	}
+	if null && isNonNull {
+		return nonNull.Type, nil
+	}
+	return typ, nil
 }
 
 // getEnum gets the Enum type information for the passed in reflect.Operation by looking it up in our enum mappings.
 func (sb *schemaBuilder) getEnum(typ reflect.Type) *internal.Enum {
 	if enum, ok := sb.enums[typ]; ok {
-		var values []string
-		for mapping := range enum.Map {
-			values = append(values, mapping)
-		}
+		values := enumValueNames(enum.Map)
 		return &internal.Enum{
 			Name:       enum.Name,
 			Values:     values,
 			ValuesDesc: enum.DescMap,
+			Deprecated: enum.DeprecatedMap,
 			ReverseMap: enum.Map,
 			Map:        enum.ReverseMap,
 			Desc:       enum.Desc,
+
+			OutputMapper: enum.outputMapper,
+			InputMapper:  enum.inputMapper,
 		}
 	}
 	return nil
 }
 
+// enumValueNames returns the names of the values of an enum in the order of their Go values, which is
+// the order iota constants are declared in, the names of equal values or values of other kinds by name.
+func enumValueNames(values map[string]interface{}) []string {
+	names := sortedKeys(values)
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := reflect.ValueOf(values[names[i]]), reflect.ValueOf(values[names[j]])
+		if a.Kind() != b.Kind() {
+			return false
+		}
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		}
+		return false
+	})
+	return names
+}
+
 // getScalar grabs the appropriate scalar graphql field type name for the passed
 // in variable reflect type.
 func (sb *schemaBuilder) getScalar(typ reflect.Type) *internal.Scalar {
 	if scalar, ok := sb.scalars[typ]; ok {
 		return &internal.Scalar{
-			Name:         scalar.Name,
-			Desc:         scalar.Desc,
-			Serialize:    scalar.Serialize,
-			ParseValue:   scalar.ParseValue,
-			ParseLiteral: scalar.ParseLiteral,
+			Name:           scalar.Name,
+			Desc:           scalar.Desc,
+			Serialize:      scalar.Serialize,
+			ParseValue:     scalar.ParseValue,
+			ParseLiteral:   scalar.ParseLiteral,
+			SpecifiedByURL: scalar.SpecifiedByURL,
 		}
 	}
 	return nil
@@ -170,8 +283,22 @@ func (sb *schemaBuilder) getInterface(typ reflect.Type) (*internal.Interface, er
 		sb.types[typ] = iface
 		sb.types[reflect.PtrTo(typ)] = iface
 		fields := make(map[string]*internal.Field)
-		for name, resolve := range inter.FieldResolve {
-			if mname := resolve.fn.(string); mname != "" {
+		for _, name := range sortedKeys(inter.FieldResolve) {
+			resolve := inter.FieldResolve[name]
+			mname, ok := resolve.fn.(string)
+			if !ok {
+				if err := checkInterfaceSource(resolve.fn, typ); err != nil {
+					return nil, fmt.Errorf("interface %s field %s parse error:%w", inter.Name, name, err)
+				}
+				field, err := sb.getField(resolve, typ)
+				if err != nil {
+					return nil, fmt.Errorf("interface %s field %s parse error:%w", inter.Name, name, err)
+				}
+				field.Name = name
+				fields[name] = field
+				continue
+			}
+			if mname != "" {
 				method, ok := typ.MethodByName(mname)
 				if !ok {
 					return nil, fmt.Errorf("%s should be method of %s", mname, typ.String())
@@ -192,26 +319,33 @@ func (sb *schemaBuilder) getInterface(typ reflect.Type) (*internal.Interface, er
 					Type: retType,
 					Desc: resolve.desc,
 				}
+				// methods without arguments resolve the field of the possible types not exposing it
+				if method.Type.NumIn() == 0 {
+					fields[name].Args = map[string]*internal.InputField{}
+					fields[name].ResolverKind = internal.FuncResolver
+					fields[name].Resolve = methodResolver(typ, method, fctx)
+				}
 			}
 		}
+		iface.Fields = fields
 		var function internal.TypeResolve
 		if inter.Fn != nil {
 			var err error
-			function, err = sb.getTypeFunction(inter.Fn, typ)
+			function, err = sb.getTypeFunction(inter.Fn, typ, iface)
 			if err != nil {
 				return nil, err
 			}
 		}
 
 		possibleTypes := make(map[string]*internal.Object)
-		for name, object := range inter.PossibleTypes {
+		for _, name := range sortedKeys(inter.PossibleTypes) {
+			object := inter.PossibleTypes[name]
 			t, err := sb.getType(reflect.TypeOf(object.Type))
 			if err != nil {
 				return nil, err
 			}
 			possibleTypes[name] = t.(*internal.NonNull).Type.(*internal.Object)
 		}
-		iface.Fields = fields
 		iface.PossibleTypes = possibleTypes
 		iface.TypeResolve = function
 		for _, innerIface := range inter.Interface {
@@ -270,9 +404,21 @@ func (sb *schemaBuilder) getDirective(directive *Directive) (*internal.Directive
 		}
 	}
 
-	arguments, err := sb.getArguments(argType)
-	if err != nil {
-		return nil, err
+	arguments := make(map[string]*internal.InputField)
+	if hasArg {
+		var err error
+		arguments, err = sb.getArguments(argType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(directive.Fields) {
+		f := directive.Fields[name]
+		arg, ok := arguments[name]
+		if !ok {
+			return nil, fmt.Errorf("directive %s has no argument %s", directive.Name, name)
+		}
+		arg.DefaultValue = f.DefaultValue
 	}
 
 	return &internal.Directive{
@@ -338,37 +484,85 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 
 		sb.types[reflect.PtrTo(typ)] = object
 		sb.types[typ] = &internal.NonNull{Type: object}
-		for name, resolve := range obj.FieldResolve {
+		for _, name := range sortedKeys(obj.FieldResolve) {
+			resolve := obj.FieldResolve[name]
 			if f, err := sb.getField(resolve, typ); err == nil && f != nil {
 				f.Name = name
 				object.Fields[name] = f
+				entry := ManifestField{
+					Coordinate: obj.Name + "." + name,
+					Type:       f.Type.String(),
+					Kind:       f.ResolverKind,
+					CallSite:   resolve.callSite,
+					Options:    resolve.options,
+					Deprecated: f.DeprecationReason,
+				}
+				funcManifest(&entry, resolve.fn, typ)
+				sb.manifest = append(sb.manifest, entry)
 			} else if err != nil {
 				return fmt.Errorf("object %s field %s parse error:%w", typ.String(), name, err)
 			}
 		}
-		for i := 0; i < typ.NumField(); i++ {
-			field := typ.Field(i)
-			buildField, err := sb.buildField(field)
-			if err != nil {
-				return err
-			}
-			if buildField == nil {
-				continue
-			}
-			if _, ok := obj.FieldResolve[buildField.Name]; ok {
-				continue
+		// objects over named types other than struct only have FieldFunc fields
+		if typ.Kind() == reflect.Struct {
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				buildField, err := sb.buildField(field)
+				if err != nil {
+					return err
+				}
+				if buildField == nil {
+					continue
+				}
+				if _, ok := obj.FieldResolve[buildField.Name]; ok {
+					continue
+				}
+				resolve, ok := obj.FieldOptions[buildField.Name]
+				if !ok {
+					resolve, ok = obj.FieldOptions[field.Name]
+				}
+				if ok {
+					if err := sb.applyFieldOptions(buildField, resolve); err != nil {
+						return fmt.Errorf("object %s field %s parse error:%w", typ.String(), buildField.Name, err)
+					}
+				}
+				object.Fields[buildField.Name] = buildField
+				entry := ManifestField{
+					Coordinate: obj.Name + "." + buildField.Name,
+					Type:       buildField.Type.String(),
+					Kind:       buildField.ResolverKind,
+					Resolver:   typ.String() + "." + field.Name,
+					Deprecated: buildField.DeprecationReason,
+				}
+				if ok {
+					entry.CallSite, entry.Options = resolve.callSite, resolve.options
+				}
+				sb.manifest = append(sb.manifest, entry)
 			}
-			object.Fields[buildField.Name] = buildField
 		}
 		for _, iface := range obj.Interface {
 			ifaceTyp, err := sb.getType(reflect.TypeOf(iface.Type))
 			if err != nil {
 				return err
 			}
-			for f := range ifaceTyp.(*internal.Interface).Fields {
-				if _, ok := object.Fields[f]; !ok {
-					return fmt.Errorf("%s must impl interface field %s", object.Name, f)
+			for _, f := range sortedKeys(ifaceTyp.(*internal.Interface).Fields) {
+				if _, ok := object.Fields[f]; ok {
+					continue
 				}
+				field := ifaceTyp.(*internal.Interface).Fields[f]
+				if field.Resolve == nil {
+					return fmt.Errorf("object %s (%s) must expose the field %s of interface %s, whose method takes arguments",
+						object.Name, typ.String(), f, iface.Name)
+				}
+				// the object resolves the field as the interface does
+				copied := *field
+				object.Fields[f] = &copied
+				sb.manifest = append(sb.manifest, ManifestField{
+					Coordinate: obj.Name + "." + f,
+					Type:       copied.Type.String(),
+					Kind:       copied.ResolverKind,
+					Resolver:   iface.Name + "." + f,
+				})
 			}
 			object.Interfaces[iface.Name] = ifaceTyp.(*internal.Interface)
 		}
@@ -390,16 +584,14 @@ func (sb *schemaBuilder) buildField(field reflect.StructField) (*internal.Field,
 	if _, ok := fieldTyp.(*internal.InputObject); ok {
 		return nil, fmt.Errorf("field %s type can not be input object", name)
 	}
-	if nonnull {
-		fieldTyp = &internal.NonNull{Type: fieldTyp}
-	}
-	if nof, ok := fieldTyp.(*internal.NonNull); ok && null {
-		fieldTyp = nof.Type
+	if fieldTyp, err = sb.tagNullability(field.Type, fieldTyp, null, nonnull, name); err != nil {
+		return nil, err
 	}
 	return &internal.Field{
-		Name: name,
-		Type: fieldTyp,
-		Args: map[string]*internal.InputField{},
+		Name:         name,
+		Type:         fieldTyp,
+		Args:         map[string]*internal.InputField{},
+		ResolverKind: internal.StructFieldResolver,
 		Resolve: func(ctx context.Context, source, args interface{}) (interface{}, error) {
 			if source == nil {
 				return nil, fmt.Errorf("source is nil")
@@ -418,6 +610,46 @@ func (sb *schemaBuilder) buildField(field reflect.StructField) (*internal.Field,
 	}, nil
 }
 
+// applyFieldOptions wraps the resolver of a struct field with the chains of FieldOption.
+func (sb *schemaBuilder) applyFieldOptions(field *internal.Field, fnresolve *fieldResolve) error {
+	resolve := field.Resolve
+	field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		for _, handler := range fnresolve.handleChain {
+			if _, err := handler.execute(executeFuncParam{
+				ctx:    ctx,
+				args:   args,
+				source: source,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		result, err := resolve(ctx, source, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, execute := range fnresolve.executeChain {
+			if result, err = execute.execute(executeFuncParam{
+				sb:     sb,
+				ctx:    ctx,
+				args:   args,
+				source: result,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	if fnresolve.desc != "" {
+		field.Desc = fnresolve.desc
+	}
+	for _, build := range fnresolve.buildChain {
+		if _, err := build.execute(buildParam{sb: sb, f: field, fnresolve: fnresolve}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (sb *schemaBuilder) buildUnion(typ reflect.Type) error {
 	union := sb.unions[typ]
 	unionTyp := &internal.Union{
@@ -428,9 +660,13 @@ func (sb *schemaBuilder) buildUnion(typ reflect.Type) error {
 	sb.types[reflect.PtrTo(typ)] = unionTyp
 	sb.types[typ] = &internal.NonNull{Type: unionTyp}
 
+	if typ.NumField() == 0 {
+		return fmt.Errorf("union %s (%s) must have at least one member", union.Name, typ.String())
+	}
+	members := make(map[string]string, typ.NumField())
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		if field.Type.Kind() != reflect.Ptr && field.Type.Elem().Kind() != reflect.Struct {
+		if field.Type.Kind() != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
 			return fmt.Errorf("%s %s %s: union's field must be struct's prt", field.PkgPath, typ.String(), field.Name)
 		}
 		if _, ok := sb.objects[field.Type.Elem()]; !ok {
@@ -440,11 +676,48 @@ func (sb *schemaBuilder) buildUnion(typ reflect.Type) error {
 		if err != nil {
 			return err
 		}
-		unionTyp.Types[object.(*internal.Object).Name] = object.(*internal.Object)
+		name := object.(*internal.Object).Name
+		if other, ok := members[name]; ok {
+			return fmt.Errorf("union %s (%s) has object %s (%s) as member twice, by its fields %s and %s",
+				union.Name, typ.String(), name, field.Type.Elem().String(), other, field.Name)
+		}
+		members[name] = field.Name
+		unionTyp.Types[name] = object.(*internal.Object)
 	}
 	return nil
 }
 
+// generateInputObject adds the input object of the struct typ, or of the elements of typ, to those
+// of the schema when it is neither registered nor anonymous, see GenerateInputObjects.
+func (sb *schemaBuilder) generateInputObject(typ reflect.Type) error {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if sb.inputNaming == nil || typ.Kind() != reflect.Struct || typ.Name() == "" {
+		return nil
+	}
+	if _, ok := sb.inputObjects[typ]; ok {
+		return nil
+	}
+	if _, ok := sb.objects[typ]; ok {
+		return nil
+	}
+	if _, ok := sb.unions[typ]; ok {
+		return nil
+	}
+	if _, ok := sb.scalars[typ]; ok {
+		return nil
+	}
+	name := sb.inputNaming(typ)
+	for other, input := range sb.inputObjects {
+		if input.Name == name {
+			return fmt.Errorf("input object %s is generated for %s, and is already the input object of %s", name, typ, other)
+		}
+	}
+	sb.inputObjects[typ] = &InputObject{Name: name, Type: reflect.New(typ).Elem().Interface(), Fields: map[string]*inputFieldResolve{}}
+	return nil
+}
+
 func (sb *schemaBuilder) builInputObject(typ reflect.Type) error {
 	input := sb.inputObjects[typ]
 	inputObject := &internal.InputObject{
@@ -456,7 +729,7 @@ func (sb *schemaBuilder) builInputObject(typ reflect.Type) error {
 	sb.types[typ] = &internal.NonNull{Type: inputObject}
 	arguments, err := sb.getArguments(typ)
 	if err != nil {
-		return nil
+		return err
 	}
 	inputObject.Fields = arguments
 	return nil
@@ -472,6 +745,15 @@ func (sb *schemaBuilder) getField(fnresolve *fieldResolve, src reflect.Type) (*i
 	in := fctx.getFuncInputTypes()
 	in = fctx.consumeContextAndSource(in)
 
+	if sb.requireNamedArgs && len(in) > 0 {
+		argTyp := in[0]
+		for argTyp.Kind() == reflect.Ptr {
+			argTyp = argTyp.Elem()
+		}
+		if argTyp.Kind() == reflect.Struct && argTyp.Name() == "" {
+			return nil, fmt.Errorf("the arguments of the field registered at %s are an anonymous struct, declare a named struct for them", fnresolve.callSite)
+		}
+	}
 	args, in, err := fctx.getArgParserAndTyp(sb, in)
 	if err != nil {
 		return nil, err
@@ -484,6 +766,7 @@ func (sb *schemaBuilder) getField(fnresolve *fieldResolve, src reflect.Type) (*i
 	}
 
 	// Parse return values. The first return value must be the actual value, and the second value can optionally be an error.
+	fctx.hasFound = fnresolve.found != nil
 	err = fctx.parseReturnSignature()
 	if err != nil {
 		return nil, err
@@ -494,10 +777,15 @@ func (sb *schemaBuilder) getField(fnresolve *fieldResolve, src reflect.Type) (*i
 		return nil, err
 	}
 
-	field := &internal.Field{
-		Type: retType,
-		Args: args,
+	var field *internal.Field
+	field = &internal.Field{
+		Type:         retType,
+		Args:         args,
+		ResolverKind: internal.FuncResolver,
 		Resolve: func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			// the hooks, the argument decoder and the resolver of this invocation share a copy of the
+			// arguments, they can not change what other invocations of the field see
+			args = copyArgs(args)
 			for _, handler := range fnresolve.handleChain {
 				if _, err := handler.execute(executeFuncParam{
 					ctx:    ctx,
@@ -514,23 +802,48 @@ func (sb *schemaBuilder) getField(fnresolve *fieldResolve, src reflect.Type) (*i
 			}
 			var funcOutputArgs []reflect.Value
 			funcOutputArgs = callableFunc.Call(funcInputArgs)
+			found := true
+			if fctx.hasFound {
+				found = funcOutputArgs[1].Bool()
+				funcOutputArgs = append(funcOutputArgs[:1:1], funcOutputArgs[2:]...)
+			}
 
 			var result interface{}
 			result, err = fctx.extractResultAndErr(funcOutputArgs)
 			if err != nil {
 				return nil, err
 			}
-			for _, execute := range fnresolve.executeChain {
-				if result, err = execute.execute(executeFuncParam{
-					sb:     sb,
-					ctx:    ctx,
-					args:   args,
-					source: result,
-				}); err != nil {
-					return nil, err
+			if !found {
+				// the type is read now, options may have changed its nullability once built
+				if nonNull, ok := field.Type.(*internal.NonNull); ok {
+					return nil, fnresolve.found.err(nonNull.Type)
 				}
+				return nil, nil
 			}
-			return result, nil
+			execute := func(result interface{}) (interface{}, error) {
+				var err error
+				for _, execute := range fnresolve.executeChain {
+					if result, err = execute.execute(executeFuncParam{
+						sb:     sb,
+						ctx:    ctx,
+						args:   args,
+						source: result,
+					}); err != nil {
+						return nil, err
+					}
+				}
+				return result, nil
+			}
+			if thunk, ok := result.(internal.Thunk); ok {
+				return internal.Thunk(func() (interface{}, error) {
+					result, err := thunk()
+					if err != nil {
+						return nil, err
+					}
+					return execute(result)
+				}), nil
+			}
+			return execute(result)
 		},
 		Desc: fnresolve.desc,
 	}
@@ -543,66 +856,198 @@ func (sb *schemaBuilder) getField(fnresolve *fieldResolve, src reflect.Type) (*i
 	return field, nil
 }
 
-func (sb *schemaBuilder) getTypeFunction(fn interface{}, source reflect.Type) (internal.TypeResolve, error) {
+// checkInterfaceSource rejects the funcs resolving a field of the Go interface typ from a value of a
+// type implementing it, which would otherwise be mistaken for their arguments.
+func checkInterfaceSource(fn interface{}, typ reflect.Type) error {
+	in := reflect.TypeOf(fn)
+	i := 0
+	if in.NumIn() > i && in.In(i) == contextType {
+		i++
+	}
+	if in.NumIn() <= i {
+		return nil
+	}
+	if source := in.In(i); source != typ && source.Kind() != reflect.Interface &&
+		(source.Implements(typ) || reflect.PtrTo(source).Implements(typ)) {
+		return fmt.Errorf("the source of the func must be %s, not %s", typ, source)
+	}
+	return nil
+}
+
+// methodResolver resolves a field with method, a method without arguments of the Go interface typ,
+// whose return signature fctx parsed. The source is a value of a possible type of the interface.
+func methodResolver(typ reflect.Type, method reflect.Method, fctx *funcContext) internal.FieldResolve {
+	return func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		value, err := interfaceSource(source, typ)
+		if err != nil {
+			return nil, err
+		}
+		return fctx.extractResultAndErr(value.Method(method.Index).Call(nil))
+	}
+}
+
+// interfaceSource returns source as a value of the Go interface typ, which the value of source
+// implements, or its pointer does.
+func interfaceSource(source interface{}, typ reflect.Type) (reflect.Value, error) {
+	value := reflect.ValueOf(source)
+	if !value.IsValid() {
+		return value, fmt.Errorf("source is nil")
+	}
+	if !value.Type().Implements(typ) && value.Kind() != reflect.Ptr && reflect.PtrTo(value.Type()).Implements(typ) {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		value = ptr
+	}
+	if !value.Type().Implements(typ) {
+		return value, fmt.Errorf("%s does not implement %s", value.Type(), typ)
+	}
+	return value.Convert(typ), nil
+}
+
+// getTypeFunction builds the TypeResolve of iface from fn, which is either a func or the name of a method of the interface.
+// It takes an optional context and the source, and returns a value of the resolved type, the *internal.Object itself
+// or the name of the resolved type, optionally followed by an error.
+func (sb *schemaBuilder) getTypeFunction(fn interface{}, source reflect.Type, iface *internal.Interface) (internal.TypeResolve, error) {
 	if fn == nil {
 		return nil, nil
 	}
 	fctx := funcContext{}
-	typ := reflect.TypeOf(fn)
-	if typ.NumIn() > 2 {
-		return nil, fmt.Errorf("interface field num in can not more than 2")
-	}
-	for i := 0; i < typ.NumIn(); i++ {
-		inTyp := typ.In(i)
-		switch inTyp {
-		case contextType:
-			fctx.hasContext = true
-		case source, reflect.New(source).Type():
-			fctx.hasSource = true
-		default:
-			return nil, fmt.Errorf("interface typeResolve func num in has error type")
+	var call func(ctx context.Context, value interface{}) ([]reflect.Value, error)
+	var typ reflect.Type
+	if name, ok := fn.(string); ok {
+		method, ok := source.MethodByName(name)
+		if !ok {
+			return nil, fmt.Errorf("%s should be method of %s", name, source.String())
+		}
+		typ = method.Type
+		if typ.NumIn() != 0 {
+			return nil, fmt.Errorf("interface typeResolve method %s can not have arguments", name)
+		}
+		call = func(ctx context.Context, value interface{}) ([]reflect.Value, error) {
+			method := reflect.ValueOf(value).MethodByName(name)
+			if !method.IsValid() {
+				return nil, fmt.Errorf("%T has no method %s", value, name)
+			}
+			return method.Call(nil), nil
+		}
+	} else {
+		typ = reflect.TypeOf(fn)
+		if typ.Kind() != reflect.Func {
+			return nil, fmt.Errorf("interface typeResolve should be a func or a method name, not %s", typ.String())
+		}
+		if typ.NumIn() > 2 {
+			return nil, fmt.Errorf("interface field num in can not more than 2")
+		}
+		var sourceTyp reflect.Type
+		for i := 0; i < typ.NumIn(); i++ {
+			inTyp := typ.In(i)
+			switch {
+			case inTyp == contextType:
+				fctx.hasContext = true
+			case inTyp == source, inTyp == reflect.PtrTo(source), inTyp.Kind() == reflect.Interface && source.Implements(inTyp):
+				fctx.hasSource = true
+				sourceTyp = inTyp
+			default:
+				return nil, fmt.Errorf("interface typeResolve func num in has error type")
+			}
+		}
+		call = func(ctx context.Context, value interface{}) ([]reflect.Value, error) {
+			var in []reflect.Value
+			if fctx.hasContext {
+				in = append(in, reflect.ValueOf(ctx))
+			}
+			if fctx.hasSource {
+				arg, err := typeResolveArg(sourceTyp, value)
+				if err != nil {
+					return nil, err
+				}
+				in = append(in, arg)
+			}
+			return reflect.ValueOf(fn).Call(in), nil
 		}
 	}
-	if typ.NumOut() != 1 {
-		return nil, fmt.Errorf("interface field num out must be 1")
+	if typ.NumOut() == 0 || typ.NumOut() > 2 || (typ.NumOut() == 2 && typ.Out(1) != errType) {
+		return nil, fmt.Errorf("interface typeResolve func should return a value, optionally followed by an error")
+	}
+	// a concrete result type is checked now rather than when resolving
+	if resTyp := typ.Out(0); resTyp.Kind() != reflect.Interface && resTyp.Kind() != reflect.String && resTyp != objectType {
+		if resTyp.Kind() != reflect.Ptr {
+			resTyp = reflect.PtrTo(resTyp)
+		}
+		res, err := sb.getType(resTyp)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := res.(*internal.Object); !ok {
+			return nil, fmt.Errorf("interface typeResolve func should return an object, not %s", res.String())
+		}
 	}
 
-	return func(ctx context.Context, value interface{}) *internal.Object {
-		var in []reflect.Value
-		if fctx.hasContext {
-			in = append(in, reflect.ValueOf(ctx))
+	// the objects are looked up in the map filled once the schema is built
+	if sb.objectsByType == nil {
+		sb.objectsByType = make(map[reflect.Type]*internal.Object)
+	}
+	objects := sb.objectsByType
+	return func(ctx context.Context, value interface{}) (*internal.Object, error) {
+		values, err := call(ctx, value)
+		if err != nil {
+			return nil, err
 		}
-		if fctx.hasSource {
-			in = append(in, reflect.ValueOf(value))
+		if len(values) == 2 && !values[1].IsNil() {
+			return nil, values[1].Interface().(error)
 		}
-		values := reflect.ValueOf(fn).Call(in)
-		if len(values) > 0 {
-			resTyp := values[0].Type()
-			var res internal.Type
-			if values[0].Kind() == reflect.Interface && !values[0].IsNil() {
-				resTyp = values[0].Elem().Type()
-			}
-			if resTyp.Kind() != reflect.Ptr {
-				resTyp = reflect.PtrTo(resTyp)
+		if values[0].Kind() == reflect.Interface {
+			if values[0].IsNil() {
+				return nil, nil
 			}
-			res, err := sb.getType(resTyp)
-			if err != nil {
-				return nil
-			}
-			if obj, ok := res.(*internal.Object); ok {
-				return obj
+			values[0] = values[0].Elem()
+		}
+		switch res := values[0].Interface().(type) {
+		case *internal.Object:
+			return res, nil
+		case string:
+			if obj, ok := iface.PossibleTypes[res]; ok {
+				return obj, nil
 			}
+			return nil, fmt.Errorf("type %q is not a possible type", res)
 		}
-		return nil
+		resTyp := values[0].Type()
+		if resTyp.Kind() != reflect.Ptr {
+			resTyp = reflect.PtrTo(resTyp)
+		}
+		if obj, ok := objects[resTyp]; ok {
+			return obj, nil
+		}
+		return nil, fmt.Errorf("type %s is not an object", resTyp.String())
 	}, nil
 }
 
+// typeResolveArg returns value as the argument of type typ of a typeResolve func: interfaces holding
+// a pointer are passed the pointer, or the value it points to, whichever typ takes.
+func typeResolveArg(typ reflect.Type, value interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(typ), nil
+	case v.Type().AssignableTo(typ):
+		return v, nil
+	case typ.Kind() == reflect.Ptr && v.Type().AssignableTo(typ.Elem()):
+		arg := reflect.New(typ.Elem())
+		arg.Elem().Set(v)
+		return arg, nil
+	case v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type().AssignableTo(typ):
+		return v.Elem(), nil
+	}
+	return reflect.Value{}, fmt.Errorf("interface typeResolve func can not take a %s", v.Type().String())
+}
+
 type funcContext struct {
 	hasContext      bool
 	hasSource       bool
 	hasArg          bool
 	hasRet          bool
 	hasErr          bool
+	hasFound        bool
 	sourcePtr       bool
 	sourceInterface bool
 	argTyp          reflect.Type
@@ -610,7 +1055,10 @@ type funcContext struct {
 	isPtrFunc       bool
 	typ             reflect.Type
 
+	// returnsFunc is set for the functions returning the func resolving their value later, thunkErr
+	// when that func returns an error
 	returnsFunc    bool
+	thunkErr       bool
 	wrapperFuncTyp reflect.Type
 }
 
@@ -689,6 +1137,14 @@ func (funcCtx *funcContext) parseReturnSignature() (err error) {
 		out = out[1:]
 	}
 
+	if funcCtx.hasFound {
+		if !funcCtx.hasRet || len(out) == 0 || out[0].Kind() != reflect.Bool {
+			err = fmt.Errorf("%s return values should be result, bool[, error] with FoundBool", funcCtx.funcType)
+			return
+		}
+		out = out[1:]
+	}
+
 	if len(out) > 0 && out[0] == errType {
 		funcCtx.hasErr = true
 		out = out[1:]
@@ -713,14 +1169,13 @@ func (funcCtx *funcContext) getReturnType(sb *schemaBuilder) (internal.Type, err
 			return nil, fmt.Errorf("%s should have zero arguments", function)
 		}
 
-		funcCtx.funcType = function
-		if funcCtx.funcType.Out(0) == errType {
-			if funcCtx.hasErr {
-				return nil, fmt.Errorf("%s should only return one error", function)
-			}
-			funcCtx.hasErr = true
-			funcCtx.hasRet = false
+		if function.NumOut() == 0 || function.NumOut() > 2 || function.NumOut() == 2 && function.Out(1) != errType {
+			return nil, fmt.Errorf("%s return values should be [result][, error]", function)
 		}
+
+		funcCtx.funcType = function
+		funcCtx.hasRet = function.Out(0) != errType
+		funcCtx.thunkErr = function.Out(function.NumOut()-1) == errType
 	}
 	if funcCtx.hasRet {
 		var err error
@@ -756,7 +1211,11 @@ func (funcCtx *funcContext) prepareResolveArgs(sb *schemaBuilder, source interfa
 		case funcCtx.sourceInterface &&
 			((ptrSource && (sourceTyp.Implements(funcCtx.typ) || sourceTyp.Elem().Implements(funcCtx.typ))) ||
 				(!ptrSource && (sourceTyp.Implements(funcCtx.typ) || reflect.PtrTo(sourceTyp).Implements(funcCtx.typ)))):
-			in = append(in, sourceValue.Convert(funcCtx.typ))
+			value, err := interfaceSource(source, funcCtx.typ)
+			if err != nil {
+				return nil, err
+			}
+			in = append(in, value)
 		case ptrSource && !funcCtx.isPtrFunc:
 			in = append(in, sourceValue.Elem())
 		case !ptrSource && funcCtx.isPtrFunc:
@@ -799,24 +1258,31 @@ func (funcCtx *funcContext) prepareResolveArgs(sb *schemaBuilder, source interfa
 
 // extractResultAndErr converts the response from calling the function into the expected type for the response object (as opposed to a reflect.Value).
 // It also handles reading whether the function ended with errors.
+// A function returning a func resolves to an internal.Thunk calling it.
 func (funcCtx *funcContext) extractResultAndErr(out []reflect.Value) (interface{}, error) {
+	if funcCtx.returnsFunc {
+		if funcCtx.hasErr {
+			if err := out[1]; !err.IsNil() {
+				return nil, err.Interface().(error)
+			}
+		}
+		if out[0].IsNil() {
+			return nil, nil
+		}
+		return internal.Thunk(func() (interface{}, error) {
+			return funcCtx.extractThunkResult(out[0].Call(nil))
+		}), nil
+	}
+
 	var result interface{}
 	if funcCtx.hasRet {
 		result = out[0].Interface()
-		if out[0].Kind() == reflect.Func {
-			call := out[0].Call(nil)
-			result = call[0].Interface()
-		}
 		out = out[1:]
 	} else {
 		result = true
 	}
 	if funcCtx.hasErr {
 		if err := out[0]; !err.IsNil() {
-			if err.Kind() == reflect.Func {
-				call := out[0].Call(nil)
-				err = call[0]
-			}
 			return nil, err.Interface().(error)
 		}
 	}
@@ -824,6 +1290,22 @@ func (funcCtx *funcContext) extractResultAndErr(out []reflect.Value) (interface{
 	return result, nil
 }
 
+// extractThunkResult is extractResultAndErr for the values returned by the func of a function
+// returning one.
+func (funcCtx *funcContext) extractThunkResult(out []reflect.Value) (interface{}, error) {
+	var result interface{} = true
+	if funcCtx.hasRet {
+		result = out[0].Interface()
+		out = out[1:]
+	}
+	if funcCtx.thunkErr {
+		if err := out[0]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+	}
+	return result, nil
+}
+
 var scalars = map[string]*Scalar{
 	"Boolean":    Boolean,
 	"Int":        Int,
@@ -844,6 +1326,7 @@ var scalars = map[string]*Scalar{
 	"Time":       Time,
 	"Bytes":      Bytes,
 	"AnyScalar":  AnyScalar,
+	"JSONObject": JSONObject,
 	"NullString": NullString,
 	"NullTime":   NullTime,
 	"NullBool":   NullBool,
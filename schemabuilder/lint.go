@@ -0,0 +1,209 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// DefaultLintMaxInputFields is the number of fields above which an input object is reported by Lint,
+// unless the schema is made with the LintMaxInputFields option.
+const DefaultLintMaxInputFields = 20
+
+// LintMaxInputFields makes Lint report the input objects with more than n fields, instead of
+// DefaultLintMaxInputFields.
+func LintMaxInputFields(n int) SchemaOption {
+	return func(s *Schema) {
+		s.lintMaxInputFields = n
+	}
+}
+
+// Warning is a schema anti-pattern reported by Lint.
+// Unlike Build errors, warnings never stop a schema from being used.
+type Warning struct {
+	Rule     string
+	Message  string
+	CallSite string // file:line of the call registering the type
+}
+
+func (w Warning) String() string {
+	if w.CallSite == "" {
+		return fmt.Sprintf("%s (%s)", w.Message, w.Rule)
+	}
+	return fmt.Sprintf("%s: %s (%s)", w.CallSite, w.Message, w.Rule)
+}
+
+// EnableLint makes MustBuild run Lint on the built schema and pass every warning to report.
+func (s *Schema) EnableLint(report func(Warning)) {
+	s.lint = report
+}
+
+// Lint builds the schema and reports anti-patterns found in the registered types:
+//   - fields returning lists whose items can be null
+//   - enums with a single value
+//   - input objects with more fields than allowed by the LintMaxInputFields option
+//   - fields whose name repeats the type name, such as Person.personName
+//   - registered types which can not be reached from any root
+//   - scalars without LiteralFunc
+//
+// A schema which does not build has no warnings, Build reports its error.
+func Lint(s *Schema) []Warning {
+	built, err := s.Build()
+	if err != nil {
+		return nil
+	}
+	return lint(s, built)
+}
+
+func lint(s *Schema, schema *internal.Schema) []Warning {
+	var warnings []Warning
+	maxInputFields := s.lintMaxInputFields
+	if maxInputFields <= 0 {
+		maxInputFields = DefaultLintMaxInputFields
+	}
+	warn := func(site, rule, format string, a ...interface{}) {
+		warnings = append(warnings, Warning{Rule: rule, Message: fmt.Sprintf(format, a...), CallSite: site})
+	}
+	reachable := reachableTypes(schema)
+	unused := func(site, name string) {
+		if !reachable[name] {
+			warn(site, "UnusedType", "type %s is registered but can not be reached from any root", name)
+		}
+	}
+
+	lintFields := func(site, typName string, fields map[string]*internal.Field) {
+		for name, f := range fields {
+			if list, ok := unwrapNonNull(f.Type).(*internal.List); ok {
+				if _, ok := list.Type.(*internal.NonNull); !ok {
+					warn(site, "NullableListItems", "field %s.%s returns %s, its items can be null", typName, name, f.Type.String())
+				}
+			}
+			if lower := strings.ToLower(name); len(lower) > len(typName) && strings.HasPrefix(lower, strings.ToLower(typName)) {
+				warn(site, "StutteringFieldName", "field %s.%s repeats the type name", typName, name)
+			}
+		}
+	}
+
+	for name, object := range s.objects {
+		if name != "Query" && name != "Mutation" && name != "Subscription" {
+			unused(object.callSite, name)
+		}
+		if typ, ok := schema.TypeMap[name].(*internal.Object); ok {
+			lintFields(object.callSite, name, typ.Fields)
+		}
+	}
+	for name, iface := range s.interfaces {
+		unused(iface.callSite, name)
+		if typ, ok := schema.TypeMap[name].(*internal.Interface); ok {
+			lintFields(iface.callSite, name, typ.Fields)
+		}
+	}
+	for name, union := range s.unions {
+		unused(union.callSite, name)
+	}
+	for name, enum := range s.enums {
+		unused(enum.callSite, name)
+		if len(enum.Map) == 1 {
+			warn(enum.callSite, "SingleValueEnum", "enum %s has a single value", name)
+		}
+	}
+	for name, input := range s.inputObjects {
+		unused(input.callSite, name)
+		if typ, ok := schema.TypeMap[name].(*internal.InputObject); ok && len(typ.Fields) > maxInputFields {
+			warn(input.callSite, "LargeInputObject", "input object %s has %d fields, more than %d", name, len(typ.Fields), maxInputFields)
+		}
+	}
+	for name, scalar := range s.scalars {
+		// builtin scalars are not registered by Schema.Scalar and have no call site
+		if scalar.callSite == "" {
+			continue
+		}
+		unused(scalar.callSite, name)
+		if !scalar.literal {
+			warn(scalar.callSite, "ScalarWithoutLiteral", "scalar %s has no LiteralFunc, literals are parsed with its ParseValue", name)
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Rule != warnings[j].Rule {
+			return warnings[i].Rule < warnings[j].Rule
+		}
+		return warnings[i].Message < warnings[j].Message
+	})
+	return warnings
+}
+
+// reachableTypes returns the names of every named type reachable from the roots of schema.
+func reachableTypes(schema *internal.Schema) map[string]bool {
+	reachable := make(map[string]bool)
+	var visit func(typ internal.Type)
+	visitFields := func(fields map[string]*internal.Field) {
+		for _, f := range fields {
+			visit(f.Type)
+			for _, arg := range f.Args {
+				visit(arg.Type)
+			}
+		}
+	}
+	visit = func(typ internal.Type) {
+		typ = unwrapNonNull(typ)
+		if list, ok := typ.(*internal.List); ok {
+			visit(list.Type)
+			return
+		}
+		named, ok := typ.(internal.NamedType)
+		if !ok || reachable[named.TypeName()] {
+			return
+		}
+		reachable[named.TypeName()] = true
+		switch typ := typ.(type) {
+		case *internal.Object:
+			visitFields(typ.Fields)
+			for _, iface := range typ.Interfaces {
+				visit(iface)
+			}
+		case *internal.Interface:
+			visitFields(typ.Fields)
+			for _, object := range typ.PossibleTypes {
+				visit(object)
+			}
+		case *internal.Union:
+			for _, object := range typ.Types {
+				visit(object)
+			}
+		case *internal.InputObject:
+			for _, f := range typ.Fields {
+				visit(f.Type)
+			}
+		}
+	}
+	for _, root := range []internal.Type{schema.Query, schema.Mutation, schema.Subscription} {
+		if root != nil {
+			visit(root)
+		}
+	}
+	for _, directive := range schema.Directives {
+		for _, arg := range directive.Args {
+			visit(arg.Type)
+		}
+	}
+	return reachable
+}
+
+func unwrapNonNull(typ internal.Type) internal.Type {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		return nonNull.Type
+	}
+	return typ
+}
+
+// callSite returns file:line of the caller skip frames above the function calling callSite.
+func callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
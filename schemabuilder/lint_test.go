@@ -0,0 +1,65 @@
+package schemabuilder_test
+
+import (
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type LintPerson struct {
+	Name      string `graphql:"name"`
+	PersonAge int    `graphql:"personAge"`
+}
+
+type LintOrphan struct {
+	Name string `graphql:"name"`
+}
+
+type LintMood int
+
+type LintMoney string
+
+type LintFilter struct {
+	Name string `graphql:"name"`
+	Age  int    `graphql:"age"`
+}
+
+func TestLint(t *testing.T) {
+	build := schemabuilder.NewSchema(schemabuilder.LintMaxInputFields(1))
+	build.Object("Person", LintPerson{})
+	build.Object("Orphan", LintOrphan{})
+	build.Enum("Mood", LintMood(0), map[string]interface{}{"HAPPY": LintMood(0)})
+	build.InputObject("Filter", LintFilter{})
+	build.Scalar("LintMoney", LintMoney(""), func(value interface{}, dest reflect.Value) error {
+		dest.SetString(value.(string))
+		return nil
+	})
+	query := build.Query()
+	query.FieldFunc("people", func(args struct {
+		Filter *LintFilter `graphql:"filter"`
+	}) []*LintPerson {
+		return nil
+	})
+	query.FieldFunc("mood", func() LintMood { return 0 })
+	query.FieldFunc("price", func() LintMoney { return "" })
+
+	var reported []schemabuilder.Warning
+	build.EnableLint(func(w schemabuilder.Warning) { reported = append(reported, w) })
+	build.MustBuild()
+
+	var rules []string
+	for _, w := range reported {
+		assert.Contains(t, w.CallSite, "lint_test.go:")
+		rules = append(rules, w.Rule+": "+w.Message)
+	}
+	assert.Equal(t, []string{
+		"LargeInputObject: input object Filter has 2 fields, more than 1",
+		"NullableListItems: field Query.people returns [Person], its items can be null",
+		"ScalarWithoutLiteral: scalar LintMoney has no LiteralFunc, literals are parsed with its ParseValue",
+		"SingleValueEnum: enum Mood has a single value",
+		"StutteringFieldName: field Person.personAge repeats the type name",
+		"UnusedType: type Orphan is registered but can not be reached from any root",
+	}, rules)
+	assert.Equal(t, reported, schemabuilder.Lint(build))
+}
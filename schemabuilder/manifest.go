@@ -0,0 +1,109 @@
+package schemabuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Manifest lists how every field of the objects of a built schema is resolved, for audits of the Go
+// code behind a schema. Schema.Manifest returns the manifest of the last schema Build made.
+type Manifest struct {
+	Fields []ManifestField `json:"fields"`
+}
+
+// ManifestField tells how a field is resolved.
+type ManifestField struct {
+	// Coordinate is the schema coordinate of the field, such as Query.user.
+	Coordinate string `json:"coordinate"`
+	// Type is the GraphQL type of the field.
+	Type string                `json:"type"`
+	Kind internal.ResolverKind `json:"kind"`
+	// Resolver is the Go function resolving the field, or the struct field it is read from.
+	Resolver string `json:"resolver"`
+	// Source is the file:line the resolver function is declared at.
+	Source string `json:"source,omitempty"`
+	// CallSite is the file:line of the FieldFunc or FieldOption call registering the field.
+	CallSite string `json:"callSite,omitempty"`
+	// Args is the Go type of the arguments of the resolver function.
+	Args string `json:"args,omitempty"`
+	// Returns are the Go types the resolver function returns.
+	Returns []string `json:"returns,omitempty"`
+	// Options are the options of the field, named after the functions making them, such as
+	// schemabuilder.MemoizePerRequest.
+	Options    []string `json:"options,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+}
+
+// WriteJSON writes m to w as indented JSON.
+func (m *Manifest) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// Manifest returns the manifest of the schema Build last made, or nil when it was not built.
+func (s *Schema) Manifest() *Manifest {
+	return s.manifest
+}
+
+// funcManifest fills in entry with the resolver function fn of a field of objects of type src.
+func funcManifest(entry *ManifestField, fn interface{}, src reflect.Type) {
+	fun := reflect.ValueOf(fn)
+	if fun.Kind() != reflect.Func {
+		return
+	}
+	if f := runtime.FuncForPC(fun.Pointer()); f != nil {
+		entry.Resolver = f.Name()
+		file, line := f.FileLine(f.Entry())
+		entry.Source = fmt.Sprintf("%s:%d", file, line)
+	}
+	fctx := &funcContext{typ: src, funcType: fun.Type()}
+	if in := fctx.consumeContextAndSource(fctx.getFuncInputTypes()); len(in) > 0 {
+		entry.Args = in[0].String()
+	}
+	for i := 0; i < fctx.funcType.NumOut(); i++ {
+		entry.Returns = append(entry.Returns, fctx.funcType.Out(i).String())
+	}
+}
+
+// closureSuffix matches the suffixes of the names of the functions a function returns.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// optionName names option after the function which made it, such as schemabuilder.Masked for the
+// function Masked returns. Options which are not functions are named after their type.
+func optionName(option interface{}) string {
+	switch option.(type) {
+	case foundBool:
+		return "schemabuilder.FoundBool"
+	}
+	value := reflect.ValueOf(option)
+	if value.Kind() != reflect.Func {
+		return value.Type().String()
+	}
+	if value.Pointer() == reflect.ValueOf(RelayConnection).Pointer() {
+		return "schemabuilder.RelayConnection"
+	}
+	f := runtime.FuncForPC(value.Pointer())
+	if f == nil {
+		return value.Type().String()
+	}
+	name := closureSuffix.ReplaceAllString(f.Name(), "")
+	// keep the last element of the import path only
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func sortManifest(fields []ManifestField) {
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Coordinate < fields[j].Coordinate
+	})
+}
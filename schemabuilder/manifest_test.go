@@ -0,0 +1,69 @@
+package schemabuilder_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type ManifestUser struct {
+	Name  string `graphql:"name"`
+	Email string `graphql:"email"`
+}
+
+type ManifestUserArgs struct {
+	ID int `graphql:"id"`
+}
+
+func manifestUser(ctx context.Context, args ManifestUserArgs) (*ManifestUser, error) {
+	return &ManifestUser{}, nil
+}
+
+func requireAdmin(ctx context.Context, args, source interface{}) error {
+	return nil
+}
+
+func TestManifest(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	user := build.Object("User", ManifestUser{})
+	user.FieldOption("email", schemabuilder.Masked(func(ctx context.Context, source interface{}) bool { return true }))
+	build.Query().FieldFunc("user", manifestUser, "a user", schemabuilder.ExecuteFunc(requireAdmin), schemabuilder.MemoizePerRequest())
+	assert.Nil(t, build.Manifest())
+	build.MustBuild()
+
+	manifest := build.Manifest()
+	var coordinates []string
+	fields := map[string]schemabuilder.ManifestField{}
+	for _, field := range manifest.Fields {
+		coordinates = append(coordinates, field.Coordinate)
+		fields[field.Coordinate] = field
+	}
+	assert.Equal(t, []string{"Query.user", "User.email", "User.name"}, coordinates)
+
+	query := fields["Query.user"]
+	assert.Equal(t, "User", query.Type)
+	assert.Equal(t, "func", string(query.Kind))
+	assert.Equal(t, "github.com/shyptr/graphql/schemabuilder_test.manifestUser", query.Resolver)
+	assert.True(t, strings.Contains(query.Source, "manifest_test.go:"), query.Source)
+	assert.True(t, strings.Contains(query.CallSite, "manifest_test.go:"), query.CallSite)
+	assert.Equal(t, "schemabuilder_test.ManifestUserArgs", query.Args)
+	assert.Equal(t, []string{"*schemabuilder_test.ManifestUser", "error"}, query.Returns)
+	assert.Equal(t, []string{"schemabuilder_test.requireAdmin", "schemabuilder.MemoizePerRequest"}, query.Options)
+
+	email := fields["User.email"]
+	assert.Equal(t, "struct field", string(email.Kind))
+	assert.Equal(t, "schemabuilder_test.ManifestUser.Email", email.Resolver)
+	assert.True(t, strings.Contains(email.CallSite, "manifest_test.go:"), email.CallSite)
+	assert.Equal(t, []string{"schemabuilder.Masked"}, email.Options)
+	assert.Empty(t, fields["User.name"].CallSite)
+
+	var buf bytes.Buffer
+	assert.NoError(t, manifest.WriteJSON(&buf))
+	var decoded schemabuilder.Manifest
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, manifest, &decoded)
+}
@@ -0,0 +1,83 @@
+package schemabuilder
+
+import "reflect"
+
+// OptionalString is the type of String arguments and input object fields which tell an omitted
+// value apart from an explicit null: Set is false when the value is omitted, Null is true when it is
+// given null, and Value holds it otherwise. An omitted value with a default value is Set to it.
+//
+// Optional values of other types are declared the same way, as a struct of the Set and Null bools
+// followed by the Value, whose nullable type the optional value has:
+//    type OptionalColor struct {
+//        Set, Null bool
+//        Value     Color
+//    }
+type OptionalString struct {
+	Set, Null bool
+	Value     string
+}
+
+// OptionalInt is the optional Int value, see OptionalString.
+type OptionalInt struct {
+	Set, Null bool
+	Value     int
+}
+
+// OptionalFloat is the optional Float value, see OptionalString.
+type OptionalFloat struct {
+	Set, Null bool
+	Value     float64
+}
+
+// OptionalBool is the optional Boolean value, see OptionalString.
+type OptionalBool struct {
+	Set, Null bool
+	Value     bool
+}
+
+// OptionalID is the optional ID value, see OptionalString.
+type OptionalID struct {
+	Set, Null bool
+	Value     Id
+}
+
+// optionalValue returns the type of the Value of typ when typ is an optional type, see OptionalString.
+func optionalValue(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Struct || typ.NumField() != 3 {
+		return nil, false
+	}
+	set, null, value := typ.Field(0), typ.Field(1), typ.Field(2)
+	if set.Name != "Set" || set.Type.Kind() != reflect.Bool || null.Name != "Null" || null.Type.Kind() != reflect.Bool ||
+		value.Name != "Value" {
+		return nil, false
+	}
+	return value.Type, true
+}
+
+// optional returns the value of the optional type typ for arg, the value of an argument or an input
+// object field, which given tells whether the argument or field was given.
+func (sb *schemaBuilder) optional(typ reflect.Type, arg interface{}, given bool) (interface{}, error) {
+	opt := reflect.New(typ).Elem()
+	opt.Field(0).SetBool(given)
+	if !given {
+		return opt.Interface(), nil
+	}
+	if arg == nil {
+		opt.Field(1).SetBool(true)
+		return opt.Interface(), nil
+	}
+	valueTyp := typ.Field(2).Type
+	for valueTyp.Kind() == reflect.Ptr {
+		valueTyp = valueTyp.Elem()
+	}
+	decoded, err := sb.cacheTypes[valueTyp](arg)
+	if err != nil {
+		return nil, err
+	}
+	if decoded != nil {
+		if err := value(opt.Field(2), reflect.ValueOf(decoded)); err != nil {
+			return nil, err
+		}
+	}
+	return opt.Interface(), nil
+}
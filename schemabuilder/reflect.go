@@ -2,9 +2,12 @@ package schemabuilder
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"github.com/shyptr/graphql/internal"
 	"go/ast"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -189,3 +192,27 @@ func value(f reflect.Value, v reflect.Value) error {
 // Common Types that we will need to perform type assertions against.
 var errType = reflect.TypeOf((*error)(nil)).Elem()
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// sqlNullTypes are the nullable wrappers of database/sql, whose fields are nullable. Other types
+// implementing driver.Valuer keep the non-null mapping of their scalar.
+var sqlNullTypes = map[reflect.Type]bool{
+	reflect.TypeOf(sql.NullString{}):  true,
+	reflect.TypeOf(sql.NullInt64{}):   true,
+	reflect.TypeOf(sql.NullInt32{}):   true,
+	reflect.TypeOf(sql.NullFloat64{}): true,
+	reflect.TypeOf(sql.NullBool{}):    true,
+	reflect.TypeOf(sql.NullTime{}):    true,
+}
+var objectType = reflect.TypeOf(&internal.Object{})
+
+// sortedKeys returns the keys of m, a map keyed by strings, in order, so that building the same
+// registrations always visits them, and reports their errors, in the same order.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
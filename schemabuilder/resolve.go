@@ -19,17 +19,26 @@ func (sb *schemaBuilder) getArguments(typ reflect.Type) (map[string]*internal.In
 		if skip {
 			continue
 		}
-		fieldTyp, err := sb.getType(field.Type)
-		if err != nil {
+		src := field.Type
+		// optional values have the nullable type of their value
+		elem := src
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if value, ok := optionalValue(elem); ok {
+			src = reflect.PtrTo(value)
+		}
+		if err := sb.generateInputObject(src); err != nil {
 			return nil, err
 		}
-		if nonnull {
-			fieldTyp = &internal.NonNull{Type: fieldTyp}
+		fieldTyp, err := sb.getType(src)
+		if err != nil {
+			return nil, err
 		}
-		if nof, ok := fieldTyp.(*internal.NonNull); ok && null {
-			fieldTyp = nof.Type
+		if fieldTyp, err = sb.tagNullability(src, fieldTyp, null, nonnull, name); err != nil {
+			return nil, err
 		}
-		err = sb.getArgResolve(field.Type, fieldTyp)
+		err = sb.getArgResolve(src, fieldTyp)
 		if err != nil {
 			return nil, err
 		}
@@ -50,6 +59,16 @@ func (sb *schemaBuilder) getArguments(typ reflect.Type) (map[string]*internal.In
 	return args, nil
 }
 
+// enumGoValue tells whether value is the Go value of one of the values of enum, rather than its name,
+// as are the enum values of variables coerced by execution.ValidateVariables.
+func enumGoValue(enum *internal.Enum, value interface{}) bool {
+	if !reflect.TypeOf(value).Comparable() {
+		return false
+	}
+	_, ok := enum.Map[value]
+	return ok
+}
+
 func (sb *schemaBuilder) getArgResolve(src reflect.Type, typ internal.Type) error {
 	for src.Kind() == reflect.Ptr {
 		src = src.Elem()
@@ -57,12 +76,22 @@ func (sb *schemaBuilder) getArgResolve(src reflect.Type, typ internal.Type) erro
 	if _, ok := sb.cacheTypes[src]; ok {
 		return nil
 	}
+	// the JSON objects of maps are decoded into their own map type
+	if _, ok := sb.scalars[src]; !ok && src.Kind() == reflect.Map {
+		sb.cacheTypes[src] = func(value interface{}) (interface{}, error) {
+			return decodeJSONMap(value, src)
+		}
+		return nil
+	}
 	switch typ := typ.(type) {
 	case *internal.Scalar:
 		sb.cacheTypes[src] = func(value interface{}) (interface{}, error) {
 			if value == nil {
 				return nil, nil
 			}
+			if literal, ok := value.(internal.LiteralValue); ok {
+				return literal.Value, nil
+			}
 			return typ.ParseValue(value)
 		}
 		return nil
@@ -71,10 +100,17 @@ func (sb *schemaBuilder) getArgResolve(src reflect.Type, typ internal.Type) erro
 			if value == nil {
 				return nil, nil
 			}
-			if _, ok := value.(string); !ok {
+			if name, ok := value.(string); ok {
+				if mapped, ok := typ.ReverseMap[name]; ok || !enumGoValue(typ, value) {
+					value = mapped
+				}
+			} else if !enumGoValue(typ, value) {
 				return nil, fmt.Errorf("enum value must be string")
 			}
-			return typ.ReverseMap[value.(string)], nil
+			if typ.InputMapper != nil {
+				return typ.InputMapper(value)
+			}
+			return value, nil
 		}
 		return nil
 	case *internal.InputObject:
@@ -137,7 +173,7 @@ func (sb *schemaBuilder) converToStruct(typ reflect.Type) resolveFunc {
 
 		if input, ok := sb.inputObjects[typ]; ok {
 			for name, f := range input.Fields {
-				if _, ok := args[name]; !ok {
+				if _, ok := args[name]; !ok && f.DefaultValue != nil {
 					args[name] = f.DefaultValue
 				}
 			}
@@ -154,6 +190,15 @@ func (sb *schemaBuilder) converToStruct(typ reflect.Type) resolveFunc {
 			for ftyp.Kind() == reflect.Ptr {
 				ftyp = ftyp.Elem()
 			}
+			if _, ok := optionalValue(ftyp); ok {
+				v, given := args[name]
+				opt, err := sb.optional(ftyp, v, given)
+				if err != nil {
+					return nil, err
+				}
+				conver[name] = opt
+				continue
+			}
 			if v, ok := args[name]; ok {
 				vv, err := sb.cacheTypes[ftyp](v)
 				if err != nil {
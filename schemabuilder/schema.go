@@ -1,13 +1,15 @@
 package schemabuilder
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/shyptr/graphql/ast"
 	"github.com/shyptr/graphql/internal"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // schema builder
@@ -28,26 +30,230 @@ type Schema struct {
 	unions       map[string]*Union
 	scalars      map[string]*Scalar
 	directives   map[string]*Directive
+	lint         func(Warning)
+	timeLayout   string
+	timeLoc      *time.Location
+	errs         RegistrationErrors
+	// strictNullability is set by StrictNullability
+	strictNullability bool
+	// guardMutations is set by GuardMutations
+	guardMutations bool
+	// lintMaxInputFields is set by LintMaxInputFields
+	lintMaxInputFields int
+	// requireNamedArgs is set by RequireNamedArgStructs, inputNaming by GenerateInputObjects
+	requireNamedArgs bool
+	inputNaming      InputNaming
+	// middlewares wrap the resolvers of all the fields, see Use
+	middlewares []Middleware
+	// manifest is made by Build
+	manifest *Manifest
+}
+
+// RegistrationErrors are the problems found while registering types, Build reports them all at once.
+type RegistrationErrors []error
+
+func (errs RegistrationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// fail records a problem found while registering types at site.
+// Types created by the builder itself belong to no schema, their problems still panic.
+func (s *Schema) fail(site string, format string, a ...interface{}) {
+	err := fmt.Errorf(format, a...)
+	if site != "" {
+		err = fmt.Errorf("%s: %w", site, err)
+	}
+	if s == nil {
+		panic(err.Error())
+	}
+	s.errs = append(s.errs, err)
+}
+
+// SchemaOption configures a Schema created by NewSchema.
+type SchemaOption func(*Schema)
+
+// StrictNullability maps the nullability of Go types to GraphQL types the same way for
+// the fields of objects, the arguments and the fields of input objects:
+//
+//   T      T!
+//   *T     T
+//   []T    [T!]!
+//   []*T   [T]!
+//   *[]T   [T!]
+//   *[]*T  [T]
+//
+// A nil slice is an empty list, only a nil pointer to a slice is null.
+// Without it slices are nullable lists as pointers to slices are.
+//
+// The null and nonnull tags may not contradict the type of the field, Build fails when a
+// nonnull tag is set on a pointer or a null tag on a value.
+func StrictNullability() SchemaOption {
+	return func(s *Schema) {
+		s.strictNullability = true
+	}
+}
+
+// GuardMutations makes the fields of Mutation fail with internal.ErrReadOnly when they are resolved
+// for a read-only operation, see execution.WithReadOnly, before their resolver runs.
+func GuardMutations() SchemaOption {
+	return func(s *Schema) {
+		s.guardMutations = true
+	}
+}
+
+// RequireNamedArgStructs makes Build fail on the fields whose arguments are an anonymous struct, such as
+// func(args struct{ Name string }), pointing at where the field was registered, so that the arguments
+// of the fields are declared by named structs which fields can share.
+func RequireNamedArgStructs() SchemaOption {
+	return func(s *Schema) {
+		s.requireNamedArgs = true
+	}
+}
+
+// InputNaming names the input object generated for a struct type, see GenerateInputObjects.
+type InputNaming func(typ reflect.Type) string
+
+// DefaultInputNaming names the input object of a struct after it, with its suffix Args replaced by Input,
+// or Input added: SearchArgs and SearchFilter make SearchInput and SearchFilterInput.
+func DefaultInputNaming(typ reflect.Type) string {
+	name := strings.TrimSuffix(typ.Name(), "Args")
+	if strings.HasSuffix(name, "Input") {
+		return name
+	}
+	return name + "Input"
+}
+
+// GenerateInputObjects makes Build generate the input objects of the named structs which arguments and
+// input objects take without them being registered with InputObject, named by naming, DefaultInputNaming
+// by default. A struct makes one input object, whatever the number of fields taking it. Two structs
+// named alike fail the build.
+func GenerateInputObjects(naming ...InputNaming) SchemaOption {
+	return func(s *Schema) {
+		s.inputNaming = DefaultInputNaming
+		if len(naming) > 0 {
+			s.inputNaming = naming[0]
+		}
+	}
+}
+
+// ResolveInfo describes the field a FieldResolver resolves.
+type ResolveInfo struct {
+	// Type is the name of the object of the field, Field the name of the field.
+	Type  string
+	Field string
+	// Path is the path of the field in the response, such as [users 0 name].
+	Path []interface{}
+}
+
+// FieldResolver resolves the field of info on the value source of its object. args are the values of the
+// arguments of the selection by name, in a map[string]interface{}.
+type FieldResolver func(ctx context.Context, info ResolveInfo, source, args interface{}) (interface{}, error)
+
+// Middleware wraps the resolver of a field, see Schema.Use.
+type Middleware func(next FieldResolver) FieldResolver
+
+// Use wraps the resolvers of all the fields of the objects of the schema with middlewares, for concerns
+// such as logging, tracing or authorization. The first middleware is the outermost one, the middlewares
+// of earlier calls wrap those of later ones, and the options of the fields run within them:
+//
+//	build.Use(func(next schemabuilder.FieldResolver) schemabuilder.FieldResolver {
+//		return func(ctx context.Context, info schemabuilder.ResolveInfo, source, args interface{}) (interface{}, error) {
+//			start := time.Now()
+//			defer func() { log.Printf("%s.%s %v took %v", info.Type, info.Field, info.Path, time.Since(start)) }()
+//			return next(ctx, info, source, args)
+//		}
+//	})
+//
+// A middleware may return an error without calling next, the field fails with it at its location.
+// A field whose resolver returns a thunk is only wrapped until the thunk is returned.
+func (s *Schema) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// useMiddlewares wraps the resolver of the field name of the object typ with middlewares.
+func useMiddlewares(typ, name string, field *internal.Field, middlewares []Middleware) {
+	resolve := field.Resolve
+	next := FieldResolver(func(ctx context.Context, info ResolveInfo, source, args interface{}) (interface{}, error) {
+		return resolve(ctx, source, args)
+	})
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+	field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		return next(ctx, ResolveInfo{Type: typ, Field: name, Path: internal.PathOf(ctx)}, source, args)
+	}
+	field.ResolvePath = true
+}
+
+func guardWritable(field *internal.Field) {
+	resolve := field.Resolve
+	field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		if internal.IsReadOnly(ctx) {
+			return nil, internal.ErrReadOnly
+		}
+		return resolve(ctx, source, args)
+	}
 }
 
 // NewSchema creates a new schema.
-func NewSchema() *Schema {
+func NewSchema(opts ...SchemaOption) *Schema {
 	schema := &Schema{
 		objects:      map[string]*Object{},
 		enums:        map[string]*Enum{},
 		inputObjects: map[string]*InputObject{},
 		interfaces:   map[string]*Interface{},
 		unions:       map[string]*Union{},
-		scalars:      scalars,
+		scalars:      make(map[string]*Scalar, len(scalars)),
 		directives: map[string]*Directive{
 			"include": IncludeDirective,
 			"skip":    SkipDirective,
 		},
 	}
+	// the builtin scalars are copied, so that the scalars registered or replaced in a schema stay in it
+	for name, scalar := range scalars {
+		schema.scalars[name] = scalar
+	}
+	for _, opt := range opts {
+		opt(schema)
+	}
 
 	return schema
 }
 
+// fingerprint identifies the parameters a type was registered with.
+// Registering again a type with an identical fingerprint is a no-op, so that packages can register
+// the types they share, while a different fingerprint under the same name is an error.
+type fingerprint struct {
+	typ    reflect.Type
+	desc   string
+	values interface{}
+}
+
+// checkRegistered records an error with both call sites when the type named name
+// was registered with a fingerprint different from fp.
+func (s *Schema) checkRegistered(kind, name string, registered, fp fingerprint, registeredSite, site string) {
+	if registered.typ == fp.typ && registered.desc == fp.desc && reflect.DeepEqual(registered.values, fp.values) {
+		return
+	}
+	if registeredSite == "" {
+		registeredSite = "builtin"
+	}
+	s.fail("", "re-registered %s %s with a different definition, registered at %s and %s", kind, name, registeredSite, site)
+}
+
+// funcPointer returns the code pointer of fn, which is all that can be compared of two funcs,
+// or 0 when fn is not a func.
+func funcPointer(fn interface{}) uintptr {
+	if value := reflect.ValueOf(fn); value.Kind() == reflect.Func {
+		return value.Pointer()
+	}
+	return 0
+}
+
 // only use in enum definition
 // can set description for enum value
 var DescFieldTyp = reflect.TypeOf(DescField{})
@@ -75,16 +281,18 @@ type DescField struct {
 //     "two":   two,
 //     "three": three,
 //   },"")
-func (s *Schema) Enum(name string, val interface{}, enum interface{}, desc ...string) {
+//
+// The options are a string for the description, EnumOutputMapper, EnumInputMapper and DeprecatedValues.
+func (s *Schema) Enum(name string, val interface{}, enum interface{}, options ...interface{}) {
+	site := callSite(1)
 	if name == "" {
-		panic("enum must provide name")
-	}
-	if _, ok := s.enums[name]; ok {
-		panic(fmt.Sprintf("duplicate enum %s", name))
+		s.fail(site, "enum must provide name")
+		return
 	}
 	enumMap := reflect.ValueOf(enum)
 	if enumMap.Kind() != reflect.Map {
-		panic("enum must be a map")
+		s.fail(site, "enum %s must be a map", name)
+		return
 	}
 	typ := reflect.TypeOf(val)
 	if s.enums == nil {
@@ -106,10 +314,12 @@ func (s *Schema) Enum(name string, val interface{}, enum interface{}, desc ...st
 				desc = value.FieldByName("Desc").String()
 				valInterface = value.FieldByName("Field").Interface()
 				if reflect.TypeOf(valInterface).Kind() != typ.Kind() {
-					panic("enum descField's field types are not equal")
+					s.fail(site, "enum %s descField's field types are not equal", name)
+					return
 				}
 			} else {
-				panic("enum types are not equal")
+				s.fail(site, "enum %s types are not equal", name)
+				return
 			}
 		}
 		key := em.Key().String()
@@ -118,16 +328,48 @@ func (s *Schema) Enum(name string, val interface{}, enum interface{}, desc ...st
 		dMap[key] = desc
 	}
 	var d string
-	if len(desc) > 0 {
-		d = desc[0]
+	var outputMapper enumOutputMapper
+	var inputMapper enumInputMapper
+	var deprecated deprecatedValues
+	for _, op := range options {
+		switch op := op.(type) {
+		case string:
+			d = op
+		case enumOutputMapper:
+			outputMapper = op
+		case enumInputMapper:
+			inputMapper = op
+		case deprecatedValues:
+			for value := range op {
+				if _, ok := eMap[value]; !ok {
+					s.fail(site, "enum %s has no value %s to deprecate", name, value)
+					return
+				}
+			}
+			deprecated = op
+		default:
+			s.fail(site, "enum %s options only receive string for desc, EnumOutputMapper, EnumInputMapper and DeprecatedValues", name)
+			return
+		}
+	}
+	fp := fingerprint{typ: typ, desc: d, values: []interface{}{eMap, dMap, map[string]string(deprecated), funcPointer(outputMapper), funcPointer(inputMapper)}}
+	if enum, ok := s.enums[name]; ok {
+		s.checkRegistered("enum", name, enum.fingerprint, fp, enum.callSite, site)
+		return
 	}
 	s.enums[name] = &Enum{
-		Name:       name,
-		Desc:       d,
-		Type:       val,
-		Map:        eMap,
-		ReverseMap: rMap,
-		DescMap:    dMap,
+		Name:          name,
+		Desc:          d,
+		Type:          val,
+		Map:           eMap,
+		ReverseMap:    rMap,
+		DescMap:       dMap,
+		DeprecatedMap: deprecated,
+		callSite:      site,
+		fingerprint:   fp,
+
+		outputMapper: outputMapper,
+		inputMapper:  inputMapper,
 	}
 }
 
@@ -136,28 +378,32 @@ func (s *Schema) Enum(name string, val interface{}, enum interface{}, desc ...st
 // we'll return an Object struct that we can use to register custom
 // relationships and fields on the object.
 func (s *Schema) Object(name string, typ interface{}, desc ...string) *Object {
+	return s.object(name, typ, callSite(1), desc...)
+}
+
+func (s *Schema) object(name string, typ interface{}, site string, desc ...string) *Object {
 	objTyp := reflect.TypeOf(typ)
 	if name == "" {
 		name = objTyp.Name()
 	}
-	if object, ok := s.objects[name]; ok {
-		if reflect.TypeOf(object.Type) != objTyp {
-			var t = reflect.TypeOf(object.Type)
-			panic(fmt.Sprintf("re-registered object with different type, already registered type:"+
-				" %s.%s", t.PkgPath(), t.Name()))
-		}
-		return object
-	}
 	var d string
 	if len(desc) > 0 {
 		d = desc[0]
 	}
+	fp := fingerprint{typ: objTyp, desc: d}
+	if object, ok := s.objects[name]; ok {
+		s.checkRegistered("object", name, object.fingerprint, fp, object.callSite, site)
+		return object
+	}
 	object := &Object{
 		Name:         name,
 		Desc:         d,
 		Type:         typ,
 		FieldResolve: map[string]*fieldResolve{},
 		Interface:    []*Interface{},
+		schema:       s,
+		callSite:     site,
+		fingerprint:  fp,
 	}
 	s.objects[name] = object
 	return object
@@ -166,22 +412,24 @@ func (s *Schema) Object(name string, typ interface{}, desc ...string) *Object {
 // InputObject registers a struct as inout object which can be passed as an argument to a Query or Mutation
 // We'll read through the fields of the struct and create argument parsers to fill the data from graphQL JSON input
 func (s *Schema) InputObject(name string, typ interface{}, desc ...string) *InputObject {
-	if inputObject, ok := s.inputObjects[name]; ok {
-		if reflect.TypeOf(inputObject.Type) != reflect.TypeOf(typ) {
-			var t = reflect.TypeOf(inputObject.Type)
-			panic(fmt.Sprintf("re-registered input object with different type, already registered type:"+
-				" %s.%s", t.PkgPath(), t.Name()))
-		}
-	}
+	site := callSite(1)
 	var d string
 	if len(desc) > 0 {
 		d = desc[0]
 	}
+	fp := fingerprint{typ: reflect.TypeOf(typ), desc: d}
+	if inputObject, ok := s.inputObjects[name]; ok {
+		s.checkRegistered("input object", name, inputObject.fingerprint, fp, inputObject.callSite, site)
+		return inputObject
+	}
 	inputObject := &InputObject{
-		Name:   name,
-		Type:   typ,
-		Desc:   d,
-		Fields: map[string]*inputFieldResolve{},
+		Name:        name,
+		Type:        typ,
+		Desc:        d,
+		Fields:      map[string]*inputFieldResolve{},
+		schema:      s,
+		callSite:    site,
+		fingerprint: fp,
 	}
 	s.inputObjects[name] = inputObject
 
@@ -217,22 +465,21 @@ func (s *Schema) InputObject(name string, typ interface{}, desc ...string) *Inpu
 //	}
 //}
 func (s *Schema) Scalar(name string, tp interface{}, options ...interface{}) *Scalar {
-
+	site := callSite(1)
 	typ := reflect.TypeOf(tp)
-	if typ.Kind() == reflect.Ptr {
-		panic("type should not be of pointer type")
-	}
-
 	if name == "" {
 		name = typ.Name()
 	}
-
-	if _, ok := s.scalars[name]; ok {
-		panic("duplicate scalar name")
+	// a scalar which fails to register is not added to the schema
+	failed := &Scalar{Name: name, Type: tp}
+	if typ.Kind() == reflect.Ptr {
+		s.fail(site, "scalar %s type should not be of pointer type", name)
+		return failed
 	}
 
 	var ufn UnmarshalFunc
 	var desc string
+	var specifiedBy SpecifiedBy
 
 	for _, op := range options {
 		switch op := op.(type) {
@@ -240,18 +487,27 @@ func (s *Schema) Scalar(name string, tp interface{}, options ...interface{}) *Sc
 			desc = op
 		case UnmarshalFunc:
 			ufn = op
+		case SpecifiedBy:
+			specifiedBy = op
 		default:
 			if reflect.TypeOf(op).ConvertibleTo(UnmarshalFuncTyp) {
 				ufn = reflect.ValueOf(op).Convert(UnmarshalFuncTyp).Interface().(UnmarshalFunc)
 				continue
 			}
-			panic("scalar options only receive string for desc and UnmarshalFunc for parseFunc")
+			s.fail(site, "scalar %s options only receive string for desc, UnmarshalFunc for parseFunc and SpecifiedBy", name)
+			return failed
 		}
 	}
+	fp := fingerprint{typ: typ, desc: desc, values: funcPointer(ufn)}
+	if scalar, ok := s.scalars[name]; ok {
+		s.checkRegistered("scalar", name, scalar.fingerprint, fp, scalar.callSite, site)
+		return scalar
+	}
 
 	if ufn == nil {
 		if !reflect.PtrTo(typ).Implements(reflect.TypeOf(new(json.Unmarshaler)).Elem()) {
-			panic("either UnmarshalFunc should be provided or the provided type should implement json.Unmarshaler interface")
+			s.fail(site, "scalar %s: either UnmarshalFunc should be provided or the provided type should implement json.Unmarshaler interface", name)
+			return failed
 		}
 		f, _ := reflect.PtrTo(typ).MethodByName("UnmarshalJSON")
 		ufn = func(value interface{}, dest reflect.Value) error {
@@ -263,6 +519,8 @@ func (s *Schema) Scalar(name string, tp interface{}, options ...interface{}) *Sc
 				x = []byte(v)
 			case float64:
 				x = []byte(strconv.FormatFloat(v, 'g', -1, 64))
+			case json.Number:
+				x = []byte(v)
 			case int64:
 				x = []byte(strconv.FormatInt(v, 10))
 			case bool:
@@ -291,106 +549,218 @@ func (s *Schema) Scalar(name string, tp interface{}, options ...interface{}) *Sc
 		return outVal.Interface(), err
 	}
 	scalar := &Scalar{
-		Name:       name,
-		Desc:       desc,
-		Type:       tp,
-		Serialize:  Serialize,
-		ParseValue: parseValue,
-		ParseLiteral: func(value ast.Value) error {
-			_, err := parseValue(value.GetValue())
-			return err
-		},
+		Name:           name,
+		Desc:           desc,
+		Type:           tp,
+		Serialize:      Serialize,
+		ParseValue:     parseValue,
+		SpecifiedByURL: string(specifiedBy),
+		callSite:       site,
+		fingerprint:    fp,
 	}
 	s.scalars[name] = scalar
 	return scalar
 }
 
+// ReplaceScalar replaces the scalar registered as name, usually a builtin one, by scalar in this
+// schema only, for example a Time scalar parsing another layout:
+//    build.ReplaceScalar("Time", &schemabuilder.Scalar{
+//        Serialize:  schemabuilder.Time.Serialize,
+//        ParseValue: parseDate,
+//    })
+//
+// scalar keeps the name and the Go type of the replaced scalar, its Type may be left nil.
+func (s *Schema) ReplaceScalar(name string, scalar *Scalar) {
+	site := callSite(1)
+	replaced, ok := s.scalars[name]
+	if !ok {
+		s.fail(site, "there is no scalar %s to replace", name)
+		return
+	}
+	if scalar.Type != nil && reflect.TypeOf(scalar.Type) != reflect.TypeOf(replaced.Type) {
+		s.fail(site, "scalar %s of type %T can not replace scalar %s of type %T", scalar.Name, scalar.Type, name, replaced.Type)
+		return
+	}
+	copied := *scalar
+	copied.Name = name
+	copied.Type = replaced.Type
+	if copied.Serialize == nil {
+		copied.Serialize = replaced.Serialize
+	}
+	if copied.ParseValue == nil {
+		copied.ParseValue = replaced.ParseValue
+	}
+	if copied.Desc == "" {
+		copied.Desc = replaced.Desc
+	}
+	s.scalars[name] = &copied
+}
+
 // Union registers a map as a GraphQL Union in our Schema.
 func (s *Schema) Union(name string, union interface{}, desc string) {
+	site := callSite(1)
 	typ := reflect.TypeOf(union)
 	if typ.Kind() != reflect.Struct {
-		panic("union must be a struct")
+		s.fail(site, "union %s must be a struct", name)
+		return
 	}
 	if _, ok := s.unions[name]; ok {
-		panic("duplicate union " + name)
+		s.fail(site, "duplicate union %s", name)
+		return
 	}
 
 	types := make([]reflect.Type, typ.NumField())
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
 		if f.Type.Kind() != reflect.Ptr || f.Type.Elem().Kind() != reflect.Struct {
-			panic("union's member must be a object struct ptr")
+			s.fail(site, "union %s's member must be a object struct ptr", name)
+			return
 		}
 		types[i] = f.Type
 	}
 
 	s.unions[name] = &Union{
-		Name:  name,
-		Desc:  desc,
-		Type:  union,
-		Types: types,
+		Name:     name,
+		Desc:     desc,
+		Type:     union,
+		Types:    types,
+		callSite: site,
 	}
 }
 
 // Interface registers a Interface as a GraphQL Interface in our Schema.
+// typeResolve is either nil, a func taking an optional context and the source, or the name of a method of the interface.
+// It returns a value of the resolved type, or the name of the resolved type, optionally followed by an error.
+// The context is the one of the request, and typeResolve is not called for nil values, which are null.
 func (s *Schema) Interface(name string, typ interface{}, typeResolve interface{}, descs ...string) *Interface {
-	if typ == nil {
-		panic("nil type passed to Interface")
-	}
-	if name == "" {
-		panic("must provide name")
-	}
-	t := reflect.TypeOf(typ)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	if t.Kind() != reflect.Interface {
-		panic("Interface must be a interface Operation in Golang")
-	}
-	if _, ok := s.interfaces[name]; ok {
-		panic("duplicate interface " + name)
-	}
+	site := callSite(1)
 	var desc string
 	if len(descs) > 0 {
 		desc = descs[0]
 	}
-	s.interfaces[name] = &Interface{
+	iface := &Interface{
 		Name:          name,
 		Desc:          desc,
 		Type:          typ,
 		Fn:            typeResolve,
 		PossibleTypes: map[string]*Object{},
+		schema:        s,
+		callSite:      site,
 	}
-	return s.interfaces[name]
+	// an interface which fails to register is returned without being added to the schema
+	if name == "" {
+		s.fail(site, "interface must provide name")
+		return iface
+	}
+	if invalid, ok := typ.(invalidInterfaceType); ok {
+		s.fail(site, "interface %s: %s", name, string(invalid))
+		return iface
+	}
+	// a nil interface value has no type, any other value but a pointer is that of a concrete type
+	t := reflect.TypeOf(typ)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		s.fail(site, "%s", interfaceRegistrationError(name))
+		return iface
+	}
+	if registered, ok := s.interfaces[name]; ok {
+		s.fail(site, "duplicate interface %s", name)
+		return registered
+	}
+	s.interfaces[name] = iface
+	return iface
+}
+
+// InterfaceType returns the value registering a Go interface with Interface, (*NamedEntity)(nil) for
+// the interface NamedEntity, from a pointer to it such as new(NamedEntity), or from its reflect.Type:
+//    build.Interface("NamedEntity", schemabuilder.InterfaceType(entityType), resolveEntity)
+//
+// Anything else makes Interface fail, Build then reports the error.
+func InterfaceType(ptr interface{}) interface{} {
+	t, ok := ptr.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(ptr)
+		if t == nil || t.Kind() != reflect.Ptr {
+			return invalidInterfaceType(fmt.Sprintf("InterfaceType needs a pointer to a Go interface, not %T", ptr))
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Interface {
+		return invalidInterfaceType(fmt.Sprintf("InterfaceType needs a pointer to a Go interface, not to %s", t))
+	}
+	return reflect.Zero(reflect.PtrTo(t)).Interface()
+}
+
+// invalidInterfaceType is returned by InterfaceType for the values it can not make a registration
+// value of, it tells why.
+type invalidInterfaceType string
+
+// interfaceRegistrationError tells how to register the Go interface of the interface name.
+func interfaceRegistrationError(name string) string {
+	return fmt.Sprintf("interface registration for %[1]s must pass a pointer to the Go interface type, e.g. (*%[1]s)(nil)", name)
 }
 
 // defined directive for schema
 //
 // use as :
 // s.Directive("dir",[]string{"Field"},struct{ a scalar `graphql:"a,nonnull,is a"` },"testdir")
+//
+// The function of a directive applied to fields takes an optional context, its arguments struct and a
+// DirectiveFn, and returns whether to go on, the value of the field and an error:
+//
+//   s.Directive("uppercase", []string{"FIELD"}, func(ctx context.Context, args struct{}, next schemabuilder.DirectiveFn) (bool, interface{}, error) {
+//     value, err := next()
+//     if err != nil {
+//       return false, nil, err
+//     }
+//     return true, strings.ToUpper(value.(string)), nil
+//   })
+//
+// skip and include are evaluated first, a field they exclude runs neither its other directives nor its
+// resolver. The other directives run in the order of the document around the resolver: next calls the
+// following directive, or the resolver after the last one, and a directive which does not call it
+// short-circuits them. The bool the function returns is not used for fields, the value it returns is
+// the value of the field. An error it returns is an error of the field located at the directive, unless
+// it is the error next returned.
 
-func (s *Schema) Directive(name string, locs []string, fn interface{}, desc ...string) {
+func (s *Schema) Directive(name string, locs []string, fn interface{}, desc ...string) *Directive {
+	site := callSite(1)
+	var d string
+	if len(desc) > 0 {
+		d = desc[0]
+	}
+	directive := &Directive{
+		Name:     name,
+		Desc:     d,
+		Fn:       fn,
+		Locs:     locs,
+		Fields:   make(map[string]*inputFieldResolve),
+		schema:   s,
+		callSite: site,
+	}
+	// a directive which fails to register is returned without being added to the schema
 	// Ensure directive is named
 	if name == "" {
-		panic("Directive must be named.")
+		s.fail(site, "Directive must be named.")
+		return directive
 	}
 	// Ensure locations are provided for directive
 	if len(locs) == 0 {
-		panic("Must provide locations for directive.")
+		s.fail(site, "Must provide locations for directive %s.", name)
+		return directive
 	}
 
 	if fn == nil {
-		panic("Must provide option func for directive")
+		s.fail(site, "Must provide option func for directive %s", name)
+		return directive
 	}
 
-	s.directives[name] = &Directive{
-		Name: name,
-		Fn:   fn,
-		Locs: locs,
-	}
-	if len(desc) > 0 {
-		s.directives[name].Desc = desc[0]
+	directive.fingerprint = fingerprint{typ: reflect.TypeOf(fn), desc: d, values: []interface{}{locs, funcPointer(fn)}}
+	if registered, ok := s.directives[name]; ok {
+		s.checkRegistered("directive", name, registered.fingerprint, directive.fingerprint, registered.callSite, site)
+		return registered
 	}
+	s.directives[name] = directive
+	return directive
 }
 
 func (s *Schema) GetInterface(name string) *Interface {
@@ -402,7 +772,7 @@ type Query struct{}
 // Query returns an Object struct that we can use to register all the top level
 // graphql Query functions we'd like to expose.
 func (s *Schema) Query() *Object {
-	return s.Object("Query", Query{}, "")
+	return s.object("Query", Query{}, callSite(1), "")
 }
 
 type Mutation struct{}
@@ -410,7 +780,7 @@ type Mutation struct{}
 // Mutation returns an Object struct that we can use to register all the top level
 // graphql mutations functions we'd like to expose.
 func (s *Schema) Mutation() *Object {
-	return s.Object("Mutation", Mutation{}, "")
+	return s.object("Mutation", Mutation{}, callSite(1), "")
 }
 
 type Subscription struct {
@@ -420,13 +790,65 @@ type Subscription struct {
 // Subscription returns an Object struct that we can use to register all the top level
 // graphql subscription functions we'd like to expose.
 func (s *Schema) Subscription() *Object {
-	return s.Object("Subscription", Subscription{}, "")
+	return s.object("Subscription", Subscription{}, callSite(1), "")
+}
+
+// wireInterfaces checks that the possible types of every interface are objects of the schema which
+// implement it, and completes the possible types of the interfaces and the interfaces of the objects
+// from each other, as either can be filled without the other, such as the exported PossibleTypes.
+func (s *Schema) wireInterfaces() error {
+	for _, name := range sortedKeys(s.interfaces) {
+		iface := s.interfaces[name]
+		ifaceTyp := reflect.TypeOf(iface.Type)
+		if ifaceTyp == nil || ifaceTyp.Kind() != reflect.Ptr || ifaceTyp.Elem().Kind() != reflect.Interface {
+			// Build reports interfaces over other types
+			continue
+		}
+		ifaceTyp = ifaceTyp.Elem()
+		for _, name := range sortedKeys(iface.PossibleTypes) {
+			object := iface.PossibleTypes[name]
+			objectTyp := reflect.TypeOf(object.Type)
+			if s.objects[object.Name] != object {
+				return fmt.Errorf("interface %s (%s) has object %s (%s) as possible type, which is not an object of the schema",
+					iface.Name, ifaceTyp.String(), object.Name, objectTyp.String())
+			}
+			if name != object.Name {
+				return fmt.Errorf("interface %s (%s) has object %s (%s) as possible type under the name %s",
+					iface.Name, ifaceTyp.String(), object.Name, objectTyp.String(), name)
+			}
+			if !objectTyp.Implements(ifaceTyp) && !reflect.PtrTo(objectTyp).Implements(ifaceTyp) {
+				return fmt.Errorf("interface %s (%s) has object %s (%s) as possible type, which does not implement it",
+					iface.Name, ifaceTyp.String(), object.Name, objectTyp.String())
+			}
+			if !hasInterface(object.Interface, iface) {
+				object.Interface = append(object.Interface, iface)
+			}
+		}
+	}
+	for _, object := range s.objects {
+		for _, iface := range object.Interface {
+			iface.PossibleTypes[object.Name] = object
+		}
+	}
+	return nil
+}
+
+func hasInterface(list []*Interface, iface *Interface) bool {
+	for _, i := range list {
+		if i == iface {
+			return true
+		}
+	}
+	return false
 }
 
 // Build takes the schema we have built on our Query, Mutation and Subscription starting points and builds a full graphql.Schema
 // We can use graphql.Schema to execute and run queries. Essentially we read through all the methods we've attached to our
 // Query, Mutation and Subscription Objects and ensure that those functions are returning other Objects that we can resolve in our GraphQL graph.
 func (s *Schema) Build() (*internal.Schema, error) {
+	if len(s.errs) > 0 {
+		return nil, s.errs
+	}
 	sb := &schemaBuilder{
 		types:      make(map[reflect.Type]internal.Type),
 		cacheTypes: make(map[reflect.Type]resolveFunc),
@@ -450,11 +872,26 @@ func (s *Schema) Build() (*internal.Schema, error) {
 				Type: ConnectionArgs{},
 			},
 		},
+		strictNullability: s.strictNullability,
+		requireNamedArgs:  s.requireNamedArgs,
+		inputNaming:       s.inputNaming,
 	}
-	for _, object := range s.objects {
+	for _, name := range sortedKeys(s.objects) {
+		object := s.objects[name]
 		typ := reflect.TypeOf(object.Type)
-		if typ.Kind() != reflect.Struct {
-			return nil, fmt.Errorf("object.Operation should be a struct, not %s", typ.String())
+		switch typ.Kind() {
+		case reflect.Struct:
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map, reflect.Func, reflect.Chan:
+			return nil, fmt.Errorf("object.Operation should be a struct or a named type, not %s", typ.String())
+		default:
+			// objects over named types such as type UserID int64 have no struct field,
+			// their fields are all resolved from the source by FieldFunc
+			if typ.Name() == "" {
+				return nil, fmt.Errorf("object.Operation should be a struct or a named type, not %s", typ.String())
+			}
+			if len(object.FieldResolve) == 0 {
+				return nil, fmt.Errorf("object %s over %s should had at least one FieldFunc", object.Name, typ.String())
+			}
 		}
 
 		if _, ok := sb.objects[typ]; ok {
@@ -464,7 +901,12 @@ func (s *Schema) Build() (*internal.Schema, error) {
 		sb.objects[typ] = object
 	}
 
-	for _, inputObject := range s.inputObjects {
+	if err := s.wireInterfaces(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedKeys(s.inputObjects) {
+		inputObject := s.inputObjects[name]
 		typ := reflect.TypeOf(inputObject.Type)
 		if typ.Kind() != reflect.Struct {
 			return nil, fmt.Errorf("inputObject.Operation should be a struct, not %s", typ.String())
@@ -477,7 +919,8 @@ func (s *Schema) Build() (*internal.Schema, error) {
 		sb.inputObjects[typ] = inputObject
 	}
 
-	for _, enum := range s.enums {
+	for _, name := range sortedKeys(s.enums) {
+		enum := s.enums[name]
 		typ := reflect.TypeOf(enum.Type)
 		if typ.Kind() == reflect.Ptr {
 			return nil, fmt.Errorf("Enum.Operation should not be a pointer")
@@ -488,7 +931,8 @@ func (s *Schema) Build() (*internal.Schema, error) {
 		sb.enums[typ] = enum
 	}
 
-	for _, inter := range s.interfaces {
+	for _, name := range sortedKeys(s.interfaces) {
+		inter := s.interfaces[name]
 		typ := reflect.TypeOf(inter.Type)
 		if typ.Kind() == reflect.Ptr {
 			typ = typ.Elem()
@@ -504,19 +948,32 @@ func (s *Schema) Build() (*internal.Schema, error) {
 		sb.interfaces[typ] = inter
 	}
 
-	for name, scalar := range s.scalars {
+	for _, name := range sortedKeys(s.scalars) {
+		scalar := s.scalars[name]
 		if name == "AnyScalar" {
 			sb.scalars[reflect.TypeOf(any).Out(0)] = scalar
 			continue
 		}
+		// the maps with string keys are JSON objects whatever their type
+		if name == "JSONObject" {
+			sb.json = scalar
+			continue
+		}
 		typ := reflect.TypeOf(scalar.Type)
 		if _, ok := sb.scalars[typ]; ok {
 			return nil, fmt.Errorf("duplicate scalar for %s", typ.String())
 		}
+		// builtin scalars are shared by every schema, copy them before changing their output format
+		if s.timeLayout != "" && (scalar == Time || scalar == NullTime) {
+			copied := *scalar
+			copied.Serialize = serializeTime(s.timeLayout, s.timeLoc)
+			scalar = &copied
+		}
 		sb.scalars[typ] = scalar
 	}
 
-	for _, union := range s.unions {
+	for _, name := range sortedKeys(s.unions) {
+		union := s.unions[name]
 		typ := reflect.TypeOf(union.Type)
 		if typ.Kind() != reflect.Struct {
 			return nil, fmt.Errorf("Scalar.Operation should  be a struct")
@@ -534,13 +991,19 @@ func (s *Schema) Build() (*internal.Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	if mutation, ok := mutationTyp.(*internal.Object); ok && s.guardMutations {
+		for _, field := range mutation.Fields {
+			guardWritable(field)
+		}
+	}
 
 	subscriptionTyp, err := sb.getType(reflect.TypeOf(&Subscription{}))
 	if err != nil {
 		return nil, err
 	}
 	directives := make(map[string]*internal.Directive, len(s.directives))
-	for name, dir := range s.directives {
+	for _, name := range sortedKeys(s.directives) {
+		dir := s.directives[name]
 		directive, err := sb.getDirective(dir)
 		if err != nil {
 			return nil, err
@@ -548,11 +1011,36 @@ func (s *Schema) Build() (*internal.Schema, error) {
 		directives[name] = directive
 	}
 
+	sortManifest(sb.manifest)
+	s.manifest = &Manifest{Fields: sb.manifest}
+
 	typeMap := make(map[string]internal.NamedType, len(sb.types))
-	for _, t := range sb.types {
+	for goType, t := range sb.types {
 		if named, ok := t.(internal.NamedType); ok {
 			typeMap[named.TypeName()] = named
 		}
+		if sb.objectsByType == nil {
+			continue
+		}
+		if nonNull, ok := t.(*internal.NonNull); ok && goType.Kind() != reflect.Ptr {
+			t, goType = nonNull.Type, reflect.PtrTo(goType)
+		}
+		if object, ok := t.(*internal.Object); ok && goType.Kind() == reflect.Ptr {
+			sb.objectsByType[goType] = object
+		}
+	}
+	if len(s.middlewares) > 0 {
+		for _, named := range typeMap {
+			object, ok := named.(*internal.Object)
+			if !ok {
+				continue
+			}
+			for name, field := range object.Fields {
+				if field.Resolve != nil {
+					useMiddlewares(object.Name, name, field, s.middlewares)
+				}
+			}
+		}
 	}
 	return &internal.Schema{
 		TypeMap:      typeMap,
@@ -569,5 +1057,10 @@ func (s *Schema) MustBuild() *internal.Schema {
 	if err != nil {
 		panic(err)
 	}
+	if s.lint != nil {
+		for _, warning := range lint(s, built) {
+			s.lint(warning)
+		}
+	}
 	return built
 }
@@ -0,0 +1,1009 @@
+package schemabuilder_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/introspection"
+	"github.com/shyptr/graphql/printer"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type RegisteredUser struct {
+	Name string `graphql:"name"`
+}
+
+type RegisteredUserFilter struct {
+	Name string `graphql:"name"`
+}
+
+type RegisteredRole int
+
+type RegisteredToken string
+
+func registerShared(build *schemabuilder.Schema) *schemabuilder.Object {
+	build.Enum("Role", RegisteredRole(0), map[string]interface{}{"ADMIN": RegisteredRole(0), "USER": RegisteredRole(1)}, "user role")
+	build.InputObject("UserFilter", RegisteredUserFilter{})
+	build.Scalar("Token", RegisteredToken(""), unmarshalToken)
+	build.Directive("audit", []string{"FIELD"}, audit)
+	return build.Object("User", RegisteredUser{}, "a user")
+}
+
+func unmarshalToken(value interface{}, dest reflect.Value) error {
+	dest.SetString(fmt.Sprint(value))
+	return nil
+}
+
+func audit(args struct{}) func(ctx context.Context) error {
+	return func(ctx context.Context) error { return nil }
+}
+
+func TestSchema_Registration(t *testing.T) {
+	t.Run("identical registrations are no-ops", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		first := registerShared(build)
+		first.FieldFunc("upper", func(u RegisteredUser) string { return strings.ToUpper(u.Name) })
+		second := registerShared(build)
+		second.FieldFunc("lower", func(u RegisteredUser) string { return strings.ToLower(u.Name) })
+		assert.Same(t, first, second)
+
+		build.Query().FieldFunc("users", func(args struct {
+			Filter *RegisteredUserFilter `graphql:"filter"`
+			Role   *RegisteredRole       `graphql:"role"`
+			Token  *RegisteredToken      `graphql:"token"`
+		}) []RegisteredUser {
+			return nil
+		})
+		schema, err := build.Build()
+		assert.NoError(t, err)
+		user := schema.TypeMap["User"].(*internal.Object)
+		assert.Contains(t, user.Fields, "name")
+		assert.Contains(t, user.Fields, "upper")
+		assert.Contains(t, user.Fields, "lower")
+		assert.Contains(t, schema.Directives, "audit")
+	})
+
+	t.Run("conflicting registrations fail the build", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			register func(build *schemabuilder.Schema)
+			expected string
+		}{
+			{"object type", func(build *schemabuilder.Schema) { build.Object("User", RegisteredUserFilter{}, "a user") },
+				"re-registered object User with a different definition"},
+			{"object description", func(build *schemabuilder.Schema) { build.Object("User", RegisteredUser{}) },
+				"re-registered object User with a different definition"},
+			{"input object", func(build *schemabuilder.Schema) { build.InputObject("UserFilter", RegisteredUser{}) },
+				"re-registered input object UserFilter with a different definition"},
+			{"enum values", func(build *schemabuilder.Schema) {
+				build.Enum("Role", RegisteredRole(0), map[string]interface{}{"ADMIN": RegisteredRole(0)}, "user role")
+			}, "re-registered enum Role with a different definition"},
+			{"scalar unmarshal", func(build *schemabuilder.Schema) {
+				build.Scalar("Token", RegisteredToken(""), func(value interface{}, dest reflect.Value) error { return nil })
+			}, "re-registered scalar Token with a different definition"},
+			{"directive locations", func(build *schemabuilder.Schema) { build.Directive("audit", []string{"QUERY"}, audit) },
+				"re-registered directive audit with a different definition"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				build := schemabuilder.NewSchema()
+				registerShared(build)
+				tt.register(build)
+				_, err := build.Build()
+				if assert.Error(t, err) {
+					msg := err.Error()
+					assert.True(t, strings.HasPrefix(msg, tt.expected), msg)
+					assert.Equal(t, 2, strings.Count(msg, "schema_test.go:"), msg)
+				}
+			})
+		}
+	})
+
+	t.Run("builtin scalars can not be replaced", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Scalar("Time", RegisteredToken(""), unmarshalToken)
+		_, err := build.Build()
+		if assert.Error(t, err) {
+			msg := err.Error()
+			assert.True(t, strings.HasPrefix(msg, "re-registered scalar Time with a different definition, registered at builtin and "), msg)
+		}
+	})
+
+	t.Run("all registration errors are reported by one build", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		named := build.Interface("Named", new(fmt.Stringer), nil)
+		user := build.Object("User", RegisteredUser{})
+		user.FieldFunc("upper", func(u RegisteredUser) string { return strings.ToUpper(u.Name) })
+		user.FieldFunc("upper", func(u RegisteredUser) string { return strings.ToUpper(u.Name) })
+		user.InterfaceList(named)
+		build.Query().FieldFunc("user", func() RegisteredUser { return RegisteredUser{} })
+
+		_, err := build.Build()
+		errs, ok := err.(schemabuilder.RegistrationErrors)
+		if assert.True(t, ok, "%T", err) && assert.Len(t, errs, 2) {
+			assert.Contains(t, errs[0].Error(), "duplicate method upper of object User")
+			assert.Contains(t, errs[1].Error(), "object User must implements interface Named")
+			assert.Panics(t, func() { build.MustBuild() })
+		}
+	})
+}
+
+type NullabilityShapes struct {
+	Value        string      `graphql:"value"`
+	Pointer      *string     `graphql:"pointer"`
+	Slice        []string    `graphql:"slice"`
+	PointerSlice []*string   `graphql:"pointerSlice"`
+	SlicePointer *[]string   `graphql:"slicePointer"`
+	Pointers     *[]*string  `graphql:"pointers"`
+	Nested       [][]string  `graphql:"nested"`
+	NestedNull   []*[]string `graphql:"nestedNull"`
+}
+
+type NullabilityInput NullabilityShapes
+
+func TestStrictNullability(t *testing.T) {
+	const shapes = `  nested: [[String!]!]!
+  nestedNull: [[String!]]!
+  pointer: String
+  pointerSlice: [String]!
+  pointers: [String]
+  slice: [String!]!
+  slicePointer: [String!]
+  value: String!
+`
+	build := schemabuilder.NewSchema(schemabuilder.StrictNullability())
+	build.Object("Shapes", NullabilityShapes{})
+	build.InputObject("ShapesInput", NullabilityInput{})
+	build.Query().FieldFunc("shapes", func(args NullabilityShapes) NullabilityShapes { return args })
+	build.Query().FieldFunc("echo", func(args struct {
+		Input *NullabilityInput `graphql:"input"`
+	}) *NullabilityShapes {
+		return (*NullabilityShapes)(args.Input)
+	})
+	schema, err := build.Build()
+	if !assert.NoError(t, err) {
+		return
+	}
+	printed := printer.Print(schema)
+	assert.Contains(t, printed, "input ShapesInput {\n"+shapes+"}\n")
+	assert.Contains(t, printed, "type Shapes {\n"+shapes+"}\n")
+	assert.Contains(t, printed, "  shapes(nested: [[String!]!]!, nestedNull: [[String!]]!, pointer: String, pointerSlice: [String]!, pointers: [String], slice: [String!]!, slicePointer: [String!], value: String!): Shapes!\n")
+
+	// introspection reports the same types
+	introspection.AddIntrospectionToSchema(schema)
+	result, errs := execution.Do(schema, execution.Params{Query: `{ __type(name: "Shapes") { fields { name type { kind ofType { kind } } } } }`})
+	if assert.Len(t, errs, 0) {
+		fields := result["__type"].(map[string]interface{})["fields"].([]interface{})
+		for _, field := range fields {
+			field := field.(map[string]interface{})
+			kind := field["type"].(map[string]interface{})["kind"]
+			switch field["name"] {
+			case "pointer", "pointers", "slicePointer":
+				assert.NotEqual(t, "NON_NULL", kind, field["name"])
+			default:
+				assert.Equal(t, "NON_NULL", kind, field["name"])
+			}
+		}
+	}
+
+	// nil slices are empty lists, nil pointers are null
+	result, errs = execution.Do(schema, execution.Params{Query: `{ echo(input: {value: "v", slice: [], pointerSlice: [], nested: [], nestedNull: []}) { value slice slicePointer } }`})
+	assert.Len(t, errs, 0)
+	assert.Equal(t, map[string]interface{}{"echo": map[string]interface{}{
+		"value":        "v",
+		"slice":        []interface{}{},
+		"slicePointer": nil,
+	}}, result)
+
+	t.Run("tags may not contradict pointers", func(t *testing.T) {
+		build := schemabuilder.NewSchema(schemabuilder.StrictNullability())
+		build.Query().FieldFunc("user", func(args struct {
+			Name *string `graphql:"name;;nonnull"`
+		}) string {
+			return ""
+		})
+		_, err := build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field user parse error:field name: nonnull tag contradicts the nullable type *string")
+		assert.Panics(t, func() { build.MustBuild() })
+
+		build = schemabuilder.NewSchema(schemabuilder.StrictNullability())
+		build.Object("User", NullUser{})
+		build.Query().FieldFunc("user", func() NullUser { return NullUser{} })
+		_, err = build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field user parse error:field name: null tag contradicts the non-null type string, use a pointer")
+	})
+}
+
+type NullUser struct {
+	Name string `graphql:"name;;null"`
+}
+
+type Attributes map[string]interface{}
+
+type AttributedItem struct {
+	ID int `graphql:"id"`
+}
+
+func TestResolverArgsOwnership(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	// the scalar keeps the map of the argument, as JSON scalars do
+	build.Scalar("Attributes", Attributes{}, func(value interface{}, dest reflect.Value) error {
+		dest.Set(reflect.ValueOf(Attributes(value.(map[string]interface{}))))
+		return nil
+	})
+	item := build.Object("Item", AttributedItem{})
+	var hooked []string
+	item.FieldFunc("label", func(args struct {
+		Attrs Attributes `graphql:"attrs"`
+	}) string {
+		if _, seen := args.Attrs["seen"]; seen {
+			return "tainted"
+		}
+		args.Attrs["seen"] = true
+		return fmt.Sprint(args.Attrs["color"], args.Attrs["hook"])
+	}, schemabuilder.ExecuteFunc(func(ctx context.Context, args, source interface{}) error {
+		attrs := args.(map[string]interface{})["attrs"].(map[string]interface{})
+		if hook, ok := attrs["hook"]; ok {
+			hooked = append(hooked, fmt.Sprint(hook))
+		}
+		attrs["hook"] = source.(AttributedItem).ID
+		return nil
+	}))
+	build.Query().FieldFunc("items", func() []AttributedItem {
+		return []AttributedItem{{ID: 1}, {ID: 2}, {ID: 3}}
+	})
+	schema := build.MustBuild()
+
+	document, err := internal.Parse(`{ items { label(attrs: {color: "red"}) } }`)
+	assert.NoError(t, err)
+	_, selectionSet, err := execution.ApplySelectionSet(schema, document, "", nil)
+	assert.NoError(t, err)
+	executor := execution.NewExecutor(schema)
+	// executing the selection set again retries every resolver with the same arguments
+	for attempt := 0; attempt < 2; attempt++ {
+		result, errs := executor.Execute(context.Background(), schema.Query, nil, selectionSet)
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"label": "red1"},
+			map[string]interface{}{"label": "red2"},
+			map[string]interface{}{"label": "red3"},
+		}}, result)
+	}
+	assert.Empty(t, hooked)
+	items := selectionSet.Selections[0].SelectionSet.Selections[0]
+	assert.Equal(t, map[string]interface{}{"attrs": map[string]interface{}{"color": "red"}}, items.Args)
+}
+
+func TestFoundBool(t *testing.T) {
+	users := map[int]RegisteredUser{1: {Name: "Ann"}}
+	type userArgs struct {
+		ID int `graphql:"id"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("User", RegisteredUser{})
+	query := build.Query()
+	query.FieldFunc("user", func(args userArgs) (*RegisteredUser, bool) {
+		user, ok := users[args.ID]
+		return &user, ok
+	}, schemabuilder.FoundBool())
+	query.FieldFunc("requiredUser", func(args userArgs) (RegisteredUser, bool) {
+		user, ok := users[args.ID]
+		return user, ok
+	}, schemabuilder.FoundBool())
+	query.FieldFunc("checkedUser", func(args userArgs) (RegisteredUser, bool, error) {
+		if args.ID <= 0 {
+			return RegisteredUser{}, false, fmt.Errorf("invalid id %d", args.ID)
+		}
+		user, ok := users[args.ID]
+		return user, ok, nil
+	}, schemabuilder.FoundBool("no such user"))
+	schema := build.MustBuild()
+
+	tests := []struct {
+		query  string
+		data   interface{}
+		errors []string
+	}{
+		{`{ user(id: 1) { name } }`, map[string]interface{}{"user": map[string]interface{}{"name": "Ann"}}, nil},
+		{`{ user(id: 2) { name } }`, map[string]interface{}{"user": nil}, nil},
+		{`{ requiredUser(id: 1) { name } }`, map[string]interface{}{"requiredUser": map[string]interface{}{"name": "Ann"}}, nil},
+		// the users which are not found are null in a non-null field, which makes the data null
+		{`{ requiredUser(id: 2) { name } }`, map[string]interface{}(nil), []string{"User not found"}},
+		{`{ checkedUser(id: 2) { name } }`, map[string]interface{}(nil), []string{"no such user"}},
+		{`{ checkedUser(id: 0) { name } }`, map[string]interface{}(nil), []string{"invalid id 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			data, errs := execution.Do(schema, execution.Params{Query: tt.query})
+			assert.Equal(t, tt.data, data)
+			var messages []string
+			for _, err := range errs {
+				messages = append(messages, err.Message)
+			}
+			assert.Equal(t, tt.errors, messages)
+		})
+	}
+
+	t.Run("is needed for a bool following the result", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Query().FieldFunc("user", func() (*RegisteredUser, bool) { return nil, false })
+		_, err := build.Build()
+		assert.Error(t, err)
+
+		build = schemabuilder.NewSchema()
+		build.Query().FieldFunc("user", func() (*RegisteredUser, error) { return nil, nil }, schemabuilder.FoundBool())
+		_, err = build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field user parse error:func() (*schemabuilder_test.RegisteredUser, error) return values should be result, bool[, error] with FoundBool")
+	})
+}
+
+func TestGuardMutations(t *testing.T) {
+	var calls int
+	build := schemabuilder.NewSchema(schemabuilder.GuardMutations())
+	build.Query().FieldFunc("calls", func() int { return calls })
+	build.Mutation().FieldFunc("call", func() int {
+		calls++
+		return calls
+	})
+	schema := build.MustBuild()
+
+	data, err := execution.Do(schema, execution.Params{Query: "mutation { call }"})
+	assert.Empty(t, err)
+	assert.Equal(t, map[string]interface{}{"call": 1}, data)
+
+	// a handler routing every operation to a read replica marks them all read-only
+	readOnly := execution.WithReadOnly(context.Background())
+	_, err = execution.Do(schema, execution.Params{Query: "mutation { call }", Context: readOnly})
+	if assert.Len(t, err, 1) {
+		assert.Equal(t, "the operation is read-only, it can not write", err[0].Message)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+type NamedUser struct {
+	Name string `graphql:"name"`
+}
+
+func (u NamedUser) String() string {
+	return u.Name
+}
+
+type EmptyUnion struct{}
+
+type TwiceUnion struct {
+	Author *RegisteredUser
+	Editor *RegisteredUser
+}
+
+func TestUnionAndInterfaceMembers(t *testing.T) {
+	t.Run("rejects unions without members", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Union("Empty", EmptyUnion{}, "")
+		build.Query().FieldFunc("empty", func() *EmptyUnion { return nil })
+		_, err := build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field empty parse error:union Empty (schemabuilder_test.EmptyUnion) must have at least one member")
+	})
+
+	t.Run("rejects unions with a member twice", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.Object("User", RegisteredUser{})
+		build.Union("Twice", TwiceUnion{}, "")
+		build.Query().FieldFunc("twice", func() *TwiceUnion { return nil })
+		_, err := build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field twice parse error:union Twice (schemabuilder_test.TwiceUnion) has object User (schemabuilder_test.RegisteredUser) as member twice, by its fields Author and Editor")
+	})
+
+	buildNamed := func(possibleType func(build *schemabuilder.Schema, named *schemabuilder.Interface)) (*internal.Schema, error) {
+		build := schemabuilder.NewSchema()
+		named := build.Interface("Named", new(fmt.Stringer), nil)
+		named.FieldFunc("name", "String")
+		possibleType(build, named)
+		build.Query().FieldFunc("named", func() fmt.Stringer { return NamedUser{Name: "ann"} })
+		return build.Build()
+	}
+
+	t.Run("wires possible types to their objects", func(t *testing.T) {
+		schema, err := buildNamed(func(build *schemabuilder.Schema, named *schemabuilder.Interface) {
+			named.PossibleTypes["User"] = build.Object("User", NamedUser{})
+		})
+		if assert.NoError(t, err) {
+			assert.Contains(t, schema.TypeMap["User"].(*internal.Object).Interfaces, "Named")
+			data, errs := execution.Do(schema, execution.Params{Query: "{ named { ... on User { name } } }"})
+			assert.Empty(t, errs)
+			assert.Equal(t, map[string]interface{}{"named": map[string]interface{}{"name": "ann"}}, data)
+		}
+	})
+
+	t.Run("rejects possible types which are not objects of the schema", func(t *testing.T) {
+		_, err := buildNamed(func(build *schemabuilder.Schema, named *schemabuilder.Interface) {
+			named.PossibleTypes["User"] = &schemabuilder.Object{Name: "User", Type: NamedUser{}}
+		})
+		assert.EqualError(t, err, "interface Named (fmt.Stringer) has object User (schemabuilder_test.NamedUser) as possible type, which is not an object of the schema")
+	})
+
+	t.Run("rejects possible types under another name", func(t *testing.T) {
+		_, err := buildNamed(func(build *schemabuilder.Schema, named *schemabuilder.Interface) {
+			named.PossibleTypes["Person"] = build.Object("User", NamedUser{})
+		})
+		assert.EqualError(t, err, "interface Named (fmt.Stringer) has object User (schemabuilder_test.NamedUser) as possible type under the name Person")
+	})
+
+	t.Run("explains how to register interfaces", func(t *testing.T) {
+		for name, typ := range map[string]interface{}{
+			"nil interface value":     fmt.Stringer(nil),
+			"non-pointer value":       fmt.Stringer(NamedUser{}),
+			"pointer to a struct":     new(NamedUser),
+			"reflect type, not value": reflect.TypeOf(new(fmt.Stringer)).Elem(),
+		} {
+			t.Run(name, func(t *testing.T) {
+				build := schemabuilder.NewSchema()
+				named := build.Interface("Named", typ, nil)
+				build.Object("User", NamedUser{}).InterfaceList(named, nil)
+				build.Query().FieldFunc("user", func() NamedUser { return NamedUser{} })
+				_, err := build.Build()
+				errs, ok := err.(schemabuilder.RegistrationErrors)
+				if assert.True(t, ok, "%T", err) && assert.Len(t, errs, 2) {
+					assert.Contains(t, errs[0].Error(), "interface registration for Named must pass a pointer to the Go interface type, e.g. (*Named)(nil)")
+					assert.Contains(t, errs[1].Error(), "nil interface passed to InterfaceList of object User")
+				}
+			})
+		}
+
+		// InterfaceType makes the registration value
+		stringer := reflect.TypeOf(new(fmt.Stringer)).Elem()
+		assert.Equal(t, (*fmt.Stringer)(nil), schemabuilder.InterfaceType(stringer))
+		assert.Equal(t, (*fmt.Stringer)(nil), schemabuilder.InterfaceType(new(fmt.Stringer)))
+		build := schemabuilder.NewSchema()
+		named := build.Interface("Named", schemabuilder.InterfaceType(stringer), nil)
+		named.FieldFunc("name", "String")
+		build.Object("User", NamedUser{}).InterfaceList(named)
+		build.Query().FieldFunc("named", func() fmt.Stringer { return NamedUser{Name: "ann"} })
+		_, err := build.Build()
+		assert.NoError(t, err)
+
+		// the values InterfaceType can not register with are reported by Build
+		build = schemabuilder.NewSchema()
+		build.Interface("Value", schemabuilder.InterfaceType(NamedUser{}), nil)
+		build.Interface("Pointer", schemabuilder.InterfaceType(new(NamedUser)), nil)
+		build.Query().FieldFunc("named", func() NamedUser { return NamedUser{} })
+		_, err = build.Build()
+		errs, ok := err.(schemabuilder.RegistrationErrors)
+		if assert.True(t, ok, "%T", err) && assert.Len(t, errs, 2) {
+			assert.Contains(t, errs[0].Error(), "interface Value: InterfaceType needs a pointer to a Go interface, not schemabuilder_test.NamedUser")
+			assert.Contains(t, errs[1].Error(), "interface Pointer: InterfaceType needs a pointer to a Go interface, not to schemabuilder_test.NamedUser")
+			assert.Contains(t, errs[0].Error(), "schema_test.go:")
+		}
+	})
+
+	t.Run("rejects possible types not implementing the interface", func(t *testing.T) {
+		_, err := buildNamed(func(build *schemabuilder.Schema, named *schemabuilder.Interface) {
+			build.Object("User", NamedUser{})
+			named.PossibleTypes["Other"] = build.Object("Other", RegisteredUser{})
+		})
+		assert.EqualError(t, err, "interface Named (fmt.Stringer) has object Other (schemabuilder_test.RegisteredUser) as possible type, which does not implement it")
+	})
+}
+
+func TestReplaceScalar(t *testing.T) {
+	build := func(opts ...func(build *schemabuilder.Schema)) *internal.Schema {
+		build := schemabuilder.NewSchema()
+		for _, opt := range opts {
+			opt(build)
+		}
+		build.Query().FieldFunc("echo", func(args struct {
+			At time.Time `graphql:"at"`
+		}) time.Time {
+			return args.At
+		})
+		return build.MustBuild()
+	}
+	dates := build(func(build *schemabuilder.Schema) {
+		build.ReplaceScalar("Time", &schemabuilder.Scalar{
+			Serialize: func(value interface{}) (interface{}, error) {
+				return value.(time.Time).Format("2006-01-02"), nil
+			},
+			ParseValue: func(value interface{}) (interface{}, error) {
+				return time.Parse("2006-01-02", value.(string))
+			},
+		})
+	})
+	times := build()
+
+	data, errs := execution.Do(dates, execution.Params{Query: `{ echo(at: "2020-01-02") }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"echo": "2020-01-02"}, data)
+	_, errs = execution.Do(times, execution.Params{Query: `{ echo(at: "2020-01-02") }`})
+	assert.NotEmpty(t, errs)
+	data, errs = execution.Do(times, execution.Params{Query: `{ echo(at: "2020-01-02T03:04:05Z") }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"echo": "2020-01-02T03:04:05Z"}, data)
+	assert.Equal(t, "time type", dates.TypeMap["Time"].(*internal.Scalar).Desc)
+
+	t.Run("keeps the scalars registered in a schema in it", func(t *testing.T) {
+		first := schemabuilder.NewSchema()
+		first.Scalar("Token", RegisteredToken(""), unmarshalToken)
+		second := schemabuilder.NewSchema()
+		second.Scalar("Token", RegisteredToken(""), func(value interface{}, dest reflect.Value) error { return nil })
+		second.Query().FieldFunc("token", func() RegisteredToken { return "" })
+		_, err := second.Build()
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unknown scalars and other types", func(t *testing.T) {
+		build := schemabuilder.NewSchema()
+		build.ReplaceScalar("Date", &schemabuilder.Scalar{})
+		build.ReplaceScalar("Time", &schemabuilder.Scalar{Name: "Date", Type: ""})
+		_, err := build.Build()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "there is no scalar Date to replace")
+			assert.Contains(t, err.Error(), "scalar Date of type string can not replace scalar Time of type time.Time")
+		}
+	})
+}
+
+type DeterministicColor int
+
+type DeterministicArgs struct {
+	Color DeterministicColor `graphql:"color"`
+	First int64              `graphql:"first"`
+	After string             `graphql:"after"`
+}
+
+func TestDeterministicBuild(t *testing.T) {
+	build := func() *internal.Schema {
+		build := schemabuilder.NewSchema()
+		build.Enum("Color", DeterministicColor(0), map[string]interface{}{
+			"RED": DeterministicColor(0), "GREEN": DeterministicColor(1), "BLUE": DeterministicColor(2),
+			"CYAN": DeterministicColor(3), "MAGENTA": DeterministicColor(4), "YELLOW": DeterministicColor(5),
+		})
+		build.Directive("tag", []string{"FIELD"}, func(args struct {
+			Name  string `graphql:"name"`
+			Value string `graphql:"value"`
+			Group string `graphql:"group"`
+		}, fn schemabuilder.DirectiveFn) (bool, interface{}, error) {
+			result, err := fn()
+			return true, result, err
+		})
+		named := build.Interface("Named", new(fmt.Stringer), nil)
+		named.FieldFunc("name", "String")
+		user := build.Object("User", NamedUser{})
+		named.PossibleTypes["User"] = user
+		for _, name := range []string{"email", "phone", "address", "city", "country", "zip"} {
+			user.FieldFunc(name, func(u NamedUser, args DeterministicArgs) string { return u.Name })
+		}
+		query := build.Query()
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			name := name
+			query.FieldFunc(name, func() (string, error) { return "", fmt.Errorf("%s failed", name) })
+		}
+		query.FieldFunc("named", func() fmt.Stringer { return NamedUser{Name: "ann"} })
+		schema := build.MustBuild()
+		introspection.AddIntrospectionToSchema(schema)
+		return schema
+	}
+
+	first := build()
+	sdl := printer.Print(first)
+	introspected, err := introspection.ComputeSchemaJSON(first)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		schema := build()
+		assert.Equal(t, sdl, printer.Print(schema))
+		json, err := introspection.ComputeSchemaJSON(schema)
+		assert.NoError(t, err)
+		assert.Equal(t, string(introspected), string(json))
+		// the values are in the order of their Go values
+		assert.Equal(t, []string{"RED", "GREEN", "BLUE", "CYAN", "MAGENTA", "YELLOW"}, schema.TypeMap["Color"].(*internal.Enum).Values)
+
+		_, errs := execution.Do(schema, execution.Params{Query: "{ h g a f b e c d }"})
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Message)
+		}
+		assert.Equal(t, []string{"h failed", "g failed", "a failed", "f failed", "b failed", "e failed", "c failed", "d failed"}, messages)
+	}
+}
+
+type MappedIdentity string
+
+const (
+	Student MappedIdentity = "student"
+	Teacher MappedIdentity = "teacher"
+)
+
+type MappedPerson struct {
+	Name     string         `graphql:"name"`
+	Identity MappedIdentity `graphql:"identity"`
+}
+
+func TestEnumMappers(t *testing.T) {
+	codes := map[MappedIdentity]MappedIdentity{"S": Student, "T": Teacher}
+	// rows hold the codes stored in the database
+	rows := []MappedPerson{{Name: "alice", Identity: "S"}, {Name: "bob", Identity: "T"}}
+
+	build := schemabuilder.NewSchema()
+	build.Enum("Identity", Student, map[string]interface{}{"STUDENT": Student, "TEACHER": Teacher}, "identity",
+		schemabuilder.EnumOutputMapper(func(value interface{}) (interface{}, error) {
+			if identity, ok := codes[value.(MappedIdentity)]; ok {
+				return identity, nil
+			}
+			return nil, fmt.Errorf("unknown identity code %v", value)
+		}),
+		schemabuilder.EnumInputMapper(func(value interface{}) (interface{}, error) {
+			for code, identity := range codes {
+				if identity == value {
+					return code, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown identity %v", value)
+		}))
+	build.Object("Person", MappedPerson{})
+	var received []MappedIdentity
+	build.Query().FieldFunc("people", func(args struct {
+		Identity *MappedIdentity `graphql:"identity"`
+	}) []MappedPerson {
+		if args.Identity == nil {
+			return rows
+		}
+		received = append(received, *args.Identity)
+		var people []MappedPerson
+		for _, row := range rows {
+			if row.Identity == *args.Identity {
+				people = append(people, row)
+			}
+		}
+		return people
+	})
+	build.Query().FieldFunc("broken", func() MappedIdentity { return "X" })
+	schema := build.MustBuild()
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ people { name identity } }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"people": []interface{}{
+		map[string]interface{}{"name": "alice", "identity": "STUDENT"},
+		map[string]interface{}{"name": "bob", "identity": "TEACHER"},
+	}}, data)
+
+	data, errs = execution.Do(schema, execution.Params{
+		Query:     `query($identity: Identity) { literal: people(identity: TEACHER) { name } variable: people(identity: $identity) { name } }`,
+		Variables: map[string]interface{}{"identity": "STUDENT"},
+	})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"literal":  []interface{}{map[string]interface{}{"name": "bob"}},
+		"variable": []interface{}{map[string]interface{}{"name": "alice"}},
+	}, data)
+	assert.Equal(t, []MappedIdentity{"T", "S"}, received)
+
+	_, errs = execution.Do(schema, execution.Params{Query: `{ broken }`})
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "unknown identity code X")
+	}
+}
+
+type DeprecatedUser struct {
+	Name     string `graphql:"name"`
+	Nickname string `graphql:"nickname"`
+}
+
+type DeprecatedStatus int
+
+func TestDeprecated(t *testing.T) {
+	build := schemabuilder.NewSchema()
+	build.Enum("Status", DeprecatedStatus(0), map[string]interface{}{
+		"ACTIVE": DeprecatedStatus(0), "INACTIVE": DeprecatedStatus(1), "DISABLED": DeprecatedStatus(2),
+	}, schemabuilder.DeprecatedValues(map[string]string{"DISABLED": "use INACTIVE"}))
+	user := build.Object("User", DeprecatedUser{})
+	user.FieldOption("nickname", schemabuilder.Deprecated("use name"))
+	user.FieldFunc("fullName", func(u DeprecatedUser) string { return u.Name }, schemabuilder.Deprecated("use name"))
+	build.Query().FieldFunc("user", func(args struct {
+		Status *DeprecatedStatus `graphql:"status"`
+	}) DeprecatedUser {
+		return DeprecatedUser{Name: "ann", Nickname: "annie"}
+	})
+	schema := build.MustBuild()
+
+	object := schema.TypeMap["User"].(*internal.Object)
+	assert.True(t, object.Fields["fullName"].IsDeprecated)
+	assert.Equal(t, "use name", object.Fields["fullName"].DeprecationReason)
+	assert.True(t, object.Fields["nickname"].IsDeprecated)
+	assert.False(t, object.Fields["name"].IsDeprecated)
+	assert.Equal(t, map[string]string{"DISABLED": "use INACTIVE"}, schema.TypeMap["Status"].(*internal.Enum).Deprecated)
+	sdl := printer.Print(schema)
+	assert.Contains(t, sdl, `fullName: String! @deprecated(reason: "use name")`)
+	assert.Contains(t, sdl, `DISABLED @deprecated(reason: "use INACTIVE")`)
+
+	// deprecated fields and values can still be queried, NoDeprecated reports them
+	query := `{ user(status: DISABLED) { fullName nickname } }`
+	data, errs := execution.Do(schema, execution.Params{Query: query})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"fullName": "ann", "nickname": "annie"}}, data)
+	_, errs = execution.Do(schema, execution.Params{Query: query, Rules: []execution.Rule{execution.NoDeprecated()}})
+	assert.Len(t, errs, 3)
+
+	build = schemabuilder.NewSchema()
+	build.Enum("Status", DeprecatedStatus(0), map[string]interface{}{"ACTIVE": DeprecatedStatus(0)},
+		schemabuilder.DeprecatedValues(map[string]string{"DISABLED": "use INACTIVE"}))
+	_, err := build.Build()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "enum Status has no value DISABLED to deprecate")
+	}
+}
+
+type SearchFilter struct {
+	Name  string `graphql:"name"`
+	Limit *int64 `graphql:"limit"`
+}
+
+type SearchArgs struct {
+	Filter SearchFilter `graphql:"filter"`
+}
+
+type CountArgs struct {
+	Filters []SearchFilter `graphql:"filters"`
+}
+
+func TestNamedArgStructs(t *testing.T) {
+	t.Run("rejects anonymous args structs", func(t *testing.T) {
+		build := schemabuilder.NewSchema(schemabuilder.RequireNamedArgStructs(), schemabuilder.GenerateInputObjects())
+		build.Query().FieldFunc("search", func(args SearchArgs) []string { return nil })
+		build.Query().FieldFunc("user", func(args struct {
+			Name string `graphql:"name"`
+		}) string {
+			return args.Name
+		})
+		_, err := build.Build()
+		if assert.Error(t, err) {
+			assert.Regexp(t, `^object schemabuilder.Query field user parse error:the arguments of the field registered at .*schema_test.go:\d+ are an anonymous struct, declare a named struct for them$`, err.Error())
+		}
+	})
+
+	t.Run("generates one input object per struct", func(t *testing.T) {
+		build := schemabuilder.NewSchema(schemabuilder.GenerateInputObjects())
+		build.Query().FieldFunc("search", func(args SearchArgs) []string { return []string{args.Filter.Name} })
+		build.Query().FieldFunc("count", func(args CountArgs) int { return len(args.Filters) })
+		schema := build.MustBuild()
+		sdl := printer.Print(schema)
+		assert.Contains(t, sdl, `input SearchFilterInput {
+  limit: Int64
+  name: String!
+}`)
+		assert.Contains(t, sdl, `type Query {
+  count(filters: [SearchFilterInput!]): Int!
+  search(filter: SearchFilterInput!): [String!]
+}`)
+		data, errs := execution.Do(schema, execution.Params{Query: `{ search(filter: {name: "ann"}) count(filters: [{name: "a"}, {name: "b", limit: 3}]) }`})
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{"search": []interface{}{"ann"}, "count": 2}, data)
+	})
+
+	t.Run("rejects structs named alike", func(t *testing.T) {
+		build := schemabuilder.NewSchema(schemabuilder.GenerateInputObjects(func(typ reflect.Type) string { return "Input" }))
+		build.Query().FieldFunc("search", func(args struct {
+			Filter SearchFilter `graphql:"filter"`
+			Args   *SearchArgs  `graphql:"args"`
+		}) int {
+			return 0
+		})
+		_, err := build.Build()
+		assert.EqualError(t, err, "object schemabuilder.Query field search parse error:input object Input is generated for schemabuilder_test.SearchArgs, and is already the input object of schemabuilder_test.SearchFilter")
+	})
+}
+
+func TestUse(t *testing.T) {
+	type Post struct {
+		Title string `graphql:"title"`
+	}
+	type Author struct {
+		Name  string `graphql:"name"`
+		Posts []Post `graphql:"posts"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("Author", Author{})
+	build.Object("Post", Post{})
+	build.Query().FieldFunc("authors", func() []Author {
+		return []Author{{Name: "ada", Posts: []Post{{Title: "notes"}}}, {Name: "alan"}}
+	})
+	build.Query().FieldFunc("secret", func(args struct {
+		Key string `graphql:"key"`
+	}) string {
+		return "classified"
+	})
+
+	// a timing middleware records the resolved fields, an authorization one denies the secret
+	var mu sync.Mutex
+	var resolved, calls []string
+	var took []time.Duration
+	build.Use(func(next schemabuilder.FieldResolver) schemabuilder.FieldResolver {
+		return func(ctx context.Context, info schemabuilder.ResolveInfo, source, args interface{}) (interface{}, error) {
+			start := time.Now()
+			value, err := next(ctx, info, source, args)
+			mu.Lock()
+			resolved = append(resolved, fmt.Sprintf("%s.%s %v", info.Type, info.Field, info.Path))
+			took = append(took, time.Since(start))
+			mu.Unlock()
+			return value, err
+		}
+	}, func(next schemabuilder.FieldResolver) schemabuilder.FieldResolver {
+		return func(ctx context.Context, info schemabuilder.ResolveInfo, source, args interface{}) (interface{}, error) {
+			calls = append(calls, "auth "+info.Field)
+			if info.Field == "secret" && args.(map[string]interface{})["key"] != "open" {
+				return nil, fmt.Errorf("not allowed to read %s.%s", info.Type, info.Field)
+			}
+			return next(ctx, info, source, args)
+		}
+	})
+	build.Use(func(next schemabuilder.FieldResolver) schemabuilder.FieldResolver {
+		return func(ctx context.Context, info schemabuilder.ResolveInfo, source, args interface{}) (interface{}, error) {
+			calls = append(calls, "last "+info.Field)
+			return next(ctx, info, source, args)
+		}
+	})
+	schema := build.MustBuild()
+
+	data, errs := execution.Do(schema, execution.Params{Query: `{ authors { name posts { title } } }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"authors": []interface{}{
+		map[string]interface{}{"name": "ada", "posts": []interface{}{map[string]interface{}{"title": "notes"}}},
+		map[string]interface{}{"name": "alan", "posts": nil},
+	}}, data)
+	assert.Equal(t, []string{
+		"Query.authors [authors]",
+		"Author.name [authors 0 name]",
+		"Author.posts [authors 0 posts]",
+		"Post.title [authors 0 posts 0 title]",
+		"Author.name [authors 1 name]",
+		"Author.posts [authors 1 posts]",
+	}, resolved)
+	assert.Len(t, took, 6)
+	assert.Equal(t, []string{"auth authors", "last authors"}, calls[:2])
+
+	// the middlewares compose in the order they were added in, and fail fields at their location
+	calls = nil
+	data, errs = execution.Do(schema, execution.Params{Query: `{ open: secret(key: "open") denied: secret(key: "guess") }`})
+	// the secret is non-null, the denied one makes the data null
+	assert.Nil(t, data)
+	assert.EqualError(t, errs, "[graphql: not allowed to read Query.secret (1:29) path: [denied]]")
+	assert.Equal(t, []string{"auth secret", "last secret", "auth secret"}, calls)
+}
+
+func TestMaps(t *testing.T) {
+	type Stats struct {
+		Counts map[string]int         `graphql:"counts"`
+		Meta   map[string]interface{} `graphql:"meta"`
+	}
+	type StatsInput struct {
+		Counts map[string]int `graphql:"counts"`
+	}
+	build := schemabuilder.NewSchema()
+	build.Object("Stats", Stats{})
+	build.InputObject("StatsInput", StatsInput{})
+	build.Query().FieldFunc("stats", func(args struct {
+		Counts map[string]int         `graphql:"counts"`
+		Meta   map[string]interface{} `graphql:"meta"`
+	}) Stats {
+		return Stats{Counts: args.Counts, Meta: args.Meta}
+	})
+	build.Query().FieldFunc("echo", func(args struct {
+		Input StatsInput `graphql:"input"`
+	}) map[string]int {
+		return args.Input.Counts
+	})
+	schema := build.MustBuild()
+	printed := printer.Print(schema)
+	assert.Contains(t, printed, "scalar JSONObject\n")
+	assert.Contains(t, printed, "  stats(counts: JSONObject, meta: JSONObject): Stats!\n")
+	assert.Contains(t, printed, "  counts: JSONObject\n")
+
+	// the maps round-trip through literals and variables, decoded into the map type of the argument
+	data, errs := execution.Do(schema, execution.Params{
+		Query:     `query q($meta: JSONObject) { stats(counts: {a: 1, b: 2}, meta: $meta) { counts meta } echo(input: {counts: {c: 3}}) }`,
+		Variables: map[string]interface{}{"meta": map[string]interface{}{"tags": []interface{}{"x"}, "n": 1.5}},
+	})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"stats": map[string]interface{}{
+			"counts": map[string]int{"a": 1, "b": 2},
+			"meta":   map[string]interface{}{"tags": []interface{}{"x"}, "n": 1.5},
+		},
+		"echo": map[string]int{"c": 3},
+	}, data)
+
+	// a nil map is null, an empty map an empty object
+	data, errs = execution.Do(schema, execution.Params{Query: `{ stats(meta: {}) { counts meta } echo(input: {}) }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"stats": map[string]interface{}{"counts": nil, "meta": map[string]interface{}{}},
+		"echo":  nil,
+	}, data)
+
+	// values which do not decode into the map type fail the field
+	_, errs = execution.Do(schema, execution.Params{Query: `{ stats(counts: {a: "one"}) { counts } }`})
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Message, "expected a JSON object of map[string]int")
+	}
+	_, errs = execution.Do(schema, execution.Params{Query: `{ stats(counts: 1) { counts } }`})
+	assert.EqualError(t, errs, "[graphql: Argument \"counts\" has invalid value: expected a JSON object but got 1 (1:9)]")
+}
+
+type Shape interface {
+	Name() string
+	Scaled(factor float64) float64
+}
+
+type Square struct {
+	Side float64 `graphql:"side"`
+}
+
+func (s Square) Name() string                  { return "square" }
+func (s Square) Scaled(factor float64) float64 { return s.Side * factor }
+
+type Circle struct {
+	Radius float64 `graphql:"radius"`
+}
+
+func (c *Circle) Name() string                  { return "circle" }
+func (c *Circle) Scaled(factor float64) float64 { return c.Radius * factor }
+
+func TestInterfaceFieldFunc(t *testing.T) {
+	buildShapes := func(fields func(shape *schemabuilder.Interface, square, circle *schemabuilder.Object)) (*internal.Schema, error) {
+		build := schemabuilder.NewSchema()
+		shape := build.Interface("Shape", new(Shape), nil)
+		square := build.Object("Square", Square{})
+		circle := build.Object("Circle", Circle{})
+		square.InterfaceList(shape)
+		circle.InterfaceList(shape)
+		fields(shape, square, circle)
+		build.Query().FieldFunc("shapes", func() []Shape { return []Shape{Square{Side: 2}, &Circle{Radius: 1}} })
+		return build.Build()
+	}
+
+	// the possible types resolve the fields they do not expose with the method or the func of the interface
+	schema, err := buildShapes(func(shape *schemabuilder.Interface, square, circle *schemabuilder.Object) {
+		shape.FieldFunc("name", "Name")
+		shape.FieldFunc("label", func(ctx context.Context, s Shape) string { return "a " + s.Name() })
+		shape.FieldFunc("scaled", func(s Shape, args struct {
+			Factor float64 `graphql:"factor"`
+		}) float64 {
+			return s.Scaled(args.Factor)
+		})
+		circle.FieldFunc("label", func(c *Circle) string { return "a round shape" })
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	printed := printer.Print(schema)
+	assert.Contains(t, printed, "interface Shape {\n  label: String!\n  name: String!\n  scaled(factor: Float!): Float!\n}\n")
+	assert.Contains(t, printed, "type Circle implements Shape {\n  label: String!\n  name: String!\n  radius: Float!\n  scaled(factor: Float!): Float!\n}\n")
+	data, errs := execution.Do(schema, execution.Params{Query: `{ shapes { name label scaled(factor: 3) } }`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"shapes": []interface{}{
+		map[string]interface{}{"name": "square", "label": "a square", "scaled": 6.0},
+		map[string]interface{}{"name": "circle", "label": "a round shape", "scaled": 3.0},
+	}}, data)
+
+	// a method taking arguments needs every possible type to expose the field
+	_, err = buildShapes(func(shape *schemabuilder.Interface, square, circle *schemabuilder.Object) {
+		shape.FieldFunc("scaled", "Scaled")
+		square.FieldFunc("scaled", func(s Square) float64 { return s.Side })
+	})
+	assert.EqualError(t, err, "object schemabuilder.Query field shapes parse error:object Circle (schemabuilder_test.Circle) must expose the field scaled of interface Shape, whose method takes arguments")
+
+	_, err = buildShapes(func(shape *schemabuilder.Interface, square, circle *schemabuilder.Object) {
+		shape.FieldFunc("name", func(s Square) string { return s.Name() })
+	})
+	assert.EqualError(t, err, "object schemabuilder.Query field shapes parse error:interface Shape field name parse error:the source of the func must be schemabuilder_test.Shape, not schemabuilder_test.Square")
+
+	_, err = buildShapes(func(shape *schemabuilder.Interface, square, circle *schemabuilder.Object) {
+		shape.FieldFunc("name", 42)
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "interface Shape field name must be resolved by a method name or a func, not int")
+}
@@ -0,0 +1,899 @@
+package schemabuilder
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/internal"
+	"reflect"
+	"strings"
+)
+
+// SDLSchema builds a schema from a document of the schema definition language, with Go functions bound
+// to its fields, scalars and abstract types by name:
+//
+//   schema := schemabuilder.FromSDL(`
+//     type Query { person(id: ID!): Person }
+//     type Person { name: String! friends: [Person!]! }
+//   `)
+//   schema.Resolve("Query", "person", func(ctx context.Context, args struct{ ID string `graphql:"id"` }) (*Person, error) {
+//     return loadPerson(ctx, args.ID)
+//   })
+//   built, err := schema.Build()
+//
+// The fields without a resolver read the struct field, or the key of the map, named after them in
+// the value of their object, struct fields being named by their graphql tag or their Go name. The
+// resolvers are checked against the document by Build, which reports every mismatch at once.
+type SDLSchema struct {
+	source        string
+	resolvers     map[string]map[string]sdlResolver
+	scalars       map[string]*Scalar
+	typeResolvers map[string]func(ctx context.Context, value interface{}) (string, error)
+	errs          RegistrationErrors
+}
+
+type sdlResolver struct {
+	fn   interface{}
+	site string
+}
+
+// FromSDL returns an SDLSchema building the schema of sdl. The errors of the document are reported by Build.
+func FromSDL(sdl string) *SDLSchema {
+	return &SDLSchema{
+		source:        sdl,
+		resolvers:     map[string]map[string]sdlResolver{},
+		scalars:       map[string]*Scalar{},
+		typeResolvers: map[string]func(ctx context.Context, value interface{}) (string, error){},
+	}
+}
+
+// Resolve binds fn to the field fieldName of the object typeName. fn takes an optional context.Context,
+// the value of the object unless it is a root type, and the arguments of the field when it has some,
+// as a struct whose fields are named like the arguments or as a map[string]interface{}. It returns the
+// value of the field and optionally an error.
+func (s *SDLSchema) Resolve(typeName, fieldName string, fn interface{}) {
+	if s.resolvers[typeName] == nil {
+		s.resolvers[typeName] = map[string]sdlResolver{}
+	}
+	s.resolvers[typeName][fieldName] = sdlResolver{fn: fn, site: callSite(1)}
+}
+
+// Scalar binds the functions parsing the input values of the scalar name, and serializing the values
+// resolvers return for it. The scalars of the document named like the scalars of this package, such
+// as Time or Int64, are bound to them unless Scalar binds them, other scalars must be bound.
+func (s *SDLSchema) Scalar(name string, parseValue, serialize func(value interface{}) (interface{}, error)) {
+	s.scalars[name] = &Scalar{Name: name, ParseValue: parseValue, Serialize: serialize, callSite: callSite(1)}
+}
+
+// ResolveType binds fn to the interface or union name, fn returns the name of the object type of a
+// value of the abstract type. Abstract types without one resolve maps to the object type named by their
+// __typename key.
+func (s *SDLSchema) ResolveType(name string, fn func(ctx context.Context, value interface{}) (string, error)) {
+	s.typeResolvers[name] = fn
+}
+
+// sdlBuild holds the types of a document while Build binds them.
+type sdlBuild struct {
+	types map[string]internal.NamedType
+	errs  RegistrationErrors
+}
+
+// fail records a problem of the document at the location of node.
+func (b *sdlBuild) fail(node ast.Node, format string, a ...interface{}) {
+	l := node.Location()
+	b.errs = append(b.errs, fmt.Errorf("%d:%d: %s", l.Line, l.Column, fmt.Sprintf(format, a...)))
+}
+
+// Build builds the schema of the document with the functions bound to it.
+func (s *SDLSchema) Build() (*internal.Schema, error) {
+	if len(s.errs) > 0 {
+		return nil, s.errs
+	}
+	doc, err := internal.ParseSchema(s.source)
+	if err != nil {
+		return nil, err
+	}
+	b := &sdlBuild{types: map[string]internal.NamedType{}}
+	for _, name := range []string{"Int", "Float", "String", "Boolean"} {
+		b.types[name] = internalScalar(scalars[name])
+	}
+	b.types["ID"] = sdlID
+
+	// the types are created before their fields, which may refer to any of them
+	var schemaDef *ast.SchemaDefinition
+	var directiveDefs []*ast.DirectiveDefinition
+	for _, def := range doc.Definition {
+		switch def := def.(type) {
+		case *ast.SchemaDefinition:
+			schemaDef = def
+			continue
+		case *ast.DirectiveDefinition:
+			directiveDefs = append(directiveDefs, def)
+			continue
+		}
+		name, typ := s.newType(b, def)
+		if typ == nil {
+			continue
+		}
+		if _, ok := b.types[name]; ok && !isBuiltinSDLScalar(name) {
+			b.fail(def, "type %s is defined twice", name)
+			continue
+		}
+		b.types[name] = typ
+	}
+	for name := range s.scalars {
+		if _, ok := b.types[name].(*internal.Scalar); !ok {
+			b.errs = append(b.errs, fmt.Errorf("%s: scalar %s is not defined by the document", s.scalars[name].callSite, name))
+		}
+	}
+
+	for _, def := range doc.Definition {
+		// a type defined twice keeps its first definition
+		switch def := def.(type) {
+		case *ast.ObjectDefinition:
+			if object, ok := b.types[def.Name.Name].(*internal.Object); ok && object.Fields == nil {
+				object.Fields = b.fields(def.Fields)
+				object.Interfaces = b.interfaces(def.Interfaces)
+				for _, iface := range object.Interfaces {
+					iface.PossibleTypes[object.Name] = object
+				}
+			}
+		case *ast.InterfaceDefinition:
+			if iface, ok := b.types[def.Name.Name].(*internal.Interface); ok && iface.Fields == nil {
+				iface.Fields = b.fields(def.Fields)
+				iface.Interfaces = b.interfaces(def.Interfaces)
+			}
+		case *ast.UnionDefinition:
+			if union, ok := b.types[def.Name.Name].(*internal.Union); ok && len(union.Types) == 0 {
+				for _, member := range def.Members {
+					object, ok := b.types[member.Name.Name].(*internal.Object)
+					if !ok {
+						b.fail(member, "member %s of union %s is not an object type", member.Name.Name, union.Name)
+						continue
+					}
+					union.Types[object.Name] = object
+				}
+			}
+		case *ast.InputObjectDefinition:
+			if input, ok := b.types[def.Name.Name].(*internal.InputObject); ok && input.Fields == nil {
+				input.Fields = b.inputValues(def.InputFields)
+			}
+		}
+	}
+
+	schema := &internal.Schema{TypeMap: b.types, Directives: map[string]*internal.Directive{}}
+	roots := map[ast.OperationType]string{ast.Query: "Query", ast.Mutation: "Mutation", ast.Subscription: "Subscription"}
+	if schemaDef != nil {
+		roots = map[ast.OperationType]string{}
+		for _, op := range schemaDef.OperationTypes {
+			roots[op.Operation] = op.Type.Name.Name
+		}
+	}
+	for _, operation := range []ast.OperationType{ast.Query, ast.Mutation, ast.Subscription} {
+		name, ok := roots[operation]
+		if !ok {
+			continue
+		}
+		typ, ok := b.types[name]
+		if !ok {
+			if schemaDef != nil {
+				b.errs = append(b.errs, fmt.Errorf("%s type %s is not defined", strings.ToLower(string(operation)), name))
+			}
+			continue
+		}
+		object, ok := typ.(*internal.Object)
+		if !ok {
+			b.errs = append(b.errs, fmt.Errorf("%s type %s is not an object type", strings.ToLower(string(operation)), name))
+			continue
+		}
+		switch operation {
+		case ast.Query:
+			schema.Query = object
+		case ast.Mutation:
+			schema.Mutation = object
+		case ast.Subscription:
+			schema.Subscription = object
+		}
+	}
+	if schema.Query == nil && len(b.errs) == 0 {
+		b.errs = append(b.errs, fmt.Errorf("the document has no query type"))
+	}
+
+	s.bindResolvers(b, schema)
+	s.bindTypeResolvers(b)
+
+	// the directives of the specification have a behaviour, those of the document are only declared
+	builtin, _ := NewSchema().Build()
+	for name, directive := range builtin.Directives {
+		schema.Directives[name] = directive
+	}
+	for _, def := range directiveDefs {
+		schema.Directives[def.Name.Name] = &internal.Directive{
+			Name:      def.Name.Name,
+			Desc:      description(def.Desc),
+			Args:      b.inputValues(def.Arguments),
+			Locs:      def.Locations,
+			FnResolve: passDirective,
+			Loc:       def.Loc,
+		}
+	}
+
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	return schema, nil
+}
+
+// MustBuild builds the schema and panics if an error occurs.
+func (s *SDLSchema) MustBuild() *internal.Schema {
+	built, err := s.Build()
+	if err != nil {
+		panic(err)
+	}
+	return built
+}
+
+// newType returns the type def defines, without its fields, members or interfaces yet.
+func (s *SDLSchema) newType(b *sdlBuild, def ast.Definition) (string, internal.NamedType) {
+	switch def := def.(type) {
+	case *ast.ScalarDefinition:
+		name := def.Name.Name
+		if isBuiltinSDLScalar(name) {
+			return name, b.types[name]
+		}
+		scalar, ok := s.scalars[name]
+		if !ok {
+			scalar, ok = scalars[name]
+		}
+		if !ok || name == "Upload" {
+			b.fail(def, "scalar %s is not bound, bind it with Scalar", name)
+			return name, nil
+		}
+		typ := internalScalar(scalar)
+		typ.Name, typ.Desc = name, description(def.Desc)
+		if url, ok := specifiedBy(def.Directives); ok {
+			typ.SpecifiedByURL = url
+		}
+		return name, typ
+	case *ast.ObjectDefinition:
+		return def.Name.Name, &internal.Object{Name: def.Name.Name, Desc: description(def.Desc)}
+	case *ast.InterfaceDefinition:
+		return def.Name.Name, &internal.Interface{Name: def.Name.Name, Desc: description(def.Desc), PossibleTypes: map[string]*internal.Object{}}
+	case *ast.UnionDefinition:
+		return def.Name.Name, &internal.Union{Name: def.Name.Name, Desc: description(def.Desc), Types: map[string]*internal.Object{}}
+	case *ast.InputObjectDefinition:
+		return def.Name.Name, &internal.InputObject{Name: def.Name.Name, Desc: description(def.Desc)}
+	case *ast.EnumDefinition:
+		enum := &internal.Enum{
+			Name:         def.Name.Name,
+			Desc:         description(def.Desc),
+			ValuesDesc:   map[string]string{},
+			ReverseMap:   map[string]interface{}{},
+			Map:          map[interface{}]string{},
+			Deprecated:   map[string]string{},
+			OutputMapper: enumName,
+		}
+		for _, value := range def.Values {
+			name := value.Value.Value
+			enum.Values = append(enum.Values, name)
+			enum.ValuesDesc[name] = description(value.Desc)
+			enum.ReverseMap[name] = name
+			enum.Map[name] = name
+			if reason, ok := deprecation(value.Directives); ok {
+				enum.Deprecated[name] = reason
+			}
+		}
+		return def.Name.Name, enum
+	}
+	return "", nil
+}
+
+func isBuiltinSDLScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+func internalScalar(scalar *Scalar) *internal.Scalar {
+	return &internal.Scalar{
+		Name:           scalar.Name,
+		Desc:           scalar.Desc,
+		Serialize:      scalar.Serialize,
+		ParseValue:     scalar.ParseValue,
+		ParseLiteral:   scalar.ParseLiteral,
+		SpecifiedByURL: scalar.SpecifiedByURL,
+	}
+}
+
+// sdlID is the ID scalar of the schemas built from SDL, whose values are strings or integers.
+var sdlID = &internal.Scalar{
+	Name: "ID",
+	Desc: ID.Desc,
+	Serialize: func(value interface{}) (interface{}, error) {
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.String:
+			return v.String(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return fmt.Sprint(value), nil
+		}
+		return nil, fmt.Errorf("unexpected type %T for ID", value)
+	},
+	ParseValue: func(value interface{}) (interface{}, error) {
+		switch value := value.(type) {
+		case string:
+			return value, nil
+		case fmt.Stringer:
+			// json.Number
+			return value.String(), nil
+		case float64:
+			return fmt.Sprint(int64(value)), nil
+		}
+		return nil, fmt.Errorf("unexpected type %T for ID", value)
+	},
+}
+
+// enumName is the OutputMapper of the enums of schemas built from SDL, whose values are the names
+// of their values, as strings or named string types.
+func enumName(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.String {
+		return nil, fmt.Errorf("unexpected type %T for an enum value", value)
+	}
+	return v.String(), nil
+}
+
+func passDirective(ctx context.Context, args interface{}, fn internal.FieldResolve, source interface{}, fieldArgs interface{}) (bool, interface{}, error) {
+	value, err := fn(ctx, source, fieldArgs)
+	return true, value, err
+}
+
+func description(desc *ast.StringValue) string {
+	if desc == nil {
+		return ""
+	}
+	return desc.Value
+}
+
+// deprecation returns the reason of the deprecated directive of directives, and whether there is one.
+func deprecation(directives []*ast.Directive) (string, bool) {
+	for _, directive := range directives {
+		if directive.Name.Name != "deprecated" {
+			continue
+		}
+		for _, arg := range directive.Args {
+			if value, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Name == "reason" {
+				return value.Value, true
+			}
+		}
+		return "No longer supported", true
+	}
+	return "", false
+}
+
+// specifiedBy returns the url of the specifiedBy directive of directives, and whether there is one.
+func specifiedBy(directives []*ast.Directive) (string, bool) {
+	for _, directive := range directives {
+		if directive.Name.Name != "specifiedBy" {
+			continue
+		}
+		for _, arg := range directive.Args {
+			if value, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Name == "url" {
+				return value.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// typ returns the type t refers to.
+func (b *sdlBuild) typ(t ast.Type) internal.Type {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		if typ := b.typ(t.Type); typ != nil {
+			return &internal.NonNull{Type: typ}
+		}
+	case *ast.List:
+		if typ := b.typ(t.Type); typ != nil {
+			return &internal.List{Type: typ}
+		}
+	case *ast.Named:
+		if typ, ok := b.types[t.Name.Name]; ok {
+			return typ
+		}
+		b.fail(t, "unknown type %s", t.Name.Name)
+	}
+	return nil
+}
+
+func (b *sdlBuild) fields(defs []*ast.FieldDefinition) map[string]*internal.Field {
+	fields := make(map[string]*internal.Field, len(defs))
+	for _, def := range defs {
+		typ := b.typ(def.Type)
+		if typ == nil {
+			continue
+		}
+		field := &internal.Field{
+			Name: def.Name.Name,
+			Type: typ,
+			Args: b.inputValues(def.Argument),
+			Desc: description(def.Desc),
+		}
+		field.DeprecationReason, field.IsDeprecated = deprecation(def.Directives)
+		fields[field.Name] = field
+	}
+	return fields
+}
+
+func (b *sdlBuild) interfaces(names []*ast.Named) map[string]*internal.Interface {
+	interfaces := make(map[string]*internal.Interface, len(names))
+	for _, name := range names {
+		iface, ok := b.types[name.Name.Name].(*internal.Interface)
+		if !ok {
+			b.fail(name, "%s is not an interface type", name.Name.Name)
+			continue
+		}
+		interfaces[iface.Name] = iface
+	}
+	return interfaces
+}
+
+func (b *sdlBuild) inputValues(defs []*ast.InputValueDefinition) map[string]*internal.InputField {
+	values := make(map[string]*internal.InputField, len(defs))
+	for _, def := range defs {
+		typ := b.typ(def.Type)
+		if typ == nil {
+			continue
+		}
+		value := &internal.InputField{Name: def.Name.Name, Type: typ, Desc: description(def.Desc)}
+		if def.DefaultValue != nil {
+			defaultValue, err := internal.ValueToJson(def.DefaultValue, nil)
+			if err != nil {
+				b.fail(def, "default value of %s: %s", def.Name.Name, err.Message)
+			}
+			value.DefaultValue = defaultValue
+		}
+		value.DeprecationReason, value.IsDeprecated = deprecation(def.Directives)
+		values[value.Name] = value
+	}
+	return values
+}
+
+// bindResolvers sets the Resolve of the fields Resolve bound a function to.
+func (s *SDLSchema) bindResolvers(b *sdlBuild, schema *internal.Schema) {
+	roots := map[internal.Type]bool{schema.Query: true, schema.Mutation: true, schema.Subscription: true}
+	for _, typeName := range sortedKeys(s.resolvers) {
+		for _, fieldName := range sortedKeys(s.resolvers[typeName]) {
+			resolver := s.resolvers[typeName][fieldName]
+			object, ok := b.types[typeName].(*internal.Object)
+			if !ok {
+				b.errs = append(b.errs, fmt.Errorf("%s: %s is not an object type of the document", resolver.site, typeName))
+				continue
+			}
+			field, ok := object.Fields[fieldName]
+			if !ok {
+				b.errs = append(b.errs, fmt.Errorf("%s: %s.%s is not a field of the document", resolver.site, typeName, fieldName))
+				continue
+			}
+			resolve, err := sdlFieldResolve(object, field, resolver.fn, roots[object])
+			if err != nil {
+				b.errs = append(b.errs, fmt.Errorf("%s: %s.%s: %w", resolver.site, typeName, fieldName, err))
+				continue
+			}
+			field.Resolve, field.ResolverKind = resolve, internal.FuncResolver
+		}
+	}
+}
+
+// bindTypeResolvers sets the TypeResolve of the interfaces and unions.
+func (s *SDLSchema) bindTypeResolvers(b *sdlBuild) {
+	for _, name := range sortedKeys(s.typeResolvers) {
+		switch b.types[name].(type) {
+		case *internal.Interface, *internal.Union:
+		default:
+			b.errs = append(b.errs, fmt.Errorf("ResolveType: %s is not an interface or union type of the document", name))
+		}
+	}
+	for name, typ := range b.types {
+		fn := s.typeResolvers[name]
+		switch typ := typ.(type) {
+		case *internal.Interface:
+			typ.TypeResolve = typeResolve(name, typ.PossibleTypes, fn)
+		case *internal.Union:
+			typ.TypeResolve = typeResolve(name, typ.Types, fn)
+		}
+	}
+}
+
+// typeResolve returns the TypeResolve of the abstract type name, which finds the object named by fn,
+// or by the __typename key of maps when fn is nil, among possible.
+func typeResolve(name string, possible map[string]*internal.Object, fn func(ctx context.Context, value interface{}) (string, error)) internal.TypeResolve {
+	return func(ctx context.Context, value interface{}) (*internal.Object, error) {
+		var typeName string
+		if fn != nil {
+			var err error
+			if typeName, err = fn(ctx, value); err != nil {
+				return nil, err
+			}
+		} else if typename, ok := sourceTypename(value); ok {
+			typeName = typename
+		} else {
+			return nil, fmt.Errorf("%T has no __typename, bind a function to %s with ResolveType", value, name)
+		}
+		object, ok := possible[typeName]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a possible type of %s", typeName, name)
+		}
+		return object, nil
+	}
+}
+
+func sourceTypename(value interface{}) (string, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		typename, ok := m["__typename"].(string)
+		return typename, ok
+	}
+	return "", false
+}
+
+// sdlFieldResolve returns the Resolve of field of object calling fn, after checking that fn takes an
+// optional context.Context, the source unless object is a root type, and the arguments when field has
+// some, and returns a value of the type of the field and an optional error.
+func sdlFieldResolve(object *internal.Object, field *internal.Field, fn interface{}, root bool) (internal.FieldResolve, error) {
+	fun := reflect.ValueOf(fn)
+	if fun.Kind() != reflect.Func {
+		return nil, fmt.Errorf("resolver is a %T, not a function", fn)
+	}
+	funcType := fun.Type()
+	var in []reflect.Type
+	for i := 0; i < funcType.NumIn(); i++ {
+		in = append(in, funcType.In(i))
+	}
+	hasContext := len(in) > 0 && in[0] == contextType
+	if hasContext {
+		in = in[1:]
+	}
+	var sourceType, argsType reflect.Type
+	if len(field.Args) > 0 && len(in) > 0 {
+		argsType, in = in[len(in)-1], in[:len(in)-1]
+	}
+	if len(in) == 1 && !root {
+		sourceType, in = in[0], in[1:]
+	}
+	if len(in) > 0 {
+		if root {
+			return nil, fmt.Errorf("resolver %s should take [context.Context][, args]", funcType)
+		}
+		return nil, fmt.Errorf("resolver %s should take [context.Context][, source][, args]", funcType)
+	}
+	if argsType != nil {
+		if err := checkArgs(argsType, field.Args); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case funcType.NumOut() == 1 && funcType.Out(0) != errType:
+	case funcType.NumOut() == 2 && funcType.Out(1) == errType:
+	default:
+		return nil, fmt.Errorf("resolver %s should return a value and an optional error", funcType)
+	}
+	if err := checkOutput(funcType.Out(0), field.Type); err != nil {
+		return nil, fmt.Errorf("resolver returns %s: %w", funcType.Out(0), err)
+	}
+
+	return func(ctx context.Context, source, args interface{}) (interface{}, error) {
+		in := make([]reflect.Value, 0, funcType.NumIn())
+		if hasContext {
+			in = append(in, reflect.ValueOf(&ctx).Elem())
+		}
+		if sourceType != nil {
+			value, err := decodeSDLValue(source, sourceType)
+			if err != nil {
+				return nil, fmt.Errorf("source of %s.%s: %w", object.Name, field.Name, err)
+			}
+			in = append(in, value)
+		}
+		if argsType != nil {
+			parsed, err := parseSDLArgs(field.Args, args)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeSDLValue(parsed, argsType)
+			if err != nil {
+				return nil, err
+			}
+			in = append(in, value)
+		}
+		out := fun.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		return out[0].Interface(), nil
+	}, nil
+}
+
+// checkArgs checks that the struct fields of typ, or of the struct typ points to, are arguments of args
+// of compatible types. Maps with string keys take any argument.
+func checkArgs(typ reflect.Type, args map[string]*internal.InputField) error {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String:
+		return nil
+	case typ.Kind() != reflect.Struct:
+		return fmt.Errorf("arguments %s should be a struct or a map[string]interface{}", typ)
+	}
+	return checkStructFields(typ, args, "argument")
+}
+
+func checkStructFields(typ reflect.Type, fields map[string]*internal.InputField, kind string) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, ok := sdlFieldName(field)
+		if !ok {
+			continue
+		}
+		input, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("field %s of %s is not an %s of the document", field.Name, typ, kind)
+		}
+		if err := checkInput(field.Type, input.Type); err != nil {
+			return fmt.Errorf("field %s of %s is %s: %w", field.Name, typ, field.Type, err)
+		}
+	}
+	return nil
+}
+
+// sdlFieldName returns the name of the struct field in the document, and false for the fields which
+// are not exported or are tagged with graphql:"-".
+func sdlFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := strings.Split(field.Tag.Get("graphql"), ";")[0]
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return tag, true
+}
+
+// checkInput checks that values of the input type typ can be decoded into the Go type goType.
+func checkInput(goType reflect.Type, typ internal.Type) error {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		typ = nonNull.Type
+	}
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if goType.Kind() == reflect.Interface {
+		return nil
+	}
+	switch typ := typ.(type) {
+	case *internal.List:
+		if goType.Kind() != reflect.Slice {
+			return fmt.Errorf("%s is not a list", goType)
+		}
+		return checkInput(goType.Elem(), typ.Type)
+	case *internal.InputObject:
+		switch {
+		case goType.Kind() == reflect.Map && goType.Key().Kind() == reflect.String:
+			return nil
+		case goType.Kind() == reflect.Struct:
+			return checkStructFields(goType, typ.Fields, "input field of "+typ.Name)
+		}
+		return fmt.Errorf("%s can not hold the input object %s", goType, typ.Name)
+	}
+	return checkLeaf(goType, typ)
+}
+
+// checkOutput checks that values of the Go type goType can be completed as values of the type typ.
+func checkOutput(goType reflect.Type, typ internal.Type) error {
+	if nonNull, ok := typ.(*internal.NonNull); ok {
+		typ = nonNull.Type
+	}
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if goType.Kind() == reflect.Interface {
+		return nil
+	}
+	switch typ := typ.(type) {
+	case *internal.List:
+		if goType.Kind() != reflect.Slice && goType.Kind() != reflect.Array {
+			return fmt.Errorf("%s is not a list", goType)
+		}
+		return checkOutput(goType.Elem(), typ.Type)
+	case *internal.Object, *internal.Interface, *internal.Union:
+		if goType.Kind() != reflect.Struct && goType.Kind() != reflect.Map {
+			return fmt.Errorf("%s can not be the object type %s", goType, typ)
+		}
+		return nil
+	}
+	return checkLeaf(goType, typ)
+}
+
+// checkLeaf checks that goType holds values of the scalar or enum typ, the custom scalars take any type.
+func checkLeaf(goType reflect.Type, typ internal.Type) error {
+	var ok bool
+	switch typ := typ.(type) {
+	case *internal.Enum:
+		ok = goType.Kind() == reflect.String
+	case *internal.Scalar:
+		switch typ.Name {
+		case "Int":
+			ok = isInteger(goType.Kind())
+		case "Float":
+			ok = isInteger(goType.Kind()) || goType.Kind() == reflect.Float32 || goType.Kind() == reflect.Float64
+		case "String":
+			ok = goType.Kind() == reflect.String
+		case "Boolean":
+			ok = goType.Kind() == reflect.Bool
+		case "ID":
+			ok = goType.Kind() == reflect.String || isInteger(goType.Kind())
+		default:
+			ok = true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s can not hold a %s", goType, typ)
+	}
+	return nil
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// parseSDLArgs returns the arguments args of a field, as the executor gives them, with their scalar
+// values parsed by ParseValue.
+func parseSDLArgs(defs map[string]*internal.InputField, args interface{}) (map[string]interface{}, error) {
+	raw, _ := args.(map[string]interface{})
+	parsed := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		def, ok := defs[name]
+		if !ok {
+			continue
+		}
+		value, err := parseSDLValue(def.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		parsed[name] = value
+	}
+	return parsed, nil
+}
+
+func parseSDLValue(typ internal.Type, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return parseSDLValue(typ.Type, value)
+	case *internal.List:
+		list, ok := value.([]interface{})
+		if !ok {
+			item, err := parseSDLValue(typ.Type, value)
+			return []interface{}{item}, err
+		}
+		parsed := make([]interface{}, len(list))
+		for i, item := range list {
+			var err error
+			if parsed[i], err = parseSDLValue(typ.Type, item); err != nil {
+				return nil, err
+			}
+		}
+		return parsed, nil
+	case *internal.InputObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		parsed := make(map[string]interface{}, len(fields))
+		for name, field := range fields {
+			def, ok := typ.Fields[name]
+			if !ok {
+				continue
+			}
+			var err error
+			if parsed[name], err = parseSDLValue(def.Type, field); err != nil {
+				return nil, err
+			}
+		}
+		return parsed, nil
+	case *internal.Scalar:
+		if literal, ok := value.(internal.LiteralValue); ok {
+			return literal.Value, nil
+		}
+		if typ.ParseValue != nil {
+			return typ.ParseValue(value)
+		}
+	}
+	return value, nil
+}
+
+// decodeSDLValue returns value as a value of typ: maps are decoded into structs by the names of their
+// fields, lists into slices, and the other values are converted to typ.
+func decodeSDLValue(value interface{}, typ reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(typ), nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(typ) {
+		out := reflect.New(typ).Elem()
+		out.Set(v)
+		return out, nil
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Zero(typ), nil
+			}
+			return decodeSDLValue(v.Elem().Interface(), typ)
+		}
+		elem, err := decodeSDLValue(value, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Slice:
+		if list, ok := value.([]interface{}); ok {
+			out := reflect.MakeSlice(typ, len(list), len(list))
+			for i, item := range list {
+				elem, err := decodeSDLValue(item, typ.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				out.Index(i).Set(elem)
+			}
+			return out, nil
+		}
+	case reflect.Struct:
+		if fields, ok := value.(map[string]interface{}); ok {
+			out := reflect.New(typ).Elem()
+			for i := 0; i < typ.NumField(); i++ {
+				name, ok := sdlFieldName(typ.Field(i))
+				if !ok {
+					continue
+				}
+				field, err := decodeSDLValue(fields[name], typ.Field(i).Type)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				out.Field(i).Set(field)
+			}
+			return out, nil
+		}
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		return decodeSDLValue(v.Elem().Interface(), typ)
+	}
+	if sameKindFamily(v.Kind(), typ.Kind()) && v.Type().ConvertibleTo(typ) {
+		return v.Convert(typ), nil
+	}
+	return reflect.Value{}, fmt.Errorf("can not decode %T into %s", value, typ)
+}
+
+// sameKindFamily reports whether values of the kinds a and b are both numbers, strings or booleans,
+// which reflect converts without changing their meaning.
+func sameKindFamily(a, b reflect.Kind) bool {
+	number := func(kind reflect.Kind) bool {
+		return isInteger(kind) || kind == reflect.Float32 || kind == reflect.Float64
+	}
+	return number(a) && number(b) || a == b
+}
@@ -0,0 +1,209 @@
+package schemabuilder_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const sdlDocument = `
+"a character of the films"
+interface Character {
+  id: ID!
+  name: String!
+}
+
+enum Role { HERO VILLAIN }
+
+scalar Upper
+
+input Filter {
+  role: Role = HERO
+  prefix: String
+}
+
+type Human implements Character {
+  id: ID!
+  name: String!
+  role: Role!
+  shout: Upper!
+  friends(filter: Filter): [Character!]!
+}
+
+type Droid implements Character {
+  id: ID!
+  name: String!
+  function: String @deprecated(reason: "use name")
+}
+
+union SearchResult = Human | Droid
+
+type Query {
+  character(id: ID!): Character
+  search(text: String!): [SearchResult!]!
+  count: Int!
+}
+`
+
+type sdlHuman struct {
+	ID      string `graphql:"id"`
+	Name    string `graphql:"name"`
+	Role    string `graphql:"role"`
+	Friends []string
+}
+
+type sdlDroid struct {
+	ID   int    `graphql:"id"`
+	Name string `graphql:"name"`
+}
+
+func TestFromSDL(t *testing.T) {
+	luke := &sdlHuman{ID: "1", Name: "Luke", Role: "HERO", Friends: []string{"2", "3"}}
+	vader := &sdlHuman{ID: "2", Name: "Vader", Role: "VILLAIN"}
+	r2 := &sdlDroid{ID: 3, Name: "R2-D2"}
+	characters := map[string]interface{}{"1": luke, "2": vader, "3": r2}
+
+	schema := schemabuilder.FromSDL(sdlDocument)
+	schema.Scalar("Upper", func(value interface{}) (interface{}, error) {
+		return value, nil
+	}, func(value interface{}) (interface{}, error) {
+		return strings.ToUpper(fmt.Sprint(value)), nil
+	})
+	schema.ResolveType("Character", func(ctx context.Context, value interface{}) (string, error) {
+		if _, ok := value.(*sdlDroid); ok {
+			return "Droid", nil
+		}
+		return "Human", nil
+	})
+	schema.Resolve("Query", "character", func(args struct {
+		ID string `graphql:"id"`
+	}) interface{} {
+		return characters[args.ID]
+	})
+	// the union resolves maps by their __typename
+	schema.Resolve("Query", "search", func(ctx context.Context, args map[string]interface{}) ([]map[string]interface{}, error) {
+		if args["text"] == "" {
+			return nil, fmt.Errorf("empty search")
+		}
+		return []map[string]interface{}{
+			{"__typename": "Droid", "id": 3, "name": "R2-D2"},
+			{"__typename": "Human", "id": "1", "name": "Luke", "role": "HERO"},
+		}, nil
+	})
+	schema.Resolve("Query", "count", func() int { return len(characters) })
+	schema.Resolve("Human", "shout", func(source *sdlHuman) string { return source.Name })
+	schema.Resolve("Human", "friends", func(source sdlHuman, args struct {
+		Filter *struct {
+			Role   string  `graphql:"role"`
+			Prefix *string `graphql:"prefix"`
+		} `graphql:"filter"`
+	}) []interface{} {
+		var friends []interface{}
+		for _, id := range source.Friends {
+			friend := characters[id]
+			if human, ok := friend.(*sdlHuman); ok && args.Filter != nil && human.Role != args.Filter.Role {
+				continue
+			}
+			friends = append(friends, friend)
+		}
+		return friends
+	})
+	built, err := schema.Build()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, errs := execution.Do(built, execution.Params{Query: `{
+		count
+		character(id: "1") {
+			name
+			... on Human { shout role friends { __typename name } heroes: friends(filter: {}) { name } }
+		}
+		search(text: "r") {
+			__typename
+			... on Droid { id name }
+			... on Human { id name }
+		}
+	}`})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"count": 3,
+		"character": map[string]interface{}{
+			"name":  "Luke",
+			"shout": "LUKE",
+			"role":  "HERO",
+			"friends": []interface{}{
+				map[string]interface{}{"__typename": "Human", "name": "Vader"},
+				map[string]interface{}{"__typename": "Droid", "name": "R2-D2"},
+			},
+			// the role of the filter defaults to HERO
+			"heroes": []interface{}{
+				map[string]interface{}{"name": "R2-D2"},
+			},
+		},
+		"search": []interface{}{
+			map[string]interface{}{"__typename": "Droid", "id": "3", "name": "R2-D2"},
+			map[string]interface{}{"__typename": "Human", "id": "1", "name": "Luke"},
+		},
+	}, data)
+
+	_, errs = execution.Do(built, execution.Params{Query: `{ search(text: "") { __typename } }`})
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "empty search", errs[0].Message)
+	}
+
+	assert.Equal(t, "a character of the films", built.TypeMap["Character"].Description())
+	function := built.TypeMap["Droid"].(*internal.Object).Fields["function"]
+	assert.True(t, function.IsDeprecated)
+	assert.Equal(t, "use name", function.DeprecationReason)
+}
+
+func TestFromSDL_Errors(t *testing.T) {
+	schema := schemabuilder.FromSDL(`
+		scalar Money
+		type Query {
+			person(id: ID!): Person
+			count: Int!
+			persons: [Person!]!
+		}
+		type Person { name: String! age: Int! pet: Pet }
+	`)
+	schema.Resolve("Query", "person", func(args struct {
+		Name string `graphql:"name"`
+	}) *struct{} {
+		return nil
+	})
+	schema.Resolve("Query", "count", func() string { return "" })
+	schema.Resolve("Query", "persons", func() ([]string, error) { return nil, nil })
+	schema.Resolve("Person", "age", func(source, other interface{}) int { return 0 })
+	schema.Resolve("Person", "height", func() int { return 0 })
+	schema.Resolve("Animal", "name", func() string { return "" })
+	_, err := schema.Build()
+	if !assert.Error(t, err) {
+		return
+	}
+	errs := err.(schemabuilder.RegistrationErrors)
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+		// the errors of resolvers tell where they were bound
+		if strings.Contains(messages[i], "sdl_test.go:") {
+			messages[i] = messages[i][strings.Index(messages[i], ": ")+2:]
+		}
+	}
+	assert.Equal(t, []string{
+		"2:3: scalar Money is not bound, bind it with Scalar",
+		"8:46: unknown type Pet",
+		"Animal is not an object type of the document",
+		"Person.age: resolver func(interface {}, interface {}) int should take [context.Context][, source][, args]",
+		"Person.height is not a field of the document",
+		"Query.count: resolver returns string: string can not hold a Int",
+		"Query.person: field Name of struct { Name string \"graphql:\\\"name\\\"\" } is not an argument of the document",
+		"Query.persons: resolver returns []string: string can not be the object type Person",
+	}, messages)
+}
@@ -3,6 +3,7 @@ package schemabuilder
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,8 @@ import (
 	"math"
 	"mime/multipart"
 	"reflect"
+	"runtime"
+	"strconv"
 	"time"
 )
 
@@ -22,7 +25,11 @@ type Object struct {
 	Desc         string
 	Type         interface{}
 	FieldResolve map[string]*fieldResolve
+	FieldOptions map[string]*fieldResolve
 	Interface    []*Interface
+	schema       *Schema
+	callSite     string
+	fingerprint  fingerprint
 }
 
 // InputObject represents the input objects passed in queries,mutations and subscriptions
@@ -31,6 +38,10 @@ type InputObject struct {
 	Desc   string
 	Type   interface{}
 	Fields map[string]*inputFieldResolve
+
+	schema      *Schema
+	callSite    string
+	fingerprint fingerprint
 }
 
 type FieldFuncOption interface {
@@ -60,6 +71,9 @@ type executeFuncParam struct {
 	source interface{}
 }
 
+// ExecuteFunc is a hook run before the resolver of a field. args are the arguments of this invocation
+// of the resolver: a copy of the arguments of the selection, which the hook may change for the
+// argument decoder and the resolver, without affecting other invocations, retries included.
 type ExecuteFunc func(ctx context.Context, args, source interface{}) error
 
 func (b ExecuteFunc) execute(arg interface{}) (interface{}, error) {
@@ -83,6 +97,277 @@ var NonNullField afterBuildFunc = func(param buildParam) error {
 	return nil
 }
 
+// FoundBool makes a FieldFunc report whether it found its value the way Go functions commonly do,
+// with a bool following the result: func(...) (T, bool) or func(...) (T, bool, error).
+//    query.FieldFunc("user", func(args struct{ ID int }) (*User, bool) {
+//        user, ok := users[args.ID]
+//        return user, ok
+//    }, schemabuilder.FoundBool())
+//
+// A value which is not found is null. A non-null field can not be null, so it reports the error
+// "User not found" instead, or notFound when it is passed.
+// Without FoundBool such a bool is rejected, so that it is never mistaken for the result of a Boolean field.
+func FoundBool(notFound ...string) foundBool {
+	found := foundBool{}
+	if len(notFound) > 0 {
+		found.notFound = notFound[0]
+	}
+	return found
+}
+
+type foundBool struct {
+	notFound string
+}
+
+// err is the error of a non-null field of type typ whose value is not found.
+func (f foundBool) err(typ internal.Type) error {
+	if f.notFound != "" {
+		return errors.New(f.notFound)
+	}
+	return fmt.Errorf("%s not found", typ.String())
+}
+
+// MaskPolicy decides what a masked non-null field does when it is hidden.
+type MaskPolicy int
+
+const (
+	// MaskError keeps the field non-null, a hidden value is reported as a null error.
+	MaskError MaskPolicy = iota
+	// MaskNullable makes the field nullable in the schema, so a hidden value is just null.
+	MaskNullable
+)
+
+// Masked hides a field from viewers who may not see it: when check returns false the
+// resolver is skipped and the field resolves to null without any error.
+//
+// A non-null field can not be null, so by default hiding it is an error as for any other
+// null value; pass MaskNullable to make the field nullable instead.
+func Masked(check func(ctx context.Context, source interface{}) bool, policy ...MaskPolicy) afterBuildFunc {
+	return func(param buildParam) error {
+		if len(policy) > 0 && policy[0] == MaskNullable {
+			if nonNull, ok := param.f.Type.(*internal.NonNull); ok {
+				param.f.Type = nonNull.Type
+			}
+		}
+		resolve := param.f.Resolve
+		param.f.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			if !check(ctx, source) {
+				return nil, nil
+			}
+			return resolve(ctx, source, args)
+		}
+		return nil
+	}
+}
+
+// DynamicOutput lets the resolver of a field of a built-in scalar type, such as String, return values
+// which are not strings, numbers or booleans, for fields which intentionally send them encoded to JSON:
+//    query.FieldFunc("settings", loadSettings, schemabuilder.DynamicOutput())
+//
+// Without it the executor reports such a value as an error, as it usually comes from a resolver
+// returning another type than the one the field was declared with.
+func DynamicOutput() afterBuildFunc {
+	return func(param buildParam) error {
+		param.f.DynamicOutput = true
+		return nil
+	}
+}
+
+// Deprecated marks a field deprecated for reason, as @deprecated(reason:) does in SDL:
+//    user.FieldFunc("fullName", fullName, schemabuilder.Deprecated("use name"))
+//
+// Introspection reports the deprecation and lists the field only when includeDeprecated is true.
+// Deprecated fields can still be queried, the NoDeprecated rule of the execution package reports them.
+func Deprecated(reason string) afterBuildFunc {
+	return func(param buildParam) error {
+		param.f.IsDeprecated = true
+		param.f.DeprecationReason = reason
+		return nil
+	}
+}
+
+// Cost makes a field count n instead of 1 toward the complexity of the operations selecting it,
+// besides the fields selected on its values, see execution.MaxComplexity:
+//    query.FieldFunc("search", search, schemabuilder.Cost(10))
+func Cost(n int) afterBuildFunc {
+	return func(param buildParam) error {
+		param.f.Complexity = func(args map[string]interface{}, childComplexity int) int {
+			return n + childComplexity
+		}
+		return nil
+	}
+}
+
+// CostFunc computes the complexity of a field with fn, from the arguments it is selected with and the
+// complexity of the fields selected on its values, see execution.MaxComplexity. The arguments are their
+// JSON values, with the default values of omitted arguments, and childComplexity is already multiplied
+// by the first or limit argument of a list.
+func CostFunc(fn func(args map[string]interface{}, childComplexity int) int) afterBuildFunc {
+	return func(param buildParam) error {
+		param.f.Complexity = fn
+		return nil
+	}
+}
+
+// MemoizePerRequest calls the resolver once per request for a source and equal arguments,
+// so a field selected again through several fragments reuses the first result:
+//    user.FieldFunc("permissions", loadPermissions, schemabuilder.MemoizePerRequest())
+//
+// Sources are told apart by pointer identity, or by value when they are not pointers;
+// identity overrides it, for example to key sources by their ID.
+// Sources which can not be compared and arguments which can not be encoded to JSON are not memoized.
+func MemoizePerRequest(identity ...func(source interface{}) interface{}) afterBuildFunc {
+	return func(param buildParam) error {
+		field := param.f
+		resolve := field.Resolve
+		field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			memo := internal.MemoFrom(ctx)
+			if memo == nil {
+				return resolve(ctx, source, args)
+			}
+			id, ok := sourceIdentity(source)
+			if len(identity) > 0 {
+				id, ok = identity[0](source), true
+			}
+			// encoding/json sorts the keys of maps, equal arguments have the same encoding
+			canonical, err := json.Marshal(args)
+			if !ok || err != nil || id != nil && !reflect.TypeOf(id).Comparable() {
+				return resolve(ctx, source, args)
+			}
+			return memo.Do(memoKey{field: field, source: id, args: string(canonical)}, func() (interface{}, error) {
+				return resolve(ctx, source, args)
+			})
+		}
+		return nil
+	}
+}
+
+// ConcurrencyScope decides which calls of a field share its ConcurrencyLimit.
+type ConcurrencyScope int
+
+const (
+	// LimitPerProcess limits the calls of the field made by all the requests together.
+	LimitPerProcess ConcurrencyScope = iota
+	// LimitPerRequest limits the calls of the field made by each request on its own.
+	LimitPerRequest
+)
+
+// ConcurrencyLimit lets at most n calls of the resolver of a field run at once, for fields calling
+// a downstream service which only takes so many concurrent requests:
+//    query.FieldFunc("quote", fetchQuote, schemabuilder.ConcurrencyLimit(5))
+//
+// Calls beyond n wait for a running call to return, or fail with the error of their context when it
+// is done first. The limit is shared by all the requests, unless LimitPerRequest is passed.
+func ConcurrencyLimit(n int, scope ...ConcurrencyScope) afterBuildFunc {
+	return func(param buildParam) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency limit must be at least 1, not %d", n)
+		}
+		field := param.f
+		resolve := field.Resolve
+		shared := make(chan struct{}, n)
+		perRequest := len(scope) > 0 && scope[0] == LimitPerRequest
+		field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			semaphore := shared
+			// outside of an execution there is no request to limit, the shared limit applies
+			if memo := internal.MemoFrom(ctx); perRequest && memo != nil {
+				value, _ := memo.Do(semaphoreKey{field: field}, func() (interface{}, error) {
+					return make(chan struct{}, n), nil
+				})
+				semaphore = value.(chan struct{})
+			}
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-semaphore }()
+			return resolve(ctx, source, args)
+		}
+		return nil
+	}
+}
+
+// BestEffort resolves a field to null with a warning rather than an error when its resolver takes
+// longer than timeout, for fields which must never fail the response, such as recommendations:
+//    query.FieldFunc("recommendations", recommend, schemabuilder.BestEffort(200*time.Millisecond))
+//
+// The context of a resolver out of time is cancelled, and its result discarded once it returns.
+// The warning is reported in the warnings extension of the response, see execution.CollectWarnings.
+// A best-effort field must be nullable.
+func BestEffort(timeout time.Duration) afterBuildFunc {
+	return func(param buildParam) error {
+		field := param.f
+		if timeout <= 0 {
+			return fmt.Errorf("best-effort timeout must be positive, not %s", timeout)
+		}
+		if _, ok := field.Type.(*internal.NonNull); ok {
+			return fmt.Errorf("best-effort field must be nullable, not %s", field.Type)
+		}
+		resolve := field.Resolve
+		field.Resolve = func(ctx context.Context, source, args interface{}) (interface{}, error) {
+			type result struct {
+				value interface{}
+				err   error
+			}
+			timed, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			// the result of a resolver out of time is sent to nobody, the buffer lets it return
+			done := make(chan result, 1)
+			go func() {
+				var res result
+				defer func() {
+					if panicErr := recover(); panicErr != nil {
+						const size = 64 << 10
+						buf := make([]byte, size)
+						buf = buf[:runtime.Stack(buf, false)]
+						res = result{err: fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)}
+					}
+					done <- res
+				}()
+				res.value, res.err = resolve(timed, source, args)
+			}()
+			select {
+			case res := <-done:
+				return res.value, res.err
+			case <-timed.Done():
+				// the request itself is over, which is no reason for a warning
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				return nil, &internal.Warning{Reason: fmt.Sprintf("%s timed out after %s", field.Name, timeout)}
+			}
+		}
+		return nil
+	}
+}
+
+type semaphoreKey struct {
+	field *internal.Field
+}
+
+type memoKey struct {
+	field  *internal.Field
+	source interface{}
+	args   string
+}
+
+// sourceIdentity returns the pointer of source, or source itself when it is comparable.
+func sourceIdentity(source interface{}) (interface{}, bool) {
+	if source == nil {
+		return nil, true
+	}
+	value := reflect.ValueOf(source)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return struct {
+			typ reflect.Type
+			ptr uintptr
+		}{value.Type(), value.Pointer()}, true
+	}
+	return source, value.Type().Comparable()
+}
+
 // Enum is a representation of an enum that includes both the mapping and reverse mapping.
 type Enum struct {
 	Name       string
@@ -91,6 +376,38 @@ type Enum struct {
 	Map        map[string]interface{}
 	ReverseMap map[interface{}]string
 	DescMap    map[string]string
+	// DeprecatedMap maps the deprecated values to their deprecation reason, see DeprecatedValues.
+	DeprecatedMap map[string]string
+	callSite      string
+	fingerprint   fingerprint
+
+	outputMapper func(value interface{}) (interface{}, error)
+	inputMapper  func(value interface{}) (interface{}, error)
+}
+
+type enumOutputMapper func(value interface{}) (interface{}, error)
+
+type enumInputMapper func(value interface{}) (interface{}, error)
+
+// EnumOutputMapper is an option of Schema.Enum converting the values resolvers return before they are
+// looked up in the enum map, for example from the codes stored in a database to the Go values of the enum.
+func EnumOutputMapper(fn func(value interface{}) (interface{}, error)) interface{} {
+	return enumOutputMapper(fn)
+}
+
+// EnumInputMapper is an option of Schema.Enum converting the values of the enum names the arguments
+// hold before they reach resolvers, for example from the Go values of the enum to the codes stored in a
+// database. The converted value must be assignable or convertible to the type of the argument.
+func EnumInputMapper(fn func(value interface{}) (interface{}, error)) interface{} {
+	return enumInputMapper(fn)
+}
+
+type deprecatedValues map[string]string
+
+// DeprecatedValues is an option of Schema.Enum marking the values named by the keys of reasons
+// deprecated, for the reasons they map to, as @deprecated(reason:) does in SDL.
+func DeprecatedValues(reasons map[string]string) interface{} {
+	return deprecatedValues(reasons)
 }
 
 // Interface is a representation of graphql interface
@@ -102,6 +419,8 @@ type Interface struct {
 	PossibleTypes map[string]*Object
 	FieldResolve  map[string]*fieldResolve
 	Interface     []*Interface
+	schema        *Schema
+	callSite      string
 }
 
 // Union is a representation of graphql union
@@ -110,16 +429,28 @@ type Union struct {
 	Desc  string
 	Type  interface{}
 	Types []reflect.Type
+
+	callSite string
 }
 
 // Scalar is a representation of graphql scalar
 type Scalar struct {
-	Name         string
-	Desc         string
-	Type         interface{}
+	Name string
+	Desc string
+	Type interface{}
+	// Serialize returns the value written in the response for a value of the scalar. A json.RawMessage,
+	// such as a precomputed GeoJSON document, is written as it is instead of as a string; when it is not
+	// valid JSON the field fails.
 	Serialize    func(interface{}) (interface{}, error)
 	ParseValue   func(interface{}) (interface{}, error)
-	ParseLiteral func(value ast.Value) error
+	ParseLiteral func(value ast.Value) (interface{}, error)
+	// SpecifiedByURL is the URL of the specification of the values of the scalar, such as an RFC,
+	// shown by introspection as its specifiedByURL.
+	SpecifiedByURL string
+
+	callSite    string
+	literal     bool
+	fingerprint fingerprint
 }
 
 type Directive struct {
@@ -128,6 +459,10 @@ type Directive struct {
 	Fn     interface{}
 	Locs   []string
 	Fields map[string]*inputFieldResolve
+
+	schema      *Schema
+	callSite    string
+	fingerprint fingerprint
 }
 
 // FieldDefault exposes a field on an object. The function f can take a number of
@@ -154,38 +489,56 @@ func (s *Object) FieldFunc(name string, fn interface{}, options ...interface{})
 	}
 
 	if _, ok := s.FieldResolve[name]; ok {
-		panic("duplicate method")
+		s.schema.fail(callSite(1), "duplicate method %s of object %s", name, s.Name)
+		return
 	}
 
-	resolve := &fieldResolve{fn: fn}
-	for _, opt := range options {
-		switch opt := opt.(type) {
-		case afterBuildFunc:
-			resolve.buildChain = append(resolve.buildChain, opt)
-		case ExecuteFunc:
-			resolve.handleChain = append(resolve.handleChain, opt)
-		case string:
-			resolve.desc = opt
-		case FieldFuncOption:
-			resolve.executeChain = append(resolve.executeChain, opt)
-		default:
-			panic("only received string or FieldFuncOption interface for options")
-		}
+	resolve := &fieldResolve{fn: fn, callSite: callSite(1)}
+	if err := resolve.addOptions(options); err != nil {
+		s.schema.fail(callSite(1), "object %s field %s: %w", s.Name, name, err)
+		return
 	}
+	s.FieldResolve[name] = resolve
+}
 
-	if _, ok := s.FieldResolve[name]; ok {
-		panic("duplicate method")
+// FieldOption attaches FieldFuncOption to a field read straight from a struct field,
+// the same way as options passed to FieldFunc:
+//    user.FieldOption("email", schemabuilder.Masked(isSelf))
+//
+// Options needing the resolver function, such as RelayConnection, can not be used here.
+func (s *Object) FieldOption(name string, options ...interface{}) {
+	if getField(s.Type, name) == nil {
+		s.schema.fail(callSite(1), "object %s FieldOption param name %s must be the name or tag of struct field", s.Name, name)
+		return
 	}
-	s.FieldResolve[name] = resolve
+	if s.FieldOptions == nil {
+		s.FieldOptions = make(map[string]*fieldResolve)
+	}
+	if _, ok := s.FieldOptions[name]; ok {
+		s.schema.fail(callSite(1), "duplicate field option: %s", name)
+		return
+	}
+	resolve := &fieldResolve{callSite: callSite(1)}
+	if err := resolve.addOptions(options); err != nil {
+		s.schema.fail(callSite(1), "object %s field %s: %w", s.Name, name, err)
+		return
+	}
+	if resolve.found != nil {
+		s.schema.fail(callSite(1), "object %s field %s: FoundBool needs a FieldFunc", s.Name, name)
+		return
+	}
+	s.FieldOptions[name] = resolve
 }
 
 // FieldDefault is used to expose the fields of an input object
 func (io *InputObject) FieldDefault(name string, defaultValue interface{}) {
 	if getField(io.Type, name) == nil {
-		panic("inputObject FieldDefault param name must be the name or tag of struct field")
+		io.schema.fail(callSite(1), "inputObject %s FieldDefault param name %s must be the name or tag of struct field", io.Name, name)
+		return
 	}
 	if _, ok := io.Fields[name]; ok {
-		panic("duplicate defaultValue: " + name)
+		io.schema.fail(callSite(1), "duplicate defaultValue: %s", name)
+		return
 	}
 	resolve := &inputFieldResolve{DefaultValue: defaultValue}
 	io.Fields[name] = resolve
@@ -194,7 +547,8 @@ func (io *InputObject) FieldDefault(name string, defaultValue interface{}) {
 // FieldDefault is used to expose the fields of an input object
 func (io *Directive) FieldDefault(name string, defaultValue interface{}) {
 	if _, ok := io.Fields[name]; ok {
-		panic("duplicate defaultValue: " + name)
+		io.schema.fail(callSite(1), "duplicate defaultValue: %s", name)
+		return
 	}
 	resolve := &inputFieldResolve{DefaultValue: defaultValue}
 	io.Fields[name] = resolve
@@ -203,52 +557,100 @@ func (io *Directive) FieldDefault(name string, defaultValue interface{}) {
 // InterfaceList exposes a interface on an object.
 func (s *Object) InterfaceList(list ...*Interface) {
 	for _, i := range list {
+		if i == nil {
+			s.schema.fail(callSite(1), "nil interface passed to InterfaceList of object %s", s.Name)
+			continue
+		}
 		interfaceTyp := reflect.TypeOf(i.Type)
-		if interfaceTyp.Kind() == reflect.Ptr {
+		if interfaceTyp != nil && interfaceTyp.Kind() == reflect.Ptr {
 			interfaceTyp = interfaceTyp.Elem()
 		}
+		if interfaceTyp == nil || interfaceTyp.Kind() != reflect.Interface {
+			// the registration of the interface failed already
+			continue
+		}
 		if typ := reflect.TypeOf(s.Type); !typ.Implements(interfaceTyp) && !reflect.PtrTo(typ).Implements(interfaceTyp) {
-			panic(fmt.Sprintf("object %s must implements interface %s", s.Name, i.Name))
+			s.schema.fail(callSite(1), "object %s must implements interface %s", s.Name, i.Name)
+			continue
 		}
 		i.PossibleTypes[s.Name] = s
 		s.Interface = append(s.Interface, i)
 	}
 }
 
-// similar as object's func, but haven't middleware func , and given name must be same as interface's method
-func (s *Interface) FieldFunc(name string, fn string, descs ...string) {
+// FieldFunc exposes a field on an interface, fn is either the name of a method of the Go interface or
+// a func taking a value of it as source, as the funcs of Object.FieldFunc do:
+//    character.FieldFunc("name", "GetName")
+//    character.FieldFunc("initials", func(c Character) string { return initials(c.GetName()) })
+//
+// The possible types of the interface which do not expose the field themselves resolve it with fn, a
+// method taking arguments needs every possible type to expose the field.
+func (s *Interface) FieldFunc(name string, fn interface{}, descs ...string) {
+	site := callSite(1)
 	if s.FieldResolve == nil {
 		s.FieldResolve = make(map[string]*fieldResolve)
 	}
 
 	if _, ok := s.FieldResolve[name]; ok {
-		panic("duplicate method")
+		s.schema.fail(site, "duplicate method %s of interface %s", name, s.Name)
+		return
+	}
+	if _, ok := fn.(string); !ok && (fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func) {
+		s.schema.fail(site, "interface %s field %s must be resolved by a method name or a func, not %T", s.Name, name, fn)
+		return
 	}
 	var desc string
 	if len(descs) > 0 {
 		desc = descs[0]
 	}
-	resolve := &fieldResolve{fn: fn, desc: desc}
+	resolve := &fieldResolve{fn: fn, desc: desc, callSite: site}
 	s.FieldResolve[name] = resolve
 }
 
 // InterfaceList exposes a interface on an Interface.
 func (s *Interface) InterfaceList(list ...*Interface) {
 	for _, i := range list {
+		if i == nil {
+			s.schema.fail(callSite(1), "nil interface passed to InterfaceList of interface %s", s.Name)
+			continue
+		}
 		interfaceTyp := reflect.TypeOf(i.Type)
-		if interfaceTyp.Kind() == reflect.Ptr {
+		if interfaceTyp != nil && interfaceTyp.Kind() == reflect.Ptr {
 			interfaceTyp = interfaceTyp.Elem()
 		}
-		if typ := reflect.TypeOf(s.Type); !typ.Implements(interfaceTyp) && !typ.Elem().Implements(interfaceTyp) {
-			panic(fmt.Sprintf("interface %s must implements interface %s", s.Name, i.Name))
+		typ := reflect.TypeOf(s.Type)
+		if interfaceTyp == nil || interfaceTyp.Kind() != reflect.Interface || typ == nil {
+			// the registration of one of the interfaces failed already
+			continue
+		}
+		if !typ.Implements(interfaceTyp) && !(typ.Kind() == reflect.Ptr && typ.Elem().Implements(interfaceTyp)) {
+			s.schema.fail(callSite(1), "interface %s must implements interface %s", s.Name, i.Name)
+			continue
 		}
 		s.Interface = append(s.Interface, i)
 	}
 }
 
-// use to valid type, if not set, will use parseValue
+// LiteralFunc validates the literals of the scalar with fn, they are then parsed with ParseValue like
+// the values of variables. Use LiteralValueFunc to parse literals differently.
 func (s *Scalar) LiteralFunc(fn func(value ast.Value) error) {
+	s.LiteralValueFunc(func(value ast.Value) (interface{}, error) {
+		if err := fn(value); err != nil {
+			return nil, err
+		}
+		raw, err := internal.ValueToJson(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s.ParseValue(raw)
+	})
+}
+
+// LiteralValueFunc parses the literals of the scalar in queries with fn, which returns the value passed
+// to resolvers. When no literal func is set, the JSON value of a literal is parsed with ParseValue.
+func (s *Scalar) LiteralValueFunc(fn func(value ast.Value) (interface{}, error)) {
 	s.ParseLiteral = fn
+	s.literal = true
 }
 
 type fieldResolve struct {
@@ -257,6 +659,33 @@ type fieldResolve struct {
 	buildChain   []FieldFuncOption
 	handleChain  []FieldFuncOption
 	executeChain []FieldFuncOption
+	found        *foundBool
+	// callSite is where the field was registered, options the names of its options, for the Manifest
+	callSite string
+	options  []string
+}
+
+func (r *fieldResolve) addOptions(options []interface{}) error {
+	for _, opt := range options {
+		if _, ok := opt.(string); !ok && opt != nil {
+			r.options = append(r.options, optionName(opt))
+		}
+		switch opt := opt.(type) {
+		case afterBuildFunc:
+			r.buildChain = append(r.buildChain, opt)
+		case ExecuteFunc:
+			r.handleChain = append(r.handleChain, opt)
+		case string:
+			r.desc = opt
+		case foundBool:
+			r.found = &opt
+		case FieldFuncOption:
+			r.executeChain = append(r.executeChain, opt)
+		default:
+			return fmt.Errorf("only received string or FieldFuncOption interface for options")
+		}
+	}
+	return nil
 }
 
 type inputFieldResolve struct {
@@ -268,6 +697,10 @@ type UnmarshalFunc func(value interface{}, dest reflect.Value) error
 
 var UnmarshalFuncTyp = reflect.TypeOf(*new(UnmarshalFunc))
 
+// SpecifiedBy is an option of Schema.Scalar setting the SpecifiedByURL of the scalar:
+//    build.Scalar("UUID", UUID{}, schemabuilder.SpecifiedBy("https://tools.ietf.org/html/rfc4122"))
+type SpecifiedBy string
+
 var Boolean = &Scalar{
 	Name:      "Boolean",
 	Desc:      "bool is the set of boolean values, true and false.",
@@ -301,6 +734,15 @@ var Int = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			// integers decoded as json.Number keep the precision float64 loses
+			if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				return int(i), nil
+			}
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int32(0), nil
@@ -327,6 +769,11 @@ var Int8 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int8(0), nil
@@ -353,6 +800,11 @@ var Int16 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int16(0), nil
@@ -379,6 +831,11 @@ var Int32 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int32(0), nil
@@ -405,6 +862,15 @@ var Int64 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			// integers decoded as json.Number keep the precision float64 loses
+			if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				return i, nil
+			}
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int64(0), nil
@@ -431,6 +897,15 @@ var Uint = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			// integers decoded as json.Number keep the precision float64 loses
+			if i, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+				return uint(i), nil
+			}
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return uint(0), nil
@@ -457,6 +932,11 @@ var Uint8 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return uint8(0), nil
@@ -483,6 +963,11 @@ var Uint16 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return uint16(0), nil
@@ -509,6 +994,11 @@ var Uint32 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return uint32(0), nil
@@ -535,6 +1025,15 @@ var Uint64 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			// integers decoded as json.Number keep the precision float64 loses
+			if i, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+				return i, nil
+			}
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return uint64(0), nil
@@ -561,6 +1060,11 @@ var Float = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return float32(0), nil
@@ -587,6 +1091,11 @@ var Float64 = &Scalar{
 			val = value
 		case *float64:
 			val = *value
+		case json.Number:
+			var err error
+			if val, err = value.Float64(); err != nil {
+				return nil, errors.New("not a number")
+			}
 		default:
 			if value == nil {
 				return int32(0), nil
@@ -645,6 +1154,10 @@ var ID = &Scalar{
 			return Id{Value: val}, nil
 		case float64:
 			return Id{Value: int(val)}, nil
+		case json.Number:
+			if i, err := val.Int64(); err == nil {
+				return Id{Value: int(i)}, nil
+			}
 		}
 		return nil, errors.New("not a ID")
 	},
@@ -686,7 +1199,7 @@ var Time = &Scalar{
 	Name:      "Time",
 	Desc:      "time type",
 	Type:      time.Time{},
-	Serialize: Serialize,
+	Serialize: serializeTime(time.RFC3339Nano, nil),
 	ParseValue: func(value interface{}) (interface{}, error) {
 		v, ok := value.(string)
 		if !ok {
@@ -696,6 +1209,37 @@ var Time = &Scalar{
 	},
 }
 
+// TimeOutputFormat makes the Time and NullTime scalars of the schema output times formatted with layout.
+// Times are converted to loc first, unless it is nil.
+func TimeOutputFormat(layout string, loc *time.Location) SchemaOption {
+	return func(s *Schema) {
+		s.timeLayout = layout
+		s.timeLoc = loc
+	}
+}
+
+func serializeTime(layout string, loc *time.Location) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		var t time.Time
+		switch value := value.(type) {
+		case time.Time:
+			t = value
+		case *time.Time:
+			t = *value
+		case sql.NullTime:
+			t = value.Time
+		case *sql.NullTime:
+			t = value.Time
+		default:
+			return nil, fmt.Errorf("expected time.Time but got %v", value)
+		}
+		if loc != nil {
+			t = t.In(loc)
+		}
+		return t.Format(layout), nil
+	}
+}
+
 var Bytes = &Scalar{
 	Name: "Bytes",
 	Desc: "byte slice type",
@@ -725,6 +1269,10 @@ var AnyScalar = &Scalar{
 	Desc: "golang interface type",
 	Type: nil,
 	Serialize: func(value interface{}) (interface{}, error) {
+		// the sql nullable wrappers are sent as their value
+		if valuer, ok := value.(driver.Valuer); ok && sqlNullTypes[reflect.TypeOf(value)] {
+			return valuer.Value()
+		}
 		return value, nil
 	},
 	ParseValue: func(value interface{}) (interface{}, error) {
@@ -739,6 +1287,49 @@ var AnyScalar = &Scalar{
 	},
 }
 
+// JSONObject is the scalar of the maps with string keys, such as map[string]interface{} or map[string]int,
+// which are sent and received as JSON objects. Arguments are decoded into the map type of their field.
+var JSONObject = &Scalar{
+	Name: "JSONObject",
+	Desc: "JSON object, a map with string keys",
+	Type: map[string]interface{}(nil),
+	Serialize: func(value interface{}) (interface{}, error) {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Map && v.IsNil() {
+			return nil, nil
+		}
+		return value, nil
+	},
+	ParseValue: func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("expected a JSON object but got %v", value)
+		}
+		return value, nil
+	},
+}
+
+// decodeJSONMap decodes value, the JSON object of a JSONObject argument, into a map of type typ.
+func decodeJSONMap(value interface{}, typ reflect.Type) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	object, err := JSONObject.ParseValue(value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	decoded := reflect.New(typ)
+	if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of %s: %v", typ, err)
+	}
+	return decoded.Elem().Interface(), nil
+}
+
 var NullString = &Scalar{
 	Name: "NullString",
 	Desc: "Alias For String",
@@ -766,19 +1357,10 @@ var NullString = &Scalar{
 }
 
 var NullTime = &Scalar{
-	Name: "NullTime",
-	Desc: "Alias For Time",
-	Type: sql.NullTime{},
-	Serialize: func(value interface{}) (interface{}, error) {
-		switch value := value.(type) {
-		case sql.NullTime:
-			return value.Time, nil
-		case time.Time:
-			return value, nil
-		default:
-			return nil, fmt.Errorf("expected sql.NullTime but got %v", value)
-		}
-	},
+	Name:      "NullTime",
+	Desc:      "Alias For Time",
+	Type:      sql.NullTime{},
+	Serialize: serializeTime(time.RFC3339Nano, nil),
 	ParseValue: func(value interface{}) (interface{}, error) {
 		t, ok := value.(time.Time)
 		if !ok {
@@ -832,6 +1414,11 @@ var NullFloat = &Scalar{
 		}
 	},
 	ParseValue: func(value interface{}) (interface{}, error) {
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				value = f
+			}
+		}
 		t, ok := value.(float64)
 		if !ok {
 			if value == nil {
@@ -858,6 +1445,14 @@ var NullInt64 = &Scalar{
 		}
 	},
 	ParseValue: func(value interface{}) (interface{}, error) {
+		if n, ok := value.(json.Number); ok {
+			// integers decoded as json.Number keep the precision float64 loses
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("expected int value for sql.NullInt64, but got %v", value)
+			}
+			return sql.NullInt64{Valid: true, Int64: i}, nil
+		}
 		t, ok := value.(float64)
 		if !ok {
 			if value == nil {
@@ -887,6 +1482,11 @@ var NullInt32 = &Scalar{
 		}
 	},
 	ParseValue: func(value interface{}) (interface{}, error) {
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				value = f
+			}
+		}
 		t, ok := value.(float64)
 		if !ok {
 			if value == nil {
@@ -926,6 +1526,8 @@ type skipArg struct {
 	If bool `graphql:"if;Skipped when true."`
 }
 
+// DirectiveFn resolves the field a directive is applied to: it calls the next directive of the field,
+// or its resolver after the last one, and returns the value they returned.
 type DirectiveFn func() (interface{}, error)
 
 var IncludeDirective = &Directive{
@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shyptr/graphql/ast"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"sort"
+	"time"
+)
+
+// SlowQuery is the record of an operation which took longer than the threshold of WithSlowQueryLog.
+type SlowQuery struct {
+	// Query is the query of the request, normalized by ast.Normalize.
+	Query         string `json:"query"`
+	OperationName string `json:"operationName,omitempty"`
+	// Variables are the variables of the request, with their values redacted.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Duration is the time the request took until its response was written.
+	Duration time.Duration `json:"duration"`
+	// Fields are the fields whose resolver took longer than the field threshold, slowest first.
+	Fields []execution.FieldTiming `json:"fields,omitempty"`
+	// Loaders are the Stats of the dataloaders attached to the context of the request, by name.
+	Loaders map[string]dataloader.Stats `json:"loaders,omitempty"`
+	// Plan is the plan of the operation, see execution.Explain, nil when it can not be planned.
+	Plan *execution.PlanNode `json:"plan,omitempty"`
+}
+
+// redacted replaces the values of the variables of a SlowQuery.
+const redacted = "[redacted]"
+
+// SlowQueryLogger logs the slow operations, see WithSlowQueryLog.
+type SlowQueryLogger interface {
+	LogSlowQuery(ctx context.Context, record *SlowQuery)
+}
+
+// WithSlowQueryLog logs the requests taking threshold or longer to logger, with the resolvers which took
+// fieldThreshold or longer. When logger is nil the records are printed as JSON by the Logger of the
+// handler. The resolvers of every request are timed, the rest of the record, such as the plan of the
+// operation, is only made for the slow ones.
+func WithSlowQueryLog(threshold, fieldThreshold time.Duration, logger SlowQueryLogger) HandlerOption {
+	return func(h *Handler) {
+		h.SlowQueryThreshold = threshold
+		h.SlowFieldThreshold = fieldThreshold
+		h.SlowQueryLogger = logger
+	}
+}
+
+// logSlowQuery logs the operation of param, which took took, when it is slow.
+func (h *Handler) logSlowQuery(ctx *Context, schema *internal.Schema, param execution.Params, took time.Duration,
+	loaders map[string]dataloader.Stats, fields []execution.FieldTiming) {
+	if h.SlowQueryThreshold <= 0 || took < h.SlowQueryThreshold {
+		return
+	}
+	record := &SlowQuery{
+		Query:         param.Query,
+		OperationName: param.OperationName,
+		Duration:      took,
+		Fields:        fields,
+		Loaders:       loaders,
+	}
+	if doc, err := internal.ParseDocument(param.Query); err == nil {
+		record.Query = ast.Normalize(doc)
+	}
+	if len(param.Variables) > 0 {
+		record.Variables = make(map[string]interface{}, len(param.Variables))
+		for name := range param.Variables {
+			record.Variables[name] = redacted
+		}
+	}
+	sort.SliceStable(record.Fields, func(i, j int) bool { return record.Fields[i].Duration > record.Fields[j].Duration })
+	if schema != nil {
+		if plan, err := execution.Explain(schema, param); err == nil {
+			record.Plan = plan
+		}
+	}
+	if h.SlowQueryLogger != nil {
+		h.SlowQueryLogger.LogSlowQuery(ctx, record)
+		return
+	}
+	if h.Logger != nil {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			h.Logger.Printf("graphql: slow query: %v", err)
+			return
+		}
+		h.Logger.Printf("graphql: slow query: %s", encoded)
+	}
+}
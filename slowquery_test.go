@@ -0,0 +1,81 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type slowQueryLogger []*graphql.SlowQuery
+
+func (l *slowQueryLogger) LogSlowQuery(ctx context.Context, record *graphql.SlowQuery) {
+	*l = append(*l, record)
+}
+
+func TestWithSlowQueryLog(t *testing.T) {
+	type Report struct {
+		Name string `graphql:"name"`
+	}
+	build := schemabuilder.NewSchema()
+	report := build.Object("Report", Report{})
+	report.FieldFunc("total", func(r Report) int {
+		time.Sleep(30 * time.Millisecond)
+		return 42
+	})
+	build.Query().FieldFunc("report", func(args struct {
+		Name string `graphql:"name"`
+	}) Report {
+		return Report{Name: args.Name}
+	})
+	build.Query().FieldFunc("version", func() string { return "1" })
+	schema := build.MustBuild()
+	post := func(handler http.Handler, body string, ctx context.Context) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	var logged slowQueryLogger
+	handler := graphql.HTTPHandler(schema, graphql.WithSlowQueryLog(20*time.Millisecond, 10*time.Millisecond, &logged))
+	ctx := dataloader.Attach(context.Background(), map[string]*dataloader.Loader{
+		"reports": dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) { return keys, nil }),
+	})
+
+	// the fast requests are not logged
+	post(handler, `{"query": "{ version }"}`, ctx)
+	assert.Empty(t, logged)
+
+	post(handler, `{"query": "query Report($name: String!) { version report(name: $name) { name total } }", "variables": {"name": "sales"}}`, ctx)
+	if assert.Len(t, logged, 1) {
+		record := logged[0]
+		assert.Equal(t, "query Report($name: String!) { version report(name: $name) { name total } }", record.Query)
+		assert.Equal(t, map[string]interface{}{"name": "[redacted]"}, record.Variables)
+		assert.True(t, record.Duration >= 30*time.Millisecond)
+		// only the slow resolver is listed
+		if assert.Len(t, record.Fields, 1) {
+			assert.Equal(t, []interface{}{"report", "total"}, record.Fields[0].Path)
+			assert.True(t, record.Fields[0].Duration >= 30*time.Millisecond)
+		}
+		assert.Contains(t, record.Loaders, "reports")
+		if assert.NotNil(t, record.Plan) {
+			assert.Len(t, record.Plan.Children, 2)
+		}
+	}
+
+	// without a SlowQueryLogger the records are printed by the Logger of the handler
+	var buf bytes.Buffer
+	handler = graphql.HTTPHandler(schema, graphql.WithSlowQueryLog(20*time.Millisecond, 10*time.Millisecond, nil),
+		graphql.WithLogger(log.New(&buf, "", 0)))
+	post(handler, `{"query": "{ report(name: \"sales\") { total } }"}`, context.Background())
+	assert.True(t, strings.HasPrefix(buf.String(), `graphql: slow query: {"query":`), buf.String())
+	assert.Contains(t, buf.String(), `"path":["report","total"]`)
+}
@@ -0,0 +1,73 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/execution"
+	"net/http"
+)
+
+// WithLoaderStats makes the handler answer every request with the Stats of the dataloaders attached to
+// its context, by name, in the "loaders" extension of the response. The stats are recorded to the
+// StatsCollector of the executor, see execution.WithStatsCollector, whether enabled or not.
+func WithLoaderStats(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.LoaderStats = enabled
+	}
+}
+
+// StatsHandler serves the stats of executor as JSON: those aggregated by its StatsCollector, the
+// plan caches, the persisted queries of the handlers of the executor and the dataloaders of their
+// requests, or only those of its plan cache when it has no StatsCollector.
+//
+//   collector := execution.NewStatsCollector()
+//   executor := execution.NewExecutor(schema, execution.WithStatsCollector(collector))
+//   http.Handle("/graphql", graphql.HTTPHandler(schema, graphql.WithExecutorOptions(execution.WithStatsCollector(collector))))
+//   http.Handle("/stats", graphql.StatsHandler(executor))
+func StatsHandler(executor *execution.Executor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := execution.Stats{
+			Caches:  map[string]execution.CacheStats{"plans": executor.CacheStats()},
+			Loaders: map[string]execution.LoaderStats{},
+		}
+		if collector := executor.StatsCollector(); collector != nil {
+			stats = collector.Snapshot()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// addCacheStats reports the stats of the persisted queries of the handler to the StatsCollector of
+// executor, if it has one.
+func (h *Handler) addCacheStats(executor *execution.Executor) {
+	collector := executor.StatsCollector()
+	if collector == nil {
+		return
+	}
+	if cache, ok := h.PersistedQueries.(execution.StatsCache); ok {
+		collector.AddCache("persistedQueries", cache)
+	}
+}
+
+// loaderStats returns the stats of the dataloaders of the operation executed with ctx, without those
+// shared had before it, and records them to the StatsCollector of executor.
+func (h *Handler) loaderStats(ctx context.Context, executor *execution.Executor,
+	shared map[string]dataloader.Stats) map[string]dataloader.Stats {
+	stats := dataloader.AttachedStats(ctx)
+	for name, before := range shared {
+		loader := stats[name]
+		loader.Loads -= before.Loads
+		loader.Hits -= before.Hits
+		loader.Batches -= before.Batches
+		loader.Keys -= before.Keys
+		stats[name] = loader
+	}
+	if collector := executor.StatsCollector(); collector != nil && stats != nil {
+		collector.RecordLoaders(stats)
+	}
+	return stats
+}
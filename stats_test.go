@@ -0,0 +1,86 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/dataloader"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatsHandler(t *testing.T) {
+	type Number struct {
+		Value int `graphql:"value"`
+	}
+	build := schemabuilder.NewSchema()
+	number := build.Object("Number", Number{})
+	number.FieldFunc("double", func(ctx context.Context, n Number) func() (int, error) {
+		thunk := dataloader.For(ctx, "double").Load(ctx, n.Value)
+		return func() (int, error) {
+			value, err := thunk()
+			if err != nil {
+				return 0, err
+			}
+			return value.(int), nil
+		}
+	})
+	build.Query().FieldFunc("numbers", func() []Number { return []Number{{1}, {2}, {1}} })
+	schema := build.MustBuild()
+
+	collector := execution.NewStatsCollector()
+	handler := graphql.HTTPHandler(schema, graphql.WithExecutorOptions(execution.WithStatsCollector(collector)),
+		graphql.WithPersistedQueries(graphql.NewQueryCache(10)), graphql.WithLoaderStats(true))
+	post := func(body string) string {
+		// every request has loaders of its own
+		ctx := dataloader.Attach(context.Background(), map[string]*dataloader.Loader{
+			"double": dataloader.NewLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, error) {
+				values := make([]interface{}, len(keys))
+				for i, key := range keys {
+					values[i] = key.(int) * 2
+				}
+				return values, nil
+			}),
+		})
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		return recorder.Body.String()
+	}
+	const query = "{ numbers { double } }"
+	sum := sha256.Sum256([]byte(query))
+	extensions := fmt.Sprintf(`{"persistedQuery": {"version": 1, "sha256Hash": %q}}`, hex.EncodeToString(sum[:]))
+
+	// the response has the stats of the loaders of the request
+	assert.JSONEq(t, `{"data": {"numbers": [{"double": 2}, {"double": 4}, {"double": 2}]}, "extensions": {"loaders": {"double": {
+		"loads": 3, "hits": 1, "batches": 1, "keys": 2, "averageBatchSize": 2, "hitRatio": 0.3333333333333333}}}}`,
+		post(fmt.Sprintf(`{"query": %q, "extensions": %s}`, query, extensions)))
+	post(fmt.Sprintf(`{"extensions": %s}`, extensions))
+	post(fmt.Sprintf(`{"extensions": %s}`, extensions))
+	assert.Contains(t, post(`{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "0000"}}}`), "PersistedQueryNotFound")
+
+	recorder := httptest.NewRecorder()
+	graphql.StatsHandler(handler.(*graphql.Handler).Executor).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"caches": {
+			"plans": {"hits": 2, "misses": 1, "evictions": 0, "size": 1},
+			"persistedQueries": {"hits": 2, "misses": 1, "evictions": 0, "size": 1}
+		},
+		"loaders": {"double": {"loads": 9, "hits": 3, "batches": 3, "keys": 6, "averageBatchSize": 2, "hitRatio": 0.3333333333333333}}
+	}`, recorder.Body.String())
+
+	// an executor without a StatsCollector only has the stats of its plan cache
+	recorder = httptest.NewRecorder()
+	graphql.StatsHandler(execution.NewExecutor(schema)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.JSONEq(t, `{"caches": {"plans": {"hits": 0, "misses": 0, "evictions": 0, "size": 0}}, "loaders": {}}`,
+		recorder.Body.String())
+}
@@ -0,0 +1,396 @@
+// Package querygen generates random operations which are valid against a schema, to fuzz its resolvers
+// or to load test a server and size its caches:
+//
+//   for seed := int64(0); seed < 1000; seed++ {
+//     query, vars := querygen.Generate(schema, querygen.GenOptions{Seed: seed, FragmentProbability: 0.2})
+//     execution.Do(schema, execution.Params{Query: query, Variables: vars})
+//   }
+//
+// The operations select random subsets of the fields of their types, and give random values to the
+// arguments, as literals or as variables. The same schema and options always generate the same operation.
+package querygen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScalarGenerator returns a random value of a scalar, as the JSON value a variable would hold.
+type ScalarGenerator func(r *rand.Rand) interface{}
+
+// GenOptions configures Generate.
+type GenOptions struct {
+	// Seed seeds the random choices.
+	Seed int64
+	// MaxDepth bounds the nesting of the selection sets and input object values, 3 by default.
+	MaxDepth int
+	// MaxFields bounds the number of fields selected in a selection set, 4 by default.
+	MaxFields int
+	// FragmentProbability is the probability that a selection set is written in a fragment, and that
+	// a selection set of an interface or a union selects the fields of one of its possible types.
+	FragmentProbability float64
+	// VariableProbability is the probability that an argument is given a variable instead of a literal.
+	VariableProbability float64
+	// Mutation generates mutations instead of queries.
+	Mutation bool
+	// Scalars generate the values of the scalars named by their key, which can not be generated
+	// otherwise, or whose values must follow another format. Optional arguments of scalars without
+	// generator are left out, fields with required ones are not selected.
+	Scalars map[string]ScalarGenerator
+}
+
+// Generate returns a random operation valid against schema, and the values of its variables.
+func Generate(schema *internal.Schema, opts GenOptions) (query string, vars map[string]interface{}) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 3
+	}
+	if opts.MaxFields <= 0 {
+		opts.MaxFields = 4
+	}
+	g := &generator{
+		opts: opts,
+		rand: rand.New(rand.NewSource(opts.Seed)),
+		vars: map[string]interface{}{},
+	}
+
+	operation, root := "query", schema.Query
+	if opts.Mutation {
+		operation, root = "mutation", schema.Mutation
+	}
+	selectionSet := "{ __typename }"
+	if root, ok := root.(internal.NamedType); ok {
+		selectionSet = g.selectionSet(root, 1, false)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(operation)
+	if len(g.varDefs) > 0 {
+		buf.WriteString("(" + strings.Join(g.varDefs, ", ") + ")")
+	}
+	buf.WriteString(" " + selectionSet)
+	for _, fragment := range g.fragments {
+		buf.WriteString("\n\n" + fragment)
+	}
+	return buf.String(), g.vars
+}
+
+type generator struct {
+	opts GenOptions
+	rand *rand.Rand
+	// vars and varDefs are the values and definitions of the variables of the operation
+	vars    map[string]interface{}
+	varDefs []string
+	// fragments are the definitions of the named fragments of the operation
+	fragments []string
+	// names numbers the aliases, variables and fragments
+	names int
+}
+
+func (g *generator) name(prefix string) string {
+	g.names++
+	return prefix + strconv.Itoa(g.names)
+}
+
+// selectionSet returns a selection set of typ. Fields selected for a possible type of an interface or
+// a union are aliased, so that they never conflict with the fields of the other possible types.
+func (g *generator) selectionSet(typ internal.NamedType, depth int, aliased bool) string {
+	var selections []string
+	switch typ := typ.(type) {
+	case *internal.Object:
+		selections = g.fields(typ.Fields, depth, aliased)
+	case *internal.Interface:
+		selections = g.fields(typ.Fields, depth, aliased)
+		for _, name := range sortedKeys(typ.PossibleTypes) {
+			if g.rand.Float64() < g.opts.FragmentProbability {
+				selections = append(selections, "... on "+name+" "+g.selectionSet(typ.PossibleTypes[name], depth, true))
+			}
+		}
+	case *internal.Union:
+		for _, name := range sortedKeys(typ.Types) {
+			if g.rand.Float64() < 0.5 {
+				selections = append(selections, "... on "+name+" "+g.selectionSet(typ.Types[name], depth, true))
+			}
+		}
+	}
+	if len(selections) == 0 {
+		selections = []string{"__typename"}
+	}
+	selectionSet := "{ " + strings.Join(selections, " ") + " }"
+
+	if g.rand.Float64() < g.opts.FragmentProbability {
+		if g.rand.Intn(2) == 0 {
+			return "{ ... on " + typ.TypeName() + " " + selectionSet + " }"
+		}
+		name := g.name("Fragment")
+		g.fragments = append(g.fragments, "fragment "+name+" on "+typ.TypeName()+" "+selectionSet)
+		return "{ ..." + name + " }"
+	}
+	return selectionSet
+}
+
+// fields returns up to MaxFields random fields of fields.
+func (g *generator) fields(fields map[string]*internal.Field, depth int, aliased bool) []string {
+	var names []string
+	for _, name := range sortedKeys(fields) {
+		// the meta-fields of introspection are left out
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	g.rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+	n := 1 + g.rand.Intn(g.opts.MaxFields)
+
+	var selections []string
+	for _, name := range names {
+		if len(selections) == n {
+			break
+		}
+		if selection, ok := g.field(fields[name], depth, aliased); ok {
+			selections = append(selections, selection)
+		}
+	}
+	return selections
+}
+
+// field returns a selection of field, or false when it can not be selected, as its type has
+// subfields deeper than MaxDepth, or one of its required arguments can not be generated.
+func (g *generator) field(field *internal.Field, depth int, aliased bool) (string, bool) {
+	named := namedType(field.Type)
+	hasSubfields := false
+	switch named.(type) {
+	case *internal.Object, *internal.Interface, *internal.Union:
+		hasSubfields = true
+		if depth >= g.opts.MaxDepth {
+			return "", false
+		}
+	}
+
+	var args []string
+	for _, name := range sortedKeys(field.Args) {
+		arg := field.Args[name]
+		_, nonNull := arg.Type.(*internal.NonNull)
+		required := nonNull && arg.DefaultValue == nil
+		if !required && g.rand.Intn(2) == 0 {
+			continue
+		}
+		value, ok := g.value(arg.Type, 1)
+		if !ok {
+			if required {
+				return "", false
+			}
+			continue
+		}
+		if g.rand.Float64() < g.opts.VariableProbability {
+			variable := g.name("v")
+			g.varDefs = append(g.varDefs, "$"+variable+": "+arg.Type.String())
+			g.vars[variable] = jsonValue(value)
+			args = append(args, name+": $"+variable)
+		} else {
+			args = append(args, name+": "+literal(value))
+		}
+	}
+
+	var buf strings.Builder
+	if aliased {
+		buf.WriteString(g.name("_") + ": ")
+	}
+	buf.WriteString(field.Name)
+	if len(args) > 0 {
+		buf.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	if hasSubfields {
+		buf.WriteString(" " + g.selectionSet(named, depth+1, false))
+	}
+	return buf.String(), true
+}
+
+// enumValue is a generated value of an enum, written as a name in literals and as a string in variables.
+type enumValue string
+
+// value returns a random value of the input type typ, or false when no value can be generated.
+// Optional fields of input objects are left out deeper than MaxDepth.
+func (g *generator) value(typ internal.Type, depth int) (interface{}, bool) {
+	switch typ := typ.(type) {
+	case *internal.NonNull:
+		return g.value(typ.Type, depth)
+	case *internal.List:
+		items := make([]interface{}, 0)
+		for i := g.rand.Intn(3); i > 0; i-- {
+			item, ok := g.value(typ.Type, depth)
+			if !ok {
+				break
+			}
+			items = append(items, item)
+		}
+		return items, true
+	case *internal.Enum:
+		if len(typ.Values) == 0 {
+			return nil, false
+		}
+		values := append([]string(nil), typ.Values...)
+		sort.Strings(values)
+		return enumValue(values[g.rand.Intn(len(values))]), true
+	case *internal.InputObject:
+		object := make(map[string]interface{})
+		for _, name := range sortedKeys(typ.Fields) {
+			field := typ.Fields[name]
+			_, nonNull := field.Type.(*internal.NonNull)
+			required := nonNull && field.DefaultValue == nil
+			if !required && (depth >= g.opts.MaxDepth || g.rand.Intn(2) == 0) {
+				continue
+			}
+			value, ok := g.value(field.Type, depth+1)
+			if !ok {
+				if required {
+					return nil, false
+				}
+				continue
+			}
+			object[name] = value
+		}
+		return object, true
+	case *internal.Scalar:
+		generate, ok := g.opts.Scalars[typ.Name]
+		if !ok {
+			generate, ok = scalars[typ.Name]
+		}
+		if !ok {
+			return nil, false
+		}
+		return generate(g.rand), true
+	}
+	return nil, false
+}
+
+// scalars generate the values of the builtin scalars of schemabuilder.
+var scalars = map[string]ScalarGenerator{
+	"Boolean":    randomBool,
+	"NullBool":   randomBool,
+	"Int":        randomInt,
+	"Int8":       randomInt,
+	"Int16":      randomInt,
+	"Int32":      randomInt,
+	"Int64":      randomInt,
+	"Uint":       randomInt,
+	"Uint8":      randomInt,
+	"Uint16":     randomInt,
+	"Uint32":     randomInt,
+	"Uint64":     randomInt,
+	"NullInt32":  randomInt,
+	"NullInt64":  randomInt,
+	"Float":      randomFloat,
+	"Float64":    randomFloat,
+	"NullFloat":  randomFloat,
+	"String":     randomString,
+	"NullString": randomString,
+	"ID":         randomString,
+	"Time":       randomTime,
+	"NullTime":   randomTime,
+	"Bytes": func(r *rand.Rand) interface{} {
+		return base64.StdEncoding.EncodeToString([]byte(randomString(r).(string)))
+	},
+}
+
+func randomBool(r *rand.Rand) interface{} { return r.Intn(2) == 0 }
+
+func randomInt(r *rand.Rand) interface{} { return float64(r.Intn(100)) }
+
+func randomFloat(r *rand.Rand) interface{} { return float64(r.Intn(10000)) / 100 }
+
+func randomString(r *rand.Rand) interface{} {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 1+r.Intn(8))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func randomTime(r *rand.Rand) interface{} {
+	return time.Unix(r.Int63n(2000000000), 0).UTC().Format(time.RFC3339)
+}
+
+// literal writes value as a GraphQL literal.
+func literal(value interface{}) string {
+	switch value := value.(type) {
+	case enumValue:
+		return string(value)
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = literal(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		fields := make([]string, 0, len(value))
+		for _, name := range sortedKeys(value) {
+			fields = append(fields, name+": "+literal(value[name]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return "null"
+	}
+	// encoding/json escapes strings the way GraphQL string literals do
+	b, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("querygen: can not write %v as a literal: %v", value, err))
+	}
+	return string(b)
+}
+
+// jsonValue returns value as the JSON value of a variable.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case enumValue:
+		return string(value)
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = jsonValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(value))
+		for name, field := range value {
+			fields[name] = jsonValue(field)
+		}
+		return fields
+	}
+	return value
+}
+
+func namedType(typ internal.Type) internal.NamedType {
+	for {
+		switch t := typ.(type) {
+		case *internal.NonNull:
+			typ = t.Type
+		case *internal.List:
+			typ = t.Type
+		default:
+			named, _ := typ.(internal.NamedType)
+			return named
+		}
+	}
+}
+
+// sortedKeys returns the keys of m, a map keyed by strings, in order, so that the random choices
+// only depend on the seed.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,134 @@
+package querygen_test
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/shyptr/graphql/testing/querygen"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Episode int
+
+type Character interface {
+	GetName() string
+}
+
+type Human struct {
+	ID        string    `graphql:"id"`
+	Name      string    `graphql:"name"`
+	Height    float64   `graphql:"height"`
+	AppearsIn []Episode `graphql:"appearsIn"`
+	Born      time.Time `graphql:"born"`
+}
+
+func (h Human) GetName() string { return h.Name }
+
+type Droid struct {
+	ID              string `graphql:"id"`
+	Name            string `graphql:"name"`
+	PrimaryFunction string `graphql:"primaryFunction"`
+}
+
+func (d Droid) GetName() string { return d.Name }
+
+type SearchResult struct {
+	*Human
+	*Droid
+}
+
+type Stars int64
+
+type ReviewInput struct {
+	Stars      Stars        `graphql:"stars"`
+	Commentary *string      `graphql:"commentary"`
+	Episodes   []Episode    `graphql:"episodes"`
+	Reply      *ReviewInput `graphql:"reply"`
+}
+
+type Review struct {
+	Stars      Stars  `graphql:"stars"`
+	Commentary string `graphql:"commentary"`
+}
+
+func starWarsSchema() *internal.Schema {
+	build := schemabuilder.NewSchema()
+	build.Enum("Episode", Episode(0), map[string]interface{}{"NEWHOPE": Episode(4), "EMPIRE": Episode(5), "JEDI": Episode(6)})
+	build.Scalar("Stars", Stars(0), func(value interface{}, dest reflect.Value) error { return nil })
+	character := build.Interface("Character", new(Character), nil)
+	character.FieldFunc("name", "GetName")
+	human := build.Object("Human", Human{})
+	human.FieldFunc("friends", func(h Human, args struct {
+		First *int64 `graphql:"first"`
+	}) []Character {
+		return nil
+	})
+	character.PossibleTypes["Human"] = human
+	character.PossibleTypes["Droid"] = build.Object("Droid", Droid{})
+	build.Union("SearchResult", SearchResult{}, "")
+	build.InputObject("ReviewInput", ReviewInput{})
+	build.Object("Review", Review{})
+
+	query := build.Query()
+	query.FieldFunc("hero", func(args struct {
+		Episode *Episode `graphql:"episode"`
+	}) Character {
+		return Human{}
+	})
+	query.FieldFunc("human", func(args struct {
+		ID string `graphql:"id"`
+	}) *Human {
+		return nil
+	})
+	query.FieldFunc("search", func(args struct {
+		Text  string     `graphql:"text"`
+		Since *time.Time `graphql:"since"`
+	}) []SearchResult {
+		return nil
+	})
+	build.Mutation().FieldFunc("createReview", func(args struct {
+		Episode Episode     `graphql:"episode"`
+		Review  ReviewInput `graphql:"review"`
+	}) Review {
+		return Review{}
+	})
+	return build.MustBuild()
+}
+
+func TestGenerate(t *testing.T) {
+	schema := starWarsSchema()
+	stars := func(r *rand.Rand) interface{} { return float64(1 + r.Intn(5)) }
+	var fragments, variables int
+	for seed := int64(0); seed < 500; seed++ {
+		opts := querygen.GenOptions{
+			Seed:                seed,
+			FragmentProbability: 0.3,
+			VariableProbability: 0.5,
+			Mutation:            seed%5 == 0,
+			Scalars:             map[string]querygen.ScalarGenerator{"Stars": stars},
+		}
+		query, vars := querygen.Generate(schema, opts)
+		doc, err := internal.Parse(query)
+		if !assert.NoError(t, err, query) {
+			continue
+		}
+		_, _, err = execution.ApplySelectionSet(schema, doc, "", vars)
+		assert.NoError(t, err, fmt.Sprintf("%s\n%v", query, vars))
+
+		again, againVars := querygen.Generate(schema, opts)
+		assert.Equal(t, query, again)
+		assert.Equal(t, vars, againVars)
+		if strings.Contains(query, "fragment ") {
+			fragments++
+		}
+		variables += len(vars)
+	}
+	assert.NotZero(t, fragments)
+	assert.NotZero(t, variables)
+}
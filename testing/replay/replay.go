@@ -0,0 +1,356 @@
+// Package replay checks a new version of a schema against operations recorded with the previous one,
+// before deploying it: the operations are executed again and their responses compared to the
+// recorded ones.
+//
+//   recordings, err := replay.Load("testdata/recordings.json")
+//   diffs, err := replay.Replay(newSchema, recordings, replay.Options{Allow: []string{"user.avatar"}})
+//
+// The recordings are written by a Recorder, which executes the operations of a test suite or of a
+// sample of the traffic with execution.Do and keeps them with their responses.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/errors"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Recording is an operation and the response it got.
+type Recording struct {
+	// Name tells the recording apart in the diffs, the index of the recording by default.
+	Name          string                 `json:"name,omitempty"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Data          interface{}            `json:"data"`
+	Errors        []Error                `json:"errors,omitempty"`
+}
+
+// Error is an error of a recorded response.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Load returns the recordings of files, each of which holds a JSON array of recordings, in the order
+// of files.
+func Load(files ...string) ([]Recording, error) {
+	var recordings []Recording
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var loaded []Recording
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return nil, fmt.Errorf("replay: %s: %v", file, err)
+		}
+		recordings = append(recordings, loaded...)
+	}
+	return recordings, nil
+}
+
+// Recorder executes operations and records them with their responses.
+type Recorder struct {
+	mu         sync.Mutex
+	recordings []Recording
+}
+
+// Do executes param against schema as execution.Do does, and records it with its response under name.
+func (r *Recorder) Do(name string, schema *internal.Schema, param execution.Params, opts ...execution.Option) (interface{}, errors.MultiError) {
+	data, errs := execution.Do(schema, param, opts...)
+	recording := Recording{
+		Name:          name,
+		Query:         param.Query,
+		OperationName: param.OperationName,
+		Variables:     param.Variables,
+	}
+	recording.Data, recording.Errors = response(data, errs)
+	r.mu.Lock()
+	r.recordings = append(r.recordings, recording)
+	r.mu.Unlock()
+	return data, errs
+}
+
+// Recordings returns the operations recorded so far, in the order they were executed in.
+func (r *Recorder) Recordings() []Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Recording(nil), r.recordings...)
+}
+
+// WriteFile writes the recordings to file, which Load reads back.
+func (r *Recorder) WriteFile(file string) error {
+	data, err := json.MarshalIndent(r.Recordings(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// response returns data and errs as they are read back from JSON, so that they compare equal to a
+// recording.
+func response(data interface{}, errs errors.MultiError) (interface{}, []Error) {
+	var recorded []Error
+	for _, err := range errs {
+		recorded = append(recorded, Error{Message: err.Message, Path: err.Path})
+	}
+	encoded, err := json.Marshal(struct {
+		Data   interface{} `json:"data"`
+		Errors []Error     `json:"errors"`
+	}{data, recorded})
+	if err != nil {
+		return nil, []Error{{Message: fmt.Sprintf("the response can not be encoded: %v", err)}}
+	}
+	var decoded struct {
+		Data   interface{} `json:"data"`
+		Errors []Error     `json:"errors"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, []Error{{Message: fmt.Sprintf("the response can not be decoded: %v", err)}}
+	}
+	return decoded.Data, decoded.Errors
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// Added is a value missing from the recorded response, or an error it did not have.
+	Added ChangeKind = "added"
+	// Removed is a value of the recorded response missing from the new one, or an error it no longer has.
+	Removed ChangeKind = "removed"
+	// Changed is a value different from the recorded one.
+	Changed ChangeKind = "changed"
+)
+
+// Change is a difference between the recorded response of an operation and the new one.
+type Change struct {
+	Kind ChangeKind
+	// Path is the path of the value in the data, such as hero.friends.0.name, or the path of the error.
+	Path string
+	// Error is the message of the added or removed error, empty for the changes of the data.
+	Error    string
+	Old, New interface{}
+	// Allowed is whether the change is expected, see Options.Allow.
+	Allowed bool
+}
+
+func (c Change) String() string {
+	if c.Error != "" {
+		if c.Path == "" {
+			return fmt.Sprintf("%s error %q", c.Kind, c.Error)
+		}
+		return fmt.Sprintf("%s error %q at %s", c.Kind, c.Error, c.Path)
+	}
+	path := c.Path
+	if path == "" {
+		path = "the data"
+	}
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("added %s: %s", path, encode(c.New))
+	case Removed:
+		return fmt.Sprintf("removed %s: %s", path, encode(c.Old))
+	default:
+		return fmt.Sprintf("changed %s: %s -> %s", path, encode(c.Old), encode(c.New))
+	}
+}
+
+func encode(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// Diff is the changes of the response of a recording.
+type Diff struct {
+	Recording string
+	Changes   []Change
+}
+
+// Unexpected returns the changes which are not allowed.
+func (d Diff) Unexpected() []Change {
+	var changes []Change
+	for _, change := range d.Changes {
+		if !change.Allowed {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Options configures Replay.
+type Options struct {
+	// Allow are the paths of the expected changes, such as user.avatar: a change is allowed when its
+	// path, or the path of a value it is within, is one of them. A * segment matches any field name or
+	// list index, and a path may be prefixed by the name of a recording and a colon to only apply to it.
+	Allow []string
+	// ExecOptions are the options of the executor of the operations.
+	ExecOptions []execution.Option
+}
+
+// Replay executes recordings against schema and returns the diffs of the recordings whose response
+// changed, in the order of recordings. The error lists the changes which are not allowed, it is nil
+// when all the changes were expected.
+func Replay(schema *internal.Schema, recordings []Recording, opts Options) ([]Diff, error) {
+	executor := execution.NewExecutor(schema, opts.ExecOptions...)
+	var diffs []Diff
+	var unexpected []string
+	for i, recording := range recordings {
+		name := recording.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		data, errs := response(executor.Do(execution.Params{
+			Query:         recording.Query,
+			OperationName: recording.OperationName,
+			Variables:     recording.Variables,
+		}))
+		var changes []Change
+		compare(&changes, nil, recording.Data, data)
+		changes = append(changes, compareErrors(recording.Errors, errs)...)
+		if len(changes) == 0 {
+			continue
+		}
+		for i := range changes {
+			changes[i].Allowed = allowed(opts.Allow, name, changes[i].Path)
+			if !changes[i].Allowed {
+				unexpected = append(unexpected, name+": "+changes[i].String())
+			}
+		}
+		diffs = append(diffs, Diff{Recording: name, Changes: changes})
+	}
+	if len(unexpected) > 0 {
+		return diffs, fmt.Errorf("replay: %d unexpected changes:\n%s", len(unexpected), strings.Join(unexpected, "\n"))
+	}
+	return diffs, nil
+}
+
+// compare appends the changes from old to new, the values at path, to changes.
+func compare(changes *[]Change, path []string, old, new interface{}) {
+	switch old := old.(type) {
+	case map[string]interface{}:
+		if new, ok := new.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(old)+len(new))
+			for key := range old {
+				keys = append(keys, key)
+			}
+			for key := range new {
+				if _, ok := old[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				compareEntry(changes, append(path[:len(path):len(path)], key), old, new, key)
+			}
+			return
+		}
+	case []interface{}:
+		if new, ok := new.([]interface{}); ok {
+			for i := 0; i < len(old) || i < len(new); i++ {
+				at := append(path[:len(path):len(path)], strconv.Itoa(i))
+				switch {
+				case i >= len(new):
+					*changes = append(*changes, Change{Kind: Removed, Path: strings.Join(at, "."), Old: old[i]})
+				case i >= len(old):
+					*changes = append(*changes, Change{Kind: Added, Path: strings.Join(at, "."), New: new[i]})
+				default:
+					compare(changes, at, old[i], new[i])
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Kind: Changed, Path: strings.Join(path, "."), Old: old, New: new})
+	}
+}
+
+func compareEntry(changes *[]Change, path []string, old, new map[string]interface{}, key string) {
+	oldValue, inOld := old[key]
+	newValue, inNew := new[key]
+	switch {
+	case !inNew:
+		*changes = append(*changes, Change{Kind: Removed, Path: strings.Join(path, "."), Old: oldValue})
+	case !inOld:
+		*changes = append(*changes, Change{Kind: Added, Path: strings.Join(path, "."), New: newValue})
+	default:
+		compare(changes, path, oldValue, newValue)
+	}
+}
+
+// compareErrors returns the errors added to old and removed from it, errors being told apart by their
+// message and path.
+func compareErrors(old, new []Error) []Change {
+	key := func(err Error) string {
+		return err.Message + "\x00" + errorPath(err)
+	}
+	count := make(map[string]int)
+	for _, err := range old {
+		count[key(err)]++
+	}
+	var changes []Change
+	for _, err := range new {
+		if count[key(err)] > 0 {
+			count[key(err)]--
+			continue
+		}
+		changes = append(changes, Change{Kind: Added, Path: errorPath(err), Error: err.Message})
+	}
+	for _, err := range old {
+		if count[key(err)] > 0 {
+			count[key(err)]--
+			changes = append(changes, Change{Kind: Removed, Path: errorPath(err), Error: err.Message})
+		}
+	}
+	return changes
+}
+
+func errorPath(err Error) string {
+	segments := make([]string, len(err.Path))
+	for i, segment := range err.Path {
+		segments[i] = fmt.Sprint(segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+// allowed reports whether one of the patterns of allow matches path, or a prefix of it, in the
+// recording name.
+func allowed(allow []string, name, path string) bool {
+	segments := strings.Split(path, ".")
+	for _, pattern := range allow {
+		if i := strings.Index(pattern, ":"); i >= 0 {
+			if pattern[:i] != name {
+				continue
+			}
+			pattern = pattern[i+1:]
+		}
+		patternSegments := strings.Split(pattern, ".")
+		if len(patternSegments) > len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range patternSegments {
+			if segment != "*" && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
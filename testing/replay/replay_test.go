@@ -0,0 +1,106 @@
+package replay_test
+
+import (
+	"flag"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/shyptr/graphql/testing/replay"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "record testdata/recordings.json again")
+
+type User struct {
+	Name  string `graphql:"name"`
+	Email string `graphql:"email"`
+}
+
+// NamedUser is the user of the second version of the schema, which no longer has an email.
+type NamedUser struct {
+	Name string `graphql:"name"`
+}
+
+// userSchema returns the first version of the schema, or the second one which no longer has the
+// email of the users, gives their full name and knows user 3.
+func userSchema(second bool) *internal.Schema {
+	build := schemabuilder.NewSchema()
+	if second {
+		users := map[int]*NamedUser{1: {Name: "Ada Lovelace"}, 2: {Name: "Alan Turing"}, 3: {Name: "Grace Hopper"}}
+		build.Object("User", NamedUser{})
+		build.Query().FieldFunc("user", func(args struct {
+			ID int `graphql:"id"`
+		}) (*NamedUser, error) {
+			if user, ok := users[args.ID]; ok {
+				return user, nil
+			}
+			return nil, fmt.Errorf("no user %d", args.ID)
+		})
+		return build.MustBuild()
+	}
+	users := map[int]*User{1: {Name: "Ada", Email: "ada@example.com"}, 2: {Name: "Alan", Email: "alan@example.com"}}
+	build.Object("User", User{})
+	build.Query().FieldFunc("user", func(args struct {
+		ID int `graphql:"id"`
+	}) (*User, error) {
+		if user, ok := users[args.ID]; ok {
+			return user, nil
+		}
+		return nil, fmt.Errorf("no user %d", args.ID)
+	})
+	return build.MustBuild()
+}
+
+func TestReplay(t *testing.T) {
+	if *update {
+		var recorder replay.Recorder
+		schema := userSchema(false)
+		recorder.Do("users", schema, execution.Params{Query: `{ first: user(id: 1) { name } second: user(id: 2) { name } }`})
+		recorder.Do("email", schema, execution.Params{
+			Query:     `query ($id: Int!) { user(id: $id) { email } }`,
+			Variables: map[string]interface{}{"id": float64(1)},
+		})
+		recorder.Do("missing", schema, execution.Params{Query: `{ user(id: 3) { name } }`})
+		if err := recorder.WriteFile("testdata/recordings.json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recordings, err := replay.Load("testdata/recordings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, recordings, 3)
+
+	// the schema the operations were recorded with responds the same
+	diffs, err := replay.Replay(userSchema(false), recordings, replay.Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// the removed field is a breaking change
+	diffs, err = replay.Replay(userSchema(true), recordings, replay.Options{Allow: []string{"users:*.name", "missing:user"}})
+	assert.Equal(t, []replay.Diff{
+		{Recording: "users", Changes: []replay.Change{
+			{Kind: replay.Changed, Path: "first.name", Old: "Ada", New: "Ada Lovelace", Allowed: true},
+			{Kind: replay.Changed, Path: "second.name", Old: "Alan", New: "Alan Turing", Allowed: true},
+		}},
+		{Recording: "email", Changes: []replay.Change{
+			{Kind: replay.Changed, Path: "", Old: map[string]interface{}{"user": map[string]interface{}{"email": "ada@example.com"}}},
+			{Kind: replay.Added, Error: `Cannot query field "email" on type "User".`},
+		}},
+		{Recording: "missing", Changes: []replay.Change{
+			{Kind: replay.Changed, Path: "user", New: map[string]interface{}{"name": "Grace Hopper"}, Allowed: true},
+			{Kind: replay.Removed, Path: "user", Error: "no user 3", Allowed: true},
+		}},
+	}, diffs)
+	assert.EqualError(t, err, `replay: 2 unexpected changes:
+email: changed the data: {"user":{"email":"ada@example.com"}} -> null
+email: added error "Cannot query field \"email\" on type \"User\"."`)
+
+	// the allowed changes only apply to the recording they are prefixed with
+	_, err = replay.Replay(userSchema(true), recordings[2:], replay.Options{Allow: []string{"users:user"}})
+	assert.Error(t, err)
+	_, err = replay.Replay(userSchema(true), recordings[2:], replay.Options{Allow: []string{"user"}})
+	assert.NoError(t, err)
+}
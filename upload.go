@@ -0,0 +1,160 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shyptr/graphql/execution"
+	"github.com/shyptr/graphql/schemabuilder"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultUploadMaxMemory is the number of bytes of the files of a multipart request kept in memory
+// unless WithUploadLimits sets it, the rest of the files is stored in temporary files.
+const DefaultUploadMaxMemory = 32 << 20
+
+// WithUploadLimits limits the multipart requests uploading files: maxMemory bytes of their files are
+// kept in memory and the rest is stored in temporary files, and requests whose body is larger than
+// maxSize bytes are answered with 413. A maxSize of 0 does not limit the size of the requests.
+func WithUploadLimits(maxMemory, maxSize int64) HandlerOption {
+	return func(h *Handler) {
+		h.UploadMaxMemory = maxMemory
+		h.UploadMaxSize = maxSize
+	}
+}
+
+// uploadError is an error of a multipart request, answered with status.
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string {
+	return e.msg
+}
+
+func badUpload(format string, args ...interface{}) *uploadError {
+	return &uploadError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// limitedBody fails the reads beyond limit bytes, and remembers it did.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// read one byte more than allowed to tell a body of limit bytes from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n, b.remaining, b.exceeded = int(b.remaining), 0, true
+		return n, fmt.Errorf("request body too large")
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// decodeMultipart decodes a request of the GraphQL multipart request specification, as sent by
+// apollo-upload-client: the operations field holds the request as JSON, with null for the variables
+// of the files, and the map field maps the name of every file part to the paths of the variables it is,
+// such as {"0": ["variables.file"], "1": ["variables.files.0", "variables.input.cover"]}. The files are
+// set in the variables as schemabuilder.Upload values, and returned to be closed after the request.
+func decodeMultipart(r *http.Request, param *execution.Params, strict bool, maxMemory, maxSize int64) ([]schemabuilder.Upload, error) {
+	var body *limitedBody
+	if maxSize > 0 {
+		body = &limitedBody{ReadCloser: r.Body, remaining: maxSize}
+		r.Body = body
+	}
+	if maxMemory <= 0 {
+		maxMemory = DefaultUploadMaxMemory
+	}
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		if body != nil && body.exceeded {
+			return nil, &uploadError{status: http.StatusRequestEntityTooLarge, msg: fmt.Sprintf("request body is larger than %d bytes", maxSize)}
+		}
+		return nil, badUpload("invalid multipart request: %s", err)
+	}
+	operations, ok := r.MultipartForm.Value["operations"]
+	if !ok {
+		return nil, badUpload("operations field is missing")
+	}
+	if err := decodeJSON(strings.NewReader(operations[0]), param, strict); err != nil {
+		return nil, badUpload("operations field is invalid JSON: %s", err)
+	}
+	var fileMap map[string][]string
+	if fields, ok := r.MultipartForm.Value["map"]; ok {
+		if err := json.Unmarshal([]byte(fields[0]), &fileMap); err != nil {
+			return nil, badUpload("map field is invalid JSON: %s", err)
+		}
+	}
+	var uploads []schemabuilder.Upload
+	for key, paths := range fileMap {
+		headers := r.MultipartForm.File[key]
+		if len(headers) == 0 {
+			closeUploads(uploads)
+			return nil, badUpload("file %q of the map is missing", key)
+		}
+		file, err := headers[0].Open()
+		if err != nil {
+			closeUploads(uploads)
+			return nil, &uploadError{status: http.StatusInternalServerError, msg: err.Error()}
+		}
+		upload := schemabuilder.Upload{File: file, Filename: headers[0].Filename, Size: headers[0].Size}
+		uploads = append(uploads, upload)
+		for _, path := range paths {
+			if err := setUpload(param, path, upload); err != nil {
+				closeUploads(uploads)
+				return nil, badUpload("file %q: %s", key, err)
+			}
+		}
+	}
+	return uploads, nil
+}
+
+// setUpload sets the variable at the dotted path, such as variables.input.files.0, to upload. The
+// variable must already be in the variables, usually as null.
+func setUpload(param *execution.Params, path string, upload schemabuilder.Upload) error {
+	keys := strings.Split(path, ".")
+	if keys[0] != "variables" || len(keys) < 2 {
+		return fmt.Errorf("path %q does not start with variables", path)
+	}
+	var value interface{} = param.Variables
+	for i, key := range keys[1:] {
+		last := i == len(keys)-2
+		switch parent := value.(type) {
+		case map[string]interface{}:
+			child, ok := parent[key]
+			if !ok {
+				return fmt.Errorf("path %q does not exist in the variables", path)
+			}
+			if last {
+				parent[key] = upload
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(parent) {
+				return fmt.Errorf("path %q does not exist in the variables", path)
+			}
+			if last {
+				parent[index] = upload
+			}
+			value = parent[index]
+		default:
+			return fmt.Errorf("path %q does not exist in the variables", path)
+		}
+	}
+	return nil
+}
+
+func closeUploads(uploads []schemabuilder.Upload) {
+	for _, upload := range uploads {
+		upload.File.Close()
+	}
+}
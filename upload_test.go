@@ -0,0 +1,92 @@
+package graphql_test
+
+import (
+	"bytes"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// postFiles posts a request of the GraphQL multipart request specification with operations, fileMap
+// and the files named after their keys in fileMap.
+func postFiles(handler http.Handler, operations, fileMap string, files map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("operations", operations)
+	writer.WriteField("map", fileMap)
+	for key, content := range files {
+		part, _ := writer.CreateFormFile(key, key+".txt")
+		part.Write([]byte(content))
+	}
+	writer.Close()
+	request := httptest.NewRequest(http.MethodPost, "/graphql", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHTTPHandler_Upload(t *testing.T) {
+	read := func(upload schemabuilder.Upload) string {
+		content, err := ioutil.ReadAll(upload.File)
+		if err != nil {
+			return err.Error()
+		}
+		return upload.Filename + ":" + string(content)
+	}
+	build := schemabuilder.NewSchema()
+	build.Mutation().FieldFunc("upload", func(args struct {
+		File schemabuilder.Upload `graphql:"file"`
+	}) string {
+		return read(args.File)
+	})
+	build.Mutation().FieldFunc("uploadMany", func(args struct {
+		Files []schemabuilder.Upload `graphql:"files"`
+	}) []string {
+		var contents []string
+		for _, file := range args.Files {
+			contents = append(contents, read(file))
+		}
+		return contents
+	})
+	handler := graphql.HTTPHandler(build.MustBuild())
+
+	recorder := postFiles(handler,
+		`{"query": "mutation ($file: Upload!) { upload(file: $file) }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`, map[string]string{"0": "hello"})
+	assert.JSONEq(t, `{"data": {"upload": "0.txt:hello"}}`, recorder.Body.String())
+
+	recorder = postFiles(handler,
+		`{"query": "mutation ($files: [Upload!]!) { uploadMany(files: $files) }", "variables": {"files": [null, null]}}`,
+		`{"a": ["variables.files.0"], "b": ["variables.files.1"]}`, map[string]string{"a": "first", "b": "second"})
+	assert.JSONEq(t, `{"data": {"uploadMany": ["a.txt:first", "b.txt:second"]}}`, recorder.Body.String())
+
+	recorder = postFiles(handler,
+		`{"query": "mutation ($files: [Upload!]!) { uploadMany(files: $files) }", "variables": {"files": [null]}}`,
+		`{"0": ["variables.files.1"]}`, map[string]string{"0": "hello"})
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "file \"0\": path \"variables.files.1\" does not exist in the variables"}]}`, recorder.Body.String())
+
+	recorder = postFiles(handler,
+		`{"query": "mutation ($file: Upload!) { upload(file: $file) }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "file \"0\" of the map is missing"}]}`, recorder.Body.String())
+
+	limited := graphql.HTTPHandler(build.MustBuild(), graphql.WithUploadLimits(1024, 1024))
+	recorder = postFiles(limited,
+		`{"query": "mutation ($file: Upload!) { upload(file: $file) }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`, map[string]string{"0": string(make([]byte, 2048))})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "request body is larger than 1024 bytes"}]}`, recorder.Body.String())
+
+	recorder = postFiles(limited,
+		`{"query": "mutation ($file: Upload!) { upload(file: $file) }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`, map[string]string{"0": "small"})
+	assert.JSONEq(t, `{"data": {"upload": "0.txt:small"}}`, recorder.Body.String())
+}
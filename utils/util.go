@@ -42,7 +42,7 @@ func GetVar(vars []*ast.VariableDefinition, name *ast.Name) *ast.VariableDefinit
 
 func GetOperation(ops []*ast.OperationDefinition, name string) *ast.OperationDefinition {
 	for _, op := range ops {
-		if op.Name.Name == name {
+		if op.Name != nil && op.Name.Name == name {
 			return op
 		}
 	}
@@ -0,0 +1,61 @@
+package graphql
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql/internal"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// VersionHeader is the header naming the API version of the requests to the handlers of VersionedHandler
+// whose path does not end with one.
+const VersionHeader = "X-API-Version"
+
+// VersionedHandler is HTTPHandler for APIs served in several versions on one endpoint, such as
+// /graphql/v1 and /graphql/v2:
+//
+//   http.Handle("/graphql/", graphql.VersionedHandler(map[string]*internal.Schema{
+//     "v1": v1.MustBuild(),
+//     "v2": v2.MustBuild(),
+//   }, graphql.WithRules(execution.NoDeprecated())))
+//
+// The version of a request is the last segment of its path when it names one of versions, otherwise
+// the X-API-Version header. Its operation is validated and executed against the schema of that
+// version only, the versions sharing the options of the handler. Schemas built from the same
+// registration functions may share their Go types.
+//
+// The version is the APIVersion of the Context of the request, for the logs and metrics of the
+// middlewares, and the "version" extension of its response. Requests without a known version are
+// answered with 400 and an error listing the versions.
+func VersionedHandler(versions map[string]*internal.Schema, opts ...HandlerOption) http.Handler {
+	h := HTTPHandler(nil, opts...).(*Handler)
+	h.Versions = versions
+	return h
+}
+
+// requestVersion returns the version of versions r asks for, by the suffix of its path or its header.
+func requestVersion(r *http.Request, versions map[string]*internal.Schema) (string, error) {
+	if version := path.Base(r.URL.Path); versions[version] != nil {
+		return version, nil
+	}
+	version := r.Header.Get(VersionHeader)
+	if version == "" {
+		return "", fmt.Errorf("the path %s does not end with an API version and the %s header is not set, the versions are %s",
+			r.URL.Path, VersionHeader, versionList(versions))
+	}
+	if versions[version] == nil {
+		return "", fmt.Errorf("unknown API version %q, the versions are %s", version, versionList(versions))
+	}
+	return version, nil
+}
+
+func versionList(versions map[string]*internal.Schema) string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
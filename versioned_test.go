@@ -0,0 +1,70 @@
+package graphql_test
+
+import (
+	"fmt"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type versionedUser struct {
+	Name  string `graphql:"name"`
+	Email string `graphql:"email"`
+}
+
+func TestVersionedHandler(t *testing.T) {
+	registerUser := func(build *schemabuilder.Schema) {
+		build.Object("User", versionedUser{})
+		build.Query().FieldFunc("user", func() versionedUser {
+			return versionedUser{Name: "alice", Email: "alice@example.com"}
+		})
+	}
+	v1 := schemabuilder.NewSchema()
+	registerUser(v1)
+	v2 := schemabuilder.NewSchema()
+	registerUser(v2)
+	v2.Query().FieldFunc("users", func() []versionedUser { return []versionedUser{{Name: "bob"}} })
+
+	var versions []string
+	handler := graphql.VersionedHandler(map[string]*internal.Schema{
+		"v1": v1.MustBuild(),
+		"v2": v2.MustBuild(),
+	}, graphql.WithMiddleware(func(ctx *graphql.Context) {
+		ctx.Next()
+		versions = append(versions, ctx.APIVersion)
+	}))
+	post := func(target, version, query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(fmt.Sprintf(`{"query": %q}`, query)))
+		if version != "" {
+			request.Header.Set(graphql.VersionHeader, version)
+		}
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := post("/graphql/v2", "", "{ user { name } users { name } }")
+	assert.JSONEq(t, `{"data": {"user": {"name": "alice"}, "users": [{"name": "bob"}]}, "extensions": {"version": "v2"}}`, recorder.Body.String())
+
+	// the field is only in the schema of v2
+	recorder = post("/graphql/v1", "", "{ users { name } }")
+	assert.JSONEq(t, `{"errors": [{"message": "Cannot query field \"users\" on type \"Query\". Did you mean \"user\"?", "locations": [{"line": 1, "column": 3}]}], "extensions": {"version": "v1"}}`, recorder.Body.String())
+
+	// the header names the version when the path does not
+	recorder = post("/graphql", "v1", "{ user { email } }")
+	assert.JSONEq(t, `{"data": {"user": {"email": "alice@example.com"}}, "extensions": {"version": "v1"}}`, recorder.Body.String())
+	assert.Equal(t, []string{"v2", "v1", "v1"}, versions)
+
+	recorder = post("/graphql/v3", "", "{ user { name } }")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "the path /graphql/v3 does not end with an API version and the X-API-Version header is not set, the versions are v1, v2"}]}`, recorder.Body.String())
+
+	recorder = post("/graphql", "v3", "{ user { name } }")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "unknown API version \"v3\", the versions are v1, v2"}]}`, recorder.Body.String())
+}
@@ -1,6 +1,7 @@
 package graphql
 
 import (
+	"bytes"
 	context2 "context"
 	"encoding/json"
 	"errors"
@@ -8,38 +9,100 @@ import (
 	errors2 "github.com/shyptr/graphql/errors"
 	"github.com/shyptr/graphql/execution"
 	"github.com/shyptr/graphql/internal"
+	"github.com/shyptr/graphql/internal/clock"
 	"github.com/shyptr/graphql/schemabuilder"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"gocloud.dev/pubsub"
 )
 
+// SubOption configures the handler returned by HTTPSubHandler.
+type SubOption func(*httpSubHandler)
+
+// WithKeepAlive sends a keep-alive message and a ping to every connection each interval,
+// and closes the connections which sent nothing, not even a pong, for longer than timeout.
+func WithKeepAlive(interval, timeout time.Duration) SubOption {
+	return func(h *httpSubHandler) {
+		h.keepAlive, h.keepAliveTimeout = interval, timeout
+	}
+}
+
+// WithMaxSubscriptions limits the number of subscriptions running at the same time on a connection,
+// starting one more is answered with an error message.
+func WithMaxSubscriptions(max int) SubOption {
+	return func(h *httpSubHandler) {
+		h.maxSubscriptions = max
+	}
+}
+
+// WithReplay resumes subscriptions started with a lastEventId in their payload, replaying the
+// events published after that one before the new events.
+//
+// Every event sent to a subscription carries its id in the eventId extension of the response.
+// The id is the "id" metadata of the pubsub message when it is a number, so that ids are
+// still meaningful after a restart, otherwise the events are numbered in the order they are received.
+func WithReplay(replayer Replayer) SubOption {
+	return func(h *httpSubHandler) {
+		h.replayer = replayer
+	}
+}
+
+// WithClock replaces the clock used for keep-alives, it is meant for tests.
+func WithClock(clock Clock) SubOption {
+	return func(h *httpSubHandler) {
+		h.clock = clock
+	}
+}
+
+// Replayer is implemented by event sources which keep the events they published.
+type Replayer interface {
+	// Replay returns the events published after the event fromID, in order.
+	Replay(fromID uint64) ([]SubscriptionEvent, error)
+}
+
+// SubscriptionEvent is an event replayed to a subscription.
+type SubscriptionEvent struct {
+	ID      uint64
+	Payload []byte
+}
+
+// Clock is the time source of the keep-alives, see WithClock.
+type Clock = clock.Clock
+
 // HTTPSubHandler implements the handler required for executing the graphql subscriptions
-func HTTPSubHandler(schema *internal.Schema, s *pubsub.Subscription) (http.Handler, func()) {
+func HTTPSubHandler(schema *internal.Schema, s *pubsub.Subscription, opts ...SubOption) (http.Handler, func()) {
 	source := make(chan *event)
 	sessions := &sessions{
 		data:  map[string][]chan *event{},
 		chans: map[string][]chan struct{}{},
 	}
-	return &httpSubHandler{
-			Handler: Handler{
-				Schema:   schema,
-				Executor: &execution.Executor{},
-			},
-			qmHandler: HTTPHandler(schema),
-			upgrader:  &websocket.Upgrader{},
-			source:    source,
-			sessions:  sessions,
-		}, func() {
-			go startListening(s, source, func() {
-				exit(sessions)
-			})
-			go listenSource(source, sessions)
-		}
+	h := &httpSubHandler{
+		Handler: Handler{
+			Schema:   schema,
+			Executor: &execution.Executor{},
+		},
+		qmHandler: HTTPHandler(schema),
+		upgrader:  &websocket.Upgrader{},
+		source:    source,
+		sessions:  sessions,
+		clock:     clock.Real,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, func() {
+		go startListening(s, source, func() {
+			exit(sessions)
+		})
+		go listenSource(source, sessions)
+	}
 }
 
 func listenSource(events chan *event, ss *sessions) {
@@ -55,6 +118,7 @@ func listenSource(events chan *event, ss *sessions) {
 }
 
 func startListening(s *pubsub.Subscription, source chan<- *event, cancel func()) {
+	var seq uint64
 	for {
 		msg, err := s.Receive(context2.Background())
 		if err != nil {
@@ -64,7 +128,13 @@ func startListening(s *pubsub.Subscription, source chan<- *event, cancel func())
 		}
 		msg.Ack()
 
+		if id, err := strconv.ParseUint(msg.Metadata["id"], 10, 64); err == nil {
+			seq = id
+		} else {
+			seq++
+		}
 		source <- &event{
+			id:      seq,
 			payload: msg.Body,
 			typ:     msg.Metadata["type"],
 		}
@@ -77,9 +147,16 @@ type httpSubHandler struct {
 	upgrader  *websocket.Upgrader
 	source    chan *event
 	sessions  *sessions
+
+	keepAlive        time.Duration
+	keepAliveTimeout time.Duration
+	maxSubscriptions int
+	replayer         Replayer
+	clock            Clock
 }
 
 type event struct {
+	id      uint64
 	typ     string
 	payload []byte
 }
@@ -100,6 +177,8 @@ type gqlPayload struct {
 	Query     string                 `json:"query"`
 	Variables map[string]interface{} `json:"variables"`
 	OpName    string                 `json:"operationName"`
+	// LastEventID is the id of the last event received before reconnecting.
+	LastEventID *uint64 `json:"lastEventId"`
 }
 
 func (h *httpSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -133,6 +212,21 @@ func (h *httpSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	conn := &webConn{conn: con}
+	conn.seen(h.clock.Now())
+	con.SetPongHandler(func(string) error {
+		conn.seen(h.clock.Now())
+		return nil
+	})
+	if h.keepAlive > 0 {
+		// the ticks start before the ack, so that no keep-alive is missed by a client which got it
+		ticks, stop := h.clock.Tick(h.keepAlive)
+		done := make(chan struct{})
+		defer func() {
+			stop()
+			close(done)
+		}()
+		go h.keepConnAlive(conn, ticks, done)
+	}
 	if msg.Type != "connection_init" {
 		if err := writeResponse(conn, "connection_error", "", nil, errors.New("expected init message")); err != nil {
 			fmt.Println(err)
@@ -143,20 +237,30 @@ func (h *httpSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(err)
 		return
 	}
+	active := &activeSubscriptions{ids: map[string]int{}}
 loop:
 	for {
 		var data wsMessage
 		if err := con.ReadJSON(&data); err != nil {
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+			default:
+				// the connection is closed
+				fmt.Println(err)
+				return
+			}
 			if err := writeResponse(conn, "connection_error", "", nil, err); err != nil {
 				fmt.Println(err)
 				return
 			}
 			fmt.Println(err)
+			continue
 		}
+		conn.seen(h.clock.Now())
 		switch data.Type {
 		case "start":
 			var gql gqlPayload
-			if err := json.Unmarshal(data.Payload, &gql); err != nil {
+			if err := decodeJSON(bytes.NewReader(data.Payload), &gql, false); err != nil {
 				if err := writeResponse(conn, "connection_error", "", nil, err); err != nil {
 					fmt.Println(err)
 					return
@@ -171,7 +275,8 @@ loop:
 					return
 				}
 				fmt.Println(err)
-				return
+				// the error only ends this operation
+				continue
 			}
 			schema := h.Schema.Subscription
 			//if err := validation.Validate(h.Schema, query, gql.Variables, 50); err != nil {
@@ -182,14 +287,22 @@ loop:
 			//	fmt.Println(err)
 			//	return
 			//}
-			_, selectionSet, err := execution.ApplySelectionSet(h.Schema, query, "subscription", gql.Variables)
+			_, selectionSet, err := h.Executor.ApplySelectionSet(h.Schema, query, gql.OpName, gql.Variables)
 			if err != nil {
 				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
 					fmt.Println(er)
 					return
 				}
 				fmt.Println(err)
-				return
+				continue
+			}
+			if !active.start(data.Id, len(selectionSet.Selections), h.maxSubscriptions) {
+				err := fmt.Errorf("too many subscriptions, at most %d can run on a connection", h.maxSubscriptions)
+				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
+					fmt.Println(er)
+					return
+				}
+				continue
 			}
 			for _, v := range selectionSet.Selections {
 				end := make(chan struct{}, 1)
@@ -197,10 +310,17 @@ loop:
 					Selections: []*internal.Selection{v},
 					Fragments:  selectionSet.Fragments,
 				}
-				go func(conn *webConn, data *wsMessage, schema internal.Type, query *internal.SelectionSet, end chan struct{}, w http.ResponseWriter, r *http.Request) {
-					if err := h.serveHTTP(conn, *data, schema, query, end, w, r); err != nil {
+				// the subscription receives the events published once its start message is handled
+				sess := make(chan *event)
+				h.sessions.Lock()
+				h.sessions.data[data.Id] = append(h.sessions.data[data.Id], sess)
+				h.sessions.chans[data.Id] = append(h.sessions.chans[data.Id], end)
+				h.sessions.Unlock()
+				go func(conn *webConn, data *wsMessage, schema internal.Type, query *internal.SelectionSet, sess chan *event, end chan struct{}, w http.ResponseWriter, r *http.Request) {
+					if err := h.serveHTTP(conn, *data, schema, query, gql.LastEventID, sess, end, w, r); err != nil {
 						fmt.Println("Id:", data.Id, ": terminated: ", err)
 					}
+					active.stop(data.Id)
 					h.sessions.Lock()
 					if _, ok := h.sessions.data[data.Id]; ok {
 						if err := writeResponse(conn, "complete", data.Id, nil, nil); err != nil {
@@ -211,7 +331,7 @@ loop:
 					delete(h.sessions.data, data.Id)
 					delete(h.sessions.chans, data.Id)
 					h.sessions.Unlock()
-				}(conn, &data, schema, modQuery, end, w, r)
+				}(conn, &data, schema, modQuery, sess, end, w, r)
 			}
 		case "stop":
 			h.sessions.RLock()
@@ -242,22 +362,87 @@ func exit(ss *sessions) {
 	ss.RUnlock()
 }
 
+// activeSubscriptions counts the subscriptions running on a connection.
+type activeSubscriptions struct {
+	sync.Mutex
+	// ids maps the id of a subscription to the number of its root fields still running
+	ids map[string]int
+}
+
+// start records the subscription id running fields root fields, unless max subscriptions are running already.
+func (a *activeSubscriptions) start(id string, fields int, max int) bool {
+	a.Lock()
+	defer a.Unlock()
+	if _, ok := a.ids[id]; !ok && max > 0 && len(a.ids) >= max {
+		return false
+	}
+	a.ids[id] += fields
+	return true
+}
+
+// stop records that one of the root fields of the subscription id stopped.
+func (a *activeSubscriptions) stop(id string) {
+	a.Lock()
+	defer a.Unlock()
+	if a.ids[id]--; a.ids[id] <= 0 {
+		delete(a.ids, id)
+	}
+}
+
+// keepConnAlive sends a keep-alive message and a ping on every tick, until the connection
+// is done or was silent for longer than the keep-alive timeout.
+func (h *httpSubHandler) keepConnAlive(conn *webConn, ticks <-chan time.Time, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticks:
+		}
+		if h.keepAliveTimeout > 0 && h.clock.Now().Sub(conn.lastSeen()) > h.keepAliveTimeout {
+			fmt.Println("closing silent websocket connection")
+			conn.conn.Close()
+			return
+		}
+		if err := writeResponse(conn, "ka", "", nil, nil); err != nil {
+			return
+		}
+		if err := conn.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+			return
+		}
+	}
+}
+
 type webConn struct {
 	sync.Mutex
 	conn *websocket.Conn
+	// seenAt is the time in unix nanoseconds the client last sent something
+	seenAt int64
+}
+
+func (w *webConn) seen(t time.Time) {
+	atomic.StoreInt64(&w.seenAt, t.UnixNano())
+}
+
+func (w *webConn) lastSeen() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&w.seenAt))
 }
 
 func writeResponse(w *webConn, typ, id string, r interface{}, er error) error {
+	return writeEvent(w, typ, id, r, er, nil)
+}
+
+// writeEvent is writeResponse for a data message, which also writes the extensions of the response.
+func writeEvent(w *webConn, typ, id string, r interface{}, er error, extensions map[string]interface{}) error {
 	var payload []byte
 	var err error
 	if typ == "data" {
 		if er != nil {
-			payload, err = json.Marshal(Response{Data: r, Errors: errors2.MultiError{errors2.New(er.Error())}})
+			payload, err = json.Marshal(Response{Data: r, Errors: errors2.MultiError{errors2.New(er.Error())}, Extensions: extensions})
 			if err != nil {
 				return err
 			}
 		} else {
-			payload, err = json.Marshal(Response{Data: r, Errors: errors2.MultiError{}})
+			payload, err = json.Marshal(Response{Data: r, Errors: errors2.MultiError{}, Extensions: extensions})
 			if err != nil {
 				return err
 			}
@@ -280,13 +465,9 @@ func writeResponse(w *webConn, typ, id string, r interface{}, er error) error {
 	return nil
 }
 
-func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema internal.Type, query *internal.SelectionSet, end chan struct{}, w http.ResponseWriter, r *http.Request) error {
+func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema internal.Type, query *internal.SelectionSet, lastEventID *uint64,
+	sess chan *event, end chan struct{}, w http.ResponseWriter, r *http.Request) error {
 	sid := data.Id
-	sess := make(chan *event)
-	h.sessions.Lock()
-	h.sessions.data[sid] = append(h.sessions.data[sid], sess)
-	h.sessions.chans[sid] = append(h.sessions.chans[sid], end)
-	h.sessions.Unlock()
 
 	cls := func(ss *sessions, sid string) {
 		ss.Lock()
@@ -303,6 +484,43 @@ func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema interna
 		ss.Unlock()
 	}
 
+	// the ids of the events sent are increasing, the events received while replaying are only sent once
+	var lastID uint64
+	sent := lastEventID != nil
+	if sent {
+		lastID = *lastEventID
+	}
+	send := func(msg *event) error {
+		if sent && msg.id <= lastID {
+			return nil
+		}
+		lastID, sent = msg.id, true
+		res, errs := h.Executor.Execute(r.Context(), schema, &schemabuilder.Subscription{msg.payload}, query)
+		// a nil MultiError is not a nil error
+		var rer error
+		if len(errs) > 0 {
+			rer = errs
+		}
+		if err := writeEvent(conn, "data", data.Id, res, rer, map[string]interface{}{"eventId": msg.id}); err != nil {
+			return err
+		}
+		return rer
+	}
+
+	if lastEventID != nil && h.replayer != nil {
+		events, err := h.replayer.Replay(*lastEventID)
+		if err != nil {
+			cls(h.sessions, sid)
+			return err
+		}
+		for _, evt := range events {
+			if err := send(&event{id: evt.ID, payload: evt.Payload}); err != nil {
+				cls(h.sessions, sid)
+				return err
+			}
+		}
+	}
+
 	// Listening on usrChannel for any source event of subType
 	for msg := range sess {
 		select {
@@ -310,17 +528,7 @@ func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema interna
 			cls(h.sessions, sid)
 			return nil
 		default:
-			if err := func() error {
-				res, err := h.Executor.Execute(r.Context(), schema, &schemabuilder.Subscription{msg.payload}, query)
-				rer := err
-				if err := writeResponse(conn, "data", data.Id, res, rer); err != nil {
-					return err
-				}
-				if rer != nil {
-					return err
-				}
-				return nil
-			}(); err != nil {
+			if err := send(msg); err != nil {
 				cls(h.sessions, sid)
 				return err
 			}
@@ -0,0 +1,163 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/shyptr/graphql"
+	"github.com/shyptr/graphql/internal/clock"
+	"github.com/shyptr/graphql/schemabuilder"
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type replayer []graphql.SubscriptionEvent
+
+func (r replayer) Replay(fromID uint64) ([]graphql.SubscriptionEvent, error) {
+	var events []graphql.SubscriptionEvent
+	for _, evt := range r {
+		if evt.ID > fromID {
+			events = append(events, evt)
+		}
+	}
+	return events, nil
+}
+
+type wsMessage struct {
+	Type    string          `json:"type"`
+	Id      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func serveSubscriptions(t *testing.T, opts ...graphql.SubOption) (*pubsub.Topic, func() *websocket.Conn, func()) {
+	build := schemabuilder.NewSchema()
+	build.Query().FieldFunc("ping", func() string { return "pong" })
+	build.Subscription().FieldFunc("message", func(s schemabuilder.Subscription) string { return string(s.Payload) })
+	topic := mempubsub.NewTopic()
+	handler, start := graphql.HTTPSubHandler(build.MustBuild(), mempubsub.NewSubscription(topic, time.Minute), opts...)
+	start()
+	server := httptest.NewServer(handler)
+
+	connect := func() *websocket.Conn {
+		dialer := websocket.Dialer{Subprotocols: []string{"graphql-ws"}}
+		conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		assert.NoError(t, conn.WriteJSON(wsMessage{Type: "connection_init"}))
+		assert.Equal(t, "connection_ack", read(t, conn).Type)
+		return conn
+	}
+	return topic, connect, func() {
+		server.Close()
+		topic.Shutdown(context.Background())
+	}
+}
+
+func read(t *testing.T, conn *websocket.Conn) wsMessage {
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func subscribe(t *testing.T, conn *websocket.Conn, id string, payload string) {
+	assert.NoError(t, conn.WriteJSON(wsMessage{Type: "start", Id: id, Payload: json.RawMessage(payload)}))
+}
+
+func publish(t *testing.T, topic *pubsub.Topic, id string, body string) {
+	assert.NoError(t, topic.Send(context.Background(), &pubsub.Message{Body: []byte(body), Metadata: map[string]string{"id": id}}))
+}
+
+func TestHTTPSubHandler(t *testing.T) {
+	t.Run("keeps connections alive", func(t *testing.T) {
+		fake := clock.NewFake(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		_, connect, stop := serveSubscriptions(t, graphql.WithKeepAlive(10*time.Second, 25*time.Second), graphql.WithClock(fake))
+		defer stop()
+		conn := connect()
+		defer conn.Close()
+
+		fake.Advance(10 * time.Second)
+		assert.Equal(t, "ka", read(t, conn).Type)
+		// any message of the client counts as a sign of life
+		subscribe(t, conn, "1", `{"query": "subscription { unknown }"}`)
+		assert.Equal(t, "error", read(t, conn).Type)
+		fake.Advance(10 * time.Second)
+		assert.Equal(t, "ka", read(t, conn).Type)
+		fake.Advance(10 * time.Second)
+		assert.Equal(t, "ka", read(t, conn).Type)
+	})
+
+	t.Run("closes silent connections", func(t *testing.T) {
+		fake := clock.NewFake(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		_, connect, stop := serveSubscriptions(t, graphql.WithKeepAlive(10*time.Second, 25*time.Second), graphql.WithClock(fake))
+		defer stop()
+		conn := connect()
+		defer conn.Close()
+
+		fake.Advance(10 * time.Second)
+		fake.Advance(20 * time.Second)
+		var err error
+		for err == nil {
+			var msg wsMessage
+			err = conn.ReadJSON(&msg)
+		}
+		if netErr, ok := err.(net.Error); ok {
+			assert.False(t, netErr.Timeout(), err.Error())
+		}
+	})
+
+	t.Run("limits the subscriptions of a connection", func(t *testing.T) {
+		topic, connect, stop := serveSubscriptions(t, graphql.WithMaxSubscriptions(1))
+		defer stop()
+		conn := connect()
+		defer conn.Close()
+
+		subscribe(t, conn, "1", `{"query": "subscription { message }"}`)
+		subscribe(t, conn, "2", `{"query": "subscription { message }"}`)
+		msg := read(t, conn)
+		assert.Equal(t, wsMessage{Type: "error", Id: "2",
+			Payload: json.RawMessage(`{"error":"too many subscriptions, at most 1 can run on a connection"}`)}, msg)
+
+		publish(t, topic, "7", "hello")
+		msg = read(t, conn)
+		assert.Equal(t, "1", msg.Id)
+		assert.JSONEq(t, `{"data":{"message":"hello"},"extensions":{"eventId":7}}`, string(msg.Payload))
+
+		// a stopped subscription makes room for another one once it completed
+		assert.NoError(t, conn.WriteJSON(wsMessage{Type: "stop", Id: "1"}))
+		publish(t, topic, "8", "bye")
+		assert.Equal(t, wsMessage{Type: "complete", Id: "1"}, read(t, conn))
+		subscribe(t, conn, "3", `{"query": "subscription { message }"}`)
+		subscribe(t, conn, "4", `{"query": "subscription { message }"}`)
+		assert.Equal(t, "4", read(t, conn).Id)
+	})
+
+	t.Run("resumes subscriptions", func(t *testing.T) {
+		topic, connect, stop := serveSubscriptions(t, graphql.WithReplay(replayer{
+			{ID: 1, Payload: []byte("one")},
+			{ID: 2, Payload: []byte("two")},
+			{ID: 3, Payload: []byte("three")},
+		}))
+		defer stop()
+		conn := connect()
+		defer conn.Close()
+
+		subscribe(t, conn, "1", `{"query": "subscription { message }", "lastEventId": 1}`)
+		assert.JSONEq(t, `{"data":{"message":"two"},"extensions":{"eventId":2}}`, string(read(t, conn).Payload))
+		assert.JSONEq(t, `{"data":{"message":"three"},"extensions":{"eventId":3}}`, string(read(t, conn).Payload))
+
+		// events sent already are skipped
+		publish(t, topic, "3", "three")
+		publish(t, topic, "4", "four")
+		assert.JSONEq(t, `{"data":{"message":"four"},"extensions":{"eventId":4}}`, string(read(t, conn).Payload))
+	})
+}